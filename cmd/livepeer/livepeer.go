@@ -122,6 +122,16 @@ func parseLivepeerConfig() starter.LivepeerConfig {
 	cfg.VerifierPath = flag.String("verifierPath", *cfg.VerifierPath, "Path to verifier shared volume")
 	cfg.LocalVerify = flag.Bool("localVerify", *cfg.LocalVerify, "Set to true to enable local verification i.e. pixel count and signature verification.")
 	cfg.HttpIngest = flag.Bool("httpIngest", *cfg.HttpIngest, "Set to true to enable HTTP ingest")
+	cfg.SrtAddr = flag.String("srtAddr", *cfg.SrtAddr, "UDP address to bind for SRT ingest; SRT ingest is disabled if not set")
+	cfg.SrtLatency = flag.Duration("srtLatency", *cfg.SrtLatency, "Receiver latency of SRT ingest connections, during which lost packets are recovered. Callers requesting a higher latency use theirs")
+	cfg.SrtPassphrase = flag.String("srtPassphrase", *cfg.SrtPassphrase, "Passphrase of SRT ingest connections, which must be encrypted with it if set. Encrypted connections are refused if not set")
+	cfg.WhipPublicIPs = flag.String("whipPublicIPs", *cfg.WhipPublicIPs, "Comma-separated public IPs advertised to WHIP publishers, e.g. if the node is behind NAT")
+	cfg.LLHLSPartTarget = flag.Duration("llhlsPartTarget", *cfg.LLHLSPartTarget, "Duration of the parts of the LL-HLS playlists of the streams that don't set their own llhlsPartTarget in the auth webhook, e.g. 500ms; these streams are segmented at this length instead of every 2s, so their keyframe interval should not exceed it, and they send 2-10x more segments to orchestrators, each with its own round trip and payment tickets. LL-HLS is disabled by default if not set")
+	cfg.DVRWindow = flag.Duration("dvrWindow", *cfg.DVRWindow, "Length of the DVR window of the live playlists, e.g. 2h, unless overridden by the auth webhook; segments are kept in the object store for the length of the window. DVR is disabled if not set")
+	cfg.HLSKeyProvider = flag.String("hlsKeyProvider", *cfg.HLSKeyProvider, "URL of the key server, or directory of the keys for testing, that the output segments are encrypted with using AES-128. Segments are not encrypted if not set")
+	cfg.HLSKeyURL = flag.String("hlsKeyUrl", *cfg.HLSKeyURL, "Base URL at which the keys of the -hlsKeyProvider directory are served to players; keys are listed with their file URL if not set")
+	cfg.HLSKeyRotation = flag.Int("hlsKeyRotation", *cfg.HLSKeyRotation, "Number of segments between the rotations of the key of a stream; keys are not rotated if not set")
+	cfg.ThumbnailInterval = flag.Int("thumbnailInterval", *cfg.ThumbnailInterval, "Number of source segments between the JPEG thumbnails of a stream, unless overridden by the auth webhook; thumbnails are disabled if not set")
 
 	// Broadcaster's Selection Algorithm
 	cfg.OrchAddr = flag.String("orchAddr", *cfg.OrchAddr, "Comma-separated list of orchestrators to connect to")
@@ -132,6 +142,20 @@ func parseLivepeerConfig() starter.LivepeerConfig {
 	cfg.SelectStakeWeight = flag.Float64("selectStakeWeight", *cfg.SelectStakeWeight, "Weight of the stake factor in the orchestrator selection algorithm")
 	cfg.SelectPriceWeight = flag.Float64("selectPriceWeight", *cfg.SelectPriceWeight, "Weight of the price factor in the orchestrator selection algorithm")
 	cfg.SelectPriceExpFactor = flag.Float64("selectPriceExpFactor", *cfg.SelectPriceExpFactor, "Expresses how significant a small change of price is for the selection algorithm; default 100")
+	cfg.OrchSelector = flag.String("orchSelector", *cfg.OrchSelector, "Session selector used for orchestrator selection; 'minls' selects on the latency score of the last segment, 'latency' selects on a tail percentile of the decayed latency and success history, 'sticky' consistently hashes the manifest ID onto the known orchestrators so that a stream keeps its orchestrator across gateways")
+	cfg.SelectLatencyPercentile = flag.Float64("selectLatencyPercentile", *cfg.SelectLatencyPercentile, "Percentile of the latency history used for orchestrator selection when -orchSelector=latency; default 0.9")
+	cfg.HedgeSegmentFraction = flag.Float64("hedgeSegmentFraction", *cfg.HedgeSegmentFraction, "Fraction of the segment duration after which a segment not transcoded yet is also submitted to a backup orchestrator and the first result is used; 0 disables hedging")
+	cfg.SegmentRetryQueueSize = flag.Int("segmentRetryQueueSize", *cfg.SegmentRetryQueueSize, "Maximum number of segments that failed after -maxAttempts and are retried with other orchestrators in the background while they are in the playlist window; 0 disables retries")
+	cfg.OrchInfoCacheTTL = flag.Duration("orchInfoCacheTTL", *cfg.OrchInfoCacheTTL, "Maximum time a spare info of an orchestrator is cached for the next discovery, spare infos are used once and refreshed in the background; 0 disables the cache")
+	cfg.MaxStreamSpend = flag.String("maxStreamSpend", *cfg.MaxStreamSpend, "Maximum amount in wei spent on transcoding a stream; unlimited if not set")
+	cfg.MaxAccountDailySpend = flag.String("maxAccountDailySpend", *cfg.MaxAccountDailySpend, "Maximum amount in wei spent per day on transcoding the streams of an account ID returned by the auth webhook; unlimited if not set")
+	cfg.MaxDailySpend = flag.String("maxDailySpend", *cfg.MaxDailySpend, "Maximum amount in wei spent per day on transcoding all streams; unlimited if not set")
+	cfg.BudgetExhaustedAction = flag.String("budgetExhaustedAction", *cfg.BudgetExhaustedAction, "Behaviour once a spending budget is exhausted; 'stop' stops the stream, 'source' only publishes the source rendition, 'webhook' keeps transcoding and only notifies -budgetWebhookUrl")
+	cfg.BudgetWebhookURL = flag.String("budgetWebhookUrl", *cfg.BudgetWebhookURL, "URL notified with a POST request whenever a spending budget is exhausted")
+	cfg.EventWebhookURLs = flag.String("eventWebhookUrls", *cfg.EventWebhookURLs, "Comma-separated URLs notified with a POST request of the lifecycle events of the streams, in order for each stream")
+	cfg.EventWebhookSecret = flag.String("eventWebhookSecret", *cfg.EventWebhookSecret, "Secret that the requests to -eventWebhookUrls are signed with using HMAC-SHA256; requests are not signed if not set")
+	cfg.EventWebhookEvents = flag.String("eventWebhookEvents", *cfg.EventWebhookEvents, "Comma-separated events sent to -eventWebhookUrls; all events are sent if not set")
+	cfg.SegmentProgressSecret = flag.String("segmentProgressSecret", *cfg.SegmentProgressSecret, "Bearer token that clients of /stream/<manifestID>/events send to receive the orchestrators and fees of the stream, which are left out otherwise")
 	cfg.OrchPerfStatsURL = flag.String("orchPerfStatsUrl", *cfg.OrchPerfStatsURL, "URL of Orchestrator Performance Stream Tester")
 	cfg.Region = flag.String("region", *cfg.Region, "Region in which a broadcaster is deployed; used to select the region while using the orchestrator's performance stats")
 	cfg.MaxPricePerUnit = flag.String("maxPricePerUnit", *cfg.MaxPricePerUnit, "The maximum transcoding price per 'pixelsPerUnit' a broadcaster is willing to accept. If not set explicitly, broadcaster is willing to accept ANY price. Can be specified in wei or a custom currency in the format <price><currency> (e.g. 0.50USD). When using a custom currency, a corresponding price feed must be configured with -priceFeedAddr")
@@ -146,6 +170,8 @@ func parseLivepeerConfig() starter.LivepeerConfig {
 	cfg.TranscodingOptions = flag.String("transcodingOptions", *cfg.TranscodingOptions, "Transcoding options for broadcast job, or path to json config")
 	cfg.MaxAttempts = flag.Int("maxAttempts", *cfg.MaxAttempts, "Maximum transcode attempts")
 	cfg.MaxSessions = flag.String("maxSessions", *cfg.MaxSessions, "Maximum number of concurrent transcoding sessions for Orchestrator or 'auto' for dynamic limit, maximum number of RTMP streams for Broadcaster, or maximum capacity for transcoder.")
+	cfg.AdmissionQueueSize = flag.Int("admissionQueueSize", *cfg.AdmissionQueueSize, "Number of new sessions that wait for a session slot when the Orchestrator is at -maxSessions, for up to the duration of their segment, instead of being rejected")
+	cfg.DrainTimeout = flag.Duration("drainTimeout", *cfg.DrainTimeout, "Max time a draining Orchestrator or Transcoder waits for its running sessions to end before it exits. Nodes drain on SIGTERM or a request to the /drain CLI endpoint")
 	cfg.CurrentManifest = flag.Bool("currentManifest", *cfg.CurrentManifest, "Expose the currently active ManifestID as \"/stream/current.m3u8\"")
 	cfg.Nvidia = flag.String("nvidia", *cfg.Nvidia, "Comma-separated list of Nvidia GPU device IDs (or \"all\" for all available devices)")
 	cfg.Netint = flag.String("netint", *cfg.Netint, "Comma-separated list of NetInt device GUIDs (or \"all\" for all available devices)")
@@ -181,6 +207,7 @@ func parseLivepeerConfig() starter.LivepeerConfig {
 	cfg.AutoAdjustPrice = flag.Bool("autoAdjustPrice", *cfg.AutoAdjustPrice, "Enable/disable automatic price adjustments based on the overhead for redeeming tickets")
 	cfg.PricePerGateway = flag.String("pricePerGateway", *cfg.PricePerGateway, `json list of price per gateway or path to json config file. Example: {"broadcasters":[{"ethaddress":"address1","priceperunit":0.5,"currency":"USD","pixelsperunit":1000000000000},{"ethaddress":"address2","priceperunit":0.3,"currency":"USD","pixelsperunit":1000000000000}]}`)
 	cfg.PricePerBroadcaster = flag.String("pricePerBroadcaster", *cfg.PricePerBroadcaster, `json list of price per broadcaster or path to json config file. Example: {"broadcasters":[{"ethaddress":"address1","priceperunit":0.5,"currency":"USD","pixelsperunit":1000000000000},{"ethaddress":"address2","priceperunit":0.3,"currency":"USD","pixelsperunit":1000000000000}]}`)
+	cfg.PriorityClasses = flag.String("priorityClasses", *cfg.PriorityClasses, `json list of priority classes of gateways or path to json config file, with the share of -maxSessions reserved for each class and the max sessions and output pixels per second of each gateway. Example: {"classes":[{"name":"premium","priority":1,"reservedshare":0.5,"maxsessions":10,"maxpixelspersecond":1000000000,"ethaddresses":["address1","address2"]}]}`)
 	// Interval to poll for blocks
 	cfg.BlockPollingInterval = flag.Int("blockPollingInterval", *cfg.BlockPollingInterval, "Interval in seconds at which different blockchain event services poll for blocks")
 	// Redemption service
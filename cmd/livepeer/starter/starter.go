@@ -13,13 +13,16 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"os/user"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
+	srt "github.com/datarhei/gosrt"
 	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
@@ -85,6 +88,16 @@ type LivepeerConfig struct {
 	VerifierPath            *string
 	LocalVerify             *bool
 	HttpIngest              *bool
+	SrtAddr                 *string
+	SrtLatency              *time.Duration
+	SrtPassphrase           *string
+	WhipPublicIPs           *string
+	LLHLSPartTarget         *time.Duration
+	ThumbnailInterval       *int
+	DVRWindow               *time.Duration
+	HLSKeyProvider          *string
+	HLSKeyURL               *string
+	HLSKeyRotation          *int
 	Orchestrator            *bool
 	Transcoder              *bool
 	Gateway                 *bool
@@ -96,11 +109,27 @@ type LivepeerConfig struct {
 	SelectStakeWeight       *float64
 	SelectPriceWeight       *float64
 	SelectPriceExpFactor    *float64
+	OrchSelector            *string
+	SelectLatencyPercentile *float64
+	HedgeSegmentFraction    *float64
+	SegmentRetryQueueSize   *int
+	OrchInfoCacheTTL        *time.Duration
+	MaxStreamSpend          *string
+	MaxAccountDailySpend    *string
+	MaxDailySpend           *string
+	BudgetExhaustedAction   *string
+	BudgetWebhookURL        *string
+	EventWebhookURLs        *string
+	EventWebhookSecret      *string
+	EventWebhookEvents      *string
+	SegmentProgressSecret   *string
 	OrchPerfStatsURL        *string
 	Region                  *string
 	MaxPricePerUnit         *string
 	MinPerfScore            *float64
 	MaxSessions             *string
+	AdmissionQueueSize      *int
+	DrainTimeout            *time.Duration
 	CurrentManifest         *bool
 	Nvidia                  *string
 	Netint                  *string
@@ -128,6 +157,7 @@ type LivepeerConfig struct {
 	AutoAdjustPrice         *bool
 	PricePerGateway         *string
 	PricePerBroadcaster     *string
+	PriorityClasses         *string
 	BlockPollingInterval    *int
 	Redeemer                *bool
 	RedeemerAddr            *string
@@ -174,7 +204,23 @@ func DefaultLivepeerConfig() LivepeerConfig {
 	defaultSelectStakeWeight := 0.7
 	defaultSelectPriceWeight := 0.0
 	defaultSelectPriceExpFactor := 100.0
+	defaultOrchSelector := server.SelectorMinLS
+	defaultSelectLatencyPercentile := 0.9
+	defaultHedgeSegmentFraction := 0.0
+	defaultSegmentRetryQueueSize := 0
+	defaultOrchInfoCacheTTL := time.Duration(0)
+	defaultMaxStreamSpend := ""
+	defaultMaxAccountDailySpend := ""
+	defaultMaxDailySpend := ""
+	defaultBudgetExhaustedAction := server.BudgetActionStop
+	defaultBudgetWebhookURL := ""
+	defaultEventWebhookURLs := ""
+	defaultEventWebhookSecret := ""
+	defaultEventWebhookEvents := ""
+	defaultSegmentProgressSecret := ""
 	defaultMaxSessions := strconv.Itoa(10)
+	defaultAdmissionQueueSize := 0
+	defaultDrainTimeout := core.DrainTimeout
 	defaultOrchPerfStatsURL := ""
 	defaultRegion := ""
 	defaultMinPerfScore := 0.0
@@ -207,6 +253,7 @@ func DefaultLivepeerConfig() LivepeerConfig {
 	defaultAutoAdjustPrice := true
 	defaultPricePerGateway := ""
 	defaultPricePerBroadcaster := ""
+	defaultPriorityClasses := ""
 	defaultBlockPollingInterval := 5
 	defaultRedeemer := false
 	defaultRedeemerAddr := ""
@@ -219,6 +266,16 @@ func DefaultLivepeerConfig() LivepeerConfig {
 
 	// Ingest:
 	defaultHttpIngest := true
+	defaultSrtAddr := ""
+	defaultSrtLatency := srt.DefaultConfig().ReceiverLatency
+	defaultSrtPassphrase := ""
+	defaultWhipPublicIPs := ""
+	defaultLLHLSPartTarget := time.Duration(0)
+	defaultThumbnailInterval := 0
+	defaultDVRWindow := time.Duration(0)
+	defaultHLSKeyProvider := ""
+	defaultHLSKeyURL := ""
+	defaultHLSKeyRotation := 0
 
 	// Verification:
 	defaultLocalVerify := true
@@ -252,25 +309,41 @@ func DefaultLivepeerConfig() LivepeerConfig {
 		VerifierPath: &defaultVerifierPath,
 
 		// Transcoding:
-		Orchestrator:         &defaultOrchestrator,
-		Transcoder:           &defaultTranscoder,
-		Gateway:              &defaultGateway,
-		Broadcaster:          &defaultBroadcaster,
-		OrchSecret:           &defaultOrchSecret,
-		TranscodingOptions:   &defaultTranscodingOptions,
-		MaxAttempts:          &defaultMaxAttempts,
-		SelectRandWeight:     &defaultSelectRandWeight,
-		SelectStakeWeight:    &defaultSelectStakeWeight,
-		SelectPriceWeight:    &defaultSelectPriceWeight,
-		SelectPriceExpFactor: &defaultSelectPriceExpFactor,
-		MaxSessions:          &defaultMaxSessions,
-		OrchPerfStatsURL:     &defaultOrchPerfStatsURL,
-		Region:               &defaultRegion,
-		MinPerfScore:         &defaultMinPerfScore,
-		CurrentManifest:      &defaultCurrentManifest,
-		Nvidia:               &defaultNvidia,
-		Netint:               &defaultNetint,
-		TestTranscoder:       &defaultTestTranscoder,
+		Orchestrator:            &defaultOrchestrator,
+		Transcoder:              &defaultTranscoder,
+		Gateway:                 &defaultGateway,
+		Broadcaster:             &defaultBroadcaster,
+		OrchSecret:              &defaultOrchSecret,
+		TranscodingOptions:      &defaultTranscodingOptions,
+		MaxAttempts:             &defaultMaxAttempts,
+		SelectRandWeight:        &defaultSelectRandWeight,
+		SelectStakeWeight:       &defaultSelectStakeWeight,
+		SelectPriceWeight:       &defaultSelectPriceWeight,
+		SelectPriceExpFactor:    &defaultSelectPriceExpFactor,
+		OrchSelector:            &defaultOrchSelector,
+		SelectLatencyPercentile: &defaultSelectLatencyPercentile,
+		HedgeSegmentFraction:    &defaultHedgeSegmentFraction,
+		SegmentRetryQueueSize:   &defaultSegmentRetryQueueSize,
+		OrchInfoCacheTTL:        &defaultOrchInfoCacheTTL,
+		MaxStreamSpend:          &defaultMaxStreamSpend,
+		MaxAccountDailySpend:    &defaultMaxAccountDailySpend,
+		MaxDailySpend:           &defaultMaxDailySpend,
+		BudgetExhaustedAction:   &defaultBudgetExhaustedAction,
+		BudgetWebhookURL:        &defaultBudgetWebhookURL,
+		EventWebhookURLs:        &defaultEventWebhookURLs,
+		EventWebhookSecret:      &defaultEventWebhookSecret,
+		EventWebhookEvents:      &defaultEventWebhookEvents,
+		SegmentProgressSecret:   &defaultSegmentProgressSecret,
+		MaxSessions:             &defaultMaxSessions,
+		AdmissionQueueSize:      &defaultAdmissionQueueSize,
+		DrainTimeout:            &defaultDrainTimeout,
+		OrchPerfStatsURL:        &defaultOrchPerfStatsURL,
+		Region:                  &defaultRegion,
+		MinPerfScore:            &defaultMinPerfScore,
+		CurrentManifest:         &defaultCurrentManifest,
+		Nvidia:                  &defaultNvidia,
+		Netint:                  &defaultNetint,
+		TestTranscoder:          &defaultTestTranscoder,
 
 		// Onchain:
 		EthAcctAddr:             &defaultEthAcctAddr,
@@ -296,6 +369,7 @@ func DefaultLivepeerConfig() LivepeerConfig {
 		AutoAdjustPrice:         &defaultAutoAdjustPrice,
 		PricePerGateway:         &defaultPricePerGateway,
 		PricePerBroadcaster:     &defaultPricePerBroadcaster,
+		PriorityClasses:         &defaultPriorityClasses,
 		BlockPollingInterval:    &defaultBlockPollingInterval,
 		Redeemer:                &defaultRedeemer,
 		RedeemerAddr:            &defaultRedeemerAddr,
@@ -307,7 +381,17 @@ func DefaultLivepeerConfig() LivepeerConfig {
 		MetadataPublishTimeout:  &defaultMetadataPublishTimeout,
 
 		// Ingest:
-		HttpIngest: &defaultHttpIngest,
+		HttpIngest:        &defaultHttpIngest,
+		SrtAddr:           &defaultSrtAddr,
+		SrtLatency:        &defaultSrtLatency,
+		SrtPassphrase:     &defaultSrtPassphrase,
+		WhipPublicIPs:     &defaultWhipPublicIPs,
+		LLHLSPartTarget:   &defaultLLHLSPartTarget,
+		ThumbnailInterval: &defaultThumbnailInterval,
+		DVRWindow:         &defaultDVRWindow,
+		HLSKeyProvider:    &defaultHLSKeyProvider,
+		HLSKeyURL:         &defaultHLSKeyURL,
+		HLSKeyRotation:    &defaultHLSKeyRotation,
 
 		// Verification:
 		LocalVerify: &defaultLocalVerify,
@@ -346,6 +430,16 @@ func StartLivepeer(ctx context.Context, cfg LivepeerConfig) {
 		core.MaxSessions = intMaxSessions
 	}
 
+	if *cfg.AdmissionQueueSize < 0 {
+		glog.Exit("-admissionQueueSize must be greater than or equal to zero")
+	}
+	core.AdmissionQueueSize = *cfg.AdmissionQueueSize
+
+	if *cfg.DrainTimeout <= 0 {
+		glog.Exit("-drainTimeout must be greater than zero")
+	}
+	core.DrainTimeout = *cfg.DrainTimeout
+
 	if *cfg.Netint != "" && *cfg.Nvidia != "" {
 		glog.Exit("both -netint and -nvidia arguments specified, this is not supported")
 	}
@@ -545,6 +639,8 @@ func StartLivepeer(ctx context.Context, cfg LivepeerConfig) {
 	watcherErr := make(chan error)
 	serviceErr := make(chan error)
 	var timeWatcher *watchers.TimeWatcher
+	// Redemptions of the queued tickets are flushed before a draining orchestrator exits
+	var senderMonitor *pm.LocalSenderMonitor
 	if *cfg.Network == "offchain" {
 		glog.Infof("***Livepeer is in off-chain mode***")
 
@@ -804,6 +900,18 @@ func StartLivepeer(ctx context.Context, cfg LivepeerConfig) {
 				n.SetBasePrice(p.EthAddress, autoPrice)
 			}
 
+			priorityClasses, err := getPriorityClasses(*cfg.PriorityClasses)
+			if err != nil {
+				exit("Error parsing -priorityClasses: %v", err)
+			}
+			for _, c := range priorityClasses {
+				for _, addr := range c.EthAddresses {
+					n.SetPriorityClass(ethcommon.HexToAddress(addr), c.PriorityClass)
+				}
+				glog.Infof("Priority class %s priority=%d reservedShare=%v maxSessions=%d maxPixelsPerSecond=%d gateways=%d",
+					c.Name, c.Priority, c.ReservedShare, c.MaxSessions, c.MaxPixelsPerSecond, len(c.EthAddresses))
+			}
+
 			n.AutoSessionLimit = *cfg.MaxSessions == "auto"
 			n.AutoAdjustPrice = *cfg.AutoAdjustPrice
 
@@ -836,7 +944,8 @@ func StartLivepeer(ctx context.Context, cfg LivepeerConfig) {
 				}
 				sm = rc
 			} else {
-				sm = pm.NewSenderMonitor(smCfg, n.Eth, senderWatcher, timeWatcher, n.Database)
+				senderMonitor = pm.NewSenderMonitor(smCfg, n.Eth, senderWatcher, timeWatcher, n.Database)
+				sm = senderMonitor
 			}
 
 			// Start sender monitor
@@ -1045,6 +1154,12 @@ func StartLivepeer(ctx context.Context, cfg LivepeerConfig) {
 			glog.Error("Error creating object store driver: ", err)
 			return
 		}
+		// Segments that leave the DVR window are deleted from the object store
+		drivers.NodeStorage, err = core.WithFileDeletion(prepared, drivers.NodeStorage)
+		if err != nil {
+			glog.Error("Error creating object store driver: ", err)
+			return
+		}
 	}
 
 	if *cfg.Recordstore != "" {
@@ -1104,6 +1219,15 @@ func StartLivepeer(ctx context.Context, cfg LivepeerConfig) {
 			n.OrchestratorPool = dbOrchPoolCache
 		}
 
+		if *cfg.OrchInfoCacheTTL < 0 {
+			exit("-orchInfoCacheTTL must not be negative, provided %v", *cfg.OrchInfoCacheTTL)
+		}
+		if *cfg.OrchInfoCacheTTL > 0 {
+			glog.Infof("Caching orchestrator info for %v", *cfg.OrchInfoCacheTTL)
+			discovery.OrchInfoCache = discovery.NewOrchestratorInfoCache(*cfg.OrchInfoCacheTTL, n.Sender)
+			go discovery.OrchInfoCache.Run(ctx)
+		}
+
 		// Set up orchestrator discovery
 		if *cfg.OrchWebhookURL != "" {
 			whurl, err := validateURL(*cfg.OrchWebhookURL)
@@ -1166,6 +1290,157 @@ func StartLivepeer(ctx context.Context, cfg LivepeerConfig) {
 		// Set max transcode attempts. <=0 is OK; it just means "don't transcode"
 		server.MaxAttempts = *cfg.MaxAttempts
 
+		switch *cfg.OrchSelector {
+		case server.SelectorMinLS:
+		case server.SelectorSticky:
+			glog.Info("Using sticky selector, streams are consistently hashed onto the known orchestrators")
+		case server.SelectorLatency:
+			if *cfg.SelectLatencyPercentile <= 0 || *cfg.SelectLatencyPercentile > 1 {
+				exit("-selectLatencyPercentile must be in the range (0, 1], provided %v", *cfg.SelectLatencyPercentile)
+			}
+			glog.Infof("Using latency selector with percentile=%v", *cfg.SelectLatencyPercentile)
+			server.LatencySelectorPercentile = *cfg.SelectLatencyPercentile
+		default:
+			exit("-orchSelector must be one of '%v', '%v' or '%v', provided %v", server.SelectorMinLS, server.SelectorLatency, server.SelectorSticky, *cfg.OrchSelector)
+		}
+		server.SessionSelector = *cfg.OrchSelector
+
+		if *cfg.HedgeSegmentFraction < 0 {
+			exit("-hedgeSegmentFraction must not be negative, provided %v", *cfg.HedgeSegmentFraction)
+		}
+		if *cfg.HedgeSegmentFraction > 0 {
+			glog.Infof("Hedging segments not transcoded within %v of their duration", *cfg.HedgeSegmentFraction)
+		}
+		server.HedgeFraction = *cfg.HedgeSegmentFraction
+
+		if *cfg.SrtLatency <= 0 {
+			exit("-srtLatency must be positive, provided %v", *cfg.SrtLatency)
+		}
+		if n := len(*cfg.SrtPassphrase); n > 0 && (n < srt.MIN_PASSPHRASE_SIZE || n > srt.MAX_PASSPHRASE_SIZE) {
+			exit("-srtPassphrase must be between %d and %d characters long", srt.MIN_PASSPHRASE_SIZE, srt.MAX_PASSPHRASE_SIZE)
+		}
+		if *cfg.WhipPublicIPs != "" {
+			for _, ip := range strings.Split(*cfg.WhipPublicIPs, ",") {
+				if net.ParseIP(ip) == nil {
+					exit("-whipPublicIPs must be a comma-separated list of IPs, provided %v", *cfg.WhipPublicIPs)
+				}
+				server.WHIPPublicIPs = append(server.WHIPPublicIPs, ip)
+			}
+		}
+		if *cfg.LLHLSPartTarget != 0 {
+			if *cfg.LLHLSPartTarget < server.LLHLSMinPartTarget || *cfg.LLHLSPartTarget > server.LLHLSSegmentTarget/2 {
+				exit("-llhlsPartTarget must be between %v and %v, provided %v", server.LLHLSMinPartTarget, server.LLHLSSegmentTarget/2, *cfg.LLHLSPartTarget)
+			}
+			glog.Infof("Serving LL-HLS playlists with parts of %v", *cfg.LLHLSPartTarget)
+			server.LLHLSPartTarget = *cfg.LLHLSPartTarget
+		}
+		if *cfg.ThumbnailInterval < 0 {
+			exit("-thumbnailInterval must not be negative, provided %v", *cfg.ThumbnailInterval)
+		}
+		server.ThumbnailInterval = uint(*cfg.ThumbnailInterval)
+		if *cfg.DVRWindow < 0 {
+			exit("-dvrWindow must not be negative, provided %v", *cfg.DVRWindow)
+		}
+		if minDVRWindow := time.Duration(core.LIVE_LIST_LENGTH) * server.SegLen; *cfg.DVRWindow > 0 && *cfg.DVRWindow < minDVRWindow {
+			exit("-dvrWindow must be at least the length of the live playlists, %v, provided %v", minDVRWindow, *cfg.DVRWindow)
+		}
+		server.DVRWindow = *cfg.DVRWindow
+		if *cfg.HLSKeyProvider != "" {
+			if *cfg.LLHLSPartTarget != 0 {
+				exit("-hlsKeyProvider can't be used with -llhlsPartTarget, since LL-HLS parts are not encrypted")
+			}
+			if *cfg.HLSKeyRotation < 0 {
+				exit("-hlsKeyRotation must not be negative, provided %v", *cfg.HLSKeyRotation)
+			}
+			if strings.HasPrefix(*cfg.HLSKeyProvider, "http://") || strings.HasPrefix(*cfg.HLSKeyProvider, "https://") {
+				keyServerURL, err := validateURL(*cfg.HLSKeyProvider)
+				if err != nil {
+					exit("Error setting HLS key server URL err=%q", err)
+				}
+				glog.Infof("Encrypting segments with keys from key server url=%s", keyServerURL.Redacted())
+				server.HLSKeyProvider = core.NewHTTPKeyProvider(keyServerURL)
+			} else {
+				glog.Infof("Encrypting segments with keys from dir=%s", *cfg.HLSKeyProvider)
+				server.HLSKeyProvider = core.NewFileKeyProvider(*cfg.HLSKeyProvider, *cfg.HLSKeyURL)
+			}
+			server.HLSKeyRotation = uint(*cfg.HLSKeyRotation)
+		}
+
+		if *cfg.SegmentRetryQueueSize < 0 {
+			exit("-segmentRetryQueueSize must not be negative, provided %v", *cfg.SegmentRetryQueueSize)
+		}
+		if *cfg.SegmentRetryQueueSize > 0 {
+			glog.Infof("Retrying up to %v failed segments in the background", *cfg.SegmentRetryQueueSize)
+			server.SegmentRetries = server.NewSegmentRetryQueue(*cfg.SegmentRetryQueueSize)
+		}
+
+		server.OrchReputation = server.NewReputationStore()
+		if err := server.OrchReputation.Load(n.Database); err != nil {
+			exit("Error loading orchestrator reputation err=%q", err)
+		}
+		go server.OrchReputation.Run(ctx)
+
+		parseBudget := func(name, value string) *big.Rat {
+			if value == "" {
+				return nil
+			}
+			budget, ok := new(big.Rat).SetString(value)
+			if !ok || budget.Sign() <= 0 {
+				exit("-%v must be a positive amount in wei, provided %v", name, value)
+			}
+			return budget
+		}
+		streamBudget := parseBudget("maxStreamSpend", *cfg.MaxStreamSpend)
+		accountBudget := parseBudget("maxAccountDailySpend", *cfg.MaxAccountDailySpend)
+		dailyBudget := parseBudget("maxDailySpend", *cfg.MaxDailySpend)
+		budgetWebhookURL, err := validateURL(*cfg.BudgetWebhookURL)
+		if err != nil {
+			exit("Error setting budget webhook URL err=%q", err)
+		}
+		switch *cfg.BudgetExhaustedAction {
+		case server.BudgetActionStop, server.BudgetActionSource:
+		case server.BudgetActionWebhook:
+			if budgetWebhookURL == nil {
+				exit("-budgetWebhookUrl must be set for -budgetExhaustedAction=%v", server.BudgetActionWebhook)
+			}
+		default:
+			exit("-budgetExhaustedAction must be one of '%v', '%v' or '%v', provided %v", server.BudgetActionStop, server.BudgetActionSource, server.BudgetActionWebhook, *cfg.BudgetExhaustedAction)
+		}
+		if streamBudget != nil || accountBudget != nil || dailyBudget != nil {
+			glog.Infof("Enforcing spending budgets stream=%v account=%v daily=%v action=%v", *cfg.MaxStreamSpend, *cfg.MaxAccountDailySpend, *cfg.MaxDailySpend, *cfg.BudgetExhaustedAction)
+			server.Budgets = server.NewBudgetTracker(streamBudget, accountBudget, dailyBudget, *cfg.BudgetExhaustedAction, budgetWebhookURL)
+			if err := server.Budgets.Load(n.Database); err != nil {
+				exit("Error loading budget spending err=%q", err)
+			}
+			go server.Budgets.Run(ctx)
+		}
+
+		if *cfg.EventWebhookURLs != "" {
+			var eventWebhookURLs []*url.URL
+			for _, u := range strings.Split(*cfg.EventWebhookURLs, ",") {
+				eventWebhookURL, err := validateURL(strings.TrimSpace(u))
+				if err != nil || eventWebhookURL == nil {
+					exit("Error setting event webhook URL=%q err=%q", u, err)
+				}
+				eventWebhookURLs = append(eventWebhookURLs, eventWebhookURL)
+			}
+			var events []string
+			for _, evt := range strings.Split(*cfg.EventWebhookEvents, ",") {
+				if evt = strings.TrimSpace(evt); evt != "" {
+					events = append(events, evt)
+				}
+			}
+			server.Events, err = server.NewEventWebhooks(eventWebhookURLs, *cfg.EventWebhookSecret, events)
+			if err != nil {
+				exit("Error setting -eventWebhookEvents err=%q, supported events are %v", err, strings.Join(server.EventTypes, ","))
+			}
+			if *cfg.EventWebhookSecret == "" {
+				glog.Warning("Event webhook requests are not signed, -eventWebhookSecret is not set")
+			}
+			glog.Infof("Sending stream events to %d event webhooks", len(eventWebhookURLs))
+		}
+		server.SegmentProgressSecret = *cfg.SegmentProgressSecret
+
 	} else if n.NodeType == core.OrchestratorNode {
 		*cfg.CliAddr = defaultAddr(*cfg.CliAddr, "127.0.0.1", OrchestratorCliPort)
 
@@ -1242,6 +1517,11 @@ func StartLivepeer(ctx context.Context, cfg LivepeerConfig) {
 			ec <- s.StartMediaServer(msCtx, *cfg.HttpAddr)
 		}()
 	}
+	if n.NodeType == core.BroadcasterNode && *cfg.SrtAddr != "" {
+		if err := s.StartSRTIngest(msCtx, *cfg.SrtAddr, *cfg.SrtLatency, *cfg.SrtPassphrase); err != nil {
+			exit("Error starting SRT ingest: err=%q", err)
+		}
+	}
 
 	go func() {
 		if core.OrchestratorNode != n.NodeType {
@@ -1271,6 +1551,33 @@ func StartLivepeer(ctx context.Context, cfg LivepeerConfig) {
 
 	}()
 
+	// Closed once a draining node finished its sessions and can exit
+	drained := make(chan struct{})
+	if n.NodeType == core.OrchestratorNode {
+		termc := make(chan os.Signal, 1)
+		signal.Notify(termc, syscall.SIGTERM)
+		defer signal.Stop(termc)
+		go func() {
+			select {
+			case sig := <-termc:
+				glog.Infof("Draining Livepeer Orchestrator: %v", sig)
+				n.Drain()
+			case <-n.Draining():
+			case <-msCtx.Done():
+				return
+			}
+			n.WaitForSessions(core.DrainTimeout)
+			if senderMonitor != nil {
+				flushCtx, flushCancel := context.WithTimeout(context.Background(), core.DrainTimeout)
+				if err := senderMonitor.Flush(flushCtx); err != nil {
+					glog.Errorf("Error flushing ticket redemptions err=%q", err)
+				}
+				flushCancel()
+			}
+			close(drained)
+		}()
+	}
+
 	if n.NodeType == core.TranscoderNode {
 		if n.OrchSecret == "" {
 			glog.Exit("Missing -orchSecret")
@@ -1279,7 +1586,10 @@ func StartLivepeer(ctx context.Context, cfg LivepeerConfig) {
 			glog.Exit("Missing -orchAddr")
 		}
 
-		go server.RunTranscoder(n, orchURLs[0].Host, core.MaxSessions, transcoderCaps)
+		go func() {
+			server.RunTranscoder(n, orchURLs[0].Host, core.MaxSessions, transcoderCaps)
+			close(drained)
+		}()
 	}
 
 	switch n.NodeType {
@@ -1312,6 +1622,9 @@ func StartLivepeer(ctx context.Context, cfg LivepeerConfig) {
 	case <-wc:
 		glog.Infof("CLI webserver shut down")
 		return
+	case <-drained:
+		glog.Infof("Node drained, exiting")
+		return
 	case <-msCtx.Done():
 		glog.Infof("MediaServer Done()")
 		return
@@ -1557,6 +1870,73 @@ func getGatewayPrices(gatewayPrices string) []GatewayPrice {
 	return prices
 }
 
+type GatewayPriorityClass struct {
+	*core.PriorityClass
+	EthAddresses []string
+}
+
+func getPriorityClasses(priorityClasses string) ([]GatewayPriorityClass, error) {
+	if priorityClasses == "" {
+		return nil, nil
+	}
+
+	// Format of priorityClasses json
+	// {"classes":[{"name":"premium","priority":1,"reservedshare":0.5,"maxsessions":10,"maxpixelspersecond":1000000000,"ethaddresses":["address1","address2"]}]}
+	var classesSet struct {
+		Classes []struct {
+			Name               string   `json:"name"`
+			Priority           int      `json:"priority"`
+			ReservedShare      float64  `json:"reservedshare"`
+			MaxSessions        int      `json:"maxsessions"`
+			MaxPixelsPerSecond int64    `json:"maxpixelspersecond"`
+			EthAddresses       []string `json:"ethaddresses"`
+		} `json:"classes"`
+	}
+	classesFileContent, _ := common.ReadFromFile(priorityClasses)
+	if err := json.Unmarshal([]byte(classesFileContent), &classesSet); err != nil {
+		return nil, err
+	}
+
+	var (
+		classes       []GatewayPriorityClass
+		reservedShare float64
+		seen          = make(map[ethcommon.Address]string)
+	)
+	for _, c := range classesSet.Classes {
+		if c.Priority < 0 || c.MaxSessions < 0 || c.MaxPixelsPerSecond < 0 {
+			return nil, fmt.Errorf("priority, maxsessions and maxpixelspersecond of class %s must be greater than or equal to zero", c.Name)
+		}
+		if c.ReservedShare < 0 || c.ReservedShare > 1 {
+			return nil, fmt.Errorf("reservedshare of class %s must be between 0 and 1, provided %v", c.Name, c.ReservedShare)
+		}
+		reservedShare += c.ReservedShare
+		for _, addr := range c.EthAddresses {
+			if !ethcommon.IsHexAddress(addr) {
+				return nil, fmt.Errorf("invalid gateway address %s in class %s", addr, c.Name)
+			}
+			if other, ok := seen[ethcommon.HexToAddress(addr)]; ok {
+				return nil, fmt.Errorf("gateway %s is in both class %s and class %s", addr, other, c.Name)
+			}
+			seen[ethcommon.HexToAddress(addr)] = c.Name
+		}
+		classes = append(classes, GatewayPriorityClass{
+			PriorityClass: &core.PriorityClass{
+				Name:               c.Name,
+				Priority:           c.Priority,
+				ReservedShare:      c.ReservedShare,
+				MaxSessions:        c.MaxSessions,
+				MaxPixelsPerSecond: c.MaxPixelsPerSecond,
+			},
+			EthAddresses: c.EthAddresses,
+		})
+	}
+	if reservedShare > 1 {
+		return nil, fmt.Errorf("reservedshare of all the classes must add up to at most 1, provided %v", reservedShare)
+	}
+
+	return classes, nil
+}
+
 func createSelectionAlgorithm(cfg LivepeerConfig) (common.SelectionAlgorithm, error) {
 	sumWeight := *cfg.SelectStakeWeight + *cfg.SelectPriceWeight + *cfg.SelectRandWeight
 	if math.Abs(sumWeight-1.0) > 0.0001 {
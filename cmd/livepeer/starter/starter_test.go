@@ -109,6 +109,36 @@ func TestParseGetGatewayPrices(t *testing.T) {
 	}
 }
 
+func TestParseGetPriorityClasses(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	classes, err := getPriorityClasses("")
+	assert.Nil(err)
+	assert.Empty(classes)
+
+	classes, err = getPriorityClasses(`{"classes":[{"name":"premium","priority":1,"reservedshare":0.5,"maxsessions":10,"maxpixelspersecond":1000,"ethaddresses":["0x0000000000000000000000000000000000000001","0x0000000000000000000000000000000000000002"]},{"name":"basic","reservedshare":0.25}]}`)
+	require.Nil(err)
+	require.Len(classes, 2)
+	assert.Equal(core.PriorityClass{Name: "premium", Priority: 1, ReservedShare: 0.5, MaxSessions: 10, MaxPixelsPerSecond: 1000}, *classes[0].PriorityClass)
+	assert.Equal([]string{"0x0000000000000000000000000000000000000001", "0x0000000000000000000000000000000000000002"}, classes[0].EthAddresses)
+	assert.Equal("basic", classes[1].Name)
+	assert.Empty(classes[1].EthAddresses)
+
+	invalid := []string{
+		`{"classes":`,
+		`{"classes":[{"name":"premium","priority":-1}]}`,
+		`{"classes":[{"name":"premium","reservedshare":1.5}]}`,
+		`{"classes":[{"name":"premium","reservedshare":0.6},{"name":"basic","reservedshare":0.6}]}`,
+		`{"classes":[{"name":"premium","ethaddresses":["foo"]}]}`,
+		`{"classes":[{"name":"premium","ethaddresses":["0x0000000000000000000000000000000000000001"]},{"name":"basic","ethaddresses":["0x0000000000000000000000000000000000000001"]}]}`,
+	}
+	for _, classes := range invalid {
+		_, err := getPriorityClasses(classes)
+		assert.NotNil(err, classes)
+	}
+}
+
 // Address provided to keystore file
 func TestParse_ParseEthKeystorePathValidFile(t *testing.T) {
 	assert := assert.New(t)
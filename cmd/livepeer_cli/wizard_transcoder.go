@@ -367,3 +367,14 @@ func (w *wizard) setMaxSessions() {
 		return
 	}
 }
+
+func (w *wizard) drain() {
+	fmt.Printf("The node will stop accepting new sessions and exit once its sessions ended. Would you like to drain the node? (y/n) - ")
+
+	input := w.readStringYesOrNo()
+	if input == "n" {
+		return
+	}
+
+	fmt.Println(httpPost(fmt.Sprintf("http://%v:%v/drain", w.host, w.httpPort)))
+}
@@ -0,0 +1,115 @@
+/*
+livepeer_selection_sim replays orchestrator selections against a recorded snapshot of orchestrators to
+evaluate the selection settings of a gateway before deploying them.
+
+The snapshot is a JSON array of orchestrators:
+
+	[
+	  {"address": "0x...", "stake": 1000, "pricePerUnit": 1200, "pixelsPerUnit": 1, "perfScore": 0.9, "latencyScore": 0.4, "failureRate": 0.01}
+	]
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/server"
+)
+
+func main() {
+	flag.Set("logtostderr", "true")
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+
+	snapshot := flag.String("snapshot", "", "Path to the JSON snapshot of orchestrators")
+	randWeight := flag.Float64("selectRandFreq", 0.3, "Weight of the random factor in the orchestrator selection algorithm")
+	stakeWeight := flag.Float64("selectStakeWeight", 0.7, "Weight of the stake factor in the orchestrator selection algorithm")
+	priceWeight := flag.Float64("selectPriceWeight", 0.0, "Weight of the price factor in the orchestrator selection algorithm")
+	priceExpFactor := flag.Float64("selectPriceExpFactor", 100, "Expresses how significant a small change of price is for the selection algorithm")
+	minPerfScore := flag.Float64("minPerfScore", 0, "The minimum orchestrator's performance score a gateway is willing to accept")
+	maxPricePerUnit := flag.Int64("maxPricePerUnit", 0, "The maximum transcoding price (in wei) per 'pixelsPerUnit' a gateway is willing to accept, 0 means no max price")
+	pixelsPerUnit := flag.Int64("pixelsPerUnit", 1, "The number of pixels the max price applies to")
+	selections := flag.Int("selections", 10000, "Number of single selections made by the selection algorithm")
+	streams := flag.Int("streams", 100, "Number of streams replayed through the session selector")
+	segments := flag.Int("segmentsPerStream", 100, "Number of segments of each replayed stream")
+	pixelsPerSegment := flag.Int64("pixelsPerSegment", 1280*720*60, "Number of pixels transcoded per segment, used for the cost; default is a 2s 720p30 segment")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "Seed for the sampled orchestrator failures")
+	jsonOut := flag.Bool("json", false, "Print the report as JSON")
+
+	flag.Parse()
+
+	if *snapshot == "" {
+		glog.Exit("Missing -snapshot")
+	}
+	sumWeight := *stakeWeight + *priceWeight + *randWeight
+	if math.Abs(sumWeight-1.0) > 0.0001 {
+		glog.Exitf("Sum of selection algorithm weights must be 1.0, stakeWeight=%v, priceWeight=%v, randWeight=%v", *stakeWeight, *priceWeight, *randWeight)
+	}
+	if *pixelsPerUnit <= 0 {
+		glog.Exitf("-pixelsPerUnit must be greater than 0, provided %v", *pixelsPerUnit)
+	}
+
+	data, err := ioutil.ReadFile(*snapshot)
+	if err != nil {
+		glog.Exitf("Error reading snapshot err=%q", err)
+	}
+	var orchs []simOrchestrator
+	if err := json.Unmarshal(data, &orchs); err != nil {
+		glog.Exitf("Error parsing snapshot err=%q", err)
+	}
+
+	cfg := simConfig{
+		Algorithm: server.ProbabilitySelectionAlgorithm{
+			MinPerfScore:   *minPerfScore,
+			StakeWeight:    *stakeWeight,
+			PriceWeight:    *priceWeight,
+			RandWeight:     *randWeight,
+			PriceExpFactor: *priceExpFactor,
+		},
+		Selections:        *selections,
+		Streams:           *streams,
+		SegmentsPerStream: *segments,
+		PixelsPerSegment:  *pixelsPerSegment,
+		Seed:              *seed,
+	}
+	if *maxPricePerUnit > 0 {
+		cfg.MaxPrice = big.NewRat(*maxPricePerUnit, *pixelsPerUnit)
+	}
+
+	report, err := simulateSelection(orchs, cfg)
+	if err != nil {
+		glog.Exitf("Error simulating selection err=%q", err)
+	}
+
+	if *jsonOut {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			glog.Exitf("Error marshalling report err=%q", err)
+		}
+		fmt.Println(string(out))
+		return
+	}
+	printDistribution("Selection algorithm (expected failures)", report.Algorithm)
+	printDistribution("Streams (sampled failures)", report.Streams)
+}
+
+func printDistribution(title string, dist simDistribution) {
+	fmt.Printf("%s\n", title)
+	fmt.Printf("selections=%d noSelection=%d failureRate=%.4f costPerSegment=%.0f wei totalCost=%.0f wei\n",
+		dist.Selections, dist.NoSelection, dist.FailureRate, dist.CostPerSegment, dist.TotalCost)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ADDRESS\tSELECTIONS\tSHARE\tFAILURES")
+	for _, o := range dist.Orchestrators {
+		fmt.Fprintf(w, "%s\t%d\t%.2f%%\t%.1f\n", o.Address.Hex(), o.Selections, o.Share*100, o.Failures)
+	}
+	w.Flush()
+	fmt.Println()
+}
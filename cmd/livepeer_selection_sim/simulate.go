@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"math/rand"
+	"sort"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-livepeer/net"
+	"github.com/livepeer/go-livepeer/server"
+)
+
+// simOrchestrator is the recorded state of an orchestrator that selections are replayed against
+type simOrchestrator struct {
+	Address       ethcommon.Address `json:"address"`
+	Stake         int64             `json:"stake"`
+	PricePerUnit  int64             `json:"pricePerUnit"`
+	PixelsPerUnit int64             `json:"pixelsPerUnit"`
+	PerfScore     float64           `json:"perfScore"`
+	// LatencyScore is the observed ratio between the round trip time and the duration of a segment
+	LatencyScore float64 `json:"latencyScore"`
+	// FailureRate is the observed fraction of segments that failed
+	FailureRate float64 `json:"failureRate"`
+}
+
+// simConfig configures the replay of selections
+type simConfig struct {
+	Algorithm common.SelectionAlgorithm
+	// MaxPrice is the max price per pixel in wei, nil means no max price
+	MaxPrice *big.Rat
+
+	// Selections is the number of single selections made by the selection algorithm
+	Selections int
+	// Streams and SegmentsPerStream are the number of streams and their segments replayed through the MinLSSelector
+	Streams           int
+	SegmentsPerStream int
+	PixelsPerSegment  int64
+
+	Seed int64
+}
+
+// simOrchStats is the traffic an orchestrator received in a replay
+type simOrchStats struct {
+	Address    ethcommon.Address `json:"address"`
+	Selections int64             `json:"selections"`
+	Share      float64           `json:"share"`
+	Failures   float64           `json:"failures"`
+}
+
+// simDistribution is the outcome of a replay. Costs are in wei
+type simDistribution struct {
+	Selections     int64          `json:"selections"`
+	NoSelection    int64          `json:"noSelection"`
+	Failures       float64        `json:"failures"`
+	FailureRate    float64        `json:"failureRate"`
+	TotalCost      float64        `json:"totalCost"`
+	CostPerSegment float64        `json:"costPerSegment"`
+	Orchestrators  []simOrchStats `json:"orchestrators"`
+}
+
+// simReport is the outcome of simulateSelection
+type simReport struct {
+	// Algorithm is the distribution of single selections made by the selection algorithm, failures are the
+	// expected number of failures based on the failure rates of the orchestrators
+	Algorithm simDistribution `json:"algorithm"`
+	// Streams is the distribution of segments of streams replayed through the MinLSSelector, failures are sampled
+	// from the failure rates of the orchestrators
+	Streams simDistribution `json:"streams"`
+}
+
+// simulateSelection replays selections against the recorded orchestrators. Every replayed stream starts
+// with a session for each orchestrator, a failed session is removed from the stream and the sessions of
+// all orchestrators are added again once the stream ran out of sessions
+func simulateSelection(orchs []simOrchestrator, cfg simConfig) (*simReport, error) {
+	if len(orchs) == 0 {
+		return nil, errors.New("no orchestrators to select from")
+	}
+
+	byAddr := make(map[ethcommon.Address]simOrchestrator)
+	var addrs []ethcommon.Address
+	stakes := make(simStakeReader)
+	prices := make(map[ethcommon.Address]*big.Rat)
+	perfScores := make(map[ethcommon.Address]float64)
+	for _, o := range orchs {
+		if o.PixelsPerUnit <= 0 {
+			o.PixelsPerUnit = 1
+		}
+		if _, ok := byAddr[o.Address]; ok {
+			return nil, errors.New("duplicate orchestrator address " + o.Address.Hex())
+		}
+		byAddr[o.Address] = o
+		addrs = append(addrs, o.Address)
+		stakes[o.Address] = o.Stake
+		prices[o.Address] = big.NewRat(o.PricePerUnit, o.PixelsPerUnit)
+		if o.PerfScore > 0 {
+			perfScores[o.Address] = o.PerfScore
+		}
+	}
+	var perfScore *common.PerfScore
+	if len(perfScores) > 0 {
+		perfScore = &common.PerfScore{Scores: perfScores}
+	}
+
+	segmentCost := func(addr ethcommon.Address) float64 {
+		cost, _ := new(big.Rat).Mul(prices[addr], new(big.Rat).SetInt64(cfg.PixelsPerSegment)).Float64()
+		return cost
+	}
+
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	report := &simReport{}
+
+	algo := newSimStats()
+	for i := 0; i < cfg.Selections; i++ {
+		addr := cfg.Algorithm.Select(addrs, stakes, cfg.MaxPrice, prices, perfScores)
+		if _, ok := byAddr[addr]; !ok {
+			algo.dist.NoSelection++
+			continue
+		}
+		algo.record(addr, segmentCost(addr), byAddr[addr].FailureRate)
+	}
+	report.Algorithm = algo.distribution()
+
+	streams := newSimStats()
+	for i := 0; i < cfg.Streams; i++ {
+		params := &core.StreamParameters{ManifestID: core.RandomManifestID()}
+		if cfg.MaxPrice != nil {
+			params.OrchConstraints = &core.OrchestratorConstraints{MaxPrice: core.NewFixedPrice(cfg.MaxPrice)}
+		}
+		var sel server.BroadcastSessionsSelector = server.NewMinLSSelector(stakes, server.SELECTOR_LATENCY_SCORE_THRESHOLD, cfg.Algorithm, perfScore)
+
+		for seg := 0; seg < cfg.SegmentsPerStream; seg++ {
+			if sel.Size() == 0 {
+				for _, o := range orchs {
+					sel.Add([]*server.BroadcastSession{simSession(o, params)})
+				}
+			}
+			sess := sel.Select(context.Background())
+			if sess == nil {
+				streams.dist.NoSelection++
+				continue
+			}
+			addr := ethcommon.BytesToAddress(sess.OrchestratorInfo.TicketParams.Recipient)
+			o := byAddr[addr]
+			if rng.Float64() < o.FailureRate {
+				streams.record(addr, segmentCost(addr), 1)
+				continue
+			}
+			streams.record(addr, segmentCost(addr), 0)
+			sess.LatencyScore = o.LatencyScore
+			sel.Complete(sess)
+		}
+	}
+	report.Streams = streams.distribution()
+
+	return report, nil
+}
+
+type simStakeReader map[ethcommon.Address]int64
+
+func (r simStakeReader) Stakes(addrs []ethcommon.Address) (map[ethcommon.Address]int64, error) {
+	stakes := make(map[ethcommon.Address]int64)
+	for _, addr := range addrs {
+		stakes[addr] = r[addr]
+	}
+	return stakes, nil
+}
+
+func simSession(o simOrchestrator, params *core.StreamParameters) *server.BroadcastSession {
+	return server.NewBroadcastSession(params, &net.OrchestratorInfo{
+		Transcoder:   o.Address.Hex(),
+		PriceInfo:    &net.PriceInfo{PricePerUnit: o.PricePerUnit, PixelsPerUnit: o.PixelsPerUnit},
+		TicketParams: &net.TicketParams{Recipient: o.Address.Bytes()},
+	})
+}
+
+type simStats struct {
+	dist  simDistribution
+	orchs map[ethcommon.Address]*simOrchStats
+}
+
+func newSimStats() *simStats {
+	return &simStats{orchs: make(map[ethcommon.Address]*simOrchStats)}
+}
+
+func (s *simStats) record(addr ethcommon.Address, cost, failures float64) {
+	o, ok := s.orchs[addr]
+	if !ok {
+		o = &simOrchStats{Address: addr}
+		s.orchs[addr] = o
+	}
+	o.Selections++
+	o.Failures += failures
+	s.dist.Selections++
+	s.dist.Failures += failures
+	s.dist.TotalCost += cost
+}
+
+// distribution returns the distribution with the orchestrators sorted by their share of the traffic
+func (s *simStats) distribution() simDistribution {
+	dist := s.dist
+	dist.Orchestrators = []simOrchStats{}
+	for _, o := range s.orchs {
+		stats := *o
+		stats.Share = float64(o.Selections) / float64(dist.Selections)
+		dist.Orchestrators = append(dist.Orchestrators, stats)
+	}
+	sort.Slice(dist.Orchestrators, func(i, j int) bool {
+		a, b := dist.Orchestrators[i], dist.Orchestrators[j]
+		if a.Selections != b.Selections {
+			return a.Selections > b.Selections
+		}
+		return a.Address.Hex() < b.Address.Hex()
+	})
+	if dist.Selections > 0 {
+		dist.FailureRate = dist.Failures / float64(dist.Selections)
+		dist.CostPerSegment = dist.TotalCost / float64(dist.Selections)
+	}
+	return dist
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/livepeer/go-livepeer/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulateSelection(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	_, err := simulateSelection(nil, simConfig{})
+	assert.EqualError(err, "no orchestrators to select from")
+
+	cheap := ethcommon.HexToAddress("0x0000000000000000000000000000000000000001")
+	expensive := ethcommon.HexToAddress("0x0000000000000000000000000000000000000002")
+	flaky := ethcommon.HexToAddress("0x0000000000000000000000000000000000000003")
+	orchs := []simOrchestrator{
+		{Address: cheap, Stake: 100, PricePerUnit: 1, LatencyScore: 0.5},
+		{Address: expensive, Stake: 100, PricePerUnit: 10, PixelsPerUnit: 1, LatencyScore: 0.5},
+		{Address: flaky, Stake: 100, PricePerUnit: 1, LatencyScore: 0.5, FailureRate: 1},
+	}
+	_, err = simulateSelection(append(orchs, orchs[0]), simConfig{})
+	assert.EqualError(err, "duplicate orchestrator address "+cheap.Hex())
+
+	cfg := simConfig{
+		Algorithm:         server.ProbabilitySelectionAlgorithm{StakeWeight: 1, PriceExpFactor: 100},
+		MaxPrice:          big.NewRat(5, 1),
+		Selections:        1000,
+		Streams:           10,
+		SegmentsPerStream: 10,
+		PixelsPerSegment:  100,
+		Seed:              1,
+	}
+	report, err := simulateSelection(orchs, cfg)
+	require.Nil(err)
+
+	// The expensive orchestrator is filtered by the max price, the rest is selected on stake
+	algo := report.Algorithm
+	assert.Equal(int64(1000), algo.Selections)
+	require.Len(algo.Orchestrators, 2)
+	for _, o := range algo.Orchestrators {
+		assert.NotEqual(expensive, o.Address)
+		assert.InDelta(0.5, o.Share, 0.1)
+	}
+	assert.InDelta(0.5, algo.FailureRate, 0.1)
+	assert.Equal(100.0, algo.CostPerSegment)
+	assert.Equal(100000.0, algo.TotalCost)
+
+	// Streams stick to the orchestrator with a good latency score once the flaky orchestrator failed
+	streams := report.Streams
+	assert.Equal(int64(100), streams.Selections)
+	require.NotEmpty(streams.Orchestrators)
+	assert.Equal(cheap, streams.Orchestrators[0].Address)
+	assert.Zero(streams.Orchestrators[0].Failures)
+	for _, o := range streams.Orchestrators[1:] {
+		assert.Equal(flaky, o.Address)
+		assert.Equal(float64(o.Selections), o.Failures)
+		assert.LessOrEqual(o.Selections, int64(10))
+	}
+	assert.Equal(100.0, streams.CostPerSegment)
+}
@@ -41,6 +41,11 @@ type DB struct {
 	findLatestMiniHeader             *sql.Stmt
 	findAllMiniHeadersSortedByNumber *sql.Stmt
 	deleteMiniHeader                 *sql.Stmt
+	updateOrchReputation             *sql.Stmt
+	selectOrchReputations            *sql.Stmt
+	updateBudgetSpend                *sql.Stmt
+	selectBudgetSpends               *sql.Stmt
+	deleteBudgetSpends               *sql.Stmt
 }
 
 // DBOrch is the type binding for a row result from the orchestrators table
@@ -53,6 +58,40 @@ type DBOrch struct {
 	Stake             int64 // Stored as a fixed point number
 }
 
+// DBOrchReputation is the type binding for a row result from the orchestratorReputation table
+type DBOrchReputation struct {
+	Orchestrator         string  `json:"orchestrator"` // ETH address, or service URI of an orchestrator without one
+	Successes            int64   `json:"successes"`
+	Failures             int64   `json:"failures"`
+	VerificationFailures int64   `json:"verificationFailures"`
+	LatencySamples       int64   `json:"latencySamples"`
+	LatencyScoreAvg      float64 `json:"latencyScoreAvg"` // exponential moving average of the latency scores
+	LastFailureReason    string  `json:"lastFailureReason,omitempty"`
+	LastFailureAt        int64   `json:"lastFailureAt,omitempty"` // unix timestamp in seconds
+	// Number of successes and failures that halves every reputation half-life, used for selection
+	RecentSuccesses float64 `json:"recentSuccesses"`
+	RecentFailures  float64 `json:"recentFailures"`
+	DecayedAt       int64   `json:"decayedAt,omitempty"` // unix timestamp in seconds of the last decay of the recent counters
+}
+
+// SuccessRate returns the fraction of all segments that the orchestrator transcoded successfully
+func (r *DBOrchReputation) SuccessRate() float64 {
+	total := r.Successes + r.Failures
+	if total == 0 {
+		return 1.0
+	}
+	return float64(r.Successes) / float64(total)
+}
+
+// DBBudgetSpend is the type binding for a row result from the budgetSpend table
+type DBBudgetSpend struct {
+	Scope     string   // stream, account or daily
+	ID        string   // stream or account ID, empty for the daily spending
+	Day       string   // UTC day of the account and daily spending, empty for the stream spending
+	Spent     *big.Rat // in wei
+	UpdatedAt int64    // unix timestamp in seconds
+}
+
 // DBOrch is the type binding for a row result from the unbondingLocks table
 type DBUnbondingLock struct {
 	ID            int64
@@ -131,6 +170,33 @@ var schema = `
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_blockheaders_number ON blockheaders(number);
+
+	CREATE TABLE IF NOT EXISTS orchestratorReputation (
+		orchestrator STRING PRIMARY KEY,
+		createdAt STRING DEFAULT CURRENT_TIMESTAMP NOT NULL,
+		updatedAt STRING DEFAULT CURRENT_TIMESTAMP NOT NULL,
+		successes int64 DEFAULT 0 NOT NULL,
+		failures int64 DEFAULT 0 NOT NULL,
+		verificationFailures int64 DEFAULT 0 NOT NULL,
+		latencySamples int64 DEFAULT 0 NOT NULL,
+		latencyScoreAvg REAL DEFAULT 0 NOT NULL,
+		lastFailureReason STRING DEFAULT '' NOT NULL,
+		lastFailureAt int64 DEFAULT 0 NOT NULL,
+		recentSuccesses REAL DEFAULT 0 NOT NULL,
+		recentFailures REAL DEFAULT 0 NOT NULL,
+		decayedAt int64 DEFAULT 0 NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS budgetSpend (
+		scope STRING NOT NULL,
+		id STRING DEFAULT '' NOT NULL,
+		day STRING DEFAULT '' NOT NULL,
+		spent STRING DEFAULT '0' NOT NULL,
+		updatedAt int64 DEFAULT 0 NOT NULL,
+		PRIMARY KEY(scope, id, day)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_budgetspend_updatedat ON budgetSpend(updatedAt);
 `
 
 func NewDBOrch(ethereumAddr string, serviceURI string, pricePerPixel int64, activationRound int64, deactivationRound int64, stake int64) *DBOrch {
@@ -360,6 +426,72 @@ func InitDB(dbPath string) (*DB, error) {
 	}
 	d.deleteMiniHeader = stmt
 
+	// updateOrchReputation prepared statement
+	stmt, err = db.Prepare(`
+	INSERT INTO orchestratorReputation(orchestrator, successes, failures, verificationFailures, latencySamples, latencyScoreAvg, lastFailureReason, lastFailureAt, recentSuccesses, recentFailures, decayedAt, createdAt, updatedAt)
+	VALUES(:orchestrator, :successes, :failures, :verificationFailures, :latencySamples, :latencyScoreAvg, :lastFailureReason, :lastFailureAt, :recentSuccesses, :recentFailures, :decayedAt, datetime(), datetime())
+	ON CONFLICT(orchestrator) DO UPDATE SET
+	updatedAt = excluded.updatedAt,
+	successes = excluded.successes,
+	failures = excluded.failures,
+	verificationFailures = excluded.verificationFailures,
+	latencySamples = excluded.latencySamples,
+	latencyScoreAvg = excluded.latencyScoreAvg,
+	lastFailureReason = excluded.lastFailureReason,
+	lastFailureAt = excluded.lastFailureAt,
+	recentSuccesses = excluded.recentSuccesses,
+	recentFailures = excluded.recentFailures,
+	decayedAt = excluded.decayedAt
+	`)
+	if err != nil {
+		glog.Error("Unable to prepare updateOrchReputation ", err)
+		d.Close()
+		return nil, err
+	}
+	d.updateOrchReputation = stmt
+
+	// Select the reputation of all orchestrators
+	stmt, err = db.Prepare("SELECT orchestrator, successes, failures, verificationFailures, latencySamples, latencyScoreAvg, lastFailureReason, lastFailureAt, recentSuccesses, recentFailures, decayedAt FROM orchestratorReputation ORDER BY orchestrator")
+	if err != nil {
+		glog.Error("Unable to prepare selectOrchReputations ", err)
+		d.Close()
+		return nil, err
+	}
+	d.selectOrchReputations = stmt
+
+	// updateBudgetSpend prepared statement
+	stmt, err = db.Prepare(`
+	INSERT INTO budgetSpend(scope, id, day, spent, updatedAt)
+	VALUES(:scope, :id, :day, :spent, :updatedAt)
+	ON CONFLICT(scope, id, day) DO UPDATE SET
+	spent = excluded.spent,
+	updatedAt = excluded.updatedAt
+	`)
+	if err != nil {
+		glog.Error("Unable to prepare updateBudgetSpend ", err)
+		d.Close()
+		return nil, err
+	}
+	d.updateBudgetSpend = stmt
+
+	// Select the spending updated since a given time
+	stmt, err = db.Prepare("SELECT scope, id, day, spent, updatedAt FROM budgetSpend WHERE updatedAt >= ? ORDER BY scope, id, day")
+	if err != nil {
+		glog.Error("Unable to prepare selectBudgetSpends ", err)
+		d.Close()
+		return nil, err
+	}
+	d.selectBudgetSpends = stmt
+
+	// Delete the spending last updated before a given time
+	stmt, err = db.Prepare("DELETE FROM budgetSpend WHERE updatedAt < ?")
+	if err != nil {
+		glog.Error("Unable to prepare deleteBudgetSpends ", err)
+		d.Close()
+		return nil, err
+	}
+	d.deleteBudgetSpends = stmt
+
 	glog.V(DEBUG).Info("Initialized DB node")
 	return &d, nil
 }
@@ -417,6 +549,21 @@ func (db *DB) Close() {
 	if db.deleteMiniHeader != nil {
 		db.deleteMiniHeader.Close()
 	}
+	if db.updateOrchReputation != nil {
+		db.updateOrchReputation.Close()
+	}
+	if db.selectOrchReputations != nil {
+		db.selectOrchReputations.Close()
+	}
+	if db.updateBudgetSpend != nil {
+		db.updateBudgetSpend.Close()
+	}
+	if db.selectBudgetSpends != nil {
+		db.selectBudgetSpends.Close()
+	}
+	if db.deleteBudgetSpends != nil {
+		db.deleteBudgetSpends.Close()
+	}
 	if db.dbh != nil {
 		db.dbh.Close()
 	}
@@ -533,6 +680,125 @@ func (db *DB) SelectOrchs(filter *DBOrchFilter) ([]*DBOrch, error) {
 	return orchs, nil
 }
 
+// UpdateOrchReputation inserts or replaces the reputation of an orchestrator
+func (db *DB) UpdateOrchReputation(rep *DBOrchReputation) error {
+	if db == nil || rep == nil || rep.Orchestrator == "" {
+		return nil
+	}
+
+	_, err := db.updateOrchReputation.Exec(
+		sql.Named("orchestrator", rep.Orchestrator),
+		sql.Named("successes", rep.Successes),
+		sql.Named("failures", rep.Failures),
+		sql.Named("verificationFailures", rep.VerificationFailures),
+		sql.Named("latencySamples", rep.LatencySamples),
+		sql.Named("latencyScoreAvg", rep.LatencyScoreAvg),
+		sql.Named("lastFailureReason", rep.LastFailureReason),
+		sql.Named("lastFailureAt", rep.LastFailureAt),
+		sql.Named("recentSuccesses", rep.RecentSuccesses),
+		sql.Named("recentFailures", rep.RecentFailures),
+		sql.Named("decayedAt", rep.DecayedAt),
+	)
+
+	if err != nil {
+		glog.Error("db: Unable to update orchestrator reputation ", err)
+	}
+
+	return err
+}
+
+// SelectOrchReputations returns the reputation of all orchestrators sorted by orchestrator
+func (db *DB) SelectOrchReputations() ([]*DBOrchReputation, error) {
+	if db == nil {
+		return nil, nil
+	}
+
+	rows, err := db.selectOrchReputations.Query()
+	if err != nil {
+		glog.Error("db: Unable to get orchestrator reputations ", err)
+		return nil, err
+	}
+	defer rows.Close()
+	reps := []*DBOrchReputation{}
+	for rows.Next() {
+		var rep DBOrchReputation
+		if err := rows.Scan(&rep.Orchestrator, &rep.Successes, &rep.Failures, &rep.VerificationFailures, &rep.LatencySamples,
+			&rep.LatencyScoreAvg, &rep.LastFailureReason, &rep.LastFailureAt, &rep.RecentSuccesses, &rep.RecentFailures, &rep.DecayedAt); err != nil {
+			glog.Error("db: Unable to fetch orchestrator reputation ", err)
+			continue
+		}
+		reps = append(reps, &rep)
+	}
+	return reps, nil
+}
+
+// UpdateBudgetSpend inserts or replaces the spending of a budget
+func (db *DB) UpdateBudgetSpend(spend *DBBudgetSpend) error {
+	if db == nil || spend == nil || spend.Spent == nil {
+		return nil
+	}
+
+	_, err := db.updateBudgetSpend.Exec(
+		sql.Named("scope", spend.Scope),
+		sql.Named("id", spend.ID),
+		sql.Named("day", spend.Day),
+		sql.Named("spent", spend.Spent.RatString()),
+		sql.Named("updatedAt", spend.UpdatedAt),
+	)
+
+	if err != nil {
+		glog.Error("db: Unable to update budget spending ", err)
+	}
+
+	return err
+}
+
+// SelectBudgetSpends returns the spending of all budgets updated at or after the given unix timestamp
+func (db *DB) SelectBudgetSpends(since int64) ([]*DBBudgetSpend, error) {
+	if db == nil {
+		return nil, nil
+	}
+
+	rows, err := db.selectBudgetSpends.Query(since)
+	if err != nil {
+		glog.Error("db: Unable to get budget spending ", err)
+		return nil, err
+	}
+	defer rows.Close()
+	spends := []*DBBudgetSpend{}
+	for rows.Next() {
+		var (
+			spend DBBudgetSpend
+			spent string
+		)
+		if err := rows.Scan(&spend.Scope, &spend.ID, &spend.Day, &spent, &spend.UpdatedAt); err != nil {
+			glog.Error("db: Unable to fetch budget spending ", err)
+			continue
+		}
+		var ok bool
+		if spend.Spent, ok = new(big.Rat).SetString(spent); !ok {
+			glog.Errorf("db: Unable to parse budget spending scope=%s id=%s spent=%s", spend.Scope, spend.ID, spent)
+			continue
+		}
+		spends = append(spends, &spend)
+	}
+	return spends, nil
+}
+
+// DeleteBudgetSpends deletes the spending of all budgets last updated before the given unix timestamp
+func (db *DB) DeleteBudgetSpends(before int64) error {
+	if db == nil {
+		return nil
+	}
+
+	_, err := db.deleteBudgetSpends.Exec(before)
+	if err != nil {
+		glog.Error("db: Unable to delete budget spending ", err)
+	}
+
+	return err
+}
+
 func (db *DB) OrchCount(filter *DBOrchFilter) (int, error) {
 	if db == nil {
 		return 0, nil
@@ -369,6 +369,105 @@ func TestSelectUpdateOrchs_AddingMultipleRows_NoError(t *testing.T) {
 	assert.Equal(orchsUpdated[1].ServiceURI, orchAdd.ServiceURI)
 }
 
+func TestSelectUpdateOrchReputations(t *testing.T) {
+	dbh, dbraw, err := TempDB(t)
+	defer dbh.Close()
+	defer dbraw.Close()
+	require := require.New(t)
+	assert := assert.New(t)
+	require.Nil(err)
+
+	reps, err := dbh.SelectOrchReputations()
+	require.Nil(err)
+	assert.Empty(reps)
+
+	// updating a nil value or a value without an orchestrator
+	require.Nil(dbh.UpdateOrchReputation(nil))
+	require.Nil(dbh.UpdateOrchReputation(&DBOrchReputation{}))
+
+	rep := &DBOrchReputation{
+		Orchestrator:    "https://127.0.0.1:8936",
+		Successes:       9,
+		Failures:        1,
+		LatencySamples:  9,
+		LatencyScoreAvg: 0.5,
+	}
+	require.Nil(dbh.UpdateOrchReputation(rep))
+	addr := pm.RandAddress().Hex()
+	require.Nil(dbh.UpdateOrchReputation(&DBOrchReputation{Orchestrator: addr, Successes: 1}))
+
+	reps, err = dbh.SelectOrchReputations()
+	require.Nil(err)
+	require.Len(reps, 2)
+	assert.Equal(addr, reps[0].Orchestrator)
+	assert.Equal(rep, reps[1])
+	assert.Equal(0.9, reps[1].SuccessRate())
+
+	// updating the row with the same orchestrator replaces it
+	rep.Failures = 2
+	rep.VerificationFailures = 1
+	rep.LastFailureReason = "transcoded results do not match the trusted results"
+	rep.LastFailureAt = 1600000000
+	rep.RecentSuccesses = 4.5
+	rep.RecentFailures = 1.25
+	rep.DecayedAt = 1600000000
+	require.Nil(dbh.UpdateOrchReputation(rep))
+
+	reps, err = dbh.SelectOrchReputations()
+	require.Nil(err)
+	require.Len(reps, 2)
+	assert.Equal(rep, reps[1])
+
+	// no segments yet
+	assert.Equal(1.0, (&DBOrchReputation{}).SuccessRate())
+}
+
+func TestSelectUpdateDeleteBudgetSpends(t *testing.T) {
+	dbh, dbraw, err := TempDB(t)
+	defer dbh.Close()
+	defer dbraw.Close()
+	require := require.New(t)
+	assert := assert.New(t)
+	require.Nil(err)
+
+	spends, err := dbh.SelectBudgetSpends(0)
+	require.Nil(err)
+	assert.Empty(spends)
+
+	// updating a nil value or a value without spending
+	require.Nil(dbh.UpdateBudgetSpend(nil))
+	require.Nil(dbh.UpdateBudgetSpend(&DBBudgetSpend{Scope: "stream", ID: "stream1"}))
+
+	stream := &DBBudgetSpend{Scope: "stream", ID: "stream1", Spent: big.NewRat(1, 3), UpdatedAt: 1600000000}
+	daily := &DBBudgetSpend{Scope: "daily", Day: "2020-09-13", Spent: big.NewRat(100, 1), UpdatedAt: 1600000100}
+	require.Nil(dbh.UpdateBudgetSpend(stream))
+	require.Nil(dbh.UpdateBudgetSpend(daily))
+
+	spends, err = dbh.SelectBudgetSpends(0)
+	require.Nil(err)
+	require.Len(spends, 2)
+	assert.Equal(daily, spends[0])
+	assert.Equal(stream, spends[1])
+
+	// updating the row with the same scope, ID and day replaces it
+	stream.Spent = big.NewRat(2, 3)
+	stream.UpdatedAt = 1600000200
+	require.Nil(dbh.UpdateBudgetSpend(stream))
+
+	// only the spending updated since the given time is selected
+	spends, err = dbh.SelectBudgetSpends(1600000150)
+	require.Nil(err)
+	require.Len(spends, 1)
+	assert.Equal(stream, spends[0])
+
+	// only the spending updated before the given time is deleted
+	require.Nil(dbh.DeleteBudgetSpends(1600000150))
+	spends, err = dbh.SelectBudgetSpends(0)
+	require.Nil(err)
+	require.Len(spends, 1)
+	assert.Equal(stream, spends[0])
+}
+
 func TestOrchCount(t *testing.T) {
 	assert := assert.New(t)
 	require := require.New(t)
@@ -38,9 +38,21 @@ type NodeStatus struct {
 	RegisteredTranscoders       []RemoteTranscoderInfo
 	LocalTranscoding            bool // Indicates orchestrator that is also transcoder
 	BroadcasterPrices           map[string]*big.Rat
+	// maps manifest IDs to the status of the RTMP destinations that the streams are pushed to
+	Restreams map[string][]RestreamStatus
 	// xxx add transcoder's version here
 }
 
+// RestreamStatus is the status of the push of a stream to an RTMP destination
+type RestreamStatus struct {
+	Target     string // Destination URL, with its stream key hidden
+	Profile    string
+	State      string
+	BytesSent  uint64
+	Reconnects int
+	LastError  string
+}
+
 type Broadcaster interface {
 	Address() ethcommon.Address
 	Sign([]byte) ([]byte, error)
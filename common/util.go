@@ -44,6 +44,9 @@ var SegUploadTimeoutMultiplier = 0.5
 // MinSegmentUploadTimeout defines the minimum timeout enforced for uploading a segment to orchestrators
 var MinSegmentUploadTimeout = 2 * time.Second
 
+// AdmissionWaitMultiplier used to set how long new sessions wait for a session slot at orchestrators at capacity
+var AdmissionWaitMultiplier = 0.5
+
 // WebhookDiscoveryRefreshInterval defines for long the Webhook Discovery values should be cached
 var WebhookDiscoveryRefreshInterval = 1 * time.Minute
 
@@ -77,9 +80,14 @@ var (
 	ext2mime = map[string]string{
 		".ts":  "video/mp2t",
 		".mp4": "video/mp4",
+		".m4s": "video/iso.segment",
 	}
 )
 
+// FormatFMP4 is the fragmented MP4 (CMAF) output format. LPMS only knows about the MP4 container, so this format is
+// kept out of the range of the LPMS formats and transcoded as MP4 with a fragmenting muxer
+const FormatFMP4 ffmpeg.Format = 100
+
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
@@ -204,6 +212,8 @@ func FFmpegProfiletoNetProfile(ffmpegProfiles []ffmpeg.VideoProfile) ([]*net.Vid
 		case ffmpeg.FormatMPEGTS:
 		case ffmpeg.FormatMP4:
 			format = net.VideoProfile_MP4
+		case FormatFMP4:
+			format = net.VideoProfile_FMP4
 		default:
 			return nil, ErrFormatProto
 		}
@@ -301,6 +311,9 @@ func ProfileExtensionFormat(ext string) ffmpeg.Format {
 }
 
 func ProfileFormatExtension(f ffmpeg.Format) (string, error) {
+	if f == FormatFMP4 {
+		return ".m4s", nil
+	}
 	ext, ok := ffmpeg.FormatExtensions[f]
 	if !ok {
 		return "", ErrFormatExt
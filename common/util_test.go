@@ -138,6 +138,12 @@ func TestFFmpegProfiletoNetProfile(t *testing.T) {
 	assert.Equal(fullProfiles[0].Format, net.VideoProfile_MP4)
 	assert.Equal(fullProfiles[1].Format, net.VideoProfile_MPEGTS)
 
+	profiles[1].Format = FormatFMP4
+	fullProfiles, err = FFmpegProfiletoNetProfile(profiles)
+	assert.Nil(err)
+	assert.Equal(fullProfiles[1].Format, net.VideoProfile_FMP4)
+	profiles[1].Format = ffmpeg.FormatMPEGTS
+
 	// Verify FPS denominator behaviour
 	assert.Equal(fullProfiles[0].FpsDen, uint32(0))
 	assert.Equal(fullProfiles[1].FpsDen, uint32(profiles[1].FramerateDen))
@@ -178,8 +184,8 @@ func TestProfilesToHex(t *testing.T) {
 }
 
 func TestVideoProfile_FormatMimeType(t *testing.T) {
-	inp := []ffmpeg.Format{ffmpeg.FormatNone, ffmpeg.FormatMPEGTS, ffmpeg.FormatMP4}
-	exp := []string{"video/mp2t", "video/mp2t", "video/mp4"}
+	inp := []ffmpeg.Format{ffmpeg.FormatNone, ffmpeg.FormatMPEGTS, ffmpeg.FormatMP4, FormatFMP4}
+	exp := []string{"video/mp2t", "video/mp2t", "video/mp4", "video/iso.segment"}
 	for i, v := range inp {
 		m, err := ProfileFormatMimeType(v)
 		m = strings.ToLower(m)
@@ -206,9 +212,9 @@ func TestVideoProfile_FormatMimeType(t *testing.T) {
 }
 
 func TestVideoProfile_FormatExtension(t *testing.T) {
-	inp := []ffmpeg.Format{ffmpeg.FormatNone, ffmpeg.FormatMPEGTS, ffmpeg.FormatMP4}
-	exp := []string{".ts", ".ts", ".mp4"}
-	if len(inp) != len(ffmpeg.FormatExtensions) {
+	inp := []ffmpeg.Format{ffmpeg.FormatNone, ffmpeg.FormatMPEGTS, ffmpeg.FormatMP4, FormatFMP4}
+	exp := []string{".ts", ".ts", ".mp4", ".m4s"}
+	if len(inp) != len(ffmpeg.FormatExtensions)+1 {
 		t.Error("Format lengths did not match; missing a new format?")
 	}
 	for i, v := range inp {
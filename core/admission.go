@@ -0,0 +1,180 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/livepeer/go-livepeer/clog"
+	"github.com/livepeer/go-livepeer/common"
+	lpmon "github.com/livepeer/go-livepeer/monitor"
+)
+
+// AdmissionQueueSize is the number of new sessions that wait for a session slot to be freed when the orchestrator is
+// at capacity, instead of being rejected right away
+var AdmissionQueueSize = 0
+
+// How long the slot freed for a waiting session is held for it, until its transcode session is created
+var admissionReservationTTL = 10 * time.Second
+
+type admissionWaiter struct {
+	mid      ManifestID
+	sender   ethcommon.Address
+	admitted chan struct{} // closed once a slot is held for the session
+}
+
+// sessionsInUse returns the number of session slots in use, including the slots held for the admitted sessions.
+// segmentMutex must be held
+func (n *LivepeerNode) sessionsInUse() int {
+	now := time.Now()
+	for mid, expiration := range n.admitted {
+		if now.After(expiration) {
+			delete(n.admitted, mid)
+			if _, ok := n.SegmentChans[mid]; !ok {
+				delete(n.sessionSenders, mid)
+			}
+		}
+	}
+	return len(n.SegmentChans) + len(n.admitted)
+}
+
+// admitSession returns nil if the session can be transcoded. New sessions that arrive when the orchestrator is at
+// capacity preempt a best-effort session if the sender has a priority class, or wait in the admission queue for up to
+// wait for a slot to be freed, in the order they arrived. Discovery requests without a session only check whether
+// the sender would be admitted
+func (n *LivepeerNode) admitSession(ctx context.Context, sender ethcommon.Address, mid ManifestID, wait time.Duration) error {
+	n.segmentMutex.Lock()
+	if _, ok := n.SegmentChans[mid]; ok {
+		n.segmentMutex.Unlock()
+		return nil
+	}
+	if _, ok := n.admitted[mid]; ok {
+		n.segmentMutex.Unlock()
+		return nil
+	}
+	if n.draining {
+		n.segmentMutex.Unlock()
+		return ErrOrchDraining
+	}
+	// Slots held for admitted sessions that never started may have expired
+	n.admitWaitingSessions()
+	if n.overSessionQuota(sender) {
+		n.segmentMutex.Unlock()
+		clog.Infof(ctx, "Sender is at the max sessions of its priority class")
+		return ErrSenderQuota
+	}
+	// Senders with a priority don't wait behind best-effort sessions
+	if (len(n.admissionQueue) == 0 || n.priorityClasses[sender].priority() > 0) && n.hasCapacity(sender) {
+		n.segmentMutex.Unlock()
+		return nil
+	}
+	if victim := n.preemptibleSession(sender); victim != "" {
+		if mid == "" {
+			n.segmentMutex.Unlock()
+			return nil
+		}
+		// Hold the slot of the preempted session so that it isn't given to a waiting session
+		n.holdSlot(mid, sender)
+		n.segmentMutex.Unlock()
+		clog.Infof(ctx, "Preempting best-effort session=%s", victim)
+		if lpmon.Enabled {
+			lpmon.SessionPreempted()
+		}
+		n.endTranscodingSession(string(victim), ctx)
+		return nil
+	}
+	if wait <= 0 || len(n.admissionQueue) >= AdmissionQueueSize {
+		n.segmentMutex.Unlock()
+		return ErrOrchCap
+	}
+	w := &admissionWaiter{mid: mid, sender: sender, admitted: make(chan struct{})}
+	n.admissionQueue = append(n.admissionQueue, w)
+	depth := len(n.admissionQueue)
+	drainStarted := n.drainChan()
+	n.segmentMutex.Unlock()
+	if lpmon.Enabled {
+		lpmon.AdmissionQueueDepth(depth)
+	}
+	clog.V(common.DEBUG).Infof(ctx, "Waiting for a session slot wait=%s queueDepth=%d", wait, depth)
+
+	start := time.Now()
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	admitted := false
+	select {
+	case <-w.admitted:
+		admitted = true
+	case <-timer.C:
+	case <-ctx.Done():
+	case <-drainStarted:
+	}
+	if !admitted {
+		n.segmentMutex.Lock()
+		// The session may have been admitted while it gave up
+		select {
+		case <-w.admitted:
+			admitted = true
+		default:
+			n.removeAdmissionWaiter(w)
+		}
+		depth = len(n.admissionQueue)
+		n.segmentMutex.Unlock()
+		if lpmon.Enabled {
+			lpmon.AdmissionQueueDepth(depth)
+		}
+	}
+	if lpmon.Enabled {
+		lpmon.AdmissionWaited(time.Since(start), admitted)
+	}
+	if !admitted {
+		clog.Infof(ctx, "Timed out waiting for a session slot waited=%s", time.Since(start))
+		return ErrOrchCap
+	}
+	clog.V(common.DEBUG).Infof(ctx, "Admitted session waited=%s", time.Since(start))
+	return nil
+}
+
+// removeAdmissionWaiter removes the session from the admission queue. segmentMutex must be held
+func (n *LivepeerNode) removeAdmissionWaiter(w *admissionWaiter) {
+	for i, waiter := range n.admissionQueue {
+		if waiter == w {
+			n.admissionQueue = append(n.admissionQueue[:i], n.admissionQueue[i+1:]...)
+			return
+		}
+	}
+}
+
+// admitWaitingSessions holds the free session slots for the sessions at the head of the admission queue.
+// segmentMutex must be held
+func (n *LivepeerNode) admitWaitingSessions() {
+	// Waiting sessions are rejected once the node is draining
+	if len(n.admissionQueue) == 0 || n.draining {
+		return
+	}
+	for len(n.admissionQueue) > 0 && n.hasCapacity(n.admissionQueue[0].sender) {
+		w := n.admissionQueue[0]
+		n.admissionQueue = n.admissionQueue[1:]
+		n.holdSlot(w.mid, w.sender)
+		close(w.admitted)
+	}
+	if lpmon.Enabled {
+		lpmon.AdmissionQueueDepth(len(n.admissionQueue))
+	}
+}
+
+// holdSlot holds a session slot for an admitted session until its transcode session is created.
+// segmentMutex must be held
+func (n *LivepeerNode) holdSlot(mid ManifestID, sender ethcommon.Address) {
+	if n.admitted == nil {
+		n.admitted = make(map[ManifestID]time.Time)
+	}
+	n.admitted[mid] = time.Now().Add(admissionReservationTTL)
+	n.setSessionSender(mid, sender)
+}
+
+// AdmissionQueueDepth returns the number of new sessions waiting for a session slot
+func (n *LivepeerNode) AdmissionQueueDepth() int {
+	n.segmentMutex.RLock()
+	defer n.segmentMutex.RUnlock()
+	return len(n.admissionQueue)
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/livepeer/go-livepeer/net"
+	"github.com/livepeer/go-tools/drivers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func admissionNode(t *testing.T, maxSessions, queueSize int) *LivepeerNode {
+	oldMaxSessions, oldQueueSize := MaxSessions, AdmissionQueueSize
+	t.Cleanup(func() { MaxSessions, AdmissionQueueSize = oldMaxSessions, oldQueueSize })
+	MaxSessions, AdmissionQueueSize = maxSessions, queueSize
+	drivers.NodeStorage = drivers.NewMemoryDriver(nil)
+	n, err := NewLivepeerNode(nil, "", nil)
+	require.Nil(t, err)
+	return n
+}
+
+func startSession(t *testing.T, n *LivepeerNode, sessionID string) {
+	md := StubSegTranscodingMetadata()
+	md.AuthToken = &net.AuthToken{SessionId: sessionID}
+	_, err := n.getSegmentChan(context.Background(), md)
+	require.Nil(t, err)
+}
+
+func admitAsync(n *LivepeerNode, mid ManifestID, wait time.Duration) chan error {
+	errc := make(chan error, 1)
+	go func() { errc <- n.admitSession(context.Background(), ethcommon.Address{}, mid, wait) }()
+	return errc
+}
+
+func waitForQueueDepth(t *testing.T, n *LivepeerNode, depth int) {
+	require.Eventually(t, func() bool { return n.AdmissionQueueDepth() == depth }, time.Second, time.Millisecond)
+}
+
+func TestAdmitSession_NoQueue(t *testing.T) {
+	assert := assert.New(t)
+	n := admissionNode(t, 1, 0)
+
+	assert.Nil(n.admitSession(context.Background(), ethcommon.Address{}, "a", time.Second))
+	startSession(t, n, "a")
+	// Sessions at capacity are rejected right away without an admission queue
+	start := time.Now()
+	assert.Equal(ErrOrchCap, n.admitSession(context.Background(), ethcommon.Address{}, "b", time.Second))
+	assert.Less(time.Since(start), 100*time.Millisecond)
+	// Existing sessions are always admitted
+	assert.Nil(n.admitSession(context.Background(), ethcommon.Address{}, "a", 0))
+}
+
+func TestAdmitSession_Queue(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	n := admissionNode(t, 1, 2)
+	startSession(t, n, "a")
+
+	// Sessions wait for a slot in the order they arrived
+	b := admitAsync(n, "b", 5*time.Second)
+	waitForQueueDepth(t, n, 1)
+	c := admitAsync(n, "c", 5*time.Second)
+	waitForQueueDepth(t, n, 2)
+	// Sessions are rejected once the queue is full, or if they can't wait
+	assert.Equal(ErrOrchCap, n.admitSession(context.Background(), ethcommon.Address{}, "d", 5*time.Second))
+	assert.Equal(ErrOrchCap, n.admitSession(context.Background(), ethcommon.Address{}, "d", 0))
+
+	n.endTranscodingSession("a", context.Background())
+	require.Nil(<-b)
+	waitForQueueDepth(t, n, 1)
+	select {
+	case <-c:
+		assert.Fail("session admitted without a free slot")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The slot is held for the admitted session, which can start its transcode session at capacity
+	assert.Nil(n.admitSession(context.Background(), ethcommon.Address{}, "b", 0))
+	assert.Equal(ErrOrchCap, n.admitSession(context.Background(), ethcommon.Address{}, "e", 0))
+	startSession(t, n, "b")
+	n.segmentMutex.RLock()
+	assert.Empty(n.admitted)
+	n.segmentMutex.RUnlock()
+
+	n.endTranscodingSession("b", context.Background())
+	require.Nil(<-c)
+	startSession(t, n, "c")
+
+	// Sessions that are not admitted after waiting are removed from the queue
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(ErrOrchCap, n.admitSession(ctx, ethcommon.Address{}, "f", 5*time.Second))
+	assert.Equal(ErrOrchCap, n.admitSession(context.Background(), ethcommon.Address{}, "f", 10*time.Millisecond))
+	assert.Equal(0, n.AdmissionQueueDepth())
+}
+
+func TestAdmitSession_RaisedLimit(t *testing.T) {
+	require := require.New(t)
+	n := admissionNode(t, 1, 2)
+	startSession(t, n, "a")
+
+	b := admitAsync(n, "b", 5*time.Second)
+	c := admitAsync(n, "c", 5*time.Second)
+	waitForQueueDepth(t, n, 2)
+	n.SetMaxSessions(3)
+	require.Nil(<-b)
+	require.Nil(<-c)
+	waitForQueueDepth(t, n, 0)
+}
+
+func TestAdmitSession_ReservationExpired(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	oldTTL := admissionReservationTTL
+	defer func() { admissionReservationTTL = oldTTL }()
+	admissionReservationTTL = 20 * time.Millisecond
+	n := admissionNode(t, 1, 2)
+	startSession(t, n, "a")
+
+	b := admitAsync(n, "b", 5*time.Second)
+	waitForQueueDepth(t, n, 1)
+	c := admitAsync(n, "c", 5*time.Second)
+	waitForQueueDepth(t, n, 2)
+	n.endTranscodingSession("a", context.Background())
+	require.Nil(<-b)
+
+	// The slot held for a session that never started is given to the next session
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(ErrOrchCap, n.admitSession(context.Background(), ethcommon.Address{}, "d", 0))
+	require.Nil(<-c)
+}
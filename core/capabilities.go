@@ -7,6 +7,7 @@ import (
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/common"
 	"github.com/livepeer/go-livepeer/net"
 	"github.com/livepeer/go-tools/drivers"
 	"github.com/livepeer/lpms/ffmpeg"
@@ -61,6 +62,7 @@ const (
 	Capability_H264_Decode_422_10bit
 	Capability_H264_Decode_420_10bit
 	Capability_SegmentSlicing
+	Capability_FMP4
 )
 
 var CapabilityNameLookup = map[Capability]string{
@@ -92,6 +94,7 @@ var CapabilityNameLookup = map[Capability]string{
 	Capability_H264_Decode_422_10bit:      "H264 Decode YUV422 10-bit",
 	Capability_H264_Decode_420_10bit:      "H264 Decode YUV420 10-bit",
 	Capability_SegmentSlicing:             "Segment slicing",
+	Capability_FMP4:                       "Fragmented MP4",
 }
 
 var CapabilityTestLookup = map[Capability]CapabilityTest{
@@ -163,6 +166,7 @@ func DefaultCapabilities() []Capability {
 		Capability_AuthToken,
 		Capability_MPEG7VideoSignature,
 		Capability_SegmentSlicing,
+		Capability_FMP4,
 	}
 }
 
@@ -541,6 +545,8 @@ func formatToCapability(format ffmpeg.Format) (Capability, error) {
 		return Capability_MPEGTS, nil
 	case ffmpeg.FormatMP4:
 		return Capability_MP4, nil
+	case common.FormatFMP4:
+		return Capability_FMP4, nil
 	}
 	return Capability_Invalid, capFormatConv
 }
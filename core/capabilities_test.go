@@ -223,6 +223,7 @@ func TestCapability_JobCapabilities(t *testing.T) {
 	profs := []ffmpeg.VideoProfile{
 		{Format: ffmpeg.FormatMPEGTS},
 		{Format: ffmpeg.FormatMP4},
+		{Format: common.FormatFMP4},
 		{FramerateDen: 1},
 		{Profile: ffmpeg.ProfileH264Main},
 		{Profile: ffmpeg.ProfileH264High},
@@ -235,6 +236,7 @@ func TestCapability_JobCapabilities(t *testing.T) {
 	assert.True(checkSuccess(params, []Capability{
 		Capability_H264,
 		Capability_MP4,
+		Capability_FMP4,
 		Capability_MPEGTS,
 		Capability_FractionalFramerates,
 		Capability_StorageS3,
@@ -383,8 +385,11 @@ func TestCapability_FormatToCapability(t *testing.T) {
 		_, err := formatToCapability(format)
 		assert.Nil(err)
 	}
+	c, err := formatToCapability(common.FormatFMP4)
+	assert.Equal(Capability_FMP4, c)
+	assert.Nil(err)
 	// ensure error is triggered for unrepresented values
-	c, err := formatToCapability(-100)
+	c, err = formatToCapability(-100)
 	assert.Equal(Capability_Invalid, c)
 	assert.Equal(capFormatConv, err)
 }
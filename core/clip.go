@@ -0,0 +1,149 @@
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/lpms/ffmpeg"
+)
+
+// ClipSegment is a segment of a clip cut from a recording. The segments at the boundaries of the clip are only partly
+// in it
+type ClipSegment struct {
+	SeqNo         uint64
+	URI           string
+	InitURI       string
+	Duration      time.Duration
+	Clip          *SegmentClip // Part of the segment that is in the clip, nil if the whole segment is
+	Discontinuity bool
+}
+
+// ClipSegments returns the segments of the track that are in the clip between the media timestamps, which count from
+// the start of the recording
+func (jpl *JsonPlaylist) ClipSegments(trackName string, from, to time.Duration) []ClipSegment {
+	var segs []ClipSegment
+	var start time.Duration
+	for _, seg := range jpl.Segments[trackName] {
+		duration := time.Duration(seg.DurationMs) * time.Millisecond
+		end := start + duration
+		if end > from && start < to {
+			cs := ClipSegment{
+				SeqNo:         seg.SeqNo,
+				URI:           seg.URI,
+				InitURI:       seg.InitURI,
+				Duration:      duration,
+				Discontinuity: seg.discontinuity,
+			}
+			if from > start || to < end {
+				cs.Clip = &SegmentClip{To: duration}
+				if from > start {
+					cs.Clip.From = from - start
+				}
+				if to < end {
+					cs.Clip.To = to - start
+				}
+			}
+			segs = append(segs, cs)
+		}
+		start = end
+	}
+	return segs
+}
+
+// MediaTime returns the media timestamp of the wall clock time, from the times at which the segments of the track were
+// recorded. Times before the recording are at its start, and times after it are at its end
+func (jpl *JsonPlaylist) MediaTime(trackName string, t time.Time) time.Duration {
+	ms := t.UnixMilli()
+	var start time.Duration
+	for _, seg := range jpl.Segments[trackName] {
+		duration := time.Duration(seg.DurationMs) * time.Millisecond
+		if seg.RecordedAtMs != 0 && ms <= seg.RecordedAtMs {
+			// Segments are recorded once they end
+			offset := duration - time.Duration(seg.RecordedAtMs-ms)*time.Millisecond
+			if offset < 0 {
+				offset = 0
+			}
+			return start + offset
+		}
+		start += duration
+	}
+	return start
+}
+
+// Track returns the track of the recording with the name
+func (jpl *JsonPlaylist) Track(trackName string) (JsonMediaTrack, bool) {
+	for _, track := range jpl.Tracks {
+		if track.Name == trackName {
+			return track, true
+		}
+	}
+	return JsonMediaTrack{}, false
+}
+
+// NewClipPlaylist returns the recording playlist of a clip made of the segments of the track
+func NewClipPlaylist(track JsonMediaTrack, segs []ClipSegment) *JsonPlaylist {
+	jpl := NewJSONPlaylist()
+	jpl.Tracks = []JsonMediaTrack{track}
+	for _, seg := range segs {
+		duration := seg.Duration
+		if seg.Clip != nil {
+			duration = seg.Clip.To - seg.Clip.From
+		}
+		durationMs := uint64(duration / time.Millisecond)
+		jpl.DurationMs += durationMs
+		jpl.Segments[track.Name] = append(jpl.Segments[track.Name], jsonSeg{
+			SeqNo:         seg.SeqNo,
+			URI:           seg.URI,
+			InitURI:       seg.InitURI,
+			DurationMs:    durationMs,
+			discontinuity: seg.Discontinuity,
+		})
+	}
+	return jpl
+}
+
+// TrimSegment re-encodes the part of the segment that is in the clip with the profile of its track. The segment is
+// re-encoded since the clip doesn't start on a keyframe
+func TrimSegment(data []byte, track JsonMediaTrack, format ffmpeg.Format, clip *SegmentClip) ([]byte, error) {
+	dir, err := ioutil.TempDir("", "clip")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temp dir for clip: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "in_"+common.RandName())
+	if err := ioutil.WriteFile(fname, data, 0644); err != nil {
+		return nil, fmt.Errorf("error writing temp file for clip: %w", err)
+	}
+
+	oname := filepath.Join(dir, "out_"+common.RandName())
+	in := &ffmpeg.TranscodeOptionsIn{Fname: fname}
+	out := []ffmpeg.TranscodeOptions{{
+		Oname: oname,
+		Profile: ffmpeg.VideoProfile{
+			Name:       track.Name,
+			Bitrate:    strconv.FormatUint(uint64(track.Bandwidth), 10),
+			Resolution: track.Resolution,
+			Format:     format,
+		},
+		AudioEncoder: ffmpeg.ComponentOptions{Name: "copy"},
+		From:         clip.From,
+		To:           clip.To,
+	}}
+	if _, err := ffmpeg.Transcode3(in, out); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(oname)
+}
+
+// RecordingPath returns the path of the recorded file in the record store, from its URI
+func RecordingPath(manifestIDs []string, uri string) string {
+	if mindex, _ := indexOf(uri, manifestIDs); mindex != -1 {
+		return uri[mindex:]
+	}
+	return uri
+}
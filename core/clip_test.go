@@ -0,0 +1,73 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	ffmpeg "github.com/livepeer/lpms/ffmpeg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONPlaylistClipSegments(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	jpl := NewJSONPlaylist()
+	source := ffmpeg.VideoProfile{Name: "source"}
+	jpl.InsertHLSSegment(&source, 1, "mani/node/source/1.ts", 2)
+	jpl.InsertHLSSegment(&source, 2, "mani/node/source/2.ts", 2)
+	jpl.InsertHLSSegment(&source, 3, "mani/node/source/3.ts", 2)
+
+	// Boundary segments are only partly in the clip
+	segs := jpl.ClipSegments("source", 1500*time.Millisecond, 5*time.Second)
+	require.Len(segs, 3)
+	assert.Equal(uint64(1), segs[0].SeqNo)
+	assert.Equal(&SegmentClip{From: 1500 * time.Millisecond, To: 2 * time.Second}, segs[0].Clip)
+	assert.Nil(segs[1].Clip)
+	assert.Equal(2*time.Second, segs[1].Duration)
+	assert.Equal(&SegmentClip{To: time.Second}, segs[2].Clip)
+
+	// Segments that end at the start of the clip or start at its end aren't in it
+	segs = jpl.ClipSegments("source", 2*time.Second, 4*time.Second)
+	require.Len(segs, 1)
+	assert.Equal("mani/node/source/2.ts", segs[0].URI)
+	assert.Nil(segs[0].Clip)
+	assert.Empty(jpl.ClipSegments("source", 6*time.Second, 8*time.Second))
+	assert.Empty(jpl.ClipSegments("other", 0, 8*time.Second))
+
+	clip := NewClipPlaylist(JsonMediaTrack{Name: "source"}, jpl.ClipSegments("source", 1500*time.Millisecond, 5*time.Second))
+	assert.Equal(uint64(3500), clip.DurationMs)
+	require.Len(clip.Segments["source"], 3)
+	assert.Equal(uint64(500), clip.Segments["source"][0].DurationMs)
+	assert.Equal(uint64(2000), clip.Segments["source"][1].DurationMs)
+	track, ok := clip.Track("source")
+	assert.True(ok)
+	assert.Equal("source", track.Name)
+	_, ok = clip.Track("other")
+	assert.False(ok)
+}
+
+func TestJSONPlaylistMediaTime(t *testing.T) {
+	assert := assert.New(t)
+
+	jpl := NewJSONPlaylist()
+	source := ffmpeg.VideoProfile{Name: "source"}
+	jpl.InsertHLSSegment(&source, 1, "mani/node/source/1.ts", 2)
+	jpl.InsertHLSSegment(&source, 2, "mani/node/source/2.ts", 2)
+	segs := jpl.Segments["source"]
+	recorded := time.UnixMilli(1700000000000)
+	segs[0].RecordedAtMs = recorded.UnixMilli()
+	segs[1].RecordedAtMs = recorded.Add(2 * time.Second).UnixMilli()
+
+	assert.Equal(time.Duration(0), jpl.MediaTime("source", recorded.Add(-time.Hour)))
+	assert.Equal(1500*time.Millisecond, jpl.MediaTime("source", recorded.Add(-500*time.Millisecond)))
+	assert.Equal(3*time.Second, jpl.MediaTime("source", recorded.Add(time.Second)))
+	assert.Equal(4*time.Second, jpl.MediaTime("source", recorded.Add(time.Hour)))
+}
+
+func TestRecordingPath(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("mani/node/source/1.ts", RecordingPath([]string{"other", "mani"}, "https://rec.test/mani/node/source/1.ts"))
+	assert.Equal("node/source/1.ts", RecordingPath([]string{"mani"}, "node/source/1.ts"))
+}
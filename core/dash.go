@@ -0,0 +1,343 @@
+package core
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	ffmpeg "github.com/livepeer/lpms/ffmpeg"
+)
+
+const (
+	dashProfileMP2T = "urn:mpeg:dash:profile:mp2t-main:2011"
+	dashProfileMP4  = "urn:mpeg:dash:profile:isoff-live:2011"
+	// Milliseconds
+	dashTimescale = 1000
+)
+
+// DASHManifest is the dynamic MPEG-DASH manifest of a live stream, made of the same segments as its HLS media
+// playlists. Segments are listed with their URIs since those are assigned by the object store, and segments with the
+// same sequence number start at the same time in every representation
+type DASHManifest struct {
+	winSize int
+
+	mu         sync.Mutex
+	start      time.Time
+	reps       []*dashRepresentation
+	starts     map[uint64]float64
+	hasLast    bool
+	lastSeqNo  uint64
+	lastStart  float64
+	lastLength float64
+}
+
+type dashRepresentation struct {
+	name       string
+	bandwidth  uint32
+	resolution string
+	// Init segment of fragmented MP4 representations
+	init     string
+	segments []dashSegment
+}
+
+type dashSegment struct {
+	seqNo    uint64
+	uri      string
+	start    float64
+	duration float64
+}
+
+func NewDASHManifest(winSize uint) *DASHManifest {
+	return &DASHManifest{
+		winSize: int(winSize),
+		starts:  make(map[uint64]float64),
+	}
+}
+
+// InsertSegment adds the segment to the representation of the profile. The availability start time of the manifest is
+// set when the first segment is added, at the start of that segment
+func (m *DASHManifest) InsertSegment(profile *ffmpeg.VideoProfile, seqNo uint64, uri string, duration float64) {
+	m.insertSegment(profile, seqNo, uri, "", duration)
+}
+
+// InsertFMP4Segment adds the fragmented MP4 segment to the representation of the profile. Representations only have
+// one init segment, so they use the init segment of their latest segment
+func (m *DASHManifest) InsertFMP4Segment(profile *ffmpeg.VideoProfile, seqNo uint64, uri, initURI string,
+	duration float64) {
+
+	m.insertSegment(profile, seqNo, uri, initURI, duration)
+}
+
+func (m *DASHManifest) insertSegment(profile *ffmpeg.VideoProfile, seqNo uint64, uri, initURI string,
+	duration float64) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.start.IsZero() {
+		m.start = time.Now().Add(-time.Duration(duration * float64(time.Second)))
+	}
+
+	var rep *dashRepresentation
+	for _, r := range m.reps {
+		if r.name == profile.Name {
+			rep = r
+			break
+		}
+	}
+	if rep == nil {
+		vParams := ffmpeg.VideoProfileToVariantParams(*profile)
+		rep = &dashRepresentation{name: profile.Name, bandwidth: vParams.Bandwidth, resolution: vParams.Resolution}
+		m.reps = append(m.reps, rep)
+	}
+
+	i := sort.Search(len(rep.segments), func(i int) bool { return rep.segments[i].seqNo >= seqNo })
+	if i < len(rep.segments) && rep.segments[i].seqNo == seqNo {
+		return
+	}
+	seg := dashSegment{seqNo: seqNo, uri: uri, start: m.segmentStart(seqNo, duration), duration: duration}
+	if initURI != "" && i == len(rep.segments) {
+		rep.init = initURI
+	}
+	rep.segments = append(rep.segments, dashSegment{})
+	copy(rep.segments[i+1:], rep.segments[i:])
+	rep.segments[i] = seg
+	if len(rep.segments) > m.winSize {
+		rep.segments = rep.segments[len(rep.segments)-m.winSize:]
+	}
+}
+
+// segmentStart returns the start of the segment relative to the availability start time. Segments follow the latest
+// one, and missing segments are presumed to have the same duration
+func (m *DASHManifest) segmentStart(seqNo uint64, duration float64) float64 {
+	if start, ok := m.starts[seqNo]; ok {
+		return start
+	}
+	var start float64
+	if m.hasLast && seqNo > m.lastSeqNo {
+		start = m.lastStart + m.lastLength + float64(seqNo-m.lastSeqNo-1)*duration
+	} else if m.hasLast {
+		start = m.lastStart - float64(m.lastSeqNo-seqNo)*duration
+		if start < 0 {
+			start = 0
+		}
+	}
+	m.starts[seqNo] = start
+	if !m.hasLast || seqNo > m.lastSeqNo {
+		m.hasLast = true
+		m.lastSeqNo, m.lastStart, m.lastLength = seqNo, start, duration
+	}
+	// Starts are kept for the segments that can still arrive in other renditions
+	for s := range m.starts {
+		if s+uint64(4*m.winSize) < m.lastSeqNo {
+			delete(m.starts, s)
+		}
+	}
+	return start
+}
+
+// Encode renders the manifest, which players reload every segment duration
+func (m *DASHManifest) Encode(now time.Time) *bytes.Buffer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var maxDuration, window float64
+	reps := make([]dashRepresentation, 0, len(m.reps))
+	for _, rep := range m.reps {
+		var repWindow float64
+		for _, seg := range rep.segments {
+			if seg.duration > maxDuration {
+				maxDuration = seg.duration
+			}
+			repWindow += seg.duration
+		}
+		if repWindow > window {
+			window = repWindow
+		}
+		reps = append(reps, *rep)
+	}
+	start := m.start
+	if start.IsZero() {
+		start = now
+	}
+
+	mpd := newMPD(reps)
+	mpd.Type = "dynamic"
+	mpd.AvailabilityStartTime = start.UTC().Format(time.RFC3339Nano)
+	mpd.PublishTime = now.UTC().Format(time.RFC3339Nano)
+	mpd.MinimumUpdatePeriod = dashDuration(maxDuration)
+	mpd.TimeShiftBufferDepth = dashDuration(window)
+	mpd.SuggestedPresentationDelay = dashDuration(3 * maxDuration)
+	mpd.MinBufferTime = dashDuration(maxDuration)
+	return mpd.encode()
+}
+
+// EncodeDASH renders the static MPEG-DASH manifest of the recording, with the segment URIs of the HLS media playlists.
+// Tracks without segments are left out
+func (jpl *JsonPlaylist) EncodeDASH(manifestIDs []string, extURL string) *bytes.Buffer {
+	var reps []dashRepresentation
+	var duration float64
+	for _, track := range jpl.Tracks {
+		segs := jpl.Segments[track.Name]
+		if len(segs) == 0 {
+			continue
+		}
+		rep := dashRepresentation{name: track.Name, bandwidth: track.Bandwidth, resolution: track.Resolution}
+		var start float64
+		for _, seg := range segs {
+			dur := float64(seg.DurationMs) / 1000.0
+			rep.segments = append(rep.segments, dashSegment{
+				seqNo:    seg.SeqNo,
+				uri:      jpl.segmentURI(manifestIDs, seg.URI, extURL),
+				start:    start,
+				duration: dur,
+			})
+			if seg.InitURI != "" {
+				rep.init = jpl.segmentURI(manifestIDs, seg.InitURI, extURL)
+			}
+			start += dur
+		}
+		if start > duration {
+			duration = start
+		}
+		reps = append(reps, rep)
+	}
+
+	mpd := newMPD(reps)
+	mpd.Type = "static"
+	mpd.MediaPresentationDuration = dashDuration(duration)
+	mpd.MinBufferTime = dashDuration(2)
+	return mpd.encode()
+}
+
+type mpd struct {
+	XMLName                    xml.Name  `xml:"urn:mpeg:dash:schema:mpd:2011 MPD"`
+	Profiles                   string    `xml:"profiles,attr"`
+	Type                       string    `xml:"type,attr"`
+	AvailabilityStartTime      string    `xml:"availabilityStartTime,attr,omitempty"`
+	PublishTime                string    `xml:"publishTime,attr,omitempty"`
+	MinimumUpdatePeriod        string    `xml:"minimumUpdatePeriod,attr,omitempty"`
+	TimeShiftBufferDepth       string    `xml:"timeShiftBufferDepth,attr,omitempty"`
+	SuggestedPresentationDelay string    `xml:"suggestedPresentationDelay,attr,omitempty"`
+	MediaPresentationDuration  string    `xml:"mediaPresentationDuration,attr,omitempty"`
+	MinBufferTime              string    `xml:"minBufferTime,attr"`
+	Period                     mpdPeriod `xml:"Period"`
+}
+
+type mpdPeriod struct {
+	ID            string           `xml:"id,attr"`
+	Start         string           `xml:"start,attr"`
+	AdaptationSet mpdAdaptationSet `xml:"AdaptationSet"`
+}
+
+type mpdAdaptationSet struct {
+	ContentType      string              `xml:"contentType,attr"`
+	SegmentAlignment bool                `xml:"segmentAlignment,attr"`
+	Representations  []mpdRepresentation `xml:"Representation"`
+}
+
+type mpdRepresentation struct {
+	ID          string         `xml:"id,attr"`
+	MimeType    string         `xml:"mimeType,attr"`
+	Bandwidth   uint32         `xml:"bandwidth,attr"`
+	Width       int            `xml:"width,attr,omitempty"`
+	Height      int            `xml:"height,attr,omitempty"`
+	SegmentList mpdSegmentList `xml:"SegmentList"`
+}
+
+type mpdSegmentList struct {
+	Timescale       int                `xml:"timescale,attr"`
+	StartNumber     uint64             `xml:"startNumber,attr"`
+	Initialization  *mpdInitialization `xml:"Initialization"`
+	SegmentTimeline []mpdS             `xml:"SegmentTimeline>S"`
+	SegmentURLs     []mpdSegmentURL    `xml:"SegmentURL"`
+}
+
+type mpdInitialization struct {
+	SourceURL string `xml:"sourceURL,attr"`
+}
+
+type mpdS struct {
+	T *int64 `xml:"t,attr"`
+	D int64  `xml:"d,attr"`
+}
+
+type mpdSegmentURL struct {
+	Media string `xml:"media,attr"`
+}
+
+// newMPD returns the manifest with a single period and adaptation set, since the renditions are muxed with the audio
+func newMPD(reps []dashRepresentation) *mpd {
+	m := &mpd{
+		Profiles: dashProfileMP4,
+		Period: mpdPeriod{
+			ID:            "0",
+			Start:         "PT0S",
+			AdaptationSet: mpdAdaptationSet{ContentType: "video", SegmentAlignment: true},
+		},
+	}
+	for _, rep := range reps {
+		r := mpdRepresentation{
+			ID:          rep.name,
+			MimeType:    "video/mp4",
+			Bandwidth:   rep.bandwidth,
+			SegmentList: mpdSegmentList{Timescale: dashTimescale},
+		}
+		if w, h, ok := strings.Cut(rep.resolution, "x"); ok {
+			r.Width, _ = strconv.Atoi(w)
+			r.Height, _ = strconv.Atoi(h)
+		}
+		if rep.init != "" {
+			r.SegmentList.Initialization = &mpdInitialization{SourceURL: rep.init}
+		}
+		var end int64 = -1
+		for i, seg := range rep.segments {
+			if i == 0 {
+				r.SegmentList.StartNumber = seg.seqNo
+			}
+			if ext := path.Ext(seg.uri); ext != ".mp4" && ext != ".m4s" {
+				r.MimeType = "video/mp2t"
+				m.Profiles = dashProfileMP2T
+			}
+			s := mpdS{D: int64(seg.duration*dashTimescale + 0.5)}
+			// The start is only needed if the segment doesn't follow the previous one
+			if t := int64(seg.start*dashTimescale + 0.5); t != end {
+				s.T = &t
+			}
+			end = dashEnd(s, end)
+			r.SegmentList.SegmentTimeline = append(r.SegmentList.SegmentTimeline, s)
+			r.SegmentList.SegmentURLs = append(r.SegmentList.SegmentURLs, mpdSegmentURL{Media: seg.uri})
+		}
+		m.Period.AdaptationSet.Representations = append(m.Period.AdaptationSet.Representations, r)
+	}
+	return m
+}
+
+func dashEnd(s mpdS, end int64) int64 {
+	if s.T != nil {
+		return *s.T + s.D
+	}
+	return end + s.D
+}
+
+func (m *mpd) encode() *bytes.Buffer {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	// Only fails for types that can't be encoded
+	_ = enc.Encode(m)
+	buf.WriteByte('\n')
+	return &buf
+}
+
+// dashDuration formats the seconds as an ISO 8601 duration
+func dashDuration(seconds float64) string {
+	return fmt.Sprintf("PT%sS", strconv.FormatFloat(math.Round(seconds*1000)/1000, 'f', -1, 64))
+}
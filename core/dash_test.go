@@ -0,0 +1,171 @@
+package core
+
+import (
+	"encoding/xml"
+	"fmt"
+	"testing"
+	"time"
+
+	ffmpeg "github.com/livepeer/lpms/ffmpeg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parsedMPD is the subset of the manifest that the tests check
+type parsedMPD struct {
+	Type                      string `xml:"type,attr"`
+	Profiles                  string `xml:"profiles,attr"`
+	AvailabilityStartTime     string `xml:"availabilityStartTime,attr"`
+	TimeShiftBufferDepth      string `xml:"timeShiftBufferDepth,attr"`
+	MinimumUpdatePeriod       string `xml:"minimumUpdatePeriod,attr"`
+	MediaPresentationDuration string `xml:"mediaPresentationDuration,attr"`
+	Representations           []struct {
+		ID          string `xml:"id,attr"`
+		MimeType    string `xml:"mimeType,attr"`
+		Bandwidth   uint32 `xml:"bandwidth,attr"`
+		Width       int    `xml:"width,attr"`
+		Height      int    `xml:"height,attr"`
+		SegmentList struct {
+			StartNumber uint64 `xml:"startNumber,attr"`
+			S           []struct {
+				T *int64 `xml:"t,attr"`
+				D int64  `xml:"d,attr"`
+			} `xml:"SegmentTimeline>S"`
+			SegmentURLs []struct {
+				Media string `xml:"media,attr"`
+			} `xml:"SegmentURL"`
+		} `xml:"SegmentList"`
+	} `xml:"Period>AdaptationSet>Representation"`
+}
+
+func parseMPD(t *testing.T, data []byte) parsedMPD {
+	var m parsedMPD
+	require.Nil(t, xml.Unmarshal(data, &m))
+	return m
+}
+
+func TestDASHManifest(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	m := NewDASHManifest(3)
+	now := time.Now()
+	empty := parseMPD(t, m.Encode(now).Bytes())
+	assert.Equal("dynamic", empty.Type)
+	assert.Empty(empty.Representations)
+
+	source := ffmpeg.VideoProfile{Name: "source", Bitrate: "4000k"}
+	for i := uint64(3); i < 8; i++ {
+		m.InsertSegment(&source, i, fmt.Sprintf("/stream/sess/source/%d.ts", i), 2)
+	}
+	// Transcoded segments arrive out of order
+	m.InsertSegment(&ffmpeg.P144p30fps16x9, 7, "/stream/sess/P144p30fps16x9/7.ts", 2)
+	m.InsertSegment(&ffmpeg.P144p30fps16x9, 5, "/stream/sess/P144p30fps16x9/5.ts", 2)
+	// Duplicates are ignored
+	m.InsertSegment(&ffmpeg.P144p30fps16x9, 5, "/stream/sess/P144p30fps16x9/dup.ts", 2)
+
+	mpd := parseMPD(t, m.Encode(now).Bytes())
+	assert.Equal("dynamic", mpd.Type)
+	assert.Equal(dashProfileMP2T, mpd.Profiles)
+	assert.Equal("PT6S", mpd.TimeShiftBufferDepth)
+	assert.Equal("PT2S", mpd.MinimumUpdatePeriod)
+	ast, err := time.Parse(time.RFC3339Nano, mpd.AvailabilityStartTime)
+	require.Nil(err)
+	assert.WithinDuration(now.Add(-2*time.Second), ast, time.Second)
+	require.Len(mpd.Representations, 2)
+
+	// The window has the last segments
+	src := mpd.Representations[0]
+	assert.Equal("source", src.ID)
+	assert.Equal("video/mp2t", src.MimeType)
+	assert.Equal(uint32(4000000), src.Bandwidth)
+	assert.Zero(src.Width)
+	assert.Equal(uint64(5), src.SegmentList.StartNumber)
+	require.Len(src.SegmentList.S, 3)
+	require.NotNil(src.SegmentList.S[0].T)
+	assert.Equal(int64(4000), *src.SegmentList.S[0].T)
+	assert.Nil(src.SegmentList.S[1].T)
+	assert.Equal(int64(2000), src.SegmentList.S[2].D)
+	assert.Equal("/stream/sess/source/7.ts", src.SegmentList.SegmentURLs[2].Media)
+
+	// Segments start at the same time in every representation, and gaps are explicit
+	rend := mpd.Representations[1]
+	assert.Equal("P144p30fps16x9", rend.ID)
+	assert.Equal(256, rend.Width)
+	assert.Equal(144, rend.Height)
+	assert.Equal(uint64(5), rend.SegmentList.StartNumber)
+	require.Len(rend.SegmentList.S, 2)
+	assert.Equal(int64(4000), *rend.SegmentList.S[0].T)
+	require.NotNil(rend.SegmentList.S[1].T)
+	assert.Equal(int64(8000), *rend.SegmentList.S[1].T)
+	assert.Equal("/stream/sess/P144p30fps16x9/5.ts", rend.SegmentList.SegmentURLs[0].Media)
+}
+
+func TestDASHManifest_FMP4(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	m := NewDASHManifest(3)
+	m.InsertFMP4Segment(&ffmpeg.P144p30fps16x9, 1, "/stream/sess/P144p30fps16x9/1.m4s", "/stream/sess/P144p30fps16x9/init_a.mp4", 2)
+	// Representations use the init segment of their latest segment
+	m.InsertFMP4Segment(&ffmpeg.P144p30fps16x9, 3, "/stream/sess/P144p30fps16x9/3.m4s", "/stream/sess/P144p30fps16x9/init_b.mp4", 2)
+	m.InsertFMP4Segment(&ffmpeg.P144p30fps16x9, 2, "/stream/sess/P144p30fps16x9/2.m4s", "/stream/sess/P144p30fps16x9/init_a.mp4", 2)
+
+	data := m.Encode(time.Now()).String()
+	assert.Contains(data, `<Initialization sourceURL="/stream/sess/P144p30fps16x9/init_b.mp4"></Initialization>`)
+	mpd := parseMPD(t, []byte(data))
+	assert.Equal(dashProfileMP4, mpd.Profiles)
+	require.Len(mpd.Representations, 1)
+	assert.Equal("video/mp4", mpd.Representations[0].MimeType)
+	assert.Len(mpd.Representations[0].SegmentList.SegmentURLs, 3)
+}
+
+func TestDASHManifest_SegmentStart(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewDASHManifest(6)
+	assert.Equal(0.0, m.segmentStart(10, 2))
+	assert.Equal(2.0, m.segmentStart(11, 1.5))
+	// Missing segments are presumed to have the same duration
+	assert.Equal(7.5, m.segmentStart(14, 2))
+	// Late segments are placed before the latest one
+	assert.Equal(5.5, m.segmentStart(13, 2))
+	assert.Equal(0.0, m.segmentStart(9, 2))
+	// Known segments keep their start
+	assert.Equal(2.0, m.segmentStart(11, 3))
+	// Old starts are dropped
+	m.segmentStart(100, 2)
+	assert.Len(m.starts, 1)
+}
+
+func TestJSONPlaylistEncodeDASH(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	jpl := NewJSONPlaylist()
+	vProfile := ffmpeg.P144p30fps16x9
+	jpl.InsertHLSSegment(&vProfile, 1, "manifestID/test_seg/1.mp4", 2.1)
+	jpl.InsertHLSSegment(&vProfile, 2, "manifestID/test_seg/2.mp4", 2.5)
+	vProfile = ffmpeg.P240p30fps16x9
+	jpl.InsertHLSSegment(&vProfile, 1, "manifestID/test_seg/1.mp4", 2.1)
+	jpl.Tracks = append(jpl.Tracks, JsonMediaTrack{Name: "empty"})
+
+	mpd := parseMPD(t, jpl.EncodeDASH([]string{"manifestID"}, "").Bytes())
+	assert.Equal("static", mpd.Type)
+	assert.Equal(dashProfileMP4, mpd.Profiles)
+	assert.Equal("PT4.6S", mpd.MediaPresentationDuration)
+	assert.Empty(mpd.AvailabilityStartTime)
+	require.Len(mpd.Representations, 2)
+	rep := mpd.Representations[0]
+	assert.Equal("video/mp4", rep.MimeType)
+	assert.Equal(uint64(1), rep.SegmentList.StartNumber)
+	require.Len(rep.SegmentList.S, 2)
+	assert.Equal(int64(0), *rep.SegmentList.S[0].T)
+	assert.Equal(int64(2100), rep.SegmentList.S[0].D)
+	assert.Equal(int64(2500), rep.SegmentList.S[1].D)
+	assert.Equal("test_seg/2.mp4", rep.SegmentList.SegmentURLs[1].Media)
+
+	// Segment URLs are absolute with the URL of the object store
+	mpd = parseMPD(t, jpl.EncodeDASH([]string{"manifestID"}, "https://pub.test/").Bytes())
+	assert.Equal("https://pub.test/manifestID/test_seg/1.mp4", mpd.Representations[1].SegmentList.SegmentURLs[0].Media)
+}
@@ -0,0 +1,90 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// ErrOrchDraining is returned for new sessions once the node is draining. It is an ErrOrchCap so that broadcasters
+// move the stream to another orchestrator
+var ErrOrchDraining = fmt.Errorf("%w: draining", ErrOrchCap)
+
+// ErrTranscoderDraining is returned by a draining remote transcoder for the segments of new sessions
+var ErrTranscoderDraining = errors.New("TranscoderDraining")
+
+// DrainTimeout is how long a draining node waits for its sessions to end before it ends them
+var DrainTimeout = 5 * time.Minute
+
+// Interval at which a draining node checks whether its sessions ended
+var drainPollInterval = time.Second
+
+// drainChan returns the channel that is closed once the node starts draining. segmentMutex must be held
+func (n *LivepeerNode) drainChan() chan struct{} {
+	if n.drainStarted == nil {
+		n.drainStarted = make(chan struct{})
+	}
+	return n.drainStarted
+}
+
+// Drain stops the node from accepting new sessions, so that it can exit once the sessions that are running ended
+func (n *LivepeerNode) Drain() {
+	n.segmentMutex.Lock()
+	defer n.segmentMutex.Unlock()
+	if n.draining {
+		return
+	}
+	n.draining = true
+	close(n.drainChan())
+	glog.Infof("Draining node, new sessions are rejected sessions=%d", len(n.SegmentChans))
+}
+
+// Draining returns a channel that is closed once the node starts draining
+func (n *LivepeerNode) Draining() <-chan struct{} {
+	n.segmentMutex.Lock()
+	defer n.segmentMutex.Unlock()
+	return n.drainChan()
+}
+
+// IsDraining returns whether the node is draining
+func (n *LivepeerNode) IsDraining() bool {
+	n.segmentMutex.RLock()
+	defer n.segmentMutex.RUnlock()
+	return n.draining
+}
+
+// WaitForSessions blocks until the sessions of the node ended, and ends the sessions that are still running after
+// timeout
+func (n *LivepeerNode) WaitForSessions(timeout time.Duration) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		n.segmentMutex.Lock()
+		sessions := n.sessionsInUse()
+		n.segmentMutex.Unlock()
+		if sessions == 0 {
+			glog.Infof("All sessions ended")
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			n.segmentMutex.RLock()
+			mids := make([]ManifestID, 0, len(n.SegmentChans))
+			for mid := range n.SegmentChans {
+				mids = append(mids, mid)
+			}
+			n.segmentMutex.RUnlock()
+			glog.Infof("Ending sessions=%d that are still running after timeout=%s", len(mids), timeout)
+			for _, mid := range mids {
+				n.endTranscodingSession(string(mid), context.Background())
+			}
+			return
+		}
+	}
+}
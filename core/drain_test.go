@@ -0,0 +1,121 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/livepeer/go-livepeer/net"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrain_RejectsNewSessions(t *testing.T) {
+	assert := assert.New(t)
+	n := admissionNode(t, 1, 1)
+	n.Capabilities = NewCapabilities(DefaultCapabilities(), nil)
+	orch := NewOrchestrator(n, nil)
+	startSession(t, n, "a")
+	waiting := admitAsync(n, "b", 5*time.Second)
+	waitForQueueDepth(t, n, 1)
+
+	assert.False(n.IsDraining())
+	for _, capacity := range orch.Capabilities().Capacities {
+		assert.NotZero(capacity)
+	}
+
+	n.Drain()
+	n.Drain()
+	<-n.Draining()
+	assert.True(n.IsDraining())
+
+	// Waiting and new sessions are rejected like a capped orchestrator, but running sessions go on
+	assert.Equal(ErrOrchCap, <-waiting)
+	err := n.admitSession(context.Background(), ethcommon.Address{}, "c", time.Second)
+	assert.Equal(ErrOrchDraining, err)
+	assert.True(errors.Is(err, ErrOrchCap))
+	assert.Nil(n.admitSession(context.Background(), ethcommon.Address{}, "a", 0))
+
+	// A draining orchestrator advertises zero capacity
+	caps := orch.Capabilities()
+	assert.NotEmpty(caps.Capacities)
+	for _, capacity := range caps.Capacities {
+		assert.Zero(capacity)
+	}
+}
+
+func TestDrain_WaitForSessions(t *testing.T) {
+	assert := assert.New(t)
+	oldInterval := drainPollInterval
+	defer func() { drainPollInterval = oldInterval }()
+	drainPollInterval = time.Millisecond
+	n := admissionNode(t, 10, 0)
+	startSession(t, n, "a")
+	startSession(t, n, "b")
+	n.Drain()
+
+	done := make(chan struct{})
+	go func() {
+		n.WaitForSessions(5 * time.Second)
+		close(done)
+	}()
+	n.endTranscodingSession("a", context.Background())
+	select {
+	case <-done:
+		assert.Fail("returned before the sessions ended")
+	case <-time.After(20 * time.Millisecond):
+	}
+	n.endTranscodingSession("b", context.Background())
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		assert.Fail("did not return once the sessions ended")
+	}
+
+	// Sessions that are still running after the timeout are ended
+	n, _ = NewLivepeerNode(nil, "", nil)
+	startSession(t, n, "c")
+	n.WaitForSessions(10 * time.Millisecond)
+	n.segmentMutex.RLock()
+	assert.Empty(n.SegmentChans)
+	n.segmentMutex.RUnlock()
+}
+
+func TestRemoteTranscoderManager_Draining(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	m := NewRemoteTranscoderManager()
+	s := &StubTranscoderServer{manager: m}
+	go m.Manage(s, 5, nil)
+	time.Sleep(1 * time.Millisecond)
+
+	t1, err := m.selectTranscoder("existing", nil)
+	require.Nil(err)
+
+	// Sessions bounced by a draining transcoder are not assigned to it again
+	s.TranscodeError = fmt.Errorf(ErrTranscoderDraining.Error())
+	_, err = m.Transcode(context.TODO(), &SegTranscodingMetadata{AuthToken: &net.AuthToken{SessionId: "new"}})
+	assert.Equal(ErrNoCompatibleTranscodersAvailable, err)
+	m.RTmutex.Lock()
+	assert.True(t1.draining)
+	assert.NotContains(m.streamSessions, "new")
+	m.RTmutex.Unlock()
+
+	// The sessions of the draining transcoder keep it
+	tc, err := m.selectTranscoder("existing", nil)
+	assert.Nil(err)
+	assert.Equal(t1, tc)
+
+	s2 := &StubTranscoderServer{manager: m}
+	go m.Manage(s2, 5, nil)
+	time.Sleep(1 * time.Millisecond)
+	res, err := m.Transcode(context.TODO(), &SegTranscodingMetadata{AuthToken: &net.AuthToken{SessionId: "new"}})
+	require.Nil(err)
+	assert.Equal("asdf", string(res.Segments[0].Data))
+	m.RTmutex.Lock()
+	assert.Equal(m.liveTranscoders[s2], m.streamSessions["new"])
+	m.RTmutex.Unlock()
+}
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var ErrDVRStartOutOfWindow = errors.New("DVRStartOutOfWindow")
+
+// DVRPlaylist is a live media playlist that keeps the segments of the DVR window of the stream, so that viewers can
+// rewind up to the length of the window. Segments stay in the object store while they are in the window
+type DVRPlaylist struct {
+	window      time.Duration
+	minSegments int
+
+	mu       sync.Mutex
+	segments []dvrSegment
+	duration time.Duration
+}
+
+type dvrSegment struct {
+	seqNo    uint64
+	uri      string
+	initURI  string
+	keyURI   string
+	duration time.Duration
+}
+
+// NewDVRPlaylist creates a playlist that keeps at least minSegments segments, so that the segments that are still
+// listed by the live playlists of the stream are not deleted
+func NewDVRPlaylist(window time.Duration, minSegments uint) *DVRPlaylist {
+	if minSegments < 1 {
+		minSegments = 1
+	}
+	return &DVRPlaylist{window: window, minSegments: int(minSegments)}
+}
+
+// InsertSegment adds the segment in the order of the sequence numbers and returns the URIs of the segments that fell
+// out of the window. The window always holds at least its length of segments. Duplicate segments are ignored.
+// Segments encrypted with AES-128 have the URI of their key
+func (p *DVRPlaylist) InsertSegment(seqNo uint64, uri, initURI, keyURI string, duration float64) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i := sort.Search(len(p.segments), func(i int) bool { return p.segments[i].seqNo >= seqNo })
+	if i < len(p.segments) && p.segments[i].seqNo == seqNo {
+		return nil
+	}
+	seg := dvrSegment{
+		seqNo:    seqNo,
+		uri:      uri,
+		initURI:  initURI,
+		keyURI:   keyURI,
+		duration: time.Duration(duration * float64(time.Second)),
+	}
+	p.segments = append(p.segments, dvrSegment{})
+	copy(p.segments[i+1:], p.segments[i:])
+	p.segments[i] = seg
+	p.duration += seg.duration
+
+	var evicted []string
+	for len(p.segments) > p.minSegments && p.duration-p.segments[0].duration >= p.window {
+		evicted = append(evicted, p.segments[0].uri)
+		p.duration -= p.segments[0].duration
+		p.segments = p.segments[1:]
+	}
+	return evicted
+}
+
+// Duration returns the length of the segments in the window
+func (p *DVRPlaylist) Duration() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.duration
+}
+
+// Encode renders the playlist. Players start playback at the live edge, unless start is set, in which case they start
+// at that offset into the window, or from the live edge if it is negative
+func (p *DVRPlaylist) Encode(start *time.Duration) (*bytes.Buffer, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.segments) == 0 {
+		return nil, ErrDVRStartOutOfWindow
+	}
+	var offset time.Duration
+	if start != nil {
+		offset = *start
+		if offset < 0 {
+			offset += p.duration
+		}
+		if offset < 0 || offset >= p.duration {
+			return nil, ErrDVRStartOutOfWindow
+		}
+	}
+
+	var targetDuration float64
+	for _, seg := range p.segments {
+		targetDuration = math.Max(targetDuration, seg.duration.Seconds())
+	}
+	version := 3
+	if p.segments[len(p.segments)-1].initURI != "" {
+		// EXT-X-MAP in playlists of segments that aren't I-frame only
+		version = 6
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("#EXTM3U\n")
+	fmt.Fprintf(&buf, "#EXT-X-VERSION:%d\n", version)
+	fmt.Fprintf(&buf, "#EXT-X-TARGETDURATION:%d\n", int(math.Ceil(targetDuration)))
+	fmt.Fprintf(&buf, "#EXT-X-MEDIA-SEQUENCE:%d\n", p.segments[0].seqNo)
+	if start != nil {
+		fmt.Fprintf(&buf, "#EXT-X-START:TIME-OFFSET=%s,PRECISE=YES\n", formatSeconds(offset))
+	}
+	var initURI string
+	for _, seg := range p.segments {
+		if seg.initURI != "" && seg.initURI != initURI {
+			initURI = seg.initURI
+			fmt.Fprintf(&buf, "#EXT-X-MAP:URI=\"%s\"\n", initURI)
+		}
+		if seg.keyURI != "" {
+			fmt.Fprintf(&buf, "#EXT-X-KEY:METHOD=AES-128,URI=\"%s\",IV=0x%x\n", seg.keyURI, segmentIV(seg.seqNo))
+		}
+		fmt.Fprintf(&buf, "#EXTINF:%s,\n%s\n", formatSeconds(seg.duration), seg.uri)
+	}
+	return &buf, nil
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
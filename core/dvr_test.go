@@ -0,0 +1,150 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/livepeer/go-tools/drivers"
+	ffmpeg "github.com/livepeer/lpms/ffmpeg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDVRPlaylist(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	pl := NewDVRPlaylist(5*time.Second, 1)
+	assert.Empty(pl.InsertSegment(1, "mani/source/1.ts", "", "", 2))
+	assert.Empty(pl.InsertSegment(2, "mani/source/2.ts", "", "", 2))
+	// The window keeps at least its length of segments
+	assert.Empty(pl.InsertSegment(3, "mani/source/3.ts", "", "", 2))
+	assert.Equal(6*time.Second, pl.Duration())
+	assert.Equal([]string{"mani/source/1.ts"}, pl.InsertSegment(4, "mani/source/4.ts", "", "", 1))
+	assert.Equal([]string{"mani/source/2.ts"}, pl.InsertSegment(5, "mani/source/5.ts", "", "", 2))
+	assert.Equal(5*time.Second, pl.Duration())
+
+	buf, err := pl.Encode(nil)
+	require.Nil(err)
+	assert.Equal("#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:2\n#EXT-X-MEDIA-SEQUENCE:3\n"+
+		"#EXTINF:2.000,\nmani/source/3.ts\n#EXTINF:1.000,\nmani/source/4.ts\n#EXTINF:2.000,\nmani/source/5.ts\n",
+		buf.String())
+
+	// Start offsets count from the start of the window, or back from the live edge
+	start := 1500 * time.Millisecond
+	buf, err = pl.Encode(&start)
+	require.Nil(err)
+	assert.Contains(buf.String(), "#EXT-X-MEDIA-SEQUENCE:3\n#EXT-X-START:TIME-OFFSET=1.500,PRECISE=YES\n")
+	start = -2 * time.Second
+	buf, err = pl.Encode(&start)
+	require.Nil(err)
+	assert.Contains(buf.String(), "#EXT-X-START:TIME-OFFSET=3.000,PRECISE=YES\n")
+
+	for _, start := range []time.Duration{5 * time.Second, -6 * time.Second} {
+		_, err = pl.Encode(&start)
+		assert.Equal(ErrDVRStartOutOfWindow, err)
+	}
+}
+
+func TestDVRPlaylist_FMP4(t *testing.T) {
+	assert := assert.New(t)
+
+	pl := NewDVRPlaylist(time.Minute, LIVE_LIST_LENGTH)
+	pl.InsertSegment(1, "mani/source/1.m4s", "mani/source/init_a.mp4", "", 2)
+	pl.InsertSegment(2, "mani/source/2.m4s", "mani/source/init_a.mp4", "", 2)
+	pl.InsertSegment(3, "mani/source/3.m4s", "mani/source/init_b.mp4", "", 2.5)
+
+	// Init segments are listed when they change
+	buf, err := pl.Encode(nil)
+	assert.Nil(err)
+	assert.Equal("#EXTM3U\n#EXT-X-VERSION:6\n#EXT-X-TARGETDURATION:3\n#EXT-X-MEDIA-SEQUENCE:1\n"+
+		"#EXT-X-MAP:URI=\"mani/source/init_a.mp4\"\n#EXTINF:2.000,\nmani/source/1.m4s\n#EXTINF:2.000,\nmani/source/2.m4s\n"+
+		"#EXT-X-MAP:URI=\"mani/source/init_b.mp4\"\n#EXTINF:2.500,\nmani/source/3.m4s\n", buf.String())
+}
+
+func TestDVRPlaylist_OutOfOrder(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	pl := NewDVRPlaylist(5*time.Second, 1)
+	assert.Empty(pl.InsertSegment(1, "mani/source/1.ts", "", "", 2))
+	// Segments are listed in the order of their sequence numbers
+	assert.Empty(pl.InsertSegment(3, "mani/source/3.ts", "", "", 2))
+	assert.Empty(pl.InsertSegment(2, "mani/source/2.ts", "", "", 2))
+	// Duplicates are ignored
+	assert.Empty(pl.InsertSegment(3, "mani/source/3.ts", "", "", 2))
+	assert.Equal(6*time.Second, pl.Duration())
+
+	// The earliest segment leaves the window first
+	assert.Equal([]string{"mani/source/1.ts"}, pl.InsertSegment(4, "mani/source/4.ts", "", "", 2))
+	buf, err := pl.Encode(nil)
+	require.Nil(err)
+	assert.Equal("#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:2\n#EXT-X-MEDIA-SEQUENCE:2\n"+
+		"#EXTINF:2.000,\nmani/source/2.ts\n#EXTINF:2.000,\nmani/source/3.ts\n#EXTINF:2.000,\nmani/source/4.ts\n",
+		buf.String())
+}
+
+func TestDVRPlaylist_MinSegments(t *testing.T) {
+	assert := assert.New(t)
+
+	// Segments are kept while they are in the live playlists, even if they left a shorter window
+	pl := NewDVRPlaylist(time.Second, 3)
+	for i := uint64(1); i <= 3; i++ {
+		assert.Empty(pl.InsertSegment(i, fmt.Sprintf("mani/source/%d.ts", i), "", "", 2))
+	}
+	assert.Equal([]string{"mani/source/1.ts"}, pl.InsertSegment(4, "mani/source/4.ts", "", "", 2))
+	assert.Equal(6*time.Second, pl.Duration())
+}
+
+func TestBasicPlaylistManager_DVR(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	vProfile := ffmpeg.P144p30fps16x9
+
+	// Segments aren't kept unless the DVR window is enabled
+	c := NewBasicPlaylistManager(ManifestID("mid"), nil, nil)
+	assert.Nil(c.InsertHLSSegment(&vProfile, 1, "https://os.test/mid/P144p30fps16x9/1.ts", 2))
+	assert.Nil(c.GetDVRMediaPlaylist(vProfile.Name))
+
+	dir := t.TempDir()
+	driver, err := drivers.ParseOSURL(dir, false)
+	require.Nil(err)
+	driver, err = WithFileDeletion(dir, driver)
+	require.Nil(err)
+	sess := driver.NewSession("mid")
+	c = NewBasicPlaylistManager(ManifestID("mid"), sess, nil)
+	c.EnableDVR(10 * time.Second)
+	var uris []string
+	for i := uint64(0); i < 8; i++ {
+		uri, err := sess.SaveData(context.Background(), fmt.Sprintf("P144p30fps16x9/%d.ts", i), bytes.NewReader([]byte("seg")), nil, 0)
+		require.Nil(err)
+		uris = append(uris, uri)
+		assert.Nil(c.InsertHLSSegment(&vProfile, i, uri, 2))
+	}
+	pl := c.GetDVRMediaPlaylist(vProfile.Name)
+	assert.NotNil(pl)
+	// The window is shorter than the live playlist, whose segments are kept
+	assert.Equal(time.Duration(LIVE_LIST_LENGTH)*2*time.Second, pl.Duration())
+	assert.Nil(c.GetDVRMediaPlaylist("source"))
+	// The live playlist keeps its own window
+	assert.Equal(LIVE_LIST_LENGTH, c.GetHLSMediaPlaylist(vProfile.Name).Count())
+
+	// Segments that left the window and the live playlist are deleted from the object store
+	exists := func(uri string) bool {
+		_, err := os.Stat(uri)
+		return err == nil
+	}
+	assert.Eventually(func() bool { return !exists(uris[0]) && !exists(uris[1]) }, time.Second, 10*time.Millisecond)
+	for _, uri := range uris[2:] {
+		assert.True(exists(uri), uri)
+	}
+	for _, seg := range c.GetHLSMediaPlaylist(vProfile.Name).Segments {
+		if seg != nil {
+			assert.True(exists(seg.URI), seg.URI)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livepeer/m3u8"
+)
+
+const hlsKeyServerTimeout = 5 * time.Second
+
+// Number of key periods before the latest one whose keys are kept, for the segments that are encrypted late
+const hlsKeyCachePeriods = 2
+
+var (
+	ErrHLSKeySize = errors.New("HLS keys must be 16 bytes")
+	ErrHLSKey     = errors.New("error getting key")
+)
+
+// HLSKey is an AES-128 key that HLS segments are encrypted with
+type HLSKey struct {
+	Key []byte
+	URI string // URI at which players fetch the key, listed in EXT-X-KEY tags
+}
+
+// KeyProvider supplies the keys of the streams. Keys are rotated every key period, whose length is set by the
+// encrypter, so providers return the same key for the same stream and period
+type KeyProvider interface {
+	Key(ctx context.Context, manifestID ManifestID, period uint64) (*HLSKey, error)
+}
+
+// FileKeyProvider creates random keys and saves them as <dir>/<manifestID>/<period>.key, for testing. Keys are listed
+// in playlists with their file URL, unless a base URL at which the directory is served is set
+type FileKeyProvider struct {
+	dir     string
+	baseURL string
+	mu      sync.Mutex
+}
+
+func NewFileKeyProvider(dir, baseURL string) *FileKeyProvider {
+	return &FileKeyProvider{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (p *FileKeyProvider) Key(ctx context.Context, manifestID ManifestID, period uint64) (*HLSKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	name := fmt.Sprintf("%s/%d.key", manifestID, period)
+	fname := filepath.Join(p.dir, filepath.FromSlash(name))
+	key, err := ioutil.ReadFile(fname)
+	if os.IsNotExist(err) {
+		key = make([]byte, aes.BlockSize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(fname), 0700); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(fname, key, 0600); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	if len(key) != aes.BlockSize {
+		return nil, ErrHLSKeySize
+	}
+
+	uri := p.baseURL + "/" + name
+	if p.baseURL == "" {
+		abs, err := filepath.Abs(fname)
+		if err != nil {
+			return nil, err
+		}
+		uri = (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+	}
+	return &HLSKey{Key: key, URI: uri}, nil
+}
+
+// HTTPKeyProvider fetches the keys from a key server. The key server is sent the manifest ID and the key period, and
+// returns the hex encoded key along with the URI at which players fetch it
+type HTTPKeyProvider struct {
+	url    *url.URL
+	client *http.Client
+}
+
+type hlsKeyRequest struct {
+	ManifestID string `json:"manifestID"`
+	Period     uint64 `json:"period"`
+}
+
+type hlsKeyResponse struct {
+	Key string `json:"key"`
+	URI string `json:"uri"`
+}
+
+func NewHTTPKeyProvider(keyServerURL *url.URL) *HTTPKeyProvider {
+	return &HTTPKeyProvider{url: keyServerURL, client: &http.Client{Timeout: hlsKeyServerTimeout}}
+}
+
+func (p *HTTPKeyProvider) Key(ctx context.Context, manifestID ManifestID, period uint64) (*HLSKey, error) {
+	body, err := json.Marshal(hlsKeyRequest{ManifestID: string(manifestID), Period: period})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", p.url.String(), bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	rbody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("key server status=%d error=%s", resp.StatusCode, string(rbody))
+	}
+	var kr hlsKeyResponse
+	if err := json.Unmarshal(rbody, &kr); err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(kr.Key)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != aes.BlockSize {
+		return nil, ErrHLSKeySize
+	}
+	if kr.URI == "" {
+		return nil, errors.New("key server returned no key URI")
+	}
+	return &HLSKey{Key: key, URI: kr.URI}, nil
+}
+
+// HLSEncrypter encrypts the segments of a stream with AES-128, with a new key every rotation segments, or with the same
+// key for the whole stream if rotation is 0. The IV of a segment is its sequence number, which is set in the EXT-X-KEY
+// tags since playlists can skip segments
+type HLSEncrypter struct {
+	provider   KeyProvider
+	manifestID ManifestID
+	rotation   uint64
+
+	mu         sync.Mutex
+	keys       map[uint64]*HLSKey
+	fetches    map[uint64]*hlsKeyFetch
+	lastPeriod uint64
+}
+
+// hlsKeyFetch is a key being fetched from the key provider, which segments of the same period wait for
+type hlsKeyFetch struct {
+	done chan struct{}
+	key  *HLSKey
+	err  error
+}
+
+func NewHLSEncrypter(provider KeyProvider, manifestID ManifestID, rotation uint) *HLSEncrypter {
+	return &HLSEncrypter{
+		provider:   provider,
+		manifestID: manifestID,
+		rotation:   uint64(rotation),
+		keys:       make(map[uint64]*HLSKey),
+		fetches:    make(map[uint64]*hlsKeyFetch),
+	}
+}
+
+func (e *HLSEncrypter) period(seqNo uint64) uint64 {
+	if e.rotation == 0 {
+		return 0
+	}
+	return seqNo / e.rotation
+}
+
+// Key returns the key of the segment, fetching it from the key provider at the start of every key period. Keys are
+// fetched without holding the lock, so that the segments of other periods aren't held up by the key provider
+func (e *HLSEncrypter) Key(ctx context.Context, seqNo uint64) (*HLSKey, error) {
+	period := e.period(seqNo)
+	e.mu.Lock()
+	if key, ok := e.keys[period]; ok {
+		e.mu.Unlock()
+		return key, nil
+	}
+	if fetch, ok := e.fetches[period]; ok {
+		e.mu.Unlock()
+		select {
+		case <-fetch.done:
+			return fetch.key, fetch.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	fetch := &hlsKeyFetch{done: make(chan struct{})}
+	e.fetches[period] = fetch
+	e.mu.Unlock()
+
+	key, err := e.provider.Key(ctx, e.manifestID, period)
+	if err != nil {
+		err = fmt.Errorf("%w for manifestID=%s period=%d: %v", ErrHLSKey, e.manifestID, period, err)
+	}
+
+	e.mu.Lock()
+	// Failed fetches aren't cached, so the next segment of the period fetches the key again
+	delete(e.fetches, period)
+	if err == nil {
+		e.keys[period] = key
+		if period > e.lastPeriod {
+			e.lastPeriod = period
+			for p := range e.keys {
+				if p+hlsKeyCachePeriods < period {
+					delete(e.keys, p)
+				}
+			}
+		}
+	}
+	e.mu.Unlock()
+	fetch.key, fetch.err = key, err
+	close(fetch.done)
+	return key, err
+}
+
+// Encrypt encrypts the segment with AES-128 in CBC mode with PKCS7 padding
+func (e *HLSEncrypter) Encrypt(ctx context.Context, seqNo uint64, data []byte) ([]byte, error) {
+	key, err := e.Key(ctx, seqNo)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key.Key)
+	if err != nil {
+		return nil, err
+	}
+	padding := aes.BlockSize - len(data)%aes.BlockSize
+	out := make([]byte, len(data)+padding)
+	copy(out, data)
+	for i := len(data); i < len(out); i++ {
+		out[i] = byte(padding)
+	}
+	cipher.NewCBCEncrypter(block, segmentIV(seqNo)).CryptBlocks(out, out)
+	return out, nil
+}
+
+// PlaylistKey returns the EXT-X-KEY of the segment
+func (e *HLSEncrypter) PlaylistKey(ctx context.Context, seqNo uint64) (*m3u8.Key, error) {
+	key, err := e.Key(ctx, seqNo)
+	if err != nil {
+		return nil, err
+	}
+	return &m3u8.Key{
+		Method: "AES-128",
+		URI:    key.URI,
+		IV:     "0x" + hex.EncodeToString(segmentIV(seqNo)),
+	}, nil
+}
+
+func segmentIV(seqNo uint64) []byte {
+	iv := make([]byte, aes.BlockSize)
+	binary.BigEndian.PutUint64(iv[8:], seqNo)
+	return iv
+}
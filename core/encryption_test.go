@@ -0,0 +1,250 @@
+package core
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ffmpeg "github.com/livepeer/lpms/ffmpeg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubKeyProvider struct {
+	mu    sync.Mutex
+	calls []uint64
+	err   error
+	// Keys of the periods in block aren't returned until their channel is closed
+	block map[uint64]chan struct{}
+}
+
+func (p *stubKeyProvider) Key(ctx context.Context, manifestID ManifestID, period uint64) (*HLSKey, error) {
+	p.mu.Lock()
+	p.calls = append(p.calls, period)
+	block, err := p.block[period], p.err
+	p.mu.Unlock()
+	if block != nil {
+		<-block
+	}
+	if err != nil {
+		return nil, err
+	}
+	key := []byte(fmt.Sprintf("%016d", period))
+	return &HLSKey{Key: key, URI: fmt.Sprintf("https://keys.test/%s/%d.key", manifestID, period)}, nil
+}
+
+func TestFileKeyProvider(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	dir := t.TempDir()
+
+	p := NewFileKeyProvider(dir, "")
+	key, err := p.Key(context.Background(), "mani", 3)
+	require.Nil(err)
+	assert.Len(key.Key, 16)
+	assert.True(strings.HasPrefix(key.URI, "file://"))
+	assert.True(strings.HasSuffix(key.URI, "/mani/3.key"))
+	data, err := ioutil.ReadFile(filepath.Join(dir, "mani", "3.key"))
+	require.Nil(err)
+	assert.Equal(key.Key, data)
+
+	// Keys are created once
+	p = NewFileKeyProvider(dir, "https://keys.test/")
+	again, err := p.Key(context.Background(), "mani", 3)
+	require.Nil(err)
+	assert.Equal(key.Key, again.Key)
+	assert.Equal("https://keys.test/mani/3.key", again.URI)
+	other, err := p.Key(context.Background(), "mani", 4)
+	require.Nil(err)
+	assert.NotEqual(key.Key, other.Key)
+
+	require.Nil(ioutil.WriteFile(filepath.Join(dir, "mani", "5.key"), []byte("short"), 0600))
+	_, err = p.Key(context.Background(), "mani", 5)
+	assert.Equal(ErrHLSKeySize, err)
+}
+
+func TestHTTPKeyProvider(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var req hlsKeyRequest
+	resp := `{"key":"000102030405060708090a0b0c0d0e0f","uri":"https://keys.test/k"}`
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &req)
+		w.WriteHeader(status)
+		w.Write([]byte(resp))
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	require.Nil(err)
+	p := NewHTTPKeyProvider(u)
+
+	key, err := p.Key(context.Background(), "mani", 7)
+	require.Nil(err)
+	assert.Equal(hlsKeyRequest{ManifestID: "mani", Period: 7}, req)
+	assert.Equal([]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}, key.Key)
+	assert.Equal("https://keys.test/k", key.URI)
+
+	resp = `{"key":"0001","uri":"https://keys.test/k"}`
+	_, err = p.Key(context.Background(), "mani", 7)
+	assert.Equal(ErrHLSKeySize, err)
+	resp = `{"key":"000102030405060708090a0b0c0d0e0f"}`
+	_, err = p.Key(context.Background(), "mani", 7)
+	assert.EqualError(err, "key server returned no key URI")
+	status, resp = http.StatusForbidden, "denied"
+	_, err = p.Key(context.Background(), "mani", 7)
+	assert.EqualError(err, "key server status=403 error=denied")
+}
+
+func TestHLSEncrypter(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	provider := &stubKeyProvider{}
+	e := NewHLSEncrypter(provider, "mani", 10)
+	data := []byte("segment data that is longer than a block")
+	encrypted, err := e.Encrypt(context.Background(), 12, data)
+	require.Nil(err)
+	assert.Len(encrypted, 48)
+
+	// Segments are decrypted with the key of their period and their sequence number as the IV
+	block, err := aes.NewCipher([]byte("0000000000000001"))
+	require.Nil(err)
+	decrypted := make([]byte, len(encrypted))
+	cipher.NewCBCDecrypter(block, segmentIV(12)).CryptBlocks(decrypted, encrypted)
+	assert.Equal(data, decrypted[:len(data)])
+	assert.Equal([]byte{8, 8, 8, 8, 8, 8, 8, 8}, decrypted[len(data):])
+
+	// Keys are fetched once per period, and the keys of old periods are dropped
+	_, err = e.Encrypt(context.Background(), 19, data)
+	require.Nil(err)
+	for _, seqNo := range []uint64{35, 42, 15} {
+		_, err = e.Key(context.Background(), seqNo)
+		require.Nil(err)
+	}
+	assert.Equal([]uint64{1, 3, 4, 1}, provider.calls)
+
+	key, err := e.PlaylistKey(context.Background(), 42)
+	require.Nil(err)
+	assert.Equal("AES-128", key.Method)
+	assert.Equal("https://keys.test/mani/4.key", key.URI)
+	assert.Equal("0x0000000000000000000000000000002a", key.IV)
+
+	// Keys are never rotated without a rotation
+	provider = &stubKeyProvider{}
+	e = NewHLSEncrypter(provider, "mani", 0)
+	e.Key(context.Background(), 1)
+	e.Key(context.Background(), 1000)
+	assert.Equal([]uint64{0}, provider.calls)
+
+	provider.err = errors.New("key server down")
+	e = NewHLSEncrypter(provider, "mani", 0)
+	_, err = e.Encrypt(context.Background(), 1, data)
+	assert.EqualError(err, "error getting key for manifestID=mani period=0: key server down")
+	_, err = e.PlaylistKey(context.Background(), 1)
+	assert.ErrorIs(err, ErrHLSKey)
+
+	// Failed fetches are retried
+	provider.err = nil
+	_, err = e.Key(context.Background(), 1)
+	assert.Nil(err)
+	assert.Equal([]uint64{0, 0, 0, 0}, provider.calls)
+}
+
+func TestHLSEncrypter_ConcurrentFetches(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	unblock := make(chan struct{})
+	provider := &stubKeyProvider{block: map[uint64]chan struct{}{1: unblock}}
+	e := NewHLSEncrypter(provider, "mani", 10)
+	_, err := e.Key(context.Background(), 1)
+	require.Nil(err)
+
+	// Segments of a period whose key is being fetched wait for that fetch
+	keys := make(chan *HLSKey, 2)
+	for i := 0; i < 2; i++ {
+		go func(seqNo uint64) {
+			key, err := e.Key(context.Background(), seqNo)
+			assert.Nil(err)
+			keys <- key
+		}(uint64(10 + i))
+	}
+	require.Eventually(func() bool {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		return e.fetches[1] != nil
+	}, time.Second, time.Millisecond)
+
+	// Segments of other periods don't wait for the key provider
+	key, err := e.Key(context.Background(), 2)
+	require.Nil(err)
+	assert.Equal("https://keys.test/mani/0.key", key.URI)
+
+	// Waiting segments give up with their context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = e.Key(ctx, 15)
+	assert.Equal(context.Canceled, err)
+
+	close(unblock)
+	for i := 0; i < 2; i++ {
+		key := <-keys
+		require.NotNil(key)
+		assert.Equal("https://keys.test/mani/1.key", key.URI)
+	}
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	assert.Equal([]uint64{0, 1}, provider.calls)
+}
+
+func TestBasicPlaylistManager_Encryption(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	vProfile := ffmpeg.P144p30fps16x9
+
+	c := NewBasicPlaylistManager(ManifestID("mani"), nil, nil)
+	data, err := c.EncryptSegment(context.Background(), 1, []byte("segment"))
+	require.Nil(err)
+	assert.Equal([]byte("segment"), data)
+	assert.NotNil(c.GetDASHManifest())
+
+	provider := &stubKeyProvider{}
+	c.EnableEncryption(NewHLSEncrypter(provider, "mani", 0))
+	c.EnableDVR(time.Minute)
+	data, err = c.EncryptSegment(context.Background(), 1, []byte("segment"))
+	require.Nil(err)
+	assert.Len(data, 16)
+	require.Nil(c.InsertHLSSegment(&vProfile, 1, "mani/P144p30fps16x9/1.ts", 2))
+	assert.Contains(c.GetHLSMediaPlaylist(vProfile.Name).Encode().String(),
+		`#EXT-X-KEY:METHOD=AES-128,URI="https://keys.test/mani/0.key",IV=0x00000000000000000000000000000001`)
+	buf, err := c.GetDVRMediaPlaylist(vProfile.Name).Encode(nil)
+	require.Nil(err)
+	assert.Contains(buf.String(), "#EXT-X-KEY:METHOD=AES-128,URI=\"https://keys.test/mani/0.key\",IV=0x00000000000000000000000000000001\n"+
+		"#EXTINF:2.000,\nmani/P144p30fps16x9/1.ts\n")
+	// DASH players can't play AES-128 encrypted segments
+	assert.Nil(c.GetDASHManifest())
+
+	// Segments whose key can't be fetched aren't listed
+	c.EnableEncryption(NewHLSEncrypter(provider, "mani", 1))
+	provider.err = errors.New("key server down")
+	assert.ErrorIs(c.InsertHLSSegment(&vProfile, 2, "mani/P144p30fps16x9/2.ts", 2), ErrHLSKey)
+	assert.NotContains(c.GetHLSMediaPlaylist(vProfile.Name).Encode().String(), "2.ts")
+	buf, err = c.GetDVRMediaPlaylist(vProfile.Name).Encode(nil)
+	require.Nil(err)
+	assert.NotContains(buf.String(), "2.ts")
+}
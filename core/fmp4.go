@@ -0,0 +1,45 @@
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var ErrFMP4Segment = errors.New("invalid fragmented MP4 segment")
+
+// SplitFMP4 splits a fragmented MP4 segment into its init segment, which is made of the boxes up to the movie box, and
+// its media segment, which is made of the movie fragments that follow
+func SplitFMP4(data []byte) ([]byte, []byte, error) {
+	var hasMoov bool
+	for off := 0; off < len(data); {
+		if len(data)-off < 8 {
+			return nil, nil, ErrFMP4Segment
+		}
+		size := uint64(binary.BigEndian.Uint32(data[off:]))
+		typ := string(data[off+4 : off+8])
+		switch size {
+		case 0:
+			// The box extends to the end of the data
+			size = uint64(len(data) - off)
+		case 1:
+			if len(data)-off < 16 {
+				return nil, nil, ErrFMP4Segment
+			}
+			size = binary.BigEndian.Uint64(data[off+8:])
+		}
+		if size < 8 || size > uint64(len(data)-off) {
+			return nil, nil, ErrFMP4Segment
+		}
+		switch typ {
+		case "moov":
+			hasMoov = true
+		case "styp", "sidx", "moof":
+			if !hasMoov {
+				return nil, nil, ErrFMP4Segment
+			}
+			return data[:off], data[off:], nil
+		}
+		off += int(size)
+	}
+	return nil, nil, ErrFMP4Segment
+}
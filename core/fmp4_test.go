@@ -0,0 +1,56 @@
+package core
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mp4Box(typ string, payload []byte) []byte {
+	b := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint32(b, uint32(8+len(payload)))
+	copy(b[4:], typ)
+	return append(b, payload...)
+}
+
+func TestSplitFMP4(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ftyp := mp4Box("ftyp", []byte("iso5"))
+	moov := mp4Box("moov", mp4Box("trak", []byte{1, 2, 3}))
+	frag := append(mp4Box("moof", []byte{4}), mp4Box("mdat", []byte{5, 6})...)
+	seg := append(append(append([]byte{}, ftyp...), moov...), frag...)
+
+	init, media, err := SplitFMP4(seg)
+	require.Nil(err)
+	assert.Equal(append(append([]byte{}, ftyp...), moov...), init)
+	assert.Equal(frag, media)
+
+	// Boxes with a 64 bit size
+	large := make([]byte, 16, 20)
+	binary.BigEndian.PutUint32(large, 1)
+	copy(large[4:], "free")
+	binary.BigEndian.PutUint64(large[8:], 20)
+	large = append(large, 0, 0, 0, 0)
+	seg = append(append(append([]byte{}, ftyp...), large...), moov...)
+	seg = append(seg, frag...)
+	init, media, err = SplitFMP4(seg)
+	require.Nil(err)
+	assert.Len(init, len(ftyp)+len(large)+len(moov))
+	assert.Equal(frag, media)
+
+	// Segments without a movie box, without fragments or with truncated boxes
+	_, _, err = SplitFMP4(append(append([]byte{}, ftyp...), frag...))
+	assert.Equal(ErrFMP4Segment, err)
+	_, _, err = SplitFMP4(append(append([]byte{}, ftyp...), moov...))
+	assert.Equal(ErrFMP4Segment, err)
+	_, _, err = SplitFMP4(append(append([]byte{}, ftyp...), moov[:10]...))
+	assert.Equal(ErrFMP4Segment, err)
+	_, _, err = SplitFMP4([]byte{0, 0, 0, 4, 'f', 't', 'y', 'p'})
+	assert.Equal(ErrFMP4Segment, err)
+	_, _, err = SplitFMP4(nil)
+	assert.Equal(ErrFMP4Segment, err)
+}
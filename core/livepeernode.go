@@ -18,6 +18,7 @@ import (
 	"sync"
 	"time"
 
+	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/golang/glog"
 	"github.com/livepeer/go-livepeer/pm"
 
@@ -96,6 +97,17 @@ type LivepeerNode struct {
 	priceInfo    map[string]*AutoConvertedPrice
 	serviceURI   url.URL
 	segmentMutex *sync.RWMutex
+	// Sessions waiting for a session slot, and the expiration of the slots held for the admitted sessions
+	admissionQueue []*admissionWaiter
+	admitted       map[ManifestID]time.Time
+	// Priority classes of the senders, the senders of the sessions that hold a session slot and the pixel rates of
+	// the senders with a max pixel rate
+	priorityClasses map[ethcommon.Address]*PriorityClass
+	sessionSenders  map[ManifestID]ethcommon.Address
+	pixelRates      map[ethcommon.Address]*pixelRate
+	// Whether the node stopped accepting new sessions, and the channel closed once it did
+	draining     bool
+	drainStarted chan struct{}
 }
 
 // NewLivepeerNode creates a new Livepeer Node. Eth can be nil.
@@ -183,6 +195,11 @@ func (n *LivepeerNode) SetMaxSessions(s int) {
 	}
 
 	glog.Infof("Updated session limit to %d", MaxSessions)
+
+	// Sessions waiting for a slot are admitted if the limit was raised
+	n.segmentMutex.Lock()
+	n.admitWaitingSessions()
+	n.segmentMutex.Unlock()
 }
 
 func (n *LivepeerNode) GetCurrentCapacity() int {
@@ -0,0 +1,338 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Number of parts that can wait for an earlier part that is missing before that part is given up on
+const llhlsMaxPendingParts = 3
+
+var ErrLLHLSPlaylistClosed = errors.New("LLHLSPlaylistClosed")
+var ErrLLHLSTooFarAhead = errors.New("LLHLSTooFarAhead")
+var ErrLLHLSNotFound = errors.New("LLHLSNotFound")
+
+// LLHLSPlaylist is a Low-Latency HLS media playlist. Every segment that is inserted becomes a part, since segments are
+// transcoded as soon as they are cut, and consecutive parts are grouped into segments of at least the target duration.
+// Segments are cut on keyframes, so parts can be longer than the configured part target, in which case the advertised
+// part target is raised to the longest part, like the target duration is raised to the longest segment.
+// Parts and segments are served from the data of the parts, and the playlist renders itself because the m3u8 package
+// doesn't support the LL-HLS tags
+type LLHLSPlaylist struct {
+	name       string
+	ext        string
+	winSize    int
+	partTarget float64
+	segTarget  float64
+
+	mu             sync.Mutex
+	updated        chan struct{}
+	closed         bool
+	started        bool
+	nextSeqNo      uint64
+	pending        map[uint64]llhlsPart
+	segments       []*llhlsSegment
+	targetDuration int
+	// Init segment of fragmented MP4 playlists
+	mapURI string
+}
+
+type llhlsPart struct {
+	duration float64
+	data     []byte
+}
+
+type llhlsSegment struct {
+	msn      uint64
+	parts    []llhlsPart
+	duration float64
+	complete bool
+}
+
+// NewLLHLSPlaylist creates a playlist whose parts and segments have URIs relative to the playlist, under the name of
+// the rendition
+func NewLLHLSPlaylist(name, ext string, winSize uint, partTarget, segTarget time.Duration) *LLHLSPlaylist {
+	return &LLHLSPlaylist{
+		name:           name,
+		ext:            ext,
+		winSize:        int(winSize),
+		partTarget:     partTarget.Seconds(),
+		segTarget:      segTarget.Seconds(),
+		updated:        make(chan struct{}),
+		pending:        make(map[uint64]llhlsPart),
+		targetDuration: int(math.Ceil((segTarget + partTarget).Seconds())),
+	}
+}
+
+// InsertPart adds the segment with the sequence number as a part. Parts are added in the order of their sequence
+// numbers, so a part that arrives early waits for the earlier ones, unless too many parts are waiting
+func (p *LLHLSPlaylist) InsertPart(seqNo uint64, duration float64, data []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	if !p.started {
+		p.started = true
+		p.nextSeqNo = seqNo
+	}
+	if seqNo < p.nextSeqNo {
+		// Duplicate, or too late
+		return
+	}
+	p.pending[seqNo] = llhlsPart{duration: duration, data: data}
+	if _, ok := p.pending[p.nextSeqNo]; !ok && len(p.pending) > llhlsMaxPendingParts {
+		next := seqNo
+		for s := range p.pending {
+			if s < next {
+				next = s
+			}
+		}
+		// The stream isn't continuous anymore, so the next part starts a new segment
+		p.nextSeqNo = next
+		p.completeSegment()
+	}
+
+	appended := false
+	for {
+		part, ok := p.pending[p.nextSeqNo]
+		if !ok {
+			break
+		}
+		delete(p.pending, p.nextSeqNo)
+		p.nextSeqNo++
+		p.appendPart(part)
+		appended = true
+	}
+	if appended {
+		p.notify()
+	}
+}
+
+func (p *LLHLSPlaylist) appendPart(part llhlsPart) {
+	var seg *llhlsSegment
+	if n := len(p.segments); n > 0 && !p.segments[n-1].complete {
+		seg = p.segments[n-1]
+	} else {
+		msn, _ := p.nextPart()
+		seg = &llhlsSegment{msn: msn}
+		p.segments = append(p.segments, seg)
+	}
+	seg.parts = append(seg.parts, part)
+	seg.duration += part.duration
+	if part.duration > p.partTarget {
+		p.partTarget = part.duration
+	}
+	if seg.duration >= p.segTarget {
+		p.completeSegment()
+	}
+}
+
+// PartTarget returns the part target of the playlist, which is raised to the longest part
+func (p *LLHLSPlaylist) PartTarget() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return time.Duration(p.partTarget * float64(time.Second))
+}
+
+// completeSegment completes the last segment if it has parts, and removes the segments that left the window
+func (p *LLHLSPlaylist) completeSegment() {
+	n := len(p.segments)
+	if n == 0 || p.segments[n-1].complete {
+		return
+	}
+	seg := p.segments[n-1]
+	seg.complete = true
+	if d := int(math.Ceil(seg.duration)); d > p.targetDuration {
+		p.targetDuration = d
+	}
+	if n > p.winSize {
+		p.segments = p.segments[n-p.winSize:]
+	}
+}
+
+// nextPart returns the media sequence number and the index of the part that will be added next
+func (p *LLHLSPlaylist) nextPart() (uint64, int) {
+	n := len(p.segments)
+	if n == 0 {
+		return 0, 0
+	}
+	last := p.segments[n-1]
+	if last.complete {
+		return last.msn + 1, 0
+	}
+	return last.msn, len(last.parts)
+}
+
+func (p *LLHLSPlaylist) notify() {
+	close(p.updated)
+	p.updated = make(chan struct{})
+}
+
+// Wait blocks until the playlist has the part of the segment with the media sequence number, or the whole segment if
+// the part is negative, like the _HLS_msn and _HLS_part parameters of blocking playlist reloads
+func (p *LLHLSPlaylist) Wait(ctx context.Context, msn uint64, part int) error {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return ErrLLHLSPlaylistClosed
+		}
+		nextMSN, nextPart := p.nextPart()
+		updated := p.updated
+		p.mu.Unlock()
+
+		if msn > nextMSN+2 {
+			return ErrLLHLSTooFarAhead
+		}
+		if nextMSN > msn || part >= 0 && nextMSN == msn && nextPart > part {
+			return nil
+		}
+		select {
+		case <-updated:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Part returns the data of the part, waiting for it if it is the next one
+func (p *LLHLSPlaylist) Part(ctx context.Context, msn uint64, part int) ([]byte, error) {
+	if err := p.Wait(ctx, msn, part); err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	seg := p.segment(msn)
+	if seg == nil || part >= len(seg.parts) {
+		return nil, ErrLLHLSNotFound
+	}
+	return seg.parts[part].data, nil
+}
+
+// Segment returns the data of the segment, which is the data of its parts
+func (p *LLHLSPlaylist) Segment(msn uint64) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	seg := p.segment(msn)
+	if seg == nil || !seg.complete {
+		return nil, ErrLLHLSNotFound
+	}
+	var data []byte
+	for _, part := range seg.parts {
+		data = append(data, part.data...)
+	}
+	return data, nil
+}
+
+func (p *LLHLSPlaylist) segment(msn uint64) *llhlsSegment {
+	for _, seg := range p.segments {
+		if seg.msn == msn {
+			return seg
+		}
+	}
+	return nil
+}
+
+// Close wakes up the requests that are waiting, and ends the playlist
+func (p *LLHLSPlaylist) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	p.completeSegment()
+	p.notify()
+}
+
+// SetMap sets the init segment of the parts and segments of fragmented MP4 playlists
+func (p *LLHLSPlaylist) SetMap(uri string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mapURI = uri
+}
+
+// Encode renders the playlist. Parts are only listed for the segments within three target durations of the end of
+// the playlist, and the part that is added next is advertised with a preload hint
+func (p *LLHLSPlaylist) Encode() *bytes.Buffer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var buf bytes.Buffer
+	buf.WriteString("#EXTM3U\n#EXT-X-VERSION:6\n")
+	fmt.Fprintf(&buf, "#EXT-X-TARGETDURATION:%d\n", p.targetDuration)
+	fmt.Fprintf(&buf, "#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=%.3f\n", 3*p.partTarget)
+	fmt.Fprintf(&buf, "#EXT-X-PART-INF:PART-TARGET=%.3f\n", p.partTarget)
+	var mediaSeq uint64
+	if len(p.segments) > 0 {
+		mediaSeq = p.segments[0].msn
+	}
+	fmt.Fprintf(&buf, "#EXT-X-MEDIA-SEQUENCE:%d\n", mediaSeq)
+	if p.mapURI != "" {
+		fmt.Fprintf(&buf, "#EXT-X-MAP:URI=\"%s\"\n", p.mapURI)
+	}
+
+	partsFrom := len(p.segments)
+	for dur := 0.0; partsFrom > 0 && dur < 3*float64(p.targetDuration); {
+		partsFrom--
+		dur += p.segments[partsFrom].duration
+	}
+	for i, seg := range p.segments {
+		if i >= partsFrom {
+			for j, part := range seg.parts {
+				fmt.Fprintf(&buf, "#EXT-X-PART:DURATION=%.3f,URI=\"%s\",INDEPENDENT=YES\n", part.duration, p.partURI(seg.msn, j))
+			}
+		}
+		if seg.complete {
+			fmt.Fprintf(&buf, "#EXTINF:%.3f,\n%s\n", seg.duration, p.segmentURI(seg.msn))
+		}
+	}
+	if p.closed {
+		buf.WriteString("#EXT-X-ENDLIST\n")
+	} else {
+		msn, part := p.nextPart()
+		fmt.Fprintf(&buf, "#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"%s\"\n", p.partURI(msn, part))
+	}
+	return &buf
+}
+
+func (p *LLHLSPlaylist) partURI(msn uint64, part int) string {
+	return fmt.Sprintf("%s/llhls_%d_%d%s", p.name, msn, part, p.ext)
+}
+
+func (p *LLHLSPlaylist) segmentURI(msn uint64) string {
+	return fmt.Sprintf("%s/llhls_%d%s", p.name, msn, p.ext)
+}
+
+// ParseLLHLSURI returns the media sequence number and the part index from the name of a part or a segment of a
+// LLHLSPlaylist. The part index is negative for segments
+func ParseLLHLSURI(name string) (uint64, int, bool) {
+	name, ok := strings.CutPrefix(name, "llhls_")
+	if !ok {
+		return 0, 0, false
+	}
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		name = name[:i]
+	}
+	msnStr, partStr, hasPart := strings.Cut(name, "_")
+	msn, err := strconv.ParseUint(msnStr, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if !hasPart {
+		return msn, -1, true
+	}
+	part, err := strconv.Atoi(partStr)
+	if err != nil || part < 0 {
+		return 0, 0, false
+	}
+	return msn, part, true
+}
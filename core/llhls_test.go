@@ -0,0 +1,198 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLLHLSPlaylist(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	pl := NewLLHLSPlaylist("source", ".ts", 2, 500*time.Millisecond, 2*time.Second)
+	expectedHeader := "#EXTM3U\n#EXT-X-VERSION:6\n#EXT-X-TARGETDURATION:3\n" +
+		"#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=1.500\n#EXT-X-PART-INF:PART-TARGET=0.500\n"
+	assert.Equal(expectedHeader+"#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"source/llhls_0_0.ts\"\n",
+		pl.Encode().String())
+
+	// Parts are grouped into segments of the target duration
+	for i := uint64(10); i < 15; i++ {
+		pl.InsertPart(i, 0.5, []byte{byte(i)})
+	}
+	expected := expectedHeader + "#EXT-X-MEDIA-SEQUENCE:0\n"
+	for i := 0; i < 4; i++ {
+		expected += fmt.Sprintf("#EXT-X-PART:DURATION=0.500,URI=\"source/llhls_0_%d.ts\",INDEPENDENT=YES\n", i)
+	}
+	expected += "#EXTINF:2.000,\nsource/llhls_0.ts\n" +
+		"#EXT-X-PART:DURATION=0.500,URI=\"source/llhls_1_0.ts\",INDEPENDENT=YES\n" +
+		"#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"source/llhls_1_1.ts\"\n"
+	assert.Equal(expected, pl.Encode().String())
+
+	data, err := pl.Segment(0)
+	require.Nil(err)
+	assert.Equal([]byte{10, 11, 12, 13}, data)
+	_, err = pl.Segment(1)
+	assert.Equal(ErrLLHLSNotFound, err)
+	data, err = pl.Part(context.Background(), 1, 0)
+	require.Nil(err)
+	assert.Equal([]byte{14}, data)
+
+	// Segments leave the window, and parts are only listed for the last segments
+	for i := uint64(15); i < 27; i++ {
+		pl.InsertPart(i, 0.5, []byte{byte(i)})
+	}
+	s := pl.Encode().String()
+	assert.Contains(s, "#EXT-X-MEDIA-SEQUENCE:2\n")
+	assert.NotContains(s, "llhls_1")
+	assert.Contains(s, "#EXTINF:2.000,\nsource/llhls_2.ts\n")
+	assert.Contains(s, "URI=\"source/llhls_2_0.ts\"")
+	_, err = pl.Segment(1)
+	assert.Equal(ErrLLHLSNotFound, err)
+
+	// Fragmented MP4 playlists have an init segment
+	pl.SetMap("/stream/sess/source/init_a.mp4")
+	assert.Contains(pl.Encode().String(), "#EXT-X-MEDIA-SEQUENCE:2\n#EXT-X-MAP:URI=\"/stream/sess/source/init_a.mp4\"\n")
+
+	// Closed playlists end
+	pl.Close()
+	s = pl.Encode().String()
+	assert.True(strings.HasSuffix(s, "#EXT-X-ENDLIST\n"))
+	assert.NotContains(s, "PRELOAD-HINT")
+	assert.Equal(ErrLLHLSPlaylistClosed, pl.Wait(context.Background(), 10, -1))
+}
+
+func TestLLHLSPlaylist_OutOfOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	pl := NewLLHLSPlaylist("source", ".ts", 6, 500*time.Millisecond, 2*time.Second)
+	pl.InsertPart(0, 0.5, []byte{0})
+	// Parts wait for the earlier ones
+	pl.InsertPart(2, 0.5, []byte{2})
+	assert.NotContains(pl.Encode().String(), "llhls_0_1.ts\",")
+	pl.InsertPart(1, 0.5, []byte{1})
+	assert.Contains(pl.Encode().String(), "llhls_0_2.ts\",")
+	// Duplicates are ignored
+	pl.InsertPart(1, 0.5, []byte{1})
+	assert.Contains(pl.Encode().String(), "URI=\"source/llhls_0_3.ts\"\n")
+
+	// Missing parts are given up on when too many parts wait, and the stream continues in a new segment
+	for i := uint64(4); i < 8; i++ {
+		pl.InsertPart(i, 0.5, []byte{byte(i)})
+	}
+	s := pl.Encode().String()
+	assert.Contains(s, "#EXTINF:1.500,\nsource/llhls_0.ts\n")
+	assert.Contains(s, "URI=\"source/llhls_1_3.ts\",INDEPENDENT=YES\n#EXTINF:2.000,\nsource/llhls_1.ts\n")
+	data, err := pl.Part(context.Background(), 1, 0)
+	assert.Nil(err)
+	assert.Equal([]byte{4}, data)
+
+	// Late parts are dropped
+	pl.InsertPart(3, 0.5, []byte{3})
+	data, err = pl.Segment(0)
+	assert.Nil(err)
+	assert.Equal([]byte{0, 1, 2}, data)
+}
+
+func TestLLHLSPlaylist_LongPart(t *testing.T) {
+	assert := assert.New(t)
+
+	pl := NewLLHLSPlaylist("source", ".ts", 6, 500*time.Millisecond, 2*time.Second)
+	pl.InsertPart(0, 0.5, []byte{0})
+	assert.Contains(pl.Encode().String(), "PART-HOLD-BACK=1.500\n#EXT-X-PART-INF:PART-TARGET=0.500\n")
+	assert.Equal(500*time.Millisecond, pl.PartTarget())
+
+	// The part target is raised to the longest part, since parts must not exceed it
+	pl.InsertPart(1, 0.8, []byte{1})
+	s := pl.Encode().String()
+	assert.Contains(s, "PART-HOLD-BACK=2.400\n#EXT-X-PART-INF:PART-TARGET=0.800\n")
+	assert.Contains(s, "#EXT-X-PART:DURATION=0.800,URI=\"source/llhls_0_1.ts\",INDEPENDENT=YES\n")
+	assert.Equal(800*time.Millisecond, pl.PartTarget())
+
+	// Shorter parts don't lower it again
+	pl.InsertPart(2, 0.4, []byte{2})
+	assert.Contains(pl.Encode().String(), "#EXT-X-PART-INF:PART-TARGET=0.800\n")
+}
+
+func TestLLHLSPlaylist_Wait(t *testing.T) {
+	assert := assert.New(t)
+
+	pl := NewLLHLSPlaylist("source", ".ts", 6, 500*time.Millisecond, 2*time.Second)
+	pl.InsertPart(0, 0.5, nil)
+
+	// Parts and segments that are in the playlist don't block
+	assert.Nil(pl.Wait(context.Background(), 0, 0))
+	// Requests too far ahead are rejected
+	assert.Equal(ErrLLHLSTooFarAhead, pl.Wait(context.Background(), 3, 0))
+
+	// Blocked requests wait until the part is added
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(context.DeadlineExceeded, pl.Wait(ctx, 0, 1))
+
+	done := make(chan error)
+	go func() { done <- pl.Wait(context.Background(), 0, -1) }()
+	go func() {
+		data, err := pl.Part(context.Background(), 0, 2)
+		assert.Nil(err)
+		assert.Equal([]byte{2}, data)
+		done <- err
+	}()
+	pl.InsertPart(1, 0.5, []byte{1})
+	pl.InsertPart(2, 0.5, []byte{2})
+	assert.Nil(<-done)
+	select {
+	case <-done:
+		t.Error("wait for segment returned before the segment was complete")
+	case <-time.After(10 * time.Millisecond):
+	}
+	pl.InsertPart(3, 0.5, []byte{3})
+	assert.Nil(<-done)
+
+	// A segment that ends before the requested part unblocks the request
+	go func() { done <- pl.Wait(context.Background(), 1, 5) }()
+	for i := uint64(4); i < 8; i++ {
+		pl.InsertPart(i, 0.5, nil)
+	}
+	assert.Nil(<-done)
+
+	// Closing the playlist wakes up the waiting requests
+	go func() { done <- pl.Wait(context.Background(), 2, 0) }()
+	pl.Close()
+	assert.Equal(ErrLLHLSPlaylistClosed, <-done)
+}
+
+func TestParseLLHLSURI(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		name string
+		msn  uint64
+		part int
+		ok   bool
+	}{
+		{"llhls_5_2.ts", 5, 2, true},
+		{"llhls_5.ts", 5, -1, true},
+		{"llhls_12_0", 12, 0, true},
+		{"5.ts", 0, 0, false},
+		{"llhls_x.ts", 0, 0, false},
+		{"llhls_1_-1.ts", 0, 0, false},
+	}
+	for _, tt := range tests {
+		msn, part, ok := ParseLLHLSURI(tt.name)
+		assert.Equal(tt.ok, ok, tt.name)
+		assert.Equal(tt.msn, msn, tt.name)
+		assert.Equal(tt.part, part, tt.name)
+	}
+
+	pl := NewLLHLSPlaylist("P240p30fps16x9", ".ts", 6, time.Second, 2*time.Second)
+	msn, part, ok := ParseLLHLSURI(strings.TrimPrefix(pl.partURI(3, 1), "P240p30fps16x9/"))
+	assert.True(ok)
+	assert.Equal(uint64(3), msn)
+	assert.Equal(1, part)
+}
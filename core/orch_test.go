@@ -688,18 +688,18 @@ func TestOrchCheckCapacity(t *testing.T) {
 	mid := ManifestID(md.AuthToken.SessionId)
 
 	// happy case
-	assert.Nil(o.CheckCapacity(mid))
+	assert.Nil(o.CheckCapacity(context.TODO(), ethcommon.Address{}, mid, 0))
 
 	// capped case
 	MaxSessions = 0
-	assert.Equal(ErrOrchCap, o.CheckCapacity(mid))
+	assert.Equal(ErrOrchCap, o.CheckCapacity(context.TODO(), ethcommon.Address{}, mid, 0))
 
 	// ensure existing segment chans pass while cap is active
 	MaxSessions = cap
 	_, err := n.getSegmentChan(context.TODO(), md) // store md into segment chans
 	assert.Nil(err)
 	MaxSessions = 0
-	assert.Nil(o.CheckCapacity(mid))
+	assert.Nil(o.CheckCapacity(context.TODO(), ethcommon.Address{}, mid, 0))
 }
 
 func TestProcessPayment_GivenRecipientError_ReturnsNil(t *testing.T) {
@@ -78,16 +78,14 @@ func (orch *orchestrator) TranscoderSecret() string {
 	return orch.node.OrchSecret
 }
 
-func (orch *orchestrator) CheckCapacity(mid ManifestID) error {
-	orch.node.segmentMutex.RLock()
-	defer orch.node.segmentMutex.RUnlock()
-	if _, ok := orch.node.SegmentChans[mid]; ok {
-		return nil
-	}
-	if len(orch.node.SegmentChans) >= MaxSessions {
-		return ErrOrchCap
-	}
-	return nil
+// CheckCapacity returns nil if the session can be transcoded within the priority class of the sender. New sessions
+// wait for up to wait in the admission queue when the orchestrator is at capacity
+func (orch *orchestrator) CheckCapacity(ctx context.Context, sender ethcommon.Address, mid ManifestID, wait time.Duration) error {
+	return orch.node.admitSession(ctx, sender, mid, wait)
+}
+
+func (orch *orchestrator) AdmissionQueueDepth() int {
+	return orch.node.AdmissionQueueDepth()
 }
 
 func (orch *orchestrator) TranscodeSeg(ctx context.Context, md *SegTranscodingMetadata, seg *stream.HLSSegment) (*TranscodeResult, error) {
@@ -337,10 +335,23 @@ func (orch *orchestrator) SufficientBalance(addr ethcommon.Address, manifestID M
 	return true
 }
 
-// DebitFees debits the balance for a ManifestID based on the amount of output pixels * price
+// SufficientPixelRate checks whether the sender is within the max pixel rate of its priority class
+func (orch *orchestrator) SufficientPixelRate(addr ethcommon.Address) bool {
+	if orch.node == nil {
+		return true
+	}
+	return orch.node.SufficientPixelRate(addr)
+}
+
+// DebitFees debits the balance for a ManifestID based on the amount of output pixels * price, and counts the pixels
+// against the max pixel rate of the sender
 func (orch *orchestrator) DebitFees(addr ethcommon.Address, manifestID ManifestID, price *net.PriceInfo, pixels int64) {
+	if orch.node == nil {
+		return
+	}
+	orch.node.debitPixels(addr, pixels)
 	// Don't debit in offchain mode
-	if orch.node == nil || orch.node.Balances == nil {
+	if orch.node.Balances == nil {
 		return
 	}
 	priceRat := big.NewRat(price.GetPricePerUnit(), price.GetPixelsPerUnit())
@@ -351,7 +362,14 @@ func (orch *orchestrator) Capabilities() *net.Capabilities {
 	if orch.node == nil {
 		return nil
 	}
-	return orch.node.Capabilities.ToNetCapabilities()
+	caps := orch.node.Capabilities.ToNetCapabilities()
+	// A draining orchestrator advertises zero capacity
+	if caps != nil && orch.node.IsDraining() {
+		for c := range caps.Capacities {
+			caps.Capacities[c] = 0
+		}
+	}
+	return caps
 }
 
 func (orch *orchestrator) AuthToken(sessionID string, expiration int64) *net.AuthToken {
@@ -487,10 +505,12 @@ func (n *LivepeerNode) getSegmentChan(ctx context.Context, md *SegTranscodingMet
 	// concurrency concerns here? what if a chan is added mid-call?
 	n.segmentMutex.Lock()
 	defer n.segmentMutex.Unlock()
-	if sc, ok := n.SegmentChans[ManifestID(md.AuthToken.SessionId)]; ok {
+	mid := ManifestID(md.AuthToken.SessionId)
+	if sc, ok := n.SegmentChans[mid]; ok {
 		return sc, nil
 	}
-	if len(n.SegmentChans) >= MaxSessions {
+	// Sessions that were admitted from the admission queue have a slot held for them
+	if _, admitted := n.admitted[mid]; !admitted && !n.hasCapacity(md.Sender) {
 		return nil, ErrOrchCap
 	}
 	sc := make(SegmentChan, maxSegmentChannels)
@@ -498,7 +518,9 @@ func (n *LivepeerNode) getSegmentChan(ctx context.Context, md *SegTranscodingMet
 	if err := n.transcodeSegmentLoop(clog.Clone(context.Background(), ctx), md, sc); err != nil {
 		return nil, err
 	}
-	n.SegmentChans[ManifestID(md.AuthToken.SessionId)] = sc
+	n.SegmentChans[mid] = sc
+	delete(n.admitted, mid)
+	n.setSessionSender(mid, md.Sender)
 	if lpmon.Enabled {
 		lpmon.CurrentSessions(len(n.SegmentChans))
 	}
@@ -722,9 +744,11 @@ func (n *LivepeerNode) endTranscodingSession(sessionId string, logCtx context.Co
 	if _, exists = n.SegmentChans[mid]; exists {
 		close(n.SegmentChans[mid])
 		delete(n.SegmentChans, mid)
+		delete(n.sessionSenders, mid)
 		if lpmon.Enabled {
 			lpmon.CurrentSessions(len(n.SegmentChans))
 		}
+		n.admitWaitingSessions()
 	}
 	n.segmentMutex.Unlock()
 	if exists {
@@ -767,6 +791,8 @@ type RemoteTranscoder struct {
 	addr         string
 	capacity     int
 	load         int
+	// Draining transcoders finish their sessions but are not assigned new ones
+	draining bool
 }
 
 // RemoteTranscoderFatalError wraps error to indicate that error is fatal
@@ -845,6 +871,14 @@ func (rt *RemoteTranscoder) Transcode(logCtx context.Context, md *SegTranscoding
 		}
 		clog.InfofErr(logCtx, "Successfully received results from remote transcoder=%s segments=%d taskId=%d fname=%s dur=%v",
 			rt.addr, segmentLen, taskID, fname, time.Since(start), chanData.Err)
+		if chanData.Err != nil && chanData.Err.Error() == ErrTranscoderDraining.Error() {
+			// The transcoder is draining; assign the session to another transcoder
+			rt.manager.RTmutex.Lock()
+			rt.draining = true
+			rt.manager.RTmutex.Unlock()
+			clog.Infof(logCtx, "Remote transcoder=%s is draining", rt.addr)
+			return nil, RemoteTranscoderFatalError{ErrTranscoderDraining}
+		}
 		return chanData.TranscodeData, chanData.Err
 	}
 }
@@ -980,6 +1014,10 @@ func (rtm *RemoteTranscoderManager) selectTranscoder(sessionId string, caps *Cap
 
 	findCompatibleTranscoder := func(rtm *RemoteTranscoderManager) int {
 		for i := len(rtm.remoteTranscoders) - 1; i >= 0; i-- {
+			// new sessions are not assigned to draining transcoders
+			if rtm.remoteTranscoders[i].draining {
+				continue
+			}
 			// no capabilities = default capabilities, all transcoders must support them
 			if caps == nil ||
 				(caps.bitstring.CompatibleWith(rtm.remoteTranscoders[i].capabilities.bitstring) &&
@@ -992,11 +1030,11 @@ func (rtm *RemoteTranscoderManager) selectTranscoder(sessionId string, caps *Cap
 
 	for checkTranscoders(rtm) {
 		currentTranscoder, sessionExists := rtm.streamSessions[sessionId]
-		lastCompatibleTranscoder := findCompatibleTranscoder(rtm)
-		if lastCompatibleTranscoder == -1 {
-			return nil, ErrNoCompatibleTranscodersAvailable
-		}
 		if !sessionExists {
+			lastCompatibleTranscoder := findCompatibleTranscoder(rtm)
+			if lastCompatibleTranscoder == -1 {
+				return nil, ErrNoCompatibleTranscodersAvailable
+			}
 			currentTranscoder = rtm.remoteTranscoders[lastCompatibleTranscoder]
 		}
 
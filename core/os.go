@@ -6,14 +6,25 @@ package core
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
 	"time"
 
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/livepeer/go-livepeer/clog"
 	"github.com/livepeer/go-livepeer/common"
 	"github.com/livepeer/go-livepeer/net"
 	"github.com/livepeer/go-tools/drivers"
+	"google.golang.org/api/option"
 )
 
 func GetSegmentData(ctx context.Context, uri string) ([]byte, error) {
@@ -95,3 +106,98 @@ func getSegmentDataHTTP(ctx context.Context, uri string) ([]byte, error) {
 	clog.V(common.VERBOSE).Infof(ctx, "Downloaded uri=%s dur=%s bytes=%d", uri, took, len(body))
 	return body, nil
 }
+
+// deleteObject deletes the object with the key from an object store
+type deleteObject func(ctx context.Context, key string) error
+
+// deletingOSDriver is an object store driver whose sessions can delete the files they saved
+type deletingOSDriver struct {
+	drivers.OSDriver
+	keyPrefix string
+	delete    deleteObject
+}
+
+func (d *deletingOSDriver) NewSession(path string) drivers.OSSession {
+	return &deletingOSSession{OSSession: d.OSDriver.NewSession(path), key: d.keyPrefix + path, delete: d.delete}
+}
+
+type deletingOSSession struct {
+	drivers.OSSession
+	key    string
+	delete deleteObject
+}
+
+// DeleteFile deletes the file saved with the name in the session
+func (s *deletingOSSession) DeleteFile(ctx context.Context, name string) error {
+	return s.delete(ctx, path.Join(s.key, name))
+}
+
+// WithFileDeletion returns the driver of the object store at the URL with sessions that can delete files, for the
+// object stores that can be written to with the credentials of the URL: the file system, S3 and S3-compatible stores
+// and Google Cloud Storage. Other drivers are returned as is
+func WithFileDeletion(osURL string, driver drivers.OSDriver) (drivers.OSDriver, error) {
+	u, err := url.Parse(osURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "":
+		// Files are saved relative to the URL, like the file system driver does
+		return &deletingOSDriver{OSDriver: driver, delete: func(ctx context.Context, key string) error {
+			return os.Remove(path.Join(u.String(), key))
+		}}, nil
+	case "s3", "s3+http", "s3+https":
+		return newS3DeletingOSDriver(u, driver)
+	case "gs":
+		client, err := storage.NewClient(context.Background(), option.WithCredentialsJSON([]byte(u.User.Username())))
+		if err != nil {
+			return nil, err
+		}
+		bucket := client.Bucket(u.Host)
+		return &deletingOSDriver{OSDriver: driver, delete: func(ctx context.Context, key string) error {
+			return bucket.Object(key).Delete(ctx)
+		}}, nil
+	}
+	return driver, nil
+}
+
+// newS3DeletingOSDriver returns the S3 driver with sessions that can delete files, parsing the URL like the driver
+func newS3DeletingOSDriver(u *url.URL, driver drivers.OSDriver) (drivers.OSDriver, error) {
+	pw, ok := u.User.Password()
+	if !ok {
+		return nil, errors.New("password is required with s3:// OS")
+	}
+	cfg := aws.NewConfig().WithCredentials(credentials.NewStaticCredentials(u.User.Username(), pw, ""))
+	var bucket, keyPrefix string
+	if u.Scheme == "s3" {
+		// Region, then the bucket followed by the key prefix
+		parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 2)
+		if parts[0] == "" {
+			return nil, errors.New("S3 bucket not found in URL path")
+		}
+		bucket = parts[0]
+		if len(parts) > 1 {
+			keyPrefix = parts[1]
+		}
+		cfg = cfg.WithRegion(u.Host)
+	} else {
+		// Custom endpoint, with an optional region before the bucket
+		base, b := path.Split(u.Path)
+		bucket = b
+		region := "ignored"
+		if base = strings.TrimSuffix(base, "/"); base != "" {
+			_, region = path.Split(base)
+		}
+		endpoint := url.URL{Scheme: strings.TrimPrefix(u.Scheme, "s3+"), Host: u.Host}
+		cfg = cfg.WithRegion(region).WithEndpoint(endpoint.String()).WithS3ForcePathStyle(true)
+	}
+	sess, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, err
+	}
+	svc := s3.New(sess)
+	return &deletingOSDriver{OSDriver: driver, keyPrefix: keyPrefix, delete: func(ctx context.Context, key string) error {
+		_, err := svc.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+		return err
+	}}, nil
+}
@@ -1,6 +1,9 @@
 package core
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"sort"
@@ -35,10 +38,42 @@ type PlaylistManager interface {
 
 	InsertHLSSegmentJSON(profile *ffmpeg.VideoProfile, seqNo uint64, uri string, duration float64)
 
+	// Saves the init segment of a fragmented MP4 rendition to the object store, or to the record store,
+	// unless the same init segment was already saved there. Returns the URI of the init segment
+	SaveInitSegment(ctx context.Context, profile *ffmpeg.VideoProfile, data []byte, record bool) (string, error)
+
+	// Inserts in media playlist given links to a fragmented MP4 media segment and to its init segment
+	InsertFMP4Segment(profile *ffmpeg.VideoProfile, seqNo uint64, uri, initURI string, duration float64) error
+
+	InsertFMP4SegmentJSON(profile *ffmpeg.VideoProfile, seqNo uint64, uri, initURI string, duration float64)
+
 	GetHLSMasterPlaylist() *m3u8.MasterPlaylist
 
 	GetHLSMediaPlaylist(rendition string) *m3u8.MediaPlaylist
 
+	// Inserts in the LL-HLS media playlist given the data of a segment, which becomes a part
+	// Does nothing unless LL-HLS is enabled
+	InsertLLHLSPart(profile *ffmpeg.VideoProfile, seqNo uint64, duration float64, data []byte) error
+
+	GetLLHLSMediaPlaylist(rendition string) *LLHLSPlaylist
+
+	// Returns nil if the segments are encrypted, since DASH players don't support AES-128 encrypted segments
+	GetDASHManifest() *DASHManifest
+
+	// Returns nil if the DVR window is disabled or the rendition has no segments yet
+	GetDVRMediaPlaylist(rendition string) *DVRPlaylist
+
+	// Keeps the thumbnail of the segment if it's the latest one
+	InsertThumbnail(seqNo uint64, data []byte)
+
+	// Sets the link to the thumbnail of the segment in the recording
+	InsertThumbnailJSON(profile *ffmpeg.VideoProfile, seqNo uint64, uri string)
+
+	GetThumbnail() []byte
+
+	// Encrypts the data of the segment with its key, or returns it as is unless encryption is enabled
+	EncryptSegment(ctx context.Context, seqNo uint64, data []byte) ([]byte, error)
+
 	GetOSSession() drivers.OSSession
 
 	GetRecordOSSession() drivers.OSSession
@@ -55,16 +90,41 @@ type BasicPlaylistManager struct {
 	// Live playlist used for broadcasting
 	masterPList        *m3u8.MasterPlaylist
 	mediaLists         map[string]*m3u8.MediaPlaylist
+	dashManifest       *DASHManifest
 	mapSync            *sync.RWMutex
 	jsonList           *JsonPlaylist
 	jsonListWriteQueue *drivers.OverwriteQueue
 	jsonListSync       *sync.Mutex
+	// LL-HLS playlists, only used if the part target is set
+	llhlsLists      map[string]*LLHLSPlaylist
+	llhlsPartTarget time.Duration
+	llhlsSegTarget  time.Duration
+	// DVR playlists, only used if the DVR window is set
+	dvrLists  map[string]*DVRPlaylist
+	dvrWindow time.Duration
+	// Encrypts the segments, only used if encryption is enabled
+	encrypter *HLSEncrypter
+	// Saved init segments of the fragmented MP4 renditions
+	initSegments       map[string][]initSegment
+	recordInitSegments map[string][]initSegment
+	initSync           *sync.Mutex
+	// Latest thumbnail of the stream
+	thumbnail      []byte
+	thumbnailSeqNo uint64
+}
+
+type initSegment struct {
+	data []byte
+	uri  string
 }
 
 type jsonSeg struct {
 	SeqNo         uint64 `json:"seq_no,omitempty"`
 	URI           string `json:"uri,omitempty"`
+	InitURI       string `json:"init_uri,omitempty"`
+	ThumbnailURI  string `json:"thumbnail_uri,omitempty"`
 	DurationMs    uint64 `json:"duration_ms,omitempty"`
+	RecordedAtMs  int64  `json:"recorded_at_ms,omitempty"` // wall clock time at which the segment was recorded
 	discontinuity bool
 }
 
@@ -101,35 +161,44 @@ func (jpl *JsonPlaylist) AddMaster(ajpl *JsonPlaylist) {
 	}
 }
 
-// AddSegmentsToMPL adds segments to the MediaPlaylist
+// AddSegmentsToMPL adds segments to the MediaPlaylist. Init segments of fragmented MP4 segments are only added when
+// they change, since they apply to all the segments that follow
 func (jpl *JsonPlaylist) AddSegmentsToMPL(manifestIDs []string, trackName string, mpl *m3u8.MediaPlaylist, extURL string) {
+	var initURI string
 	for _, seg := range jpl.Segments[trackName] {
-		// make relative URL from absolute one
-		uri := seg.URI
-		mindex, manifestIDlen := indexOf(uri, manifestIDs)
-		if mindex != -1 {
-			// If extURL was specified we will put absolute URL to the segment into manifest
-			// extURL points to the root of object store, so we should take part of the 'uri'
-			// which contains manifestID.
-			// If extURL is not specified then we're serving relative URL to the segment,
-			// and address at which manifest is served already contains manfiestID
-			// (broadcaster.com/recordings/manifestID/index.m3u8), so we're taking
-			// part of the 'uri' after the manifestID
-			if extURL != "" {
-				uri = common.JoinURL(extURL, uri[mindex:])
-			} else {
-				uri = uri[mindex+manifestIDlen+1:]
-			}
-		}
 		mseg := &m3u8.MediaSegment{
-			URI:           uri,
+			URI:           jpl.segmentURI(manifestIDs, seg.URI, extURL),
 			Duration:      float64(seg.DurationMs) / 1000.0,
 			Discontinuity: seg.discontinuity,
 		}
+		if seg.InitURI != "" && (seg.InitURI != initURI || seg.discontinuity) {
+			initURI = seg.InitURI
+			mseg.Map = &m3u8.Map{URI: jpl.segmentURI(manifestIDs, seg.InitURI, extURL)}
+		}
 		mpl.InsertSegment(seg.SeqNo, mseg)
 	}
 }
 
+// segmentURI makes relative URL from absolute one
+func (jpl *JsonPlaylist) segmentURI(manifestIDs []string, uri, extURL string) string {
+	mindex, manifestIDlen := indexOf(uri, manifestIDs)
+	if mindex != -1 {
+		// If extURL was specified we will put absolute URL to the segment into manifest
+		// extURL points to the root of object store, so we should take part of the 'uri'
+		// which contains manifestID.
+		// If extURL is not specified then we're serving relative URL to the segment,
+		// and address at which manifest is served already contains manfiestID
+		// (broadcaster.com/recordings/manifestID/index.m3u8), so we're taking
+		// part of the 'uri' after the manifestID
+		if extURL != "" {
+			uri = common.JoinURL(extURL, uri[mindex:])
+		} else {
+			uri = uri[mindex+manifestIDlen+1:]
+		}
+	}
+	return uri
+}
+
 func (jpl *JsonPlaylist) hasTrack(trackName string) bool {
 	for _, track := range jpl.Tracks {
 		if track.Name == trackName {
@@ -199,6 +268,12 @@ func (jpl *JsonPlaylist) AddTrack(ajpl *JsonPlaylist, trackName string) {
 func (jpl *JsonPlaylist) InsertHLSSegment(profile *ffmpeg.VideoProfile, seqNo uint64, uri string,
 	duration float64) {
 
+	jpl.InsertFMP4Segment(profile, seqNo, uri, "", duration)
+}
+
+func (jpl *JsonPlaylist) InsertFMP4Segment(profile *ffmpeg.VideoProfile, seqNo uint64, uri, initURI string,
+	duration float64) {
+
 	durationMs := uint64(duration * 1000)
 	if profile.Name == "source" {
 		jpl.DurationMs += durationMs
@@ -212,9 +287,11 @@ func (jpl *JsonPlaylist) InsertHLSSegment(profile *ffmpeg.VideoProfile, seqNo ui
 		})
 	}
 	jpl.Segments[profile.Name] = append(jpl.Segments[profile.Name], jsonSeg{
-		URI:        uri,
-		DurationMs: durationMs,
-		SeqNo:      seqNo,
+		URI:          uri,
+		InitURI:      initURI,
+		DurationMs:   durationMs,
+		RecordedAtMs: time.Now().UnixMilli(),
+		SeqNo:        seqNo,
 	})
 }
 
@@ -228,9 +305,15 @@ func NewBasicPlaylistManager(manifestID ManifestID,
 		manifestID:     manifestID,
 		masterPList:    m3u8.NewMasterPlaylist(),
 		mediaLists:     make(map[string]*m3u8.MediaPlaylist),
+		dashManifest:   NewDASHManifest(LIVE_LIST_LENGTH),
+		llhlsLists:     make(map[string]*LLHLSPlaylist),
+		dvrLists:       make(map[string]*DVRPlaylist),
 		mapSync:        &sync.RWMutex{},
+		initSegments:   make(map[string][]initSegment),
+		initSync:       &sync.Mutex{},
 	}
 	if recordSession != nil {
+		bplm.recordInitSegments = make(map[string][]initSegment)
 		bplm.jsonList = NewJSONPlaylist()
 		bplm.jsonListSync = &sync.Mutex{}
 		bplm.makeNewOverwriteQueue()
@@ -238,6 +321,46 @@ func NewBasicPlaylistManager(manifestID ManifestID,
 	return bplm
 }
 
+// EnableLLHLS makes the inserted parts available in LL-HLS media playlists, grouped into segments of the target
+// duration
+func (mgr *BasicPlaylistManager) EnableLLHLS(partTarget, segTarget time.Duration) {
+	mgr.mapSync.Lock()
+	defer mgr.mapSync.Unlock()
+	mgr.llhlsPartTarget = partTarget
+	mgr.llhlsSegTarget = segTarget
+}
+
+// EnableDVR makes the inserted segments available in DVR media playlists for the length of the window. Segments that
+// leave the window are deleted from the object store, if it supports deleting files, once they also left the live
+// playlists
+func (mgr *BasicPlaylistManager) EnableDVR(window time.Duration) {
+	mgr.mapSync.Lock()
+	defer mgr.mapSync.Unlock()
+	mgr.dvrWindow = window
+}
+
+// EnableEncryption makes the segments inserted from then on listed with the EXT-X-KEY of their key. Segments are
+// encrypted with EncryptSegment before they are saved to the object store
+func (mgr *BasicPlaylistManager) EnableEncryption(encrypter *HLSEncrypter) {
+	mgr.mapSync.Lock()
+	defer mgr.mapSync.Unlock()
+	mgr.encrypter = encrypter
+}
+
+func (mgr *BasicPlaylistManager) getEncrypter() *HLSEncrypter {
+	mgr.mapSync.RLock()
+	defer mgr.mapSync.RUnlock()
+	return mgr.encrypter
+}
+
+func (mgr *BasicPlaylistManager) EncryptSegment(ctx context.Context, seqNo uint64, data []byte) ([]byte, error) {
+	encrypter := mgr.getEncrypter()
+	if encrypter == nil {
+		return data, nil
+	}
+	return encrypter.Encrypt(ctx, seqNo, data)
+}
+
 func (mgr *BasicPlaylistManager) makeNewOverwriteQueue() {
 	if mgr.jsonListWriteQueue != nil {
 		mgr.jsonListWriteQueue.StopAfter(JsonPlaylistQuitTimeout)
@@ -258,6 +381,11 @@ func (mgr *BasicPlaylistManager) Cleanup() {
 	if mgr.jsonListWriteQueue != nil {
 		mgr.jsonListWriteQueue.StopAfter(JsonPlaylistQuitTimeout)
 	}
+	mgr.mapSync.RLock()
+	for _, pl := range mgr.llhlsLists {
+		pl.Close()
+	}
+	mgr.mapSync.RUnlock()
 }
 
 func (mgr *BasicPlaylistManager) GetOSSession() drivers.OSSession {
@@ -320,14 +448,76 @@ func (mgr *BasicPlaylistManager) InsertHLSSegmentJSON(profile *ffmpeg.VideoProfi
 	}
 }
 
+func (mgr *BasicPlaylistManager) InsertFMP4SegmentJSON(profile *ffmpeg.VideoProfile, seqNo uint64, uri,
+	initURI string, duration float64) {
+
+	if mgr.jsonList != nil {
+		mgr.jsonListSync.Lock()
+		mgr.jsonList.InsertFMP4Segment(profile, seqNo, uri, initURI, duration)
+		mgr.jsonListSync.Unlock()
+	}
+}
+
+func (mgr *BasicPlaylistManager) SaveInitSegment(ctx context.Context, profile *ffmpeg.VideoProfile, data []byte,
+	record bool) (string, error) {
+
+	sess, saved := mgr.storageSession, mgr.initSegments
+	if record {
+		sess, saved = mgr.recordSession, mgr.recordInitSegments
+	}
+	if sess == nil {
+		return "", fmt.Errorf("no object store for the init segment of rendition=%s", profile.Name)
+	}
+
+	mgr.initSync.Lock()
+	defer mgr.initSync.Unlock()
+	for _, init := range saved[profile.Name] {
+		if bytes.Equal(init.data, data) {
+			return init.uri, nil
+		}
+	}
+	// Renditions only get a new init segment when the orchestrator encodes them differently, so they are named
+	// after their content
+	sum := sha256.Sum256(data)
+	name := fmt.Sprintf("%s/init_%x.mp4", profile.Name, sum[:8])
+	var uri string
+	var err error
+	if record {
+		uri, err = drivers.SaveRetried(ctx, sess, name, data, nil, 3)
+	} else {
+		uri, err = sess.SaveData(ctx, name, bytes.NewReader(data), nil, 0)
+	}
+	if err != nil {
+		return "", err
+	}
+	saved[profile.Name] = append(saved[profile.Name], initSegment{data: data, uri: uri})
+	return uri, nil
+}
+
 func (mgr *BasicPlaylistManager) InsertHLSSegment(profile *ffmpeg.VideoProfile, seqNo uint64, uri string,
 	duration float64) error {
 
+	return mgr.InsertFMP4Segment(profile, seqNo, uri, "", duration)
+}
+
+// InsertFMP4Segment inserts the segment like InsertHLSSegment. Playlists only reference one init segment, so the
+// media playlists, the LL-HLS playlists and the DASH manifest use the init segment of the latest segment
+func (mgr *BasicPlaylistManager) InsertFMP4Segment(profile *ffmpeg.VideoProfile, seqNo uint64, uri, initURI string,
+	duration float64) error {
+
 	mpl, err := mgr.getOrCreatePL(profile)
 	if err != nil {
 		return err
 	}
 	mseg := newMediaSegment(uri, duration)
+	var keyURI string
+	if encrypter := mgr.getEncrypter(); encrypter != nil {
+		// Encrypted segments listed without their key can't be played, so they aren't listed at all
+		if mseg.Key, err = encrypter.PlaylistKey(context.Background(), seqNo); err != nil {
+			return err
+		}
+		keyURI = mseg.Key.URI
+	}
 	if mpl.Count() >= mpl.WinSize() {
 		mpl.Remove()
 	}
@@ -335,7 +525,130 @@ func (mgr *BasicPlaylistManager) InsertHLSSegment(profile *ffmpeg.VideoProfile,
 		mpl.SeqNo = mseg.SeqId
 	}
 
-	return mpl.InsertSegment(seqNo, mseg)
+	if err := mpl.InsertSegment(seqNo, mseg); err != nil {
+		return err
+	}
+	if initURI != "" {
+		if mpl.Map == nil || mpl.Map.URI != initURI {
+			mpl.SetDefaultMap(initURI, 0, 0)
+		}
+		if pl := mgr.GetLLHLSMediaPlaylist(profile.Name); pl != nil {
+			pl.SetMap(initURI)
+		}
+	}
+	mgr.dashManifest.InsertFMP4Segment(profile, seqNo, uri, initURI, duration)
+	if pl := mgr.getOrCreateDVRPL(profile.Name); pl != nil {
+		if evicted := pl.InsertSegment(seqNo, uri, initURI, keyURI, duration); len(evicted) > 0 {
+			go mgr.deleteSegments(evicted)
+		}
+	}
+	return nil
+}
+
+func (mgr *BasicPlaylistManager) getOrCreateDVRPL(rendition string) *DVRPlaylist {
+	mgr.mapSync.Lock()
+	defer mgr.mapSync.Unlock()
+	if mgr.dvrWindow <= 0 {
+		return nil
+	}
+	pl, ok := mgr.dvrLists[rendition]
+	if !ok {
+		pl = NewDVRPlaylist(mgr.dvrWindow, LIVE_LIST_LENGTH)
+		mgr.dvrLists[rendition] = pl
+	}
+	return pl
+}
+
+// fileDeleter is implemented by the sessions of the object stores returned by WithFileDeletion
+type fileDeleter interface {
+	DeleteFile(ctx context.Context, name string) error
+}
+
+// deleteSegments deletes the segments that left the DVR window from the object store. Init segments are kept since
+// they are shared by the segments of the rendition
+func (mgr *BasicPlaylistManager) deleteSegments(uris []string) {
+	deleter, ok := mgr.storageSession.(fileDeleter)
+	if !ok {
+		return
+	}
+	for _, uri := range uris {
+		// Files are named relative to the session of the stream
+		name := uri
+		if mindex, mlen := indexOf(uri, []string{string(mgr.manifestID) + "/"}); mindex != -1 {
+			name = uri[mindex+mlen:]
+		}
+		if err := deleter.DeleteFile(context.Background(), name); err != nil {
+			glog.Errorf("Error deleting segment outside of the DVR window manifestID=%s name=%s err=%q", mgr.manifestID, name, err)
+		}
+	}
+}
+
+func (mgr *BasicPlaylistManager) InsertLLHLSPart(profile *ffmpeg.VideoProfile, seqNo uint64, duration float64,
+	data []byte) error {
+
+	mgr.mapSync.Lock()
+	if mgr.llhlsPartTarget <= 0 {
+		mgr.mapSync.Unlock()
+		return nil
+	}
+	pl, ok := mgr.llhlsLists[profile.Name]
+	if !ok {
+		ext, err := common.ProfileFormatExtension(profile.Format)
+		if err != nil {
+			mgr.mapSync.Unlock()
+			return err
+		}
+		pl = NewLLHLSPlaylist(profile.Name, ext, LIVE_LIST_LENGTH, mgr.llhlsPartTarget, mgr.llhlsSegTarget)
+		mgr.llhlsLists[profile.Name] = pl
+	}
+	mgr.mapSync.Unlock()
+
+	pl.InsertPart(seqNo, duration, data)
+	return nil
+}
+
+// GetLLHLSMediaPlaylist returns nil if LL-HLS is disabled or the rendition has no parts yet
+func (mgr *BasicPlaylistManager) GetLLHLSMediaPlaylist(rendition string) *LLHLSPlaylist {
+	mgr.mapSync.RLock()
+	defer mgr.mapSync.RUnlock()
+	return mgr.llhlsLists[rendition]
+}
+
+func (mgr *BasicPlaylistManager) GetDVRMediaPlaylist(rendition string) *DVRPlaylist {
+	mgr.mapSync.RLock()
+	defer mgr.mapSync.RUnlock()
+	return mgr.dvrLists[rendition]
+}
+
+// GetDASHManifest returns the live DASH manifest, with the same segments as the media playlists
+func (mgr *BasicPlaylistManager) GetDASHManifest() *DASHManifest {
+	if mgr.getEncrypter() != nil {
+		return nil
+	}
+	return mgr.dashManifest
+}
+
+func (mgr *BasicPlaylistManager) InsertThumbnail(seqNo uint64, data []byte) {
+	mgr.mapSync.Lock()
+	defer mgr.mapSync.Unlock()
+	if mgr.thumbnail == nil || seqNo >= mgr.thumbnailSeqNo {
+		mgr.thumbnail, mgr.thumbnailSeqNo = data, seqNo
+	}
+}
+
+func (mgr *BasicPlaylistManager) InsertThumbnailJSON(profile *ffmpeg.VideoProfile, seqNo uint64, uri string) {
+	if mgr.jsonList != nil {
+		mgr.jsonListSync.Lock()
+		mgr.jsonList.InsertThumbnail(profile, seqNo, uri)
+		mgr.jsonListSync.Unlock()
+	}
+}
+
+// GetThumbnail returns the latest thumbnail, or nil if the stream has none
+func (mgr *BasicPlaylistManager) GetThumbnail() []byte {
+	mgr.mapSync.RLock()
+	defer mgr.mapSync.RUnlock()
+	return mgr.thumbnail
 }
 
 // GetHLSMasterPlaylist ..
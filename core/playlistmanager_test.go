@@ -4,13 +4,16 @@ import (
 	"bytes"
 	"context"
 	"net/url"
+	"path"
 	"testing"
 	"time"
 
+	"github.com/livepeer/go-livepeer/common"
 	"github.com/livepeer/go-tools/drivers"
 	ffmpeg "github.com/livepeer/lpms/ffmpeg"
 	"github.com/livepeer/m3u8"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func init() {
@@ -308,3 +311,83 @@ func TestCleanup(t *testing.T) {
 		t.Fatal("Data should be cleaned up")
 	}
 }
+
+func TestInsertLLHLSPart(t *testing.T) {
+	assert := assert.New(t)
+	vProfile := ffmpeg.P144p30fps16x9
+
+	// Parts are ignored unless LL-HLS is enabled
+	c := NewBasicPlaylistManager(ManifestID("mid"), nil, nil)
+	assert.Nil(c.InsertLLHLSPart(&vProfile, 1, 0.5, []byte("part")))
+	assert.Nil(c.GetLLHLSMediaPlaylist(vProfile.Name))
+
+	c.EnableLLHLS(500*time.Millisecond, 2*time.Second)
+	assert.Nil(c.InsertLLHLSPart(&vProfile, 1, 0.5, []byte("part")))
+	pl := c.GetLLHLSMediaPlaylist(vProfile.Name)
+	assert.NotNil(pl)
+	assert.Contains(pl.Encode().String(), "URI=\"P144p30fps16x9/llhls_0_0.ts\",INDEPENDENT=YES")
+	assert.Nil(c.GetLLHLSMediaPlaylist("source"))
+
+	vProfile.Name = "unknown"
+	vProfile.Format = -1
+	assert.NotNil(c.InsertLLHLSPart(&vProfile, 1, 0.5, []byte("part")))
+
+	// Cleanup ends the playlists
+	c.Cleanup()
+	assert.Contains(pl.Encode().String(), "#EXT-X-ENDLIST")
+}
+
+func TestFMP4Segments(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	vProfile := ffmpeg.P144p30fps16x9
+	vProfile.Format = common.FormatFMP4
+
+	osSession := drivers.NewMemoryDriver(nil).NewSession("mid")
+	memoryOS := osSession.(*drivers.MemorySession)
+	c := NewBasicPlaylistManager(ManifestID("mid"), osSession, nil)
+	c.EnableLLHLS(500*time.Millisecond, 2*time.Second)
+
+	// Init segments are saved once
+	initURI, err := c.SaveInitSegment(context.TODO(), &vProfile, []byte("init"), false)
+	require.Nil(err)
+	assert.Regexp(`P144p30fps16x9/init_[0-9a-f]{16}\.mp4$`, initURI)
+	assert.Equal([]byte("init"), memoryOS.GetData("mid/P144p30fps16x9/"+path.Base(initURI)))
+	uri, err := c.SaveInitSegment(context.TODO(), &vProfile, []byte("init"), false)
+	require.Nil(err)
+	assert.Equal(initURI, uri)
+	newInitURI, err := c.SaveInitSegment(context.TODO(), &vProfile, []byte("new init"), false)
+	require.Nil(err)
+	assert.NotEqual(initURI, newInitURI)
+	// There's no record store
+	_, err = c.SaveInitSegment(context.TODO(), &vProfile, []byte("init"), true)
+	assert.NotNil(err)
+
+	// Playlists reference the init segment of the latest segment
+	require.Nil(c.InsertLLHLSPart(&vProfile, 1, 0.5, []byte("media")))
+	require.Nil(c.InsertFMP4Segment(&vProfile, 1, "/stream/mid/P144p30fps16x9/1.m4s", initURI, 2))
+	assert.Contains(c.GetHLSMediaPlaylist(vProfile.Name).Encode().String(), `#EXT-X-MAP:URI="`+initURI+`"`)
+	assert.Contains(c.GetLLHLSMediaPlaylist(vProfile.Name).Encode().String(), `#EXT-X-MAP:URI="`+initURI+`"`)
+	assert.Contains(c.GetDASHManifest().Encode(time.Now()).String(), `<Initialization sourceURL="`+initURI+`"></Initialization>`)
+	require.Nil(c.InsertFMP4Segment(&vProfile, 2, "/stream/mid/P144p30fps16x9/2.m4s", newInitURI, 2))
+	assert.Contains(c.GetHLSMediaPlaylist(vProfile.Name).Encode().String(), `#EXT-X-MAP:URI="`+newInitURI+`"`)
+	assert.Contains(c.GetLLHLSMediaPlaylist(vProfile.Name).Encode().String(), `#EXT-X-MAP:URI="`+newInitURI+`"`)
+}
+
+func TestJSONListFMP4(t *testing.T) {
+	assert := assert.New(t)
+	jspl := NewJSONPlaylist()
+	vProfile := ffmpeg.P144p30fps16x9
+	jspl.InsertFMP4Segment(&vProfile, 1, "mid/P144p30fps16x9/1.m4s", "mid/P144p30fps16x9/init_a.mp4", 2)
+	jspl.InsertFMP4Segment(&vProfile, 2, "mid/P144p30fps16x9/2.m4s", "mid/P144p30fps16x9/init_a.mp4", 2)
+	jspl.InsertFMP4Segment(&vProfile, 3, "mid/P144p30fps16x9/3.m4s", "mid/P144p30fps16x9/init_b.mp4", 2)
+	assert.Equal("mid/P144p30fps16x9/init_a.mp4", jspl.Segments[vProfile.Name][0].InitURI)
+
+	// Init segments are listed when they change
+	mpl, err := m3u8.NewMediaPlaylist(10, 10)
+	assert.Nil(err)
+	jspl.AddSegmentsToMPL([]string{"mid"}, vProfile.Name, mpl, "")
+	assert.Equal(&m3u8.Map{URI: "P144p30fps16x9/init_a.mp4"}, mpl.Segments[0].Map)
+	assert.Nil(mpl.Segments[1].Map)
+	assert.Equal(&m3u8.Map{URI: "P144p30fps16x9/init_b.mp4"}, mpl.Segments[2].Map)
+}
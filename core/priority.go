@@ -0,0 +1,188 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+// ErrSenderQuota is returned when a sender is over the quotas of its priority class. It is an ErrOrchCap so that
+// broadcasters move the stream to another orchestrator
+var ErrSenderQuota = fmt.Errorf("%w: sender quota exceeded", ErrOrchCap)
+
+// Pixels a sender can transcode in a burst, in seconds of its max pixel rate
+var pixelRateBurst = 10 * time.Second
+
+// PriorityClass sets the share of the session slots reserved for a group of broadcasters and the quotas of each of
+// them. Senders without a priority class are best-effort
+type PriorityClass struct {
+	Name string
+	// Sessions of classes with a priority above zero may preempt best-effort sessions when the orchestrator is at
+	// capacity. Classes with a priority of zero are best-effort
+	Priority int
+	// Share of MaxSessions that is held for the sessions of the class, and can't be used by other senders
+	ReservedShare float64
+	// Max number of concurrent sessions of each sender of the class, unlimited if zero
+	MaxSessions int
+	// Max rate of output pixels of each sender of the class, unlimited if zero
+	MaxPixelsPerSecond int64
+}
+
+func (c *PriorityClass) priority() int {
+	if c == nil {
+		return 0
+	}
+	return c.Priority
+}
+
+// reservedSessions returns the number of session slots held for the class
+func (c *PriorityClass) reservedSessions() int {
+	if c == nil {
+		return 0
+	}
+	return int(c.ReservedShare * float64(MaxSessions))
+}
+
+type pixelRate struct {
+	// Pixels the sender can transcode, negative once the sender is over its rate
+	pixels float64
+	last   time.Time
+}
+
+// SetPriorityClass sets the priority class of a sender
+func (n *LivepeerNode) SetPriorityClass(sender ethcommon.Address, class *PriorityClass) {
+	n.segmentMutex.Lock()
+	defer n.segmentMutex.Unlock()
+	if n.priorityClasses == nil {
+		n.priorityClasses = make(map[ethcommon.Address]*PriorityClass)
+	}
+	n.priorityClasses[sender] = class
+}
+
+// setSessionSender records the sender of a session that holds a session slot. segmentMutex must be held
+func (n *LivepeerNode) setSessionSender(mid ManifestID, sender ethcommon.Address) {
+	if n.sessionSenders == nil {
+		n.sessionSenders = make(map[ManifestID]ethcommon.Address)
+	}
+	n.sessionSenders[mid] = sender
+}
+
+// senderSessions returns the number of session slots in use by a sender. segmentMutex must be held
+func (n *LivepeerNode) senderSessions(sender ethcommon.Address) int {
+	count := 0
+	for _, s := range n.sessionSenders {
+		if s == sender {
+			count++
+		}
+	}
+	return count
+}
+
+// classSessions returns the number of session slots in use by the senders of a class. segmentMutex must be held
+func (n *LivepeerNode) classSessions(class *PriorityClass) int {
+	count := 0
+	for _, s := range n.sessionSenders {
+		if n.priorityClasses[s] == class {
+			count++
+		}
+	}
+	return count
+}
+
+// unusedReservedSessions returns the number of session slots held for the other classes that they don't use.
+// segmentMutex must be held
+func (n *LivepeerNode) unusedReservedSessions(exclude *PriorityClass) int {
+	unused := 0
+	seen := make(map[*PriorityClass]bool)
+	for _, c := range n.priorityClasses {
+		if c == exclude || seen[c] {
+			continue
+		}
+		seen[c] = true
+		if free := c.reservedSessions() - n.classSessions(c); free > 0 {
+			unused += free
+		}
+	}
+	return unused
+}
+
+// hasCapacity returns whether a new session of the sender fits within MaxSessions without using the slots held for
+// the other classes. segmentMutex must be held
+func (n *LivepeerNode) hasCapacity(sender ethcommon.Address) bool {
+	return n.sessionsInUse()+n.unusedReservedSessions(n.priorityClasses[sender]) < MaxSessions
+}
+
+// preemptibleSession returns a best-effort session that can be ended to free a slot for a new session of the sender,
+// or an empty ManifestID. segmentMutex must be held
+func (n *LivepeerNode) preemptibleSession(sender ethcommon.Address) ManifestID {
+	class := n.priorityClasses[sender]
+	if class.priority() <= 0 {
+		return ""
+	}
+	// The freed slot must not be held for another class
+	if n.sessionsInUse()-1+n.unusedReservedSessions(class) >= MaxSessions {
+		return ""
+	}
+	for mid := range n.SegmentChans {
+		c := n.priorityClasses[n.sessionSenders[mid]]
+		if c.priority() > 0 {
+			continue
+		}
+		// Ending a session within the reserved slots of its class would only free a slot for that class
+		if c != nil && n.classSessions(c) <= c.reservedSessions() {
+			continue
+		}
+		return mid
+	}
+	return ""
+}
+
+// overSessionQuota returns whether the sender already uses the max number of sessions of its class.
+// segmentMutex must be held
+func (n *LivepeerNode) overSessionQuota(sender ethcommon.Address) bool {
+	class := n.priorityClasses[sender]
+	return class != nil && class.MaxSessions > 0 && n.senderSessions(sender) >= class.MaxSessions
+}
+
+// SufficientPixelRate returns whether the sender is within the max pixel rate of its class
+func (n *LivepeerNode) SufficientPixelRate(sender ethcommon.Address) bool {
+	n.segmentMutex.Lock()
+	defer n.segmentMutex.Unlock()
+	rate := n.refillPixelRate(sender)
+	return rate == nil || rate.pixels > 0
+}
+
+// debitPixels counts the pixels transcoded for the sender against the max pixel rate of its class
+func (n *LivepeerNode) debitPixels(sender ethcommon.Address, pixels int64) {
+	n.segmentMutex.Lock()
+	defer n.segmentMutex.Unlock()
+	if rate := n.refillPixelRate(sender); rate != nil {
+		rate.pixels -= float64(pixels)
+	}
+}
+
+// refillPixelRate returns the pixel rate of the sender with the pixels accrued since it was last used, or nil if the
+// sender has no max pixel rate. segmentMutex must be held
+func (n *LivepeerNode) refillPixelRate(sender ethcommon.Address) *pixelRate {
+	class := n.priorityClasses[sender]
+	if class == nil || class.MaxPixelsPerSecond <= 0 {
+		return nil
+	}
+	burst := float64(class.MaxPixelsPerSecond) * pixelRateBurst.Seconds()
+	now := time.Now()
+	rate, ok := n.pixelRates[sender]
+	if !ok {
+		if n.pixelRates == nil {
+			n.pixelRates = make(map[ethcommon.Address]*pixelRate)
+		}
+		rate = &pixelRate{pixels: burst, last: now}
+		n.pixelRates[sender] = rate
+	}
+	rate.pixels += float64(class.MaxPixelsPerSecond) * now.Sub(rate.last).Seconds()
+	if rate.pixels > burst {
+		rate.pixels = burst
+	}
+	rate.last = now
+	return rate
+}
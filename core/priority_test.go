@@ -0,0 +1,133 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/livepeer/go-livepeer/net"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	premiumSender    = ethcommon.BytesToAddress([]byte("premium"))
+	bestEffortSender = ethcommon.BytesToAddress([]byte("bestEffort"))
+)
+
+func startSenderSession(t *testing.T, n *LivepeerNode, sessionID string, sender ethcommon.Address) {
+	md := StubSegTranscodingMetadata()
+	md.AuthToken = &net.AuthToken{SessionId: sessionID}
+	md.Sender = sender
+	_, err := n.getSegmentChan(context.Background(), md)
+	require.Nil(t, err)
+}
+
+func TestPriorityClass_SessionQuota(t *testing.T) {
+	assert := assert.New(t)
+	n := admissionNode(t, 10, 0)
+	n.SetPriorityClass(premiumSender, &PriorityClass{Name: "premium", MaxSessions: 2})
+
+	for _, mid := range []string{"a", "b"} {
+		require.Nil(t, n.admitSession(context.Background(), premiumSender, ManifestID(mid), 0))
+		startSenderSession(t, n, mid, premiumSender)
+	}
+	// Senders at their quota are rejected like a capped orchestrator, including on discovery
+	err := n.admitSession(context.Background(), premiumSender, "c", time.Second)
+	assert.Equal(ErrSenderQuota, err)
+	assert.True(errors.Is(err, ErrOrchCap))
+	assert.Equal(ErrSenderQuota, n.admitSession(context.Background(), premiumSender, "", 0))
+	// Existing sessions and other senders are admitted
+	assert.Nil(n.admitSession(context.Background(), premiumSender, "a", 0))
+	assert.Nil(n.admitSession(context.Background(), bestEffortSender, "c", 0))
+
+	n.endTranscodingSession("a", context.Background())
+	assert.Nil(n.admitSession(context.Background(), premiumSender, "c", 0))
+}
+
+func TestPriorityClass_ReservedShare(t *testing.T) {
+	assert := assert.New(t)
+	n := admissionNode(t, 4, 0)
+	n.SetPriorityClass(premiumSender, &PriorityClass{Name: "premium", ReservedShare: 0.5})
+
+	// Best-effort senders can't use the slots held for the class
+	for _, mid := range []string{"a", "b"} {
+		require.Nil(t, n.admitSession(context.Background(), bestEffortSender, ManifestID(mid), 0))
+		startSenderSession(t, n, mid, bestEffortSender)
+	}
+	assert.Equal(ErrOrchCap, n.admitSession(context.Background(), bestEffortSender, "c", 0))
+	md := StubSegTranscodingMetadata()
+	md.AuthToken = &net.AuthToken{SessionId: "c"}
+	md.Sender = bestEffortSender
+	_, err := n.getSegmentChan(context.Background(), md)
+	assert.Equal(ErrOrchCap, err)
+
+	// The class uses its reserved slots
+	for _, mid := range []string{"d", "e"} {
+		require.Nil(t, n.admitSession(context.Background(), premiumSender, ManifestID(mid), 0))
+		startSenderSession(t, n, mid, premiumSender)
+	}
+	assert.Equal(ErrOrchCap, n.admitSession(context.Background(), premiumSender, "f", 0))
+
+	// Best-effort senders can use the slots that are not reserved, but still can't use the unused reserved slots
+	n.endTranscodingSession("a", context.Background())
+	n.endTranscodingSession("d", context.Background())
+	require.Nil(t, n.admitSession(context.Background(), bestEffortSender, "f", 0))
+	startSenderSession(t, n, "f", bestEffortSender)
+	assert.Equal(ErrOrchCap, n.admitSession(context.Background(), bestEffortSender, "g", 0))
+	assert.Nil(n.admitSession(context.Background(), premiumSender, "g", 0))
+}
+
+func TestPriorityClass_Preemption(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	n := admissionNode(t, 2, 2)
+	n.SetPriorityClass(premiumSender, &PriorityClass{Name: "premium", Priority: 1})
+	lowSender := ethcommon.BytesToAddress([]byte("low"))
+	n.SetPriorityClass(lowSender, &PriorityClass{Name: "low", ReservedShare: 0.5})
+
+	startSenderSession(t, n, "a", lowSender)
+	startSenderSession(t, n, "b", bestEffortSender)
+	waiting := admitAsync(n, "c", 5*time.Second)
+	waitForQueueDepth(t, n, 1)
+
+	// Discovery requests don't preempt sessions
+	require.Nil(n.admitSession(context.Background(), premiumSender, "", 0))
+	assert.Len(n.SegmentChans, 2)
+
+	// Priority senders preempt best-effort sessions, and the freed slot is not given to the waiting sessions
+	require.Nil(n.admitSession(context.Background(), premiumSender, "d", 0))
+	assert.Len(n.SegmentChans, 1)
+	assert.Contains(n.SegmentChans, ManifestID("a"))
+	assert.Equal(1, n.AdmissionQueueDepth())
+	startSenderSession(t, n, "d", premiumSender)
+
+	// Sessions within the reserved slots of their class are not preempted
+	assert.Equal(ErrOrchCap, n.admitSession(context.Background(), premiumSender, "e", 0))
+	assert.Len(n.SegmentChans, 2)
+
+	n.endTranscodingSession("d", context.Background())
+	require.Nil(<-waiting)
+}
+
+func TestPriorityClass_PixelRate(t *testing.T) {
+	assert := assert.New(t)
+	oldBurst := pixelRateBurst
+	defer func() { pixelRateBurst = oldBurst }()
+	pixelRateBurst = 10 * time.Millisecond
+	n := admissionNode(t, 10, 0)
+	n.SetPriorityClass(premiumSender, &PriorityClass{Name: "premium", MaxPixelsPerSecond: 1000})
+
+	// Senders without a max pixel rate are never limited
+	n.debitPixels(bestEffortSender, 1000000)
+	assert.True(n.SufficientPixelRate(bestEffortSender))
+
+	assert.True(n.SufficientPixelRate(premiumSender))
+	n.debitPixels(premiumSender, 15)
+	assert.False(n.SufficientPixelRate(premiumSender))
+	// Pixels accrue at the max pixel rate
+	time.Sleep(20 * time.Millisecond)
+	assert.True(n.SufficientPixelRate(premiumSender))
+}
@@ -38,6 +38,27 @@ type StreamParameters struct {
 	Codec             ffmpeg.VideoCodec
 	PixelFormat       ffmpeg.PixelFormat
 	TimeoutMultiplier int // Used in the VOD workflow to allow us to be more lenient with timeouts
+	AccountID         string
+	OrchConstraints   *OrchestratorConstraints // Restricts the orchestrators used for this stream if set
+	ThumbnailInterval uint                     // Number of source segments between thumbnails, no thumbnails if 0
+	DVRWindow         time.Duration            // Length of the DVR window of the live playlists, no DVR if 0
+	LLHLSPartTarget   time.Duration            // Part target of the LL-HLS playlists and length of the source segments, no LL-HLS if 0
+	Encrypt           bool                     // Output segments are encrypted with AES-128 if set
+	Restreams         []RestreamTarget         // RTMP destinations that the stream is pushed to
+}
+
+// RestreamTarget is an RTMP or RTMPS destination that the source or a rendition of a stream is pushed to
+type RestreamTarget struct {
+	URL     string
+	Profile string // Name of the pushed rendition, or "source"
+}
+
+// OrchestratorConstraints restricts the orchestrators that a stream is transcoded by
+type OrchestratorConstraints struct {
+	MaxPrice     *AutoConvertedPrice // Max price per pixel, overrides the broadcaster's max price if set
+	Allowlist    []string            // ETH addresses or service URIs, all orchestrators are allowed if empty
+	Denylist     []string            // ETH addresses or service URIs
+	MinPerfScore float64
 }
 
 func (s *StreamParameters) StreamID() string {
@@ -66,6 +87,7 @@ type SegTranscodingMetadata struct {
 	AuthToken          *net.AuthToken
 	CalcPerceptualHash bool
 	SegmentParameters  *SegmentParameters
+	Sender             ethcommon.Address // Broadcaster that sent the segment, set by the orchestrator
 }
 
 func (md *SegTranscodingMetadata) Flatten() []byte {
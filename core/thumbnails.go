@@ -0,0 +1,172 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/lpms/ffmpeg"
+)
+
+// Thumbnails are scaled to the same size so that they can be tiled in sprite sheets
+const (
+	ThumbnailWidth  = 256
+	ThumbnailHeight = 144
+	spriteColumns   = 10
+	spriteRows      = 10
+)
+
+var ErrSpriteSize = errors.New("invalid number of thumbnails for a sprite sheet")
+
+// GenerateThumbnail returns a JPEG thumbnail of the start of the segment
+func GenerateThumbnail(data []byte) ([]byte, error) {
+	dir, err := ioutil.TempDir("", "thumbnail")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temp dir for thumbnail: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	fname := filepath.Join(dir, "in_"+common.RandName())
+	if err := ioutil.WriteFile(fname, data, 0644); err != nil {
+		return nil, fmt.Errorf("error writing temp file for thumbnail: %w", err)
+	}
+
+	oname := filepath.Join(dir, "out.jpg")
+	in := &ffmpeg.TranscodeOptionsIn{Fname: fname}
+	out := []ffmpeg.TranscodeOptions{{
+		Oname: oname,
+		Profile: ffmpeg.VideoProfile{
+			Resolution:   fmt.Sprintf("%dx%d", ThumbnailWidth, ThumbnailHeight),
+			Framerate:    1,
+			FramerateDen: 1,
+		},
+		VideoEncoder: ffmpeg.ComponentOptions{Name: "mjpeg"},
+		AudioEncoder: ffmpeg.ComponentOptions{Name: "drop"},
+		// The image is overwritten by every frame, so only the first second is decoded
+		Muxer: ffmpeg.ComponentOptions{Name: "image2", Opts: map[string]string{"update": "1"}},
+		To:    time.Second,
+	}}
+	if _, err := ffmpeg.Transcode3(in, out); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(oname)
+}
+
+// ComposeSprite tiles the JPEG thumbnails of a sprite sheet row by row. Thumbnails that can't be decoded are left blank
+func ComposeSprite(thumbnails [][]byte) ([]byte, error) {
+	if len(thumbnails) == 0 || len(thumbnails) > spriteColumns*spriteRows {
+		return nil, ErrSpriteSize
+	}
+	cols := spriteColumns
+	if len(thumbnails) < cols {
+		cols = len(thumbnails)
+	}
+	rows := (len(thumbnails) + spriteColumns - 1) / spriteColumns
+	sprite := image.NewRGBA(image.Rect(0, 0, cols*ThumbnailWidth, rows*ThumbnailHeight))
+	for i, data := range thumbnails {
+		thumb, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			glog.Errorf("Error decoding thumbnail for sprite sheet err=%q", err)
+			continue
+		}
+		x, y := spriteTile(i)
+		draw.Draw(sprite, image.Rect(x, y, x+ThumbnailWidth, y+ThumbnailHeight), thumb, thumb.Bounds().Min, draw.Src)
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, sprite, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// spriteTile returns the position of the thumbnail in its sprite sheet
+func spriteTile(i int) (int, int) {
+	i %= spriteColumns * spriteRows
+	return (i % spriteColumns) * ThumbnailWidth, (i / spriteColumns) * ThumbnailHeight
+}
+
+func spriteName(sprite int) string {
+	return fmt.Sprintf("sprite_%d.jpg", sprite)
+}
+
+// ParseSpriteName returns the index of the sprite sheet named in the WebVTT thumbnails track
+func ParseSpriteName(name string) (int, bool) {
+	if !strings.HasPrefix(name, "sprite_") || !strings.HasSuffix(name, ".jpg") {
+		return 0, false
+	}
+	sprite, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "sprite_"), ".jpg"))
+	if err != nil || sprite < 0 {
+		return 0, false
+	}
+	return sprite, true
+}
+
+// InsertThumbnail sets the thumbnail of the segment of the track
+func (jpl *JsonPlaylist) InsertThumbnail(profile *ffmpeg.VideoProfile, seqNo uint64, uri string) {
+	segs := jpl.Segments[profile.Name]
+	// The thumbnail is usually inserted right after its segment
+	for i := len(segs) - 1; i >= 0; i-- {
+		if segs[i].SeqNo == seqNo {
+			segs[i].ThumbnailURI = uri
+			return
+		}
+	}
+}
+
+// EncodeThumbnailsVTT renders the WebVTT thumbnails track of the recording. Each thumbnail is shown from the start of
+// its segment until the next thumbnail, and points to its tile in the sprite sheets
+func (jpl *JsonPlaylist) EncodeThumbnailsVTT(trackName string) *bytes.Buffer {
+	var buf bytes.Buffer
+	buf.WriteString("WEBVTT\n")
+	var pos, cueStart uint64
+	n := 0
+	writeCue := func(end uint64) {
+		x, y := spriteTile(n - 1)
+		fmt.Fprintf(&buf, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n", vttTimestamp(cueStart), vttTimestamp(end),
+			spriteName((n-1)/(spriteColumns*spriteRows)), x, y, ThumbnailWidth, ThumbnailHeight)
+	}
+	for _, seg := range jpl.Segments[trackName] {
+		if seg.ThumbnailURI != "" {
+			if n > 0 {
+				writeCue(pos)
+			}
+			cueStart = pos
+			n++
+		}
+		pos += seg.DurationMs
+	}
+	if n > 0 {
+		writeCue(pos)
+	}
+	return &buf
+}
+
+// SpriteThumbnails returns the object store paths of the thumbnails tiled in the sprite sheet
+func (jpl *JsonPlaylist) SpriteThumbnails(manifestIDs []string, trackName string, sprite int) []string {
+	var paths []string
+	n := 0
+	for _, seg := range jpl.Segments[trackName] {
+		if seg.ThumbnailURI == "" {
+			continue
+		}
+		if n/(spriteColumns*spriteRows) == sprite {
+			paths = append(paths, RecordingPath(manifestIDs, seg.ThumbnailURI))
+		}
+		n++
+	}
+	return paths
+}
+
+func vttTimestamp(ms uint64) string {
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"strings"
+	"testing"
+
+	ffmpeg "github.com/livepeer/lpms/ffmpeg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testThumbnail(t *testing.T, c color.Color) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, ThumbnailWidth, ThumbnailHeight))
+	for x := 0; x < ThumbnailWidth; x++ {
+		for y := 0; y < ThumbnailHeight; y++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	require.Nil(t, jpeg.Encode(&buf, img, nil))
+	return buf.Bytes()
+}
+
+func TestComposeSprite(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	white := testThumbnail(t, color.White)
+	thumbnails := make([][]byte, 12)
+	for i := range thumbnails {
+		thumbnails[i] = white
+	}
+	// Thumbnails that can't be decoded are left blank
+	thumbnails[11] = []byte("not a jpeg")
+
+	data, err := ComposeSprite(thumbnails)
+	require.Nil(err)
+	sprite, err := jpeg.Decode(bytes.NewReader(data))
+	require.Nil(err)
+	assert.Equal(image.Rect(0, 0, 10*ThumbnailWidth, 2*ThumbnailHeight), sprite.Bounds())
+	r, _, _, _ := sprite.At(ThumbnailWidth/2, ThumbnailHeight+ThumbnailHeight/2).RGBA()
+	assert.Greater(r, uint32(0xf000))
+	r, _, _, _ = sprite.At(ThumbnailWidth+ThumbnailWidth/2, ThumbnailHeight+ThumbnailHeight/2).RGBA()
+	assert.Less(r, uint32(0x1000))
+
+	// Sprite sheets have up to 100 thumbnails
+	_, err = ComposeSprite(nil)
+	assert.Equal(ErrSpriteSize, err)
+	_, err = ComposeSprite(make([][]byte, 101))
+	assert.Equal(ErrSpriteSize, err)
+}
+
+func TestParseSpriteName(t *testing.T) {
+	assert := assert.New(t)
+
+	sprite, ok := ParseSpriteName(spriteName(3))
+	assert.True(ok)
+	assert.Equal(3, sprite)
+	for _, name := range []string{"sprite_.jpg", "sprite_-1.jpg", "sprite_1.png", "thumbnail.jpg"} {
+		_, ok = ParseSpriteName(name)
+		assert.False(ok, name)
+	}
+}
+
+func TestJSONPlaylistThumbnails(t *testing.T) {
+	assert := assert.New(t)
+
+	jpl := NewJSONPlaylist()
+	source := ffmpeg.VideoProfile{Name: "source"}
+	for i := uint64(0); i < 5; i++ {
+		jpl.InsertHLSSegment(&source, i, "https://rec.test/mani/node/source/0.ts", 2.5)
+	}
+	assert.Equal("WEBVTT\n", jpl.EncodeThumbnailsVTT("source").String())
+
+	jpl.InsertThumbnail(&source, 0, "https://rec.test/mani/node/thumbnails/0.jpg")
+	jpl.InsertThumbnail(&source, 2, "https://rec.test/mani/node/thumbnails/2.jpg")
+	// Segments that aren't in the recording are ignored
+	jpl.InsertThumbnail(&source, 7, "https://rec.test/mani/node/thumbnails/7.jpg")
+
+	// Thumbnails last until the next one
+	assert.Equal("WEBVTT\n"+
+		"\n00:00:00.000 --> 00:00:05.000\nsprite_0.jpg#xywh=0,0,256,144\n"+
+		"\n00:00:05.000 --> 00:00:12.500\nsprite_0.jpg#xywh=256,0,256,144\n",
+		jpl.EncodeThumbnailsVTT("source").String())
+
+	assert.Equal([]string{"mani/node/thumbnails/0.jpg", "mani/node/thumbnails/2.jpg"},
+		jpl.SpriteThumbnails([]string{"mani"}, "source", 0))
+	assert.Empty(jpl.SpriteThumbnails([]string{"mani"}, "source", 1))
+}
+
+func TestJSONPlaylistThumbnails_SpriteSheets(t *testing.T) {
+	assert := assert.New(t)
+
+	// Thumbnails after the first 100 are tiled in the next sprite sheet, from its top left corner
+	jpl := NewJSONPlaylist()
+	source := ffmpeg.VideoProfile{Name: "source"}
+	for i := uint64(0); i < 102; i++ {
+		jpl.InsertHLSSegment(&source, i, fmt.Sprintf("mani/node/source/%d.ts", i), 1)
+		jpl.InsertThumbnail(&source, i, fmt.Sprintf("mani/node/thumbnails/%d.jpg", i))
+	}
+	vtt := jpl.EncodeThumbnailsVTT("source").String()
+	assert.True(strings.HasPrefix(vtt, "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nsprite_0.jpg#xywh=0,0,256,144\n"))
+	assert.Contains(vtt, "\n00:00:11.000 --> 00:00:12.000\nsprite_0.jpg#xywh=256,144,256,144\n")
+	assert.Contains(vtt, "\n00:01:39.000 --> 00:01:40.000\nsprite_0.jpg#xywh=2304,1296,256,144\n")
+	assert.True(strings.HasSuffix(vtt, "\n00:01:40.000 --> 00:01:41.000\nsprite_1.jpg#xywh=0,0,256,144\n"+
+		"\n00:01:41.000 --> 00:01:42.000\nsprite_1.jpg#xywh=256,0,256,144\n"))
+	assert.Equal(102, strings.Count(vtt, " --> "))
+
+	assert.Len(jpl.SpriteThumbnails([]string{"mani"}, "source", 0), 100)
+	assert.Equal([]string{"mani/node/thumbnails/100.jpg", "mani/node/thumbnails/101.jpg"},
+		jpl.SpriteThumbnails([]string{"mani"}, "source", 1))
+}
+
+func TestVTTTimestamp(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("00:00:00.000", vttTimestamp(0))
+	assert.Equal("01:02:03.004", vttTimestamp(3723004))
+}
@@ -429,6 +429,8 @@ func resToTranscodeData(ctx context.Context, res *ffmpeg.TranscodeResults, opts
 	}, nil
 }
 
+const fmp4MovFlags = "frag_keyframe+empty_moov+default_base_moof"
+
 func profilesToTranscodeOptions(workDir string, accel ffmpeg.Acceleration, profiles []ffmpeg.VideoProfile, calcPHash bool,
 	segPar *SegmentParameters) []ffmpeg.TranscodeOptions {
 
@@ -445,6 +447,15 @@ func profilesToTranscodeOptions(workDir string, accel ffmpeg.Acceleration, profi
 			o.From = segPar.Clip.From
 			o.To = segPar.Clip.To
 		}
+		if profiles[i].Format == common.FormatFMP4 {
+			// LPMS doesn't know about fragmented MP4, so the rendition is muxed as MP4 with the moov box up front
+			// and a fragment at every keyframe. The init segment is split out by the broadcaster
+			o.Profile.Format = ffmpeg.FormatMP4
+			o.Muxer = ffmpeg.ComponentOptions{
+				Name: "mp4",
+				Opts: map[string]string{"movflags": fmp4MovFlags},
+			}
+		}
 		opts[i] = o
 	}
 	return opts
@@ -219,6 +219,17 @@ func TestProfilesToTranscodeOptions(t *testing.T) {
 		assert.Equal(p, opts[i].Profile)
 		assert.Equal("copy", opts[i].AudioEncoder.Name)
 	}
+
+	// Test fragmented MP4
+	fmp4 := ffmpeg.P144p30fps16x9
+	fmp4.Format = common.FormatFMP4
+	profiles = []ffmpeg.VideoProfile{fmp4, ffmpeg.P240p30fps16x9}
+	opts = profilesToTranscodeOptions(workDir, ffmpeg.Software, profiles, false, nil)
+	assert.Equal(ffmpeg.FormatMP4, opts[0].Profile.Format)
+	assert.Equal("mp4", opts[0].Muxer.Name)
+	assert.Equal(fmp4MovFlags, opts[0].Muxer.Opts["movflags"])
+	assert.Equal(ffmpeg.P240p30fps16x9, opts[1].Profile)
+	assert.Empty(opts[1].Muxer.Name)
 }
 
 func TestAudioCopy(t *testing.T) {
@@ -0,0 +1,186 @@
+package discovery
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/net"
+)
+
+// Cached infos are dropped if they were not used by a discovery for orchInfoCacheIdleTimeout, so that orchestrators
+// that left the pool are not refreshed forever
+var orchInfoCacheIdleTimeout = 10 * time.Minute
+
+// An info is refreshed before the auth token expires by orchInfoCacheExpirationBuffer
+var orchInfoCacheExpirationBuffer = time.Minute
+
+// OrchInfoCache caches the OrchestratorInfo of the orchestrators for all orchestrator pools of the node.
+// Discovery fetches the OrchestratorInfo from the orchestrators on every call if it is nil
+var OrchInfoCache *OrchestratorInfoCache
+
+// OrchestratorInfoCache keeps a spare OrchestratorInfo of each orchestrator URI for at most ttl and refreshes the
+// infos in use in the background, so that creating sessions for a new stream does not need to wait for the
+// orchestrators. The ticket params and the auth token of an info belong to a single session, so a spare info is
+// handed out once and replaced in the background. An info is not used anymore once its auth token or its ticket
+// params expired
+type OrchestratorInfoCache struct {
+	mu       sync.Mutex
+	entries  map[string]*orchInfoEntry
+	inflight map[string]bool
+
+	ttl                   time.Duration
+	ticketParamsValidator ticketParamsValidator
+
+	now func() time.Time
+}
+
+type orchInfoEntry struct {
+	uri   *url.URL
+	bcast common.Broadcaster
+	// spare info, nil once it was handed out until it is replaced
+	info      *net.OrchestratorInfo
+	expiresAt time.Time
+	lastUsed  time.Time
+}
+
+// NewOrchestratorInfoCache returns an OrchestratorInfoCache keeping infos for at most ttl. The ticket params of cached
+// infos are validated with the validator if it is not nil
+func NewOrchestratorInfoCache(ttl time.Duration, validator ticketParamsValidator) *OrchestratorInfoCache {
+	return &OrchestratorInfoCache{
+		entries:               make(map[string]*orchInfoEntry),
+		inflight:              make(map[string]bool),
+		ttl:                   ttl,
+		ticketParamsValidator: validator,
+		now:                   time.Now,
+	}
+}
+
+// GetOrchestratorInfo returns the spare OrchestratorInfo of the orchestrator if it is still valid, otherwise it
+// fetches an info from the orchestrator. Every call returns a different info, the spare info is replaced in the
+// background once it was handed out
+func (c *OrchestratorInfoCache) GetOrchestratorInfo(ctx context.Context, bcast common.Broadcaster, uri *url.URL) (*net.OrchestratorInfo, error) {
+	if c == nil {
+		return serverGetOrchInfo(ctx, bcast, uri)
+	}
+
+	key := uri.String()
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		e.lastUsed = c.now()
+		if c.valid(e) {
+			info := e.info
+			e.info = nil
+			c.fetch(bcast, uri)
+			c.mu.Unlock()
+			return info, nil
+		}
+	}
+	c.mu.Unlock()
+
+	info, err := serverGetOrchInfo(ctx, bcast, uri)
+	if err != nil {
+		return nil, err
+	}
+	// Prepare a spare info for the next session
+	c.mu.Lock()
+	c.fetch(bcast, uri)
+	c.mu.Unlock()
+	return info, nil
+}
+
+// Run refreshes the cached infos every half ttl until the context is done
+func (c *OrchestratorInfoCache) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.ttl / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.refresh()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// refresh drops the idle infos and fetches the infos that expire before the next refresh
+func (c *OrchestratorInfoCache) refresh() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for key, e := range c.entries {
+		if now.Sub(e.lastUsed) > orchInfoCacheIdleTimeout {
+			delete(c.entries, key)
+			continue
+		}
+		if !c.valid(e) || e.expiresAt.Before(now.Add(c.ttl/2)) {
+			c.fetch(e.bcast, e.uri)
+		}
+	}
+}
+
+// fetch replaces the spare info of the orchestrator in the background, unless it is already being replaced.
+// The caller needs to ensure c.mu is acquired before calling this
+func (c *OrchestratorInfoCache) fetch(bcast common.Broadcaster, uri *url.URL) {
+	key := uri.String()
+	if c.inflight[key] {
+		return
+	}
+	c.inflight[key] = true
+
+	go func() {
+		// The fetch does not depend on the context of the caller that triggered it
+		ctx, cancel := context.WithTimeout(context.Background(), maxGetOrchestratorCutoffTimeout)
+		defer cancel()
+		info, err := serverGetOrchInfo(ctx, bcast, uri)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.inflight, key)
+		if err != nil {
+			// Keep a valid info until it expires, the orchestrator might be back by the next refresh
+			glog.V(common.DEBUG).Infof("Error refreshing orchestrator info orch=%v err=%q", uri, err)
+			return
+		}
+		lastUsed := c.now()
+		if e, ok := c.entries[key]; ok {
+			lastUsed = e.lastUsed
+		}
+		c.entries[key] = &orchInfoEntry{
+			uri:       uri,
+			bcast:     bcast,
+			info:      info,
+			expiresAt: c.expiration(info),
+			lastUsed:  lastUsed,
+		}
+	}()
+}
+
+// expiration returns when the info needs to be fetched again, which is after ttl or shortly before its auth token expires
+func (c *OrchestratorInfoCache) expiration(info *net.OrchestratorInfo) time.Time {
+	expiresAt := c.now().Add(c.ttl)
+	if token := info.GetAuthToken(); token != nil && token.Expiration > 0 {
+		tokenExpiresAt := time.Unix(token.Expiration, 0).Add(-orchInfoCacheExpirationBuffer)
+		if tokenExpiresAt.Before(expiresAt) {
+			expiresAt = tokenExpiresAt
+		}
+	}
+	return expiresAt
+}
+
+// the caller needs to ensure c.mu is acquired before calling this
+func (c *OrchestratorInfoCache) valid(e *orchInfoEntry) bool {
+	if e.info == nil || !c.now().Before(e.expiresAt) {
+		return false
+	}
+	if c.ticketParamsValidator != nil && e.info.TicketParams != nil {
+		if err := c.ticketParamsValidator.ValidateTicketParams(pmTicketParams(e.info.TicketParams)); err != nil {
+			return false
+		}
+	}
+	return true
+}
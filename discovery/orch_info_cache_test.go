@@ -0,0 +1,242 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/net"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stubOrchInfoFetcher(err error) (func() int, func()) {
+	var mu sync.Mutex
+	calls := 0
+	oldOrchInfo := serverGetOrchInfo
+	serverGetOrchInfo = func(ctx context.Context, bcast common.Broadcaster, uri *url.URL) (*net.OrchestratorInfo, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if err != nil {
+			return nil, err
+		}
+		return &net.OrchestratorInfo{Transcoder: uri.String(), TicketParams: &net.TicketParams{}}, nil
+	}
+	return func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return calls
+		}, func() {
+			serverGetOrchInfo = oldOrchInfo
+		}
+}
+
+func waitForOrchInfoFetches(t *testing.T, c *OrchestratorInfoCache) {
+	assert.Eventually(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return len(c.inflight) == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestOrchestratorInfoCache_Get(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	calls, restore := stubOrchInfoFetcher(nil)
+	defer restore()
+
+	uri, _ := url.Parse("https://127.0.0.1:8936")
+	validator := &stubTicketParamsValidator{}
+	c := NewOrchestratorInfoCache(time.Minute, validator)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	// A spare info is fetched in the background for the next session
+	info, err := c.GetOrchestratorInfo(context.TODO(), nil, uri)
+	require.Nil(err)
+	assert.Equal(uri.String(), info.Transcoder)
+	waitForOrchInfoFetches(t, c)
+	assert.Equal(2, calls())
+
+	// The spare info is used once and replaced
+	spare, err := c.GetOrchestratorInfo(context.TODO(), nil, uri)
+	require.Nil(err)
+	assert.NotSame(info, spare)
+	assert.Equal(uri.String(), spare.Transcoder)
+	waitForOrchInfoFetches(t, c)
+	assert.Equal(3, calls())
+	c.mu.Lock()
+	assert.NotNil(c.entries[uri.String()].info)
+	assert.NotSame(spare, c.entries[uri.String()].info)
+	c.mu.Unlock()
+
+	// Info is fetched again once the ticket params expired
+	validator.err = errors.New("ticket params expired")
+	_, err = c.GetOrchestratorInfo(context.TODO(), nil, uri)
+	require.Nil(err)
+	waitForOrchInfoFetches(t, c)
+	assert.Equal(5, calls())
+	validator.err = nil
+
+	// Info is fetched again after the ttl
+	now = now.Add(time.Minute)
+	_, err = c.GetOrchestratorInfo(context.TODO(), nil, uri)
+	require.Nil(err)
+	waitForOrchInfoFetches(t, c)
+	assert.Equal(7, calls())
+
+	// A nil cache always fetches the info
+	var nilCache *OrchestratorInfoCache
+	_, err = nilCache.GetOrchestratorInfo(context.TODO(), nil, uri)
+	require.Nil(err)
+	assert.Equal(8, calls())
+}
+
+func TestOrchestratorInfoCache_AuthTokenExpiration(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewOrchestratorInfoCache(time.Hour, nil)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	expiration := now.Add(10 * time.Minute)
+	info := &net.OrchestratorInfo{AuthToken: &net.AuthToken{Expiration: expiration.Unix()}}
+	assert.Equal(time.Unix(expiration.Unix(), 0).Add(-orchInfoCacheExpirationBuffer), c.expiration(info))
+
+	assert.Equal(now.Add(time.Hour), c.expiration(&net.OrchestratorInfo{}))
+}
+
+func TestOrchestratorInfoCache_NotShared(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	_, restore := stubOrchInfoFetcher(nil)
+	defer restore()
+
+	uri, _ := url.Parse("https://127.0.0.1:8936")
+	c := NewOrchestratorInfoCache(time.Minute, nil)
+	first, err := c.GetOrchestratorInfo(context.TODO(), nil, uri)
+	require.Nil(err)
+	waitForOrchInfoFetches(t, c)
+
+	// Concurrent sessions never share the ticket params and auth token of an info
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		infos = map[*net.OrchestratorInfo]bool{first: true}
+	)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			info, err := c.GetOrchestratorInfo(context.TODO(), nil, uri)
+			assert.Nil(err)
+			mu.Lock()
+			infos[info] = true
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	assert.Len(infos, 6)
+	waitForOrchInfoFetches(t, c)
+}
+
+func TestOrchestratorInfoCache_Refresh(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	calls, restore := stubOrchInfoFetcher(nil)
+	defer restore()
+
+	uris := stringsToURIs([]string{"https://127.0.0.1:8936", "https://127.0.0.1:8937"})
+	c := NewOrchestratorInfoCache(time.Minute, nil)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	for _, uri := range uris {
+		_, err := c.GetOrchestratorInfo(context.TODO(), nil, uri)
+		require.Nil(err)
+	}
+	waitForOrchInfoFetches(t, c)
+	assert.Equal(4, calls())
+
+	// Fresh infos are not refreshed
+	c.refresh()
+	assert.Equal(4, calls())
+
+	// Infos expiring before the next refresh are refreshed in the background
+	now = now.Add(40 * time.Second)
+	c.refresh()
+	waitForOrchInfoFetches(t, c)
+	assert.Equal(6, calls())
+	_, err := c.GetOrchestratorInfo(context.TODO(), nil, uris[0])
+	require.Nil(err)
+	waitForOrchInfoFetches(t, c)
+	assert.Equal(7, calls())
+
+	// Idle infos are dropped
+	now = now.Add(orchInfoCacheIdleTimeout - time.Second)
+	c.refresh()
+	waitForOrchInfoFetches(t, c)
+	assert.Equal(8, calls())
+	c.mu.Lock()
+	assert.Len(c.entries, 1)
+	assert.Contains(c.entries, uris[0].String())
+	c.mu.Unlock()
+}
+
+func TestOrchestratorInfoCache_FetchError(t *testing.T) {
+	assert := assert.New(t)
+
+	calls, restore := stubOrchInfoFetcher(errors.New("some error"))
+	defer restore()
+
+	uri, _ := url.Parse("https://127.0.0.1:8936")
+	c := NewOrchestratorInfoCache(time.Minute, nil)
+
+	// Errors are not cached
+	_, err := c.GetOrchestratorInfo(context.TODO(), nil, uri)
+	assert.EqualError(err, "some error")
+	_, err = c.GetOrchestratorInfo(context.TODO(), nil, uri)
+	assert.EqualError(err, "some error")
+	assert.Equal(2, calls())
+	assert.Empty(c.entries)
+}
+
+func TestOrchestratorPool_OrchInfoCache(t *testing.T) {
+	assert := assert.New(t)
+
+	calls, restore := stubOrchInfoFetcher(nil)
+	defer restore()
+
+	oldCache := OrchInfoCache
+	defer func() { OrchInfoCache = oldCache }()
+	OrchInfoCache = NewOrchestratorInfoCache(time.Minute, nil)
+
+	addresses := stringsToURIs([]string{"https://127.0.0.1:8936", "https://127.0.0.1:8937", "https://127.0.0.1:8938"})
+	pool := NewOrchestratorPool(nil, addresses, common.Score_Trusted, []string{})
+
+	ods, err := pool.GetOrchestrators(context.TODO(), len(addresses), newStubSuspender(), newStubCapabilities(), common.ScoreAtLeast(0))
+	assert.Nil(err)
+	assert.Len(ods, 3)
+	waitForOrchInfoFetches(t, OrchInfoCache)
+	assert.Equal(6, calls())
+
+	// Discovery for the next stream uses the spare infos, which are not shared with the previous stream
+	next, err := pool.GetOrchestrators(context.TODO(), len(addresses), newStubSuspender(), newStubCapabilities(), common.ScoreAtLeast(0))
+	assert.Nil(err)
+	assert.Len(next, 3)
+	for _, od := range ods {
+		for _, nextOd := range next {
+			assert.NotSame(od.RemoteInfo, nextOd.RemoteInfo)
+		}
+	}
+	waitForOrchInfoFetches(t, OrchInfoCache)
+	assert.Equal(9, calls())
+}
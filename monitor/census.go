@@ -154,6 +154,26 @@ type (
 		mRecordingSaveErrors          *stats.Int64Measure
 		mRecordingSavedSegments       *stats.Int64Measure
 		mOrchestratorSwaps            *stats.Int64Measure
+		mSegmentHedged                *stats.Int64Measure
+		mSegmentHedgeWon              *stats.Int64Measure
+		mSegmentRetryQueueSize        *stats.Int64Measure
+		mSegmentRetrySucceeded        *stats.Int64Measure
+		mSegmentRetryFailed           *stats.Int64Measure
+		mSRTConnections               *stats.Int64Measure
+		mSRTConnectionsRejected       *stats.Int64Measure
+		mSRTPacketsReceived           *stats.Int64Measure
+		mSRTPacketsLost               *stats.Int64Measure
+		mSRTPacketsRetransmitted      *stats.Int64Measure
+		mSRTPacketsDropped            *stats.Int64Measure
+		mSRTRTT                       *stats.Float64Measure
+		mRestreamTargets              *stats.Int64Measure
+		mRestreamBytesSent            *stats.Int64Measure
+		mRestreamReconnects           *stats.Int64Measure
+		mRestreamErrors               *stats.Int64Measure
+		mAdmissionQueueDepth          *stats.Int64Measure
+		mAdmissionWaitTime            *stats.Float64Measure
+		mAdmissionTimeouts            *stats.Int64Measure
+		mSessionsPreempted            *stats.Int64Measure
 
 		// Metrics for sending payments
 		mTicketValueSent    *stats.Float64Measure
@@ -301,6 +321,26 @@ func InitCensus(nodeType NodeType, version string) {
 	census.mRecordingSaveErrors = stats.Int64("recording_save_errors", "Number of errors during save to the recording OS", "tot")
 	census.mRecordingSavedSegments = stats.Int64("recording_saved_segments", "Number of segments saved to the recording OS", "tot")
 	census.mOrchestratorSwaps = stats.Int64("orchestrator_swaps", "Number of orchestrator swaps mid-stream", "tot")
+	census.mSegmentHedged = stats.Int64("segment_hedged_total", "Number of segments submitted to a backup orchestrator because the primary was slow", "tot")
+	census.mSegmentHedgeWon = stats.Int64("segment_hedge_won_total", "Number of hedged segments for which the backup orchestrator returned first", "tot")
+	census.mSegmentRetryQueueSize = stats.Int64("segment_retry_queue_size", "Number of failed segments waiting to be retried", "tot")
+	census.mSegmentRetrySucceeded = stats.Int64("segment_retry_succeeded_total", "Number of failed segments transcoded by a retry", "tot")
+	census.mSegmentRetryFailed = stats.Int64("segment_retry_failed_total", "Number of failed segments that could not be transcoded by a retry", "tot")
+	census.mSRTConnections = stats.Int64("srt_connections", "Number of SRT ingest connections", "tot")
+	census.mSRTConnectionsRejected = stats.Int64("srt_connections_rejected_total", "Number of rejected SRT ingest connections", "tot")
+	census.mSRTPacketsReceived = stats.Int64("srt_packets_received_total", "Number of packets received on SRT ingest connections", "tot")
+	census.mSRTPacketsLost = stats.Int64("srt_packets_lost_total", "Number of packets lost on SRT ingest connections", "tot")
+	census.mSRTPacketsRetransmitted = stats.Int64("srt_packets_retransmitted_total", "Number of retransmitted packets received on SRT ingest connections", "tot")
+	census.mSRTPacketsDropped = stats.Int64("srt_packets_dropped_total", "Number of packets of SRT ingest connections that were not recovered in time", "tot")
+	census.mSRTRTT = stats.Float64("srt_rtt_ms", "Round trip time of SRT ingest connections", "ms")
+	census.mRestreamTargets = stats.Int64("restream_targets", "Number of RTMP destinations that streams are pushed to", "tot")
+	census.mRestreamBytesSent = stats.Int64("restream_bytes_sent_total", "Number of bytes pushed to RTMP destinations", "byte")
+	census.mRestreamReconnects = stats.Int64("restream_reconnects_total", "Number of reconnections to RTMP destinations", "tot")
+	census.mRestreamErrors = stats.Int64("restream_errors_total", "Number of errors pushing to RTMP destinations", "tot")
+	census.mAdmissionQueueDepth = stats.Int64("admission_queue_depth", "Number of new sessions waiting for a session slot", "tot")
+	census.mAdmissionWaitTime = stats.Float64("admission_wait_time_seconds", "Time new sessions waited for a session slot", "sec")
+	census.mAdmissionTimeouts = stats.Int64("admission_timeouts_total", "Number of new sessions rejected after waiting for a session slot", "tot")
+	census.mSessionsPreempted = stats.Int64("sessions_preempted_total", "Number of best-effort sessions ended for the sessions of priority senders", "tot")
 
 	// Metrics for sending payments
 	census.mTicketValueSent = stats.Float64("ticket_value_sent", "TicketValueSent", "gwei")
@@ -667,6 +707,146 @@ func InitCensus(nodeType NodeType, version string) {
 			TagKeys:     baseTagsWithManifestID,
 			Aggregation: view.Count(),
 		},
+		{
+			Name:        "segment_hedged_total",
+			Measure:     census.mSegmentHedged,
+			Description: "Number of segments submitted to a backup orchestrator because the primary was slow",
+			TagKeys:     baseTagsWithManifestID,
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        "segment_hedge_won_total",
+			Measure:     census.mSegmentHedgeWon,
+			Description: "Number of hedged segments for which the backup orchestrator returned first",
+			TagKeys:     baseTagsWithManifestID,
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        "segment_retry_queue_size",
+			Measure:     census.mSegmentRetryQueueSize,
+			Description: "Number of failed segments waiting to be retried",
+			TagKeys:     baseTags,
+			Aggregation: view.LastValue(),
+		},
+		{
+			Name:        "segment_retry_succeeded_total",
+			Measure:     census.mSegmentRetrySucceeded,
+			Description: "Number of failed segments transcoded by a retry",
+			TagKeys:     baseTagsWithManifestID,
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        "segment_retry_failed_total",
+			Measure:     census.mSegmentRetryFailed,
+			Description: "Number of failed segments that could not be transcoded by a retry",
+			TagKeys:     baseTagsWithManifestID,
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        "srt_connections",
+			Measure:     census.mSRTConnections,
+			Description: "Number of SRT ingest connections",
+			TagKeys:     baseTags,
+			Aggregation: view.LastValue(),
+		},
+		{
+			Name:        "srt_connections_rejected_total",
+			Measure:     census.mSRTConnectionsRejected,
+			Description: "Number of rejected SRT ingest connections",
+			TagKeys:     baseTags,
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        "srt_packets_received_total",
+			Measure:     census.mSRTPacketsReceived,
+			Description: "Number of packets received on SRT ingest connections",
+			TagKeys:     baseTagsWithManifestID,
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        "srt_packets_lost_total",
+			Measure:     census.mSRTPacketsLost,
+			Description: "Number of packets lost on SRT ingest connections",
+			TagKeys:     baseTagsWithManifestID,
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        "srt_packets_retransmitted_total",
+			Measure:     census.mSRTPacketsRetransmitted,
+			Description: "Number of retransmitted packets received on SRT ingest connections",
+			TagKeys:     baseTagsWithManifestID,
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        "srt_packets_dropped_total",
+			Measure:     census.mSRTPacketsDropped,
+			Description: "Number of packets of SRT ingest connections that were not recovered in time",
+			TagKeys:     baseTagsWithManifestID,
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        "srt_rtt_ms",
+			Measure:     census.mSRTRTT,
+			Description: "Round trip time of SRT ingest connections",
+			TagKeys:     baseTagsWithManifestID,
+			Aggregation: view.LastValue(),
+		},
+		{
+			Name:        "restream_targets",
+			Measure:     census.mRestreamTargets,
+			Description: "Number of RTMP destinations that streams are pushed to",
+			TagKeys:     baseTags,
+			Aggregation: view.LastValue(),
+		},
+		{
+			Name:        "restream_bytes_sent_total",
+			Measure:     census.mRestreamBytesSent,
+			Description: "Number of bytes pushed to RTMP destinations",
+			TagKeys:     baseTagsWithManifestID,
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        "restream_reconnects_total",
+			Measure:     census.mRestreamReconnects,
+			Description: "Number of reconnections to RTMP destinations",
+			TagKeys:     baseTagsWithManifestID,
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        "restream_errors_total",
+			Measure:     census.mRestreamErrors,
+			Description: "Number of errors pushing to RTMP destinations",
+			TagKeys:     baseTagsWithManifestID,
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        "admission_queue_depth",
+			Measure:     census.mAdmissionQueueDepth,
+			Description: "Number of new sessions waiting for a session slot",
+			TagKeys:     baseTags,
+			Aggregation: view.LastValue(),
+		},
+		{
+			Name:        "admission_wait_time_seconds",
+			Measure:     census.mAdmissionWaitTime,
+			Description: "Time new sessions waited for a session slot",
+			TagKeys:     baseTags,
+			Aggregation: view.Distribution(0, .100, .250, .500, .750, 1.000, 1.500, 2.000, 3.000, 4.000, 5.000, 10.000),
+		},
+		{
+			Name:        "admission_timeouts_total",
+			Measure:     census.mAdmissionTimeouts,
+			Description: "Number of new sessions rejected after waiting for a session slot",
+			TagKeys:     baseTags,
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        "sessions_preempted_total",
+			Measure:     census.mSessionsPreempted,
+			Description: "Number of best-effort sessions ended for the sessions of priority senders",
+			TagKeys:     baseTags,
+			Aggregation: view.Count(),
+		},
 
 		// Metrics for sending payments
 		{
@@ -1135,6 +1315,90 @@ func OrchestratorSwapped(ctx context.Context) {
 	}
 }
 
+func SegmentHedged(ctx context.Context) {
+	if err := stats.RecordWithTags(census.ctx, manifestIDTag(ctx), census.mSegmentHedged.M(1)); err != nil {
+		clog.Errorf(ctx, "Error recording metric err=%q", err)
+	}
+}
+
+func SegmentHedgeWon(ctx context.Context) {
+	if err := stats.RecordWithTags(census.ctx, manifestIDTag(ctx), census.mSegmentHedgeWon.M(1)); err != nil {
+		clog.Errorf(ctx, "Error recording metric err=%q", err)
+	}
+}
+
+func SegmentRetryQueueSize(size int) {
+	stats.Record(census.ctx, census.mSegmentRetryQueueSize.M(int64(size)))
+}
+
+func SegmentRetried(ctx context.Context, success bool) {
+	m := census.mSegmentRetryFailed
+	if success {
+		m = census.mSegmentRetrySucceeded
+	}
+	if err := stats.RecordWithTags(census.ctx, manifestIDTag(ctx), m.M(1)); err != nil {
+		clog.Errorf(ctx, "Error recording metric err=%q", err)
+	}
+}
+
+func SRTConnections(connections int) {
+	stats.Record(census.ctx, census.mSRTConnections.M(int64(connections)))
+}
+
+func SRTConnectionRejected() {
+	stats.Record(census.ctx, census.mSRTConnectionsRejected.M(1))
+}
+
+// SRTStats records the packets of an SRT ingest connection since the last call, and its current round trip time
+func SRTStats(ctx context.Context, received, lost, retransmitted, dropped uint64, rtt time.Duration) {
+	if err := stats.RecordWithTags(census.ctx, manifestIDTag(ctx),
+		census.mSRTPacketsReceived.M(int64(received)),
+		census.mSRTPacketsLost.M(int64(lost)),
+		census.mSRTPacketsRetransmitted.M(int64(retransmitted)),
+		census.mSRTPacketsDropped.M(int64(dropped)),
+		census.mSRTRTT.M(float64(rtt)/float64(time.Millisecond))); err != nil {
+		clog.Errorf(ctx, "Error recording metric err=%q", err)
+	}
+}
+
+func RestreamTargets(targets int) {
+	stats.Record(census.ctx, census.mRestreamTargets.M(int64(targets)))
+}
+
+func RestreamBytesSent(ctx context.Context, bytes int) {
+	if err := stats.RecordWithTags(census.ctx, manifestIDTag(ctx), census.mRestreamBytesSent.M(int64(bytes))); err != nil {
+		clog.Errorf(ctx, "Error recording metric err=%q", err)
+	}
+}
+
+func RestreamReconnected(ctx context.Context) {
+	if err := stats.RecordWithTags(census.ctx, manifestIDTag(ctx), census.mRestreamReconnects.M(1)); err != nil {
+		clog.Errorf(ctx, "Error recording metric err=%q", err)
+	}
+}
+
+func RestreamError(ctx context.Context) {
+	if err := stats.RecordWithTags(census.ctx, manifestIDTag(ctx), census.mRestreamErrors.M(1)); err != nil {
+		clog.Errorf(ctx, "Error recording metric err=%q", err)
+	}
+}
+
+func AdmissionQueueDepth(depth int) {
+	stats.Record(census.ctx, census.mAdmissionQueueDepth.M(int64(depth)))
+}
+
+func AdmissionWaited(wait time.Duration, admitted bool) {
+	if admitted {
+		stats.Record(census.ctx, census.mAdmissionWaitTime.M(wait.Seconds()))
+	} else {
+		stats.Record(census.ctx, census.mAdmissionTimeouts.M(1))
+	}
+}
+
+func SessionPreempted() {
+	stats.Record(census.ctx, census.mSessionsPreempted.M(1))
+}
+
 func CurrentSessions(currentSessions int) {
 	stats.Record(census.ctx, census.mCurrentSessions.M(int64(currentSessions)))
 }
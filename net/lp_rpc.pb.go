@@ -54,16 +54,19 @@ type VideoProfile_Format int32
 const (
 	VideoProfile_MPEGTS VideoProfile_Format = 0
 	VideoProfile_MP4    VideoProfile_Format = 1
+	VideoProfile_FMP4   VideoProfile_Format = 2
 )
 
 var VideoProfile_Format_name = map[int32]string{
 	0: "MPEGTS",
 	1: "MP4",
+	2: "FMP4",
 }
 
 var VideoProfile_Format_value = map[string]int32{
 	"MPEGTS": 0,
 	"MP4":    1,
+	"FMP4":   2,
 }
 
 func (x VideoProfile_Format) String() string {
@@ -645,6 +648,8 @@ type OrchestratorInfo struct {
 	Capabilities *Capabilities `protobuf:"bytes,5,opt,name=capabilities,proto3" json:"capabilities,omitempty"`
 	// Data for transcoding authentication
 	AuthToken *AuthToken `protobuf:"bytes,6,opt,name=auth_token,json=authToken,proto3" json:"auth_token,omitempty"`
+	// Number of new sessions waiting for a free session slot on the orchestrator
+	AdmissionQueueDepth uint32 `protobuf:"varint,7,opt,name=admission_queue_depth,json=admissionQueueDepth,proto3" json:"admission_queue_depth,omitempty"`
 	// Orchestrator returns info about own input object storage, if it wants it to be used.
 	Storage              []*OSInfo `protobuf:"bytes,32,rep,name=storage,proto3" json:"storage,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
@@ -719,6 +724,13 @@ func (m *OrchestratorInfo) GetAuthToken() *AuthToken {
 	return nil
 }
 
+func (m *OrchestratorInfo) GetAdmissionQueueDepth() uint32 {
+	if m != nil {
+		return m.AdmissionQueueDepth
+	}
+	return 0
+}
+
 func (m *OrchestratorInfo) GetStorage() []*OSInfo {
 	if m != nil {
 		return m.Storage
@@ -1805,125 +1817,127 @@ func init() {
 }
 
 var fileDescriptor_034e29c79f9ba827 = []byte{
-	// 1911 bytes of a gzipped FileDescriptorProto
+	// 1940 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x58, 0xef, 0x6e, 0x1b, 0xc7,
-	0x11, 0x17, 0xff, 0x88, 0x7f, 0x86, 0xa4, 0x74, 0x5c, 0x4b, 0xca, 0x49, 0xb1, 0x53, 0xf9, 0x12,
-	0x07, 0xca, 0x07, 0x2b, 0x06, 0x25, 0xbb, 0x71, 0x81, 0xa2, 0xa5, 0x24, 0x5a, 0x62, 0x60, 0x49,
-	0xc4, 0x52, 0x36, 0xd0, 0x7e, 0x28, 0x7b, 0xba, 0x5b, 0x92, 0x57, 0x91, 0x7b, 0xe7, 0xbd, 0x65,
-	0x6c, 0x05, 0x7d, 0x81, 0x3e, 0x42, 0xfb, 0xa5, 0x40, 0x81, 0xbe, 0x47, 0x1f, 0xa0, 0x0f, 0x50,
-	0xf4, 0x39, 0xfa, 0x00, 0xc5, 0xce, 0xee, 0x1d, 0x8f, 0xa2, 0x92, 0x18, 0xf9, 0xb6, 0xf3, 0x9b,
-	0xd9, 0xd9, 0xd9, 0x99, 0x9d, 0x3f, 0x77, 0x60, 0x71, 0x26, 0xbf, 0x9e, 0x44, 0x03, 0x11, 0x79,
-	0xfb, 0x91, 0x08, 0x65, 0x48, 0x0a, 0x9c, 0x49, 0x67, 0x17, 0x2a, 0xbd, 0x80, 0x8f, 0x7a, 0x21,
-	0x1f, 0x91, 0x0d, 0x58, 0xfd, 0xce, 0x9d, 0xcc, 0x98, 0x9d, 0xdb, 0xcd, 0xed, 0xd5, 0xa9, 0x26,
-	0x9c, 0x73, 0x78, 0xd8, 0xe1, 0xfe, 0x95, 0x70, 0x79, 0xec, 0x85, 0x7e, 0xc0, 0x47, 0x7d, 0x16,
-	0xc7, 0x41, 0xc8, 0x29, 0x7b, 0x37, 0x63, 0xb1, 0x24, 0x4f, 0x01, 0xdc, 0x99, 0x1c, 0x0f, 0x64,
-	0x78, 0xc3, 0x38, 0x6e, 0xad, 0xb5, 0xd6, 0xf6, 0x39, 0x93, 0xfb, 0xed, 0x99, 0x1c, 0x5f, 0x29,
-	0x94, 0x56, 0xdd, 0x64, 0xe9, 0xfc, 0x02, 0x1e, 0xfd, 0x80, 0xba, 0x38, 0x0a, 0x79, 0xcc, 0x9c,
-	0x36, 0x3c, 0xb8, 0x14, 0xde, 0x98, 0xc5, 0x52, 0xb8, 0x32, 0x14, 0xc9, 0x31, 0x36, 0x94, 0x5d,
-	0xdf, 0x17, 0x2c, 0x8e, 0x8d, 0x79, 0x09, 0x49, 0x2c, 0x28, 0xc4, 0xc1, 0xc8, 0xce, 0x23, 0xaa,
-	0x96, 0xce, 0x5f, 0x73, 0x50, 0xba, 0xec, 0x77, 0xf9, 0x30, 0x24, 0x2f, 0xa1, 0x16, 0xcb, 0x50,
-	0xb8, 0x23, 0x76, 0x75, 0x1b, 0xe9, 0x9b, 0xad, 0xb5, 0x3e, 0x41, 0xf3, 0xb4, 0xc4, 0x7e, 0x7f,
-	0xce, 0xa6, 0x59, 0x59, 0xf2, 0x04, 0x4a, 0xf1, 0x41, 0xc0, 0x87, 0xa1, 0x6d, 0xe1, 0xa5, 0x1a,
-	0xb8, 0xab, 0x7f, 0xa0, 0xf7, 0x51, 0xc3, 0x74, 0x9e, 0x42, 0x2d, 0xa3, 0x82, 0x00, 0x94, 0x4e,
-	0xba, 0xb4, 0x73, 0x7c, 0x65, 0xad, 0x90, 0x12, 0xe4, 0xfb, 0x07, 0x56, 0x4e, 0x61, 0xa7, 0x97,
-	0x97, 0xa7, 0xaf, 0x3b, 0x56, 0xde, 0xf9, 0x47, 0x0e, 0x2a, 0x89, 0x0e, 0x42, 0xa0, 0x38, 0x0e,
-	0x63, 0x89, 0x66, 0x55, 0x29, 0xae, 0xd5, 0x75, 0x6e, 0xd8, 0x2d, 0x5e, 0xa7, 0x4a, 0xd5, 0x92,
-	0x6c, 0x41, 0x29, 0x0a, 0x27, 0x81, 0x77, 0x6b, 0x17, 0x10, 0x34, 0x14, 0x79, 0x08, 0xd5, 0x38,
-	0x18, 0x71, 0x57, 0xce, 0x04, 0xb3, 0x8b, 0xc8, 0x9a, 0x03, 0xe4, 0x33, 0x00, 0x4f, 0x30, 0x9f,
-	0x71, 0x19, 0xb8, 0x13, 0x7b, 0x15, 0xd9, 0x19, 0x84, 0xec, 0x40, 0xe5, 0x43, 0x7b, 0xfa, 0xfd,
-	0x89, 0x2b, 0x99, 0x5d, 0x42, 0x6e, 0x4a, 0x3b, 0x6f, 0xa0, 0xda, 0x13, 0x81, 0xc7, 0xd0, 0x48,
-	0x07, 0xea, 0x91, 0x22, 0x7a, 0x4c, 0xbc, 0xe1, 0x81, 0x36, 0xb6, 0x40, 0x17, 0x30, 0xf2, 0x05,
-	0x34, 0xa2, 0xe0, 0x03, 0x9b, 0xc4, 0x89, 0x50, 0x1e, 0x85, 0x16, 0x41, 0xe7, 0xbf, 0x79, 0xa8,
-	0x1f, 0xbb, 0x91, 0x7b, 0x1d, 0x4c, 0x02, 0x19, 0xb0, 0x58, 0xdd, 0xe0, 0x3a, 0x90, 0xb1, 0x14,
-	0x01, 0x1f, 0xd9, 0xb9, 0xdd, 0xc2, 0x5e, 0x91, 0xce, 0x01, 0xb2, 0x0b, 0xb5, 0xa9, 0xcb, 0x7d,
-	0xf5, 0x0a, 0x02, 0x16, 0xdb, 0x79, 0xe4, 0x67, 0x21, 0xd2, 0x06, 0xf0, 0xdc, 0xc8, 0xf5, 0x50,
-	0x9b, 0x5d, 0xd8, 0x2d, 0xec, 0xd5, 0x5a, 0x8f, 0x31, 0x4c, 0xd9, 0x63, 0x90, 0xd0, 0x32, 0x1d,
-	0x2e, 0xc5, 0x2d, 0xcd, 0x6c, 0x52, 0xef, 0xea, 0x3b, 0x26, 0xd4, 0x0b, 0x34, 0x2e, 0x4c, 0x48,
-	0xf2, 0x1b, 0xa8, 0x79, 0x21, 0x57, 0xcf, 0x30, 0xe0, 0x32, 0x46, 0x0f, 0xd6, 0x5a, 0x8f, 0xee,
-	0xd1, 0x3e, 0x17, 0xa2, 0xd9, 0x1d, 0x3b, 0xbf, 0x86, 0xf5, 0x3b, 0x27, 0x27, 0xc1, 0x55, 0x2e,
-	0x6c, 0xe8, 0xe0, 0xa6, 0x49, 0x97, 0x47, 0x4c, 0x13, 0xbf, 0xca, 0x7f, 0x93, 0xdb, 0x79, 0x0a,
-	0xb5, 0x8c, 0x6a, 0x15, 0xcf, 0x69, 0xc0, 0xdf, 0x1a, 0x5b, 0xf5, 0x8b, 0xc9, 0x20, 0xce, 0xbf,
-	0xf2, 0x60, 0x65, 0x13, 0x07, 0x63, 0xf7, 0x19, 0x80, 0x34, 0xa9, 0xc6, 0x44, 0xb2, 0x69, 0x8e,
-	0x90, 0x17, 0xd0, 0x90, 0x81, 0x77, 0xc3, 0xe4, 0x20, 0x72, 0x85, 0x3b, 0x8d, 0xd1, 0x8a, 0x5a,
-	0xab, 0x89, 0xb7, 0xbc, 0x42, 0x4e, 0x0f, 0x19, 0xb4, 0x2e, 0x33, 0x94, 0x4a, 0x7a, 0x8c, 0xff,
-	0x00, 0xf3, 0xa3, 0x90, 0x49, 0xfa, 0xf4, 0xdd, 0xd0, 0x6a, 0x94, 0x3e, 0xa1, 0x4c, 0xf2, 0x16,
-	0x17, 0x93, 0xf7, 0x39, 0xd4, 0xbd, 0x8c, 0x33, 0x8d, 0x97, 0x9b, 0x4b, 0x5e, 0xa6, 0x0b, 0x62,
-	0x77, 0x8a, 0x4e, 0xe9, 0x27, 0x8a, 0x0e, 0x79, 0x02, 0x65, 0x93, 0xd9, 0xf6, 0x2e, 0x3e, 0x92,
-	0x5a, 0xa6, 0x02, 0xd0, 0x84, 0xe7, 0xfc, 0x11, 0xaa, 0xe9, 0x76, 0x15, 0x98, 0x79, 0x49, 0xab,
-	0x53, 0x4d, 0x90, 0x47, 0x00, 0xb1, 0x2e, 0x58, 0x83, 0xc0, 0x37, 0x49, 0x5a, 0x35, 0x48, 0xd7,
-	0x57, 0xfe, 0x66, 0x1f, 0xa2, 0x40, 0xb8, 0x52, 0x05, 0xa9, 0x80, 0x49, 0x90, 0x41, 0x9c, 0xff,
-	0x15, 0xa1, 0xdc, 0x67, 0xa3, 0x13, 0x57, 0xba, 0x18, 0x50, 0x97, 0x07, 0x43, 0x16, 0xcb, 0xae,
-	0x6f, 0x4e, 0xc9, 0x20, 0x58, 0xd7, 0xd8, 0x3b, 0x93, 0x49, 0x6a, 0x89, 0xe5, 0xc2, 0x8d, 0xc7,
-	0xa8, 0xb7, 0x4e, 0x71, 0xad, 0xd2, 0x38, 0x12, 0xe1, 0x30, 0x98, 0xb0, 0xc4, 0xb7, 0x29, 0x9d,
-	0x54, 0xc6, 0xd5, 0xb4, 0x32, 0x2a, 0x69, 0x7f, 0x66, 0xac, 0x53, 0x5e, 0x5b, 0xa5, 0x29, 0xbd,
-	0x14, 0x8a, 0xf2, 0xcf, 0x09, 0x45, 0xe5, 0xa7, 0x42, 0xf1, 0x0c, 0x36, 0x3c, 0x77, 0xe2, 0x0d,
-	0x22, 0x26, 0x3c, 0x16, 0xc9, 0x99, 0x3b, 0x19, 0xe0, 0x9d, 0x60, 0x37, 0xb7, 0x57, 0xa1, 0x44,
-	0xf1, 0x7a, 0x29, 0xeb, 0x4c, 0xdd, 0xf0, 0xe3, 0x82, 0xa7, 0xcc, 0x1f, 0xce, 0x26, 0x93, 0x5e,
-	0xe2, 0x8c, 0xc7, 0x28, 0xab, 0xcd, 0x7f, 0x1b, 0xf8, 0x2c, 0x34, 0x1c, 0xba, 0x20, 0x46, 0x7e,
-	0x09, 0x8d, 0x2c, 0xdd, 0xb2, 0x9d, 0x1f, 0xda, 0xb7, 0x28, 0x77, 0x77, 0xe3, 0x81, 0xfd, 0xf9,
-	0x47, 0x6d, 0x3c, 0x20, 0x6d, 0x20, 0x31, 0x1b, 0x4d, 0x19, 0x37, 0x49, 0xc7, 0x24, 0x13, 0xb1,
-	0xfd, 0x04, 0x1d, 0x47, 0x74, 0x8f, 0x61, 0xa3, 0x5e, 0xca, 0xa1, 0x4d, 0x23, 0x3d, 0x87, 0xc8,
-	0x3e, 0x90, 0x57, 0xa1, 0xf0, 0x58, 0xda, 0x3b, 0x03, 0x55, 0x73, 0xbf, 0xd4, 0x2e, 0x5c, 0xe6,
-	0x38, 0x07, 0xd0, 0x58, 0xd0, 0xa9, 0x5e, 0xd2, 0x50, 0x84, 0x53, 0x7c, 0x75, 0x45, 0x8a, 0x6b,
-	0xb2, 0x06, 0x79, 0x19, 0xe2, 0x73, 0x2b, 0xd2, 0xbc, 0x0c, 0x9d, 0x7f, 0xaf, 0x42, 0x3d, 0x7b,
-	0x0f, 0xb5, 0x89, 0xbb, 0x53, 0x86, 0xed, 0xb0, 0x4a, 0x71, 0xad, 0xb2, 0xe4, 0x7d, 0xe0, 0xcb,
-	0xb1, 0xdd, 0xc4, 0xd7, 0xa4, 0x09, 0xd5, 0xb1, 0xc6, 0x2c, 0x18, 0x8d, 0xa5, 0x4d, 0x10, 0x36,
-	0x94, 0xaa, 0x03, 0xd7, 0x81, 0x2a, 0x4f, 0xcc, 0x7e, 0x80, 0x8c, 0x84, 0x54, 0x4f, 0x75, 0x18,
-	0xc5, 0xf6, 0x86, 0x2e, 0x8c, 0xc3, 0x28, 0x26, 0xcf, 0xa0, 0x34, 0x0c, 0xc5, 0xd4, 0x95, 0xf6,
-	0x26, 0x36, 0x6d, 0x7b, 0xc9, 0xb1, 0xfb, 0xaf, 0x90, 0x4f, 0x8d, 0x9c, 0x3a, 0x75, 0x18, 0xc5,
-	0x27, 0x8c, 0xdb, 0x5b, 0xa8, 0xc6, 0x50, 0xe4, 0x00, 0xca, 0x26, 0x25, 0xec, 0x4f, 0x50, 0xd5,
-	0xf6, 0xb2, 0xaa, 0x24, 0x56, 0x89, 0xa4, 0x32, 0x68, 0x14, 0x46, 0xb6, 0x8d, 0x66, 0xaa, 0x25,
-	0x79, 0x01, 0x65, 0xc6, 0x75, 0x21, 0xdd, 0x46, 0x35, 0x0f, 0x97, 0xd5, 0x20, 0x71, 0x1c, 0xfa,
-	0xcc, 0xa3, 0x89, 0x30, 0x36, 0xe2, 0x70, 0x12, 0x8a, 0x13, 0x16, 0xc9, 0xb1, 0xbd, 0x83, 0x0a,
-	0x33, 0x08, 0x39, 0x85, 0xba, 0x37, 0x16, 0xe1, 0xd4, 0xd5, 0xd7, 0xb1, 0x3f, 0x45, 0xe5, 0x9f,
-	0x2f, 0x2b, 0x3f, 0x46, 0xa9, 0xfe, 0xec, 0x3a, 0x76, 0xa7, 0xd1, 0x24, 0xe0, 0x23, 0xba, 0xb0,
-	0x51, 0x79, 0xf7, 0xdd, 0xcc, 0x9d, 0x04, 0xf2, 0xd6, 0x7e, 0x88, 0x0e, 0x48, 0x48, 0xe7, 0x11,
-	0x94, 0x8c, 0x0c, 0x40, 0xe9, 0xbc, 0xd7, 0x39, 0xbd, 0xea, 0x5b, 0x2b, 0xa4, 0x0c, 0x85, 0xf3,
-	0xde, 0xa1, 0x95, 0x73, 0xfe, 0x04, 0xe5, 0x24, 0xc6, 0x0f, 0x60, 0xbd, 0x73, 0x71, 0x7c, 0x79,
-	0xd2, 0xa1, 0x83, 0x93, 0xce, 0xab, 0xf6, 0x9b, 0xd7, 0x6a, 0x8e, 0x69, 0x42, 0xe3, 0xac, 0xf5,
-	0xe2, 0x70, 0x70, 0xd4, 0xee, 0x77, 0x5e, 0x77, 0x2f, 0x3a, 0x56, 0x8e, 0x34, 0xa0, 0x8a, 0xd0,
-	0x79, 0xbb, 0x7b, 0x61, 0xe5, 0x53, 0xf2, 0xac, 0x7b, 0x7a, 0x66, 0x15, 0xc8, 0x36, 0x6c, 0x22,
-	0x79, 0x7c, 0x79, 0xd1, 0xbf, 0xa2, 0xed, 0xee, 0x45, 0xe7, 0x44, 0xb3, 0x8a, 0x4e, 0x0b, 0x60,
-	0xee, 0x24, 0x52, 0x81, 0xa2, 0x12, 0xb4, 0x56, 0xcc, 0xea, 0xb9, 0x95, 0x53, 0x66, 0xbd, 0xed,
-	0x7d, 0x63, 0xe5, 0xf5, 0xe2, 0xa5, 0x55, 0x70, 0x8e, 0xa1, 0xb9, 0x74, 0x77, 0xb2, 0x06, 0x70,
-	0x7c, 0x46, 0x2f, 0xcf, 0xdb, 0x83, 0xc3, 0xd6, 0x33, 0x6b, 0x65, 0x81, 0x6e, 0x59, 0xb9, 0x2c,
-	0x7d, 0x78, 0x68, 0xe5, 0x9d, 0x77, 0xb0, 0x99, 0x4c, 0x9d, 0xcc, 0xef, 0xeb, 0x94, 0xc2, 0x3a,
-	0x6c, 0x41, 0x61, 0x26, 0x26, 0xa6, 0x39, 0xaa, 0x25, 0x0e, 0x5c, 0x38, 0xb8, 0x98, 0xe2, 0x6b,
-	0x28, 0xb2, 0x0f, 0x0f, 0xee, 0x94, 0xad, 0x81, 0xda, 0xa9, 0xa7, 0xb2, 0x66, 0xb4, 0x50, 0xb6,
-	0xde, 0x88, 0x89, 0xf3, 0x3b, 0x68, 0xa4, 0x47, 0xe2, 0x51, 0x2f, 0xa0, 0x62, 0x92, 0x39, 0xc6,
-	0x71, 0xa7, 0xd6, 0xda, 0xd1, 0x9d, 0xf6, 0x3e, 0xc3, 0x68, 0x2a, 0x7b, 0xcf, 0x88, 0xfb, 0xb7,
-	0x1c, 0xac, 0xa7, 0xbb, 0x28, 0x8b, 0x67, 0x13, 0x99, 0x34, 0x8c, 0xdc, 0xbc, 0x61, 0x6c, 0xc1,
-	0x2a, 0x13, 0x22, 0x14, 0xba, 0x51, 0x9d, 0xad, 0x50, 0x4d, 0x92, 0x3d, 0x28, 0xfa, 0xae, 0x74,
-	0x4d, 0xe3, 0x26, 0x8b, 0x36, 0xa8, 0xb3, 0xcf, 0x56, 0x28, 0x4a, 0x90, 0xaf, 0xa0, 0x98, 0x19,
-	0x81, 0x37, 0x75, 0xe5, 0xbd, 0x33, 0x65, 0x50, 0x14, 0x39, 0xaa, 0x40, 0x49, 0xa0, 0x21, 0xce,
-	0x9f, 0x61, 0x9d, 0xb2, 0x51, 0x10, 0x4b, 0x96, 0x8e, 0xef, 0x5b, 0x50, 0x8a, 0x99, 0x27, 0x58,
-	0x32, 0xeb, 0x1a, 0x4a, 0x35, 0x24, 0x33, 0x8c, 0xdd, 0x1a, 0x67, 0xa7, 0xf4, 0x52, 0x43, 0x2a,
-	0x7c, 0x54, 0x43, 0x72, 0xfe, 0x92, 0x83, 0xc6, 0x45, 0x28, 0x83, 0xe1, 0xad, 0x71, 0xe6, 0x3d,
-	0x11, 0xfe, 0x12, 0xca, 0xb1, 0x6e, 0xc3, 0x46, 0x6b, 0x3d, 0x29, 0xbc, 0xe8, 0xf9, 0x84, 0xa9,
-	0xcc, 0x96, 0x6e, 0x7c, 0xd3, 0xf5, 0xd1, 0x01, 0x05, 0x6a, 0xa8, 0x85, 0xae, 0xdb, 0x5c, 0xec,
-	0xba, 0xdf, 0x16, 0x2b, 0x79, 0xab, 0xf0, 0x6d, 0xb1, 0xf2, 0xd8, 0x72, 0x9c, 0xbf, 0xe7, 0xa1,
-	0x9e, 0x1d, 0xa3, 0xd4, 0xc4, 0x2b, 0x98, 0x17, 0x44, 0x01, 0xe3, 0xd2, 0xf4, 0xfc, 0x39, 0xa0,
-	0xa6, 0x8b, 0xa1, 0xeb, 0xb1, 0xc1, 0x7c, 0x22, 0xac, 0xd3, 0xaa, 0x42, 0xde, 0x2a, 0x80, 0x6c,
-	0x43, 0xe5, 0x7d, 0xc0, 0x07, 0x91, 0x08, 0xaf, 0xcd, 0x0c, 0x50, 0x7e, 0x1f, 0xf0, 0x9e, 0x08,
-	0xaf, 0xd5, 0xd3, 0x4c, 0xd5, 0x0c, 0x84, 0xcb, 0x7d, 0xdd, 0x55, 0xf5, 0x44, 0xd0, 0x4c, 0x59,
-	0xd4, 0xe5, 0x3e, 0x36, 0x55, 0x02, 0xc5, 0x98, 0x31, 0xdf, 0xcc, 0x06, 0xb8, 0x26, 0x5f, 0x81,
-	0x35, 0x1f, 0x55, 0x06, 0xd7, 0x93, 0xd0, 0xbb, 0xc1, 0x21, 0xa1, 0x4e, 0xd7, 0xe7, 0xf8, 0x91,
-	0x82, 0xc9, 0x19, 0x34, 0x33, 0xa2, 0x66, 0x76, 0xd4, 0x03, 0xc3, 0xa7, 0x99, 0xd9, 0xb1, 0x93,
-	0xca, 0x98, 0x29, 0x32, 0x73, 0x80, 0x46, 0x9c, 0x2e, 0x10, 0x2d, 0xdb, 0x67, 0xdc, 0x67, 0xc2,
-	0xb8, 0xe9, 0x31, 0xd4, 0x63, 0xa4, 0x07, 0x3c, 0xe4, 0x1e, 0x33, 0x03, 0x73, 0x4d, 0x63, 0x17,
-	0x0a, 0xba, 0x27, 0x27, 0xbe, 0x87, 0xad, 0xfb, 0x8f, 0x25, 0x4f, 0x60, 0xcd, 0x13, 0x4c, 0x1b,
-	0x2b, 0xc2, 0x19, 0xf7, 0x4d, 0x92, 0x34, 0x12, 0x94, 0x2a, 0x90, 0xbc, 0x84, 0xed, 0x45, 0x31,
-	0xed, 0x04, 0xed, 0x4a, 0x7d, 0xd0, 0xd6, 0xc2, 0x0e, 0x74, 0x86, 0xf2, 0xa7, 0xf3, 0xcf, 0x3c,
-	0x94, 0x7b, 0xee, 0x2d, 0x3e, 0xb7, 0xa5, 0xa1, 0x3a, 0xf7, 0x71, 0x43, 0x35, 0xe6, 0x88, 0xba,
-	0xa0, 0x39, 0xcb, 0x50, 0xf7, 0x3b, 0xbb, 0xf0, 0x33, 0x9c, 0x4d, 0xba, 0xb0, 0x61, 0x2c, 0x33,
-	0xde, 0x35, 0xca, 0x8a, 0x58, 0x8b, 0x3e, 0xc9, 0x28, 0xcb, 0x46, 0x83, 0x12, 0xb9, 0x1c, 0xa1,
-	0xe7, 0xb0, 0xc6, 0x3e, 0x44, 0xcc, 0x93, 0xcc, 0x1f, 0xe0, 0xa0, 0x6f, 0x46, 0xf7, 0xbb, 0x5f,
-	0x01, 0x8d, 0x44, 0x0a, 0xa1, 0xd6, 0x7f, 0x72, 0x50, 0xcf, 0xd6, 0x0f, 0x72, 0x04, 0xeb, 0xa7,
-	0x4c, 0x2e, 0x40, 0xf6, 0x52, 0x95, 0x31, 0x55, 0x64, 0xe7, 0xfe, 0xfa, 0x43, 0xfe, 0x00, 0x9b,
-	0xf7, 0xfe, 0x53, 0x20, 0xfa, 0x5b, 0xf0, 0xc7, 0x7e, 0x5f, 0xec, 0x38, 0x3f, 0x26, 0xa2, 0x7f,
-	0x49, 0x90, 0x2f, 0xa0, 0xd8, 0x53, 0x2d, 0x47, 0xff, 0x01, 0x48, 0xfe, 0x97, 0xec, 0x2c, 0x92,
-	0xad, 0x0b, 0x80, 0xab, 0xf9, 0x97, 0xd5, 0x6f, 0x81, 0x24, 0x35, 0x30, 0x83, 0x6e, 0xe0, 0x96,
-	0x3b, 0xc5, 0x71, 0x47, 0x17, 0xe0, 0x85, 0x9a, 0xf5, 0x2c, 0x77, 0x54, 0xfe, 0xfd, 0xea, 0xfe,
-	0xd7, 0x9c, 0xc9, 0xeb, 0x12, 0xfe, 0xaf, 0x39, 0xf8, 0x7f, 0x00, 0x00, 0x00, 0xff, 0xff, 0x8c,
-	0x55, 0x8b, 0x9a, 0xc3, 0x11, 0x00, 0x00,
+	0x11, 0x17, 0xff, 0x88, 0x7f, 0x86, 0xa4, 0x74, 0x5a, 0xfd, 0xf1, 0x49, 0xb1, 0x53, 0xf9, 0x12,
+	0xa7, 0xca, 0x07, 0x2b, 0x06, 0x65, 0xbb, 0x71, 0x81, 0xa2, 0xa5, 0x24, 0x5a, 0x62, 0x60, 0x49,
+	0xec, 0x52, 0x36, 0xd0, 0x7e, 0x28, 0x7b, 0xba, 0x5b, 0x92, 0x57, 0x91, 0x77, 0xa7, 0xdd, 0x65,
+	0x6c, 0x05, 0x7d, 0x81, 0x3e, 0x42, 0xfb, 0xa5, 0x40, 0x81, 0xbe, 0x52, 0x51, 0xf4, 0x39, 0xf2,
+	0x00, 0xc5, 0xce, 0xee, 0x1d, 0x8f, 0xa2, 0x92, 0x18, 0xf9, 0xc4, 0x9d, 0xdf, 0xcc, 0xce, 0xce,
+	0xce, 0xec, 0xfc, 0xe1, 0x81, 0x15, 0x32, 0xf9, 0xd5, 0x38, 0xee, 0xf3, 0xd8, 0xdb, 0x8f, 0x79,
+	0x24, 0x23, 0x52, 0x08, 0x99, 0x74, 0x76, 0xa1, 0xd2, 0x0d, 0xc2, 0x61, 0x37, 0x0a, 0x87, 0x64,
+	0x03, 0x96, 0xbf, 0x75, 0xc7, 0x53, 0x66, 0xe7, 0x76, 0x73, 0x7b, 0x75, 0xaa, 0x09, 0xe7, 0x0c,
+	0x1e, 0xb6, 0x43, 0xff, 0x92, 0xbb, 0xa1, 0xf0, 0x22, 0x3f, 0x08, 0x87, 0x3d, 0x26, 0x44, 0x10,
+	0x85, 0x94, 0xdd, 0x4c, 0x99, 0x90, 0xe4, 0x29, 0x80, 0x3b, 0x95, 0xa3, 0xbe, 0x8c, 0xae, 0x59,
+	0x88, 0x5b, 0x6b, 0xcd, 0x95, 0xfd, 0x90, 0xc9, 0xfd, 0xd6, 0x54, 0x8e, 0x2e, 0x15, 0x4a, 0xab,
+	0x6e, 0xb2, 0x74, 0x7e, 0x01, 0x8f, 0x7e, 0x40, 0x9d, 0x88, 0xa3, 0x50, 0x30, 0xa7, 0x05, 0xeb,
+	0x17, 0xdc, 0x1b, 0x31, 0x21, 0xb9, 0x2b, 0x23, 0x9e, 0x1c, 0x63, 0x43, 0xd9, 0xf5, 0x7d, 0xce,
+	0x84, 0x30, 0xe6, 0x25, 0x24, 0xb1, 0xa0, 0x20, 0x82, 0xa1, 0x9d, 0x47, 0x54, 0x2d, 0x9d, 0xbf,
+	0xe7, 0xa0, 0x74, 0xd1, 0xeb, 0x84, 0x83, 0x88, 0xbc, 0x82, 0x9a, 0x90, 0x11, 0x77, 0x87, 0xec,
+	0xf2, 0x36, 0xd6, 0x37, 0x5b, 0x69, 0x3e, 0x40, 0xf3, 0xb4, 0xc4, 0x7e, 0x6f, 0xc6, 0xa6, 0x59,
+	0x59, 0xf2, 0x04, 0x4a, 0xe2, 0x20, 0x08, 0x07, 0x91, 0x6d, 0xe1, 0xa5, 0x1a, 0xb8, 0xab, 0x77,
+	0xa0, 0xf7, 0x51, 0xc3, 0x74, 0x9e, 0x42, 0x2d, 0xa3, 0x82, 0x00, 0x94, 0x8e, 0x3b, 0xb4, 0x7d,
+	0x74, 0x69, 0x2d, 0x91, 0x12, 0xe4, 0x7b, 0x07, 0x56, 0x4e, 0x61, 0x27, 0x17, 0x17, 0x27, 0x6f,
+	0xda, 0x56, 0xde, 0xf9, 0x57, 0x0e, 0x2a, 0x89, 0x0e, 0x42, 0xa0, 0x38, 0x8a, 0x84, 0x44, 0xb3,
+	0xaa, 0x14, 0xd7, 0xea, 0x3a, 0xd7, 0xec, 0x16, 0xaf, 0x53, 0xa5, 0x6a, 0x49, 0xb6, 0xa0, 0x14,
+	0x47, 0xe3, 0xc0, 0xbb, 0xb5, 0x0b, 0x08, 0x1a, 0x8a, 0x3c, 0x84, 0xaa, 0x08, 0x86, 0xa1, 0x2b,
+	0xa7, 0x9c, 0xd9, 0x45, 0x64, 0xcd, 0x00, 0xf2, 0x29, 0x80, 0xc7, 0x99, 0xcf, 0x42, 0x19, 0xb8,
+	0x63, 0x7b, 0x19, 0xd9, 0x19, 0x84, 0xec, 0x40, 0xe5, 0x43, 0x6b, 0xf2, 0xdd, 0xb1, 0x2b, 0x99,
+	0x5d, 0x42, 0x6e, 0x4a, 0x3b, 0x6f, 0xa1, 0xda, 0xe5, 0x81, 0xc7, 0xd0, 0x48, 0x07, 0xea, 0xb1,
+	0x22, 0xba, 0x8c, 0xbf, 0x0d, 0x03, 0x6d, 0x6c, 0x81, 0xce, 0x61, 0xe4, 0x73, 0x68, 0xc4, 0xc1,
+	0x07, 0x36, 0x16, 0x89, 0x50, 0x1e, 0x85, 0xe6, 0x41, 0xe7, 0x7f, 0x79, 0xa8, 0x1f, 0xb9, 0xb1,
+	0x7b, 0x15, 0x8c, 0x03, 0x19, 0x30, 0xa1, 0x6e, 0x70, 0x15, 0x48, 0x21, 0x79, 0x10, 0x0e, 0xed,
+	0xdc, 0x6e, 0x61, 0xaf, 0x48, 0x67, 0x00, 0xd9, 0x85, 0xda, 0xc4, 0x0d, 0x7d, 0xf5, 0x0a, 0x02,
+	0x26, 0xec, 0x3c, 0xf2, 0xb3, 0x10, 0x69, 0x01, 0x78, 0x6e, 0xec, 0x7a, 0xa8, 0xcd, 0x2e, 0xec,
+	0x16, 0xf6, 0x6a, 0xcd, 0xc7, 0x18, 0xa6, 0xec, 0x31, 0xfb, 0x47, 0xa9, 0x4c, 0x3b, 0x94, 0xfc,
+	0x96, 0x66, 0x36, 0xa9, 0x77, 0xf5, 0x2d, 0xe3, 0xea, 0x05, 0x1a, 0x17, 0x26, 0x24, 0xf9, 0x2d,
+	0xd4, 0xbc, 0x28, 0x54, 0xcf, 0x30, 0x08, 0xa5, 0x40, 0x0f, 0xd6, 0x9a, 0x8f, 0xee, 0xd1, 0x3e,
+	0x13, 0xa2, 0xd9, 0x1d, 0x3b, 0xbf, 0x81, 0xd5, 0x3b, 0x27, 0x27, 0xc1, 0x55, 0x2e, 0x6c, 0xe8,
+	0xe0, 0xa6, 0x49, 0x97, 0x47, 0x4c, 0x13, 0xbf, 0xce, 0x7f, 0x9d, 0xdb, 0x79, 0x0a, 0xb5, 0x8c,
+	0x6a, 0x15, 0xcf, 0x49, 0x10, 0xbe, 0x33, 0xb6, 0xea, 0x17, 0x93, 0x41, 0x9c, 0xef, 0xf3, 0x60,
+	0x65, 0x13, 0x07, 0x63, 0xf7, 0x29, 0x80, 0x34, 0xa9, 0xc6, 0x78, 0xb2, 0x69, 0x86, 0x90, 0x97,
+	0xd0, 0x90, 0x81, 0x77, 0xcd, 0x64, 0x3f, 0x76, 0xb9, 0x3b, 0x11, 0x68, 0x45, 0xad, 0xb9, 0x86,
+	0xb7, 0xbc, 0x44, 0x4e, 0x17, 0x19, 0xb4, 0x2e, 0x33, 0x94, 0x4a, 0x7a, 0x8c, 0x7f, 0x1f, 0xf3,
+	0xa3, 0x90, 0x49, 0xfa, 0xf4, 0xdd, 0xd0, 0x6a, 0x9c, 0x2c, 0xb3, 0xc9, 0x5b, 0x9c, 0x4f, 0xde,
+	0x17, 0x50, 0xf7, 0x32, 0xce, 0xb4, 0x97, 0x33, 0xe7, 0x67, 0xbd, 0x4c, 0xe7, 0xc4, 0xee, 0x14,
+	0x9d, 0xd2, 0x4f, 0x14, 0x1d, 0xd2, 0x84, 0x4d, 0xd7, 0x9f, 0x04, 0x58, 0x68, 0xfa, 0x37, 0x53,
+	0x36, 0x65, 0x7d, 0x9f, 0xc5, 0x72, 0x64, 0x97, 0xd1, 0xe9, 0xeb, 0x29, 0xf3, 0xf7, 0x8a, 0x77,
+	0xac, 0x58, 0xe4, 0x09, 0x94, 0x4d, 0x35, 0xb0, 0x77, 0xf1, 0x61, 0xd5, 0x32, 0x55, 0x83, 0x26,
+	0x3c, 0xe7, 0xcf, 0x50, 0x4d, 0x8f, 0x54, 0xc1, 0x9c, 0x95, 0xc1, 0x3a, 0xd5, 0x04, 0x79, 0x04,
+	0x20, 0x74, 0x91, 0xeb, 0x07, 0xbe, 0x49, 0xec, 0xaa, 0x41, 0x3a, 0xbe, 0x8a, 0x11, 0xfb, 0x10,
+	0x07, 0xdc, 0x95, 0x2a, 0xb0, 0x05, 0x4c, 0x9c, 0x0c, 0xe2, 0x7c, 0x5f, 0x84, 0x72, 0x8f, 0x0d,
+	0x8f, 0x5d, 0xe9, 0xe2, 0x23, 0x70, 0xc3, 0x60, 0xc0, 0x84, 0xec, 0xf8, 0xe6, 0x94, 0x0c, 0x82,
+	0xb5, 0x90, 0xdd, 0x98, 0xec, 0x53, 0x4b, 0x2c, 0x31, 0xae, 0x18, 0xa1, 0xde, 0x3a, 0xc5, 0xb5,
+	0x4a, 0xfd, 0x98, 0x47, 0x83, 0x60, 0xcc, 0x92, 0x78, 0xa4, 0x74, 0x52, 0x4d, 0x97, 0xd3, 0x6a,
+	0xaa, 0xa4, 0xfd, 0xa9, 0xb1, 0x4e, 0x79, 0x7a, 0x99, 0xa6, 0xf4, 0x42, 0xf8, 0xca, 0x3f, 0x27,
+	0x7c, 0x95, 0x9f, 0x0a, 0xdf, 0x33, 0xd8, 0xf0, 0xdc, 0xb1, 0xd7, 0x8f, 0x19, 0xf7, 0x58, 0x2c,
+	0xa7, 0xee, 0xb8, 0x8f, 0x77, 0x82, 0xdd, 0xdc, 0x5e, 0x85, 0x12, 0xc5, 0xeb, 0xa6, 0xac, 0x53,
+	0x57, 0x7c, 0x6c, 0xf0, 0x94, 0xf9, 0x83, 0xe9, 0x78, 0xdc, 0x4d, 0x9c, 0xf1, 0x78, 0xb7, 0x90,
+	0x9a, 0xff, 0x2e, 0xf0, 0x59, 0x64, 0x38, 0x74, 0x4e, 0x8c, 0xfc, 0x0a, 0x1a, 0x59, 0xba, 0x69,
+	0x3b, 0x3f, 0xb4, 0x6f, 0x5e, 0xee, 0xee, 0xc6, 0x03, 0xfb, 0xb3, 0x8f, 0xda, 0x78, 0x40, 0x5a,
+	0x40, 0x04, 0x1b, 0x4e, 0x58, 0x68, 0x12, 0x95, 0x49, 0xc6, 0x85, 0xfd, 0x04, 0x1d, 0x47, 0x74,
+	0x5f, 0x62, 0xc3, 0x6e, 0xca, 0xa1, 0x6b, 0x46, 0x7a, 0x06, 0x91, 0x7d, 0x20, 0xaf, 0x23, 0xee,
+	0xb1, 0xb4, 0xdf, 0x06, 0xaa, 0x4e, 0x7f, 0xa1, 0x5d, 0xb8, 0xc8, 0x71, 0x0e, 0xa0, 0x31, 0xa7,
+	0x53, 0xbd, 0xa4, 0x01, 0x8f, 0x26, 0xf8, 0xea, 0x8a, 0x14, 0xd7, 0x64, 0x05, 0xf2, 0x32, 0xc2,
+	0xe7, 0x56, 0xa4, 0x79, 0x19, 0x39, 0xff, 0x59, 0x86, 0x7a, 0xf6, 0x1e, 0x6a, 0x53, 0xe8, 0x4e,
+	0x18, 0xb6, 0xd0, 0x2a, 0xc5, 0xb5, 0xca, 0x92, 0xf7, 0x81, 0x2f, 0x47, 0xf6, 0x1a, 0xbe, 0x26,
+	0x4d, 0xa8, 0x2e, 0x37, 0x62, 0xc1, 0x70, 0x24, 0x6d, 0x82, 0xb0, 0xa1, 0x54, 0xed, 0xb8, 0x0a,
+	0x24, 0x57, 0x6d, 0x6a, 0x1d, 0x19, 0x09, 0xa9, 0x9e, 0xea, 0x20, 0x16, 0xf6, 0x86, 0x2e, 0xa6,
+	0x83, 0x58, 0x90, 0x67, 0x50, 0x1a, 0x44, 0x7c, 0xe2, 0x4a, 0x7b, 0x13, 0x1b, 0xbd, 0xbd, 0xe0,
+	0xd8, 0xfd, 0xd7, 0xc8, 0xa7, 0x46, 0x4e, 0x9d, 0x3a, 0x88, 0xc5, 0x31, 0x0b, 0xed, 0x2d, 0x54,
+	0x63, 0x28, 0x72, 0x00, 0x65, 0x93, 0x12, 0xf6, 0x03, 0x54, 0xb5, 0xbd, 0xa8, 0xca, 0xfc, 0xd2,
+	0x44, 0x52, 0x19, 0x34, 0x8c, 0x62, 0xdb, 0x46, 0x33, 0xd5, 0x92, 0xbc, 0x84, 0x32, 0x0b, 0x75,
+	0xf1, 0xdd, 0x46, 0x35, 0x0f, 0x17, 0xd5, 0x20, 0x71, 0x14, 0xf9, 0xcc, 0xa3, 0x89, 0x30, 0x36,
+	0xef, 0x68, 0x1c, 0x71, 0x2c, 0x45, 0xf6, 0x0e, 0x2a, 0xcc, 0x20, 0xe4, 0x04, 0xea, 0xde, 0x88,
+	0x47, 0x13, 0x57, 0x5f, 0xc7, 0xfe, 0x04, 0x95, 0x7f, 0xb6, 0xa8, 0xfc, 0x08, 0xa5, 0x7a, 0xd3,
+	0x2b, 0xe1, 0x4e, 0xe2, 0x71, 0x10, 0x0e, 0xe9, 0xdc, 0x46, 0xe5, 0xdd, 0x9b, 0xa9, 0x3b, 0x0e,
+	0xe4, 0xad, 0xfd, 0x10, 0x1d, 0x90, 0x90, 0xce, 0x2f, 0xa1, 0x64, 0x64, 0x00, 0x4a, 0x67, 0xdd,
+	0xf6, 0xc9, 0x65, 0xcf, 0x5a, 0x22, 0x65, 0x28, 0x9c, 0x75, 0x9f, 0x5b, 0x39, 0x52, 0x81, 0xe2,
+	0x6b, 0xb5, 0xca, 0x3b, 0x7f, 0x81, 0x72, 0x12, 0xed, 0x75, 0x58, 0x6d, 0x9f, 0x1f, 0x5d, 0x1c,
+	0xb7, 0x69, 0xff, 0xb8, 0xfd, 0xba, 0xf5, 0xf6, 0x8d, 0x9a, 0x82, 0xd6, 0xa0, 0x71, 0xda, 0x7c,
+	0xf9, 0xbc, 0x7f, 0xd8, 0xea, 0xb5, 0xdf, 0x74, 0xce, 0xdb, 0x56, 0x8e, 0x34, 0xa0, 0x8a, 0xd0,
+	0x59, 0xab, 0x73, 0x6e, 0xe5, 0x53, 0xf2, 0xb4, 0x73, 0x72, 0x6a, 0x15, 0xc8, 0x36, 0x6c, 0x22,
+	0x79, 0x74, 0x71, 0xde, 0xbb, 0xa4, 0xad, 0xce, 0x79, 0xfb, 0x58, 0xb3, 0x8a, 0x4e, 0x13, 0x60,
+	0xe6, 0x2e, 0x65, 0x83, 0x12, 0xb4, 0x96, 0xcc, 0xea, 0x85, 0x95, 0x53, 0x06, 0xbe, 0xeb, 0x7e,
+	0x6d, 0xe5, 0xf5, 0xe2, 0x95, 0x55, 0x70, 0x8e, 0x60, 0x6d, 0xc1, 0x0b, 0x64, 0x05, 0xe0, 0xe8,
+	0x94, 0x5e, 0x9c, 0xb5, 0xfa, 0xcf, 0x9b, 0xcf, 0xac, 0xa5, 0x39, 0xba, 0x69, 0xe5, 0xb2, 0xf4,
+	0x73, 0x75, 0xc9, 0x1b, 0xd8, 0x4c, 0x66, 0x56, 0xe6, 0xf7, 0x74, 0x72, 0x61, 0x45, 0xb6, 0xa0,
+	0x30, 0xe5, 0x63, 0xd3, 0x5a, 0xd5, 0x12, 0xc7, 0x35, 0x1c, 0x7b, 0x4c, 0x19, 0x36, 0x14, 0xd9,
+	0x87, 0xf5, 0x3b, 0x05, 0xac, 0xaf, 0x76, 0xea, 0x99, 0x6e, 0x2d, 0x9e, 0x2b, 0x60, 0x6f, 0xf9,
+	0xd8, 0xf9, 0x03, 0x34, 0xd2, 0x23, 0xf1, 0xa8, 0x97, 0x50, 0x31, 0x69, 0x2d, 0x70, 0x58, 0xaa,
+	0x35, 0x77, 0x74, 0x9f, 0xbe, 0xcf, 0x30, 0x9a, 0xca, 0xde, 0x33, 0x20, 0xff, 0x23, 0x07, 0xab,
+	0xe9, 0x2e, 0xca, 0xc4, 0x74, 0x2c, 0x93, 0xd6, 0x91, 0x9b, 0xb5, 0x8e, 0x2d, 0x58, 0x66, 0x9c,
+	0x47, 0x5c, 0xb7, 0xac, 0xd3, 0x25, 0xaa, 0x49, 0xb2, 0x07, 0x45, 0xdf, 0x95, 0xae, 0x5d, 0xc8,
+	0x94, 0x9f, 0x39, 0x4b, 0x4f, 0x97, 0x28, 0x4a, 0x90, 0x2f, 0xa1, 0x98, 0x19, 0xa0, 0x37, 0x75,
+	0x0d, 0xbe, 0x33, 0xa3, 0x50, 0x14, 0x39, 0xac, 0x40, 0x89, 0xa3, 0x21, 0xce, 0x5f, 0x61, 0x95,
+	0xb2, 0x61, 0x20, 0x24, 0x4b, 0x87, 0xff, 0x2d, 0x28, 0x09, 0xe6, 0x71, 0x96, 0x4c, 0xca, 0x86,
+	0x52, 0xad, 0xc9, 0x8c, 0x72, 0xb7, 0xc6, 0xd9, 0x29, 0xbd, 0xd0, 0x9a, 0x0a, 0x1f, 0xd5, 0x9a,
+	0x9c, 0xbf, 0xe5, 0xa0, 0x71, 0x1e, 0xc9, 0x60, 0x70, 0x6b, 0x9c, 0x79, 0x4f, 0x84, 0xbf, 0x80,
+	0xb2, 0xd0, 0x0d, 0xd9, 0x68, 0xad, 0x27, 0x25, 0x18, 0x3d, 0x9f, 0x30, 0x95, 0xd9, 0xd2, 0x15,
+	0xd7, 0x1d, 0x1f, 0x1d, 0x50, 0xa0, 0x86, 0x9a, 0xeb, 0xbf, 0x6b, 0xf3, 0xfd, 0xf7, 0x9b, 0x62,
+	0x25, 0x6f, 0x15, 0xbe, 0x29, 0x56, 0x1e, 0x5b, 0x8e, 0xf3, 0xcf, 0x3c, 0xd4, 0xb3, 0x43, 0x98,
+	0x9a, 0x97, 0x39, 0xf3, 0x82, 0x38, 0x60, 0xa1, 0x34, 0xdd, 0x7f, 0x06, 0xa8, 0x39, 0x63, 0xe0,
+	0x7a, 0xac, 0x3f, 0x9b, 0x27, 0xeb, 0xb4, 0xaa, 0x90, 0x77, 0x0a, 0x20, 0xdb, 0x50, 0x79, 0x1f,
+	0x84, 0xfd, 0x98, 0x47, 0x57, 0x66, 0x1a, 0x28, 0xbf, 0x0f, 0xc2, 0x2e, 0x8f, 0xae, 0xd4, 0xd3,
+	0x4c, 0xd5, 0xf4, 0xb9, 0x1b, 0xfa, 0xba, 0xbf, 0xea, 0xd9, 0x60, 0x2d, 0x65, 0x51, 0x37, 0xf4,
+	0xb1, 0xbd, 0x12, 0x28, 0x0a, 0xc6, 0x7c, 0x33, 0x25, 0xe0, 0x9a, 0x7c, 0x09, 0xd6, 0x6c, 0x68,
+	0xe9, 0x5f, 0x8d, 0x23, 0xef, 0x1a, 0xc7, 0x85, 0x3a, 0x5d, 0x9d, 0xe1, 0x87, 0x0a, 0x26, 0xa7,
+	0xb0, 0x96, 0x11, 0x35, 0x93, 0xa7, 0x1e, 0x1d, 0x3e, 0xc9, 0x4c, 0x9e, 0xed, 0x54, 0xc6, 0xcc,
+	0xa0, 0x16, 0xbb, 0x83, 0x38, 0x1d, 0x20, 0x5a, 0xb6, 0xc7, 0x42, 0x9f, 0x71, 0xe3, 0xa6, 0xc7,
+	0x50, 0x17, 0x48, 0xf7, 0xc3, 0x28, 0xf4, 0x98, 0x19, 0xb7, 0x6b, 0x1a, 0x3b, 0x57, 0xd0, 0x3d,
+	0x39, 0xf1, 0x1d, 0x6c, 0xdd, 0x7f, 0x2c, 0x79, 0x02, 0x2b, 0x1e, 0x67, 0xda, 0x58, 0x1e, 0x4d,
+	0x43, 0xdf, 0x24, 0x49, 0x23, 0x41, 0xa9, 0x02, 0xc9, 0x2b, 0xd8, 0x9e, 0x17, 0xd3, 0x4e, 0xd0,
+	0xae, 0xd4, 0x07, 0x6d, 0xcd, 0xed, 0x40, 0x67, 0x28, 0x7f, 0x3a, 0xff, 0xce, 0x43, 0xb9, 0xeb,
+	0xde, 0xe2, 0x73, 0x5b, 0x18, 0xc9, 0x73, 0x1f, 0x37, 0x92, 0x63, 0x8e, 0xa8, 0x0b, 0x9a, 0xb3,
+	0x0c, 0x75, 0xbf, 0xb3, 0x0b, 0x3f, 0xc3, 0xd9, 0xa4, 0x03, 0x1b, 0xc6, 0x32, 0xe3, 0x5d, 0xa3,
+	0xac, 0x88, 0xb5, 0xe8, 0x41, 0x46, 0x59, 0x36, 0x1a, 0x94, 0xc8, 0xc5, 0x08, 0xbd, 0x80, 0x15,
+	0xf6, 0x21, 0x66, 0x9e, 0x64, 0x7e, 0x1f, 0xff, 0x26, 0xd8, 0xcb, 0x99, 0x21, 0x70, 0xf6, 0x1f,
+	0xa2, 0x91, 0x48, 0x21, 0xd4, 0xfc, 0x6f, 0x0e, 0xea, 0xd9, 0xfa, 0x41, 0x0e, 0x61, 0xf5, 0x84,
+	0xc9, 0x39, 0xc8, 0x5e, 0xa8, 0x32, 0xa6, 0x8a, 0xec, 0xdc, 0x5f, 0x7f, 0xc8, 0x9f, 0x60, 0xf3,
+	0xde, 0x2f, 0x12, 0x44, 0xff, 0x93, 0xfc, 0xb1, 0x8f, 0x1f, 0x3b, 0xce, 0x8f, 0x89, 0xe8, 0x0f,
+	0x1a, 0xe4, 0x73, 0x28, 0xaa, 0x4f, 0x2c, 0x44, 0x7f, 0x3f, 0x48, 0xbe, 0xb6, 0xec, 0xcc, 0x93,
+	0xcd, 0x73, 0x80, 0xcb, 0xd9, 0xff, 0xb2, 0xdf, 0x01, 0x49, 0x6a, 0x60, 0x06, 0xdd, 0xc0, 0x2d,
+	0x77, 0x8a, 0xe3, 0x8e, 0x2e, 0xc0, 0x73, 0x35, 0xeb, 0x59, 0xee, 0xb0, 0xfc, 0xc7, 0xe5, 0xfd,
+	0xaf, 0x42, 0x26, 0xaf, 0x4a, 0xf8, 0xb5, 0xe7, 0xe0, 0xff, 0x03, 0x00, 0xd9, 0xa0, 0xab, 0x3a,
+	0x01, 0x12, 0x00, 0x00,
 }
@@ -121,6 +121,36 @@ func (sm *LocalSenderMonitor) Stop() {
 	close(sm.quit)
 }
 
+// Flush redeems the queued tickets that can be redeemed as of the last seen block, and waits for the redemptions
+// that are in progress. Tickets that can't be redeemed yet stay in the ticket store to be redeemed after a restart
+func (sm *LocalSenderMonitor) Flush(ctx context.Context) error {
+	latestL1Block := sm.tm.LastSeenL1Block()
+	if latestL1Block == nil {
+		return nil
+	}
+
+	sm.mu.Lock()
+	queues := make([]*ticketQueue, 0, len(sm.senders))
+	for _, s := range sm.senders {
+		queues = append(queues, s.queue)
+	}
+	sm.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		for _, q := range queues {
+			q.handleBlockEvent(latestL1Block)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // addFloat adds to a remote sender's max float
 func (sm *LocalSenderMonitor) addFloat(addr ethcommon.Address, amount *big.Int) error {
 	sm.mu.Lock()
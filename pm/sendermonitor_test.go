@@ -310,6 +310,47 @@ func TestQueueTicketAndSignalNewBlock(t *testing.T) {
 	assert.True(b.IsUsedTicket(signedT3.Ticket))
 }
 
+func TestFlush(t *testing.T) {
+	cfg, b, smgr, tm := localSenderMonitorFixture()
+	addr := RandAddress()
+	smgr.info[addr] = &SenderInfo{
+		Deposit:       big.NewInt(500),
+		WithdrawRound: big.NewInt(0),
+		Reserve: &ReserveInfo{
+			FundsRemaining:        big.NewInt(5000),
+			ClaimedInCurrentRound: big.NewInt(0),
+		},
+	}
+	smgr.claimedReserve[addr] = big.NewInt(100)
+	ts := newStubTicketStore()
+	sm := NewSenderMonitor(cfg, b, smgr, tm, ts)
+	sm.Start()
+	defer sm.Stop()
+
+	assert := assert.New(t)
+
+	// Nothing is redeemed before a block is seen
+	assert.Nil(sm.Flush(context.Background()))
+
+	signedT := defaultSignedTicket(addr, uint32(0))
+	assert.Nil(sm.QueueTicket(signedT))
+	tm.lastSeenBlock = big.NewInt(5)
+
+	// Queued tickets are redeemed without waiting for a new block
+	assert.Nil(sm.Flush(context.Background()))
+	qlen, err := sm.senders[addr].queue.Length()
+	assert.Nil(err)
+	assert.Equal(0, qlen)
+	assert.True(b.IsUsedTicket(signedT.Ticket))
+
+	// Flushing stops when the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	sm.senders[addr].queue.mu.Lock()
+	assert.Equal(context.Canceled, sm.Flush(ctx))
+	sm.senders[addr].queue.mu.Unlock()
+}
+
 func TestCleanup(t *testing.T) {
 	cfg, b, smgr, tm := localSenderMonitorFixture()
 	cfg.TTL = 5
@@ -9,6 +9,7 @@ import (
 	"math/big"
 	"math/rand"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -86,7 +87,8 @@ func (cfg *BroadcastConfig) SetMaxPrice(price *core.AutoConvertedPrice) {
 
 type sessionsCreator func() ([]*BroadcastSession, error)
 type SessionPool struct {
-	mid core.ManifestID
+	mid    core.ManifestID
+	params *core.StreamParameters
 
 	// Accessing or changing any of the below requires ownership of this mutex
 	lock sync.Mutex
@@ -101,28 +103,54 @@ type SessionPool struct {
 	finished   bool // set at stream end
 
 	createSessions sessionsCreator
-	sus            *suspender
+	breakers       *circuitBreakers
+	// sessions picked while their orchestrator's circuit was half-open, which hold a probe slot until the
+	// outcome of a segment is recorded or they are given back
+	probes map[*BroadcastSession]bool
 }
 
-func NewSessionPool(mid core.ManifestID, poolSize, numOrchs int, sus *suspender, createSession sessionsCreator,
+func NewSessionPool(params *core.StreamParameters, poolSize, numOrchs int, breakers *circuitBreakers, createSession sessionsCreator,
 	sel BroadcastSessionsSelector) *SessionPool {
 
 	return &SessionPool{
-		mid:            mid,
+		mid:            params.ManifestID,
+		params:         params,
 		numOrchs:       numOrchs,
 		poolSize:       poolSize,
 		sessMap:        make(map[string]*BroadcastSession),
 		sel:            sel,
 		createSessions: createSession,
-		sus:            sus,
+		breakers:       breakers,
+		probes:         make(map[*BroadcastSession]bool),
 	}
 }
 
-func (sp *SessionPool) suspend(orch string) {
-	poolSize := math.Max(1, float64(sp.poolSize))
-	numOrchs := math.Max(1, float64(sp.numOrchs))
-	penalty := int(math.Ceil(poolSize / numOrchs))
-	sp.sus.suspend(orch, penalty)
+// pick returns true if the circuit of the orchestrator of a session newly taken from the selector allows
+// sending segments to it, and keeps track of the probe slot the session might hold.
+// the caller needs to ensure sp.lock is acquired before calling this
+func (sp *SessionPool) pick(sess *BroadcastSession) bool {
+	allowed, probe := sp.breakers.acquire(sess.Transcoder())
+	if probe {
+		sp.probes[sess] = true
+	}
+	return allowed
+}
+
+// releaseProbe frees the probe slot held by the session, if any, as no outcome was recorded for it.
+// the caller needs to ensure sp.lock is acquired before calling this
+func (sp *SessionPool) releaseProbe(sess *BroadcastSession) {
+	if sp.probes[sess] {
+		delete(sp.probes, sess)
+		sp.breakers.release(sess.Transcoder())
+	}
+}
+
+// probeDone forgets the probe slot held by the session once the outcome of one of its segments is recorded,
+// which frees the slot
+func (sp *SessionPool) probeDone(sess *BroadcastSession) {
+	sp.lock.Lock()
+	defer sp.lock.Unlock()
+	delete(sp.probes, sess)
 }
 
 func (sp *SessionPool) refreshSessions(ctx context.Context) {
@@ -142,8 +170,6 @@ func (sp *SessionPool) refreshSessions(ctx context.Context) {
 	sp.refreshing = true
 	sp.lock.Unlock()
 
-	sp.sus.signalRefresh()
-
 	newBroadcastSessions, err := sp.createSessions()
 	if err != nil {
 		sp.lock.Lock()
@@ -333,6 +359,16 @@ func (sp *SessionPool) selectSessions(ctx context.Context, sessionsNum int) []*B
 			selection time by retrying the selection.
 		*/
 
+		// Sessions newly taken from the selector may take a probe slot of a half-open circuit, while re-used
+		// sessions already hold theirs and are only dropped once the circuit opened again
+		if _, ok := sp.sessMap[sess.Transcoder()]; ok &&
+			(gotFromLast && sp.breakers.state(sess.Transcoder()) == circuitOpen || !gotFromLast && !sp.pick(sess)) {
+			// The circuit of the orchestrator is open or it is already probed with enough segments
+			clog.V(common.DEBUG).Infof(ctx, "Circuit of orch=%v is not closed, removing it from manifestID=%s session list", sess.Transcoder(), sp.mid)
+			delete(sp.sessMap, sess.Transcoder())
+			sp.releaseProbe(sess)
+		}
+
 		if _, ok := sp.sessMap[sess.Transcoder()]; ok {
 			selectedSessions = append(selectedSessions, sess)
 
@@ -349,6 +385,9 @@ func (sp *SessionPool) selectSessions(ctx context.Context, sessionsNum int) []*B
 				if monitor.Enabled {
 					monitor.OrchestratorSwapped(ctx)
 				}
+				evt := newStreamEvent(EventOrchestratorSwapped, sp.params)
+				evt.PreviousOrchestrator = sess.Transcoder()
+				Events.Send(ctx, evt)
 			}
 		}
 	}
@@ -365,6 +404,10 @@ func (sp *SessionPool) selectSessions(ctx context.Context, sessionsNum int) []*B
 				if monitor.Enabled {
 					monitor.OrchestratorSwapped(ctx)
 				}
+				evt := newStreamEvent(EventOrchestratorSwapped, sp.params)
+				evt.Orchestrator = selectedSessions[0].Transcoder()
+				evt.PreviousOrchestrator = ls.Transcoder()
+				Events.Send(ctx, evt)
 			}
 		}
 		sp.lastSess = append([]*BroadcastSession{}, selectedSessions...)
@@ -377,12 +420,16 @@ func (sp *SessionPool) removeSession(session *BroadcastSession) {
 	defer sp.lock.Unlock()
 
 	delete(sp.sessMap, session.Transcoder())
+	sp.releaseProbe(session)
 }
 
 func (sp *SessionPool) cleanup() {
 	sp.lock.Lock()
 	defer sp.lock.Unlock()
 	sp.finished = true
+	for sess := range sp.probes {
+		sp.releaseProbe(sess)
+	}
 	sp.lastSess = nil
 	sp.sel.Clear()
 	sp.sessMap = make(map[string]*BroadcastSession) // prevent segfaults
@@ -391,6 +438,7 @@ func (sp *SessionPool) cleanup() {
 func (sp *SessionPool) completeSession(sess *BroadcastSession) {
 	sp.lock.Lock()
 	defer sp.lock.Unlock()
+	sp.releaseProbe(sess)
 	if existingSess, ok := sp.sessMap[sess.Transcoder()]; ok {
 		if existingSess != sess {
 			// that means that sess object was removed from pool and then same
@@ -409,8 +457,9 @@ func (sp *SessionPool) completeSession(sess *BroadcastSession) {
 		}
 
 		// If the latency score meets the selector threshold, we skip giving the session back to the selector
-		// because we consider it for re-use in selectSession()
-		if sess.LatencyScore > 0 && sess.LatencyScore <= SELECTOR_LATENCY_SCORE_THRESHOLD {
+		// because we consider it for re-use in selectSession(). Sessions that are not among the last used ones
+		// (e.g. backup sessions of hedged segments) are not considered there, so they are always given back
+		if includesSession(sp.lastSess, sess) && sess.LatencyScore > 0 && sess.LatencyScore <= SELECTOR_LATENCY_SCORE_THRESHOLD {
 			return
 		}
 
@@ -456,42 +505,60 @@ func NewSessionManager(ctx context.Context, node *core.LivepeerNode, params *cor
 		trustedPoolSize = float64(node.OrchestratorPool.SizeWith(common.ScoreAtLeast(common.Score_Trusted)))
 		untrustedPoolSize = float64(node.OrchestratorPool.SizeWith(common.ScoreEqualTo(common.Score_Untrusted)))
 	}
-	maxInflight := common.HTTPTimeout.Seconds() / SegLen.Seconds()
+	maxInflight := common.HTTPTimeout.Seconds() / streamSegLen(params).Seconds()
 	trustedNumOrchs := int(math.Min(trustedPoolSize, maxInflight*2))
+	if params.OrchConstraints != nil || SessionSelector == SelectorSticky {
+		// Orchestrators not allowed for the stream are dropped after discovery and the orchestrator the stream
+		// is hashed onto might not be among the first ones to respond, so discover all of them
+		trustedNumOrchs = int(trustedPoolSize)
+	}
 	untrustedNumOrchs := int(untrustedPoolSize)
-	susTrusted := newSuspender()
-	susUntrusted := newSuspender()
 	createSessionsTrusted := func() ([]*BroadcastSession, error) {
-		return selectOrchestrator(ctx, node, params, trustedNumOrchs, susTrusted, common.ScoreAtLeast(common.Score_Trusted))
+		return selectOrchestrator(ctx, node, params, trustedNumOrchs, orchCircuitBreakers, common.ScoreAtLeast(common.Score_Trusted))
 	}
 	createSessionsUntrusted := func() ([]*BroadcastSession, error) {
-		return selectOrchestrator(ctx, node, params, untrustedNumOrchs, susUntrusted, common.ScoreEqualTo(common.Score_Untrusted))
-	}
-	var stakeRdr stakeReader
-	if node.Eth != nil {
-		stakeRdr = &storeStakeReader{store: node.Database}
+		return selectOrchestrator(ctx, node, params, untrustedNumOrchs, orchCircuitBreakers, common.ScoreEqualTo(common.Score_Untrusted))
 	}
 	bsm := &BroadcastSessionsManager{
 		mid:              params.ManifestID,
 		VerificationFreq: params.VerificationFreq,
-		trustedPool:      NewSessionPool(params.ManifestID, int(trustedPoolSize), trustedNumOrchs, susTrusted, createSessionsTrusted, NewMinLSSelector(stakeRdr, 1.0, node.SelectionAlgorithm, node.OrchPerfScore)),
-		untrustedPool:    NewSessionPool(params.ManifestID, int(untrustedPoolSize), untrustedNumOrchs, susUntrusted, createSessionsUntrusted, NewMinLSSelector(stakeRdr, 1.0, node.SelectionAlgorithm, node.OrchPerfScore)),
+		trustedPool:      NewSessionPool(params, int(trustedPoolSize), trustedNumOrchs, orchCircuitBreakers, createSessionsTrusted, sel()),
+		untrustedPool:    NewSessionPool(params, int(untrustedPoolSize), untrustedNumOrchs, orchCircuitBreakers, createSessionsUntrusted, sel()),
 	}
 	bsm.trustedPool.refreshSessions(ctx)
 	bsm.untrustedPool.refreshSessions(ctx)
 	return bsm
 }
 
-func (bsm *BroadcastSessionsManager) suspendAndRemoveOrch(sess *BroadcastSession) {
+// suspendAndRemoveOrch records the failure of the session's orchestrator, which might open its circuit, and
+// removes the session from the pool
+func (bsm *BroadcastSessionsManager) suspendAndRemoveOrch(sess *BroadcastSession, err error) {
+	OrchLatencyHistory.RecordFailure(latencyHistoryKey(sess))
+	OrchReputation.RecordFailure(latencyHistoryKey(sess), err)
 	if sess.OrchestratorScore == common.Score_Untrusted {
-		bsm.untrustedPool.suspend(sess.OrchestratorInfo.GetTranscoder())
+		bsm.untrustedPool.breakers.recordFailure(sess.OrchestratorInfo.GetTranscoder(), err)
+		bsm.untrustedPool.probeDone(sess)
 		bsm.untrustedPool.removeSession(sess)
 	} else {
-		bsm.trustedPool.suspend(sess.OrchestratorInfo.GetTranscoder())
+		bsm.trustedPool.breakers.recordFailure(sess.OrchestratorInfo.GetTranscoder(), err)
+		bsm.trustedPool.probeDone(sess)
 		bsm.trustedPool.removeSession(sess)
 	}
 }
 
+// recordSuccess records a segment successfully transcoded by the session's orchestrator
+func (bsm *BroadcastSessionsManager) recordSuccess(sess *BroadcastSession, latencyScore float64) {
+	OrchLatencyHistory.RecordSuccess(latencyHistoryKey(sess), latencyScore)
+	OrchReputation.RecordSuccess(latencyHistoryKey(sess), latencyScore)
+	if sess.OrchestratorScore == common.Score_Untrusted {
+		bsm.untrustedPool.breakers.recordSuccess(sess.Transcoder())
+		bsm.untrustedPool.probeDone(sess)
+	} else {
+		bsm.trustedPool.breakers.recordSuccess(sess.Transcoder())
+		bsm.trustedPool.probeDone(sess)
+	}
+}
+
 func (bsm *BroadcastSessionsManager) removeSession(session *BroadcastSession) {
 	bsm.sessLock.Lock()
 	defer bsm.sessLock.Unlock()
@@ -681,7 +748,7 @@ func (bsm *BroadcastSessionsManager) chooseResults(ctx context.Context, seg *str
 			}
 			// suspend sessions which returned incorrect results
 			for _, s := range sessionsToSuspend {
-				bsm.suspendAndRemoveOrch(s)
+				bsm.suspendAndRemoveOrch(s, errVerificationMismatch)
 			}
 			return untrustedResult.Session, untrustedResult.TranscodeResult, untrustedResult.Err
 		} else {
@@ -721,7 +788,7 @@ func (bsm *BroadcastSessionsManager) collectResults(submitResultsCh chan *Submit
 			if isNonRetryableError(err) {
 				bsm.completeSession(context.TODO(), res.Session, false)
 			} else {
-				bsm.suspendAndRemoveOrch(res.Session)
+				bsm.suspendAndRemoveOrch(res.Session, err)
 			}
 		}
 	}
@@ -765,7 +832,7 @@ func (bsm *BroadcastSessionsManager) usingVerified() bool {
 	return bsm.verifiedSession != nil
 }
 
-func selectOrchestrator(ctx context.Context, n *core.LivepeerNode, params *core.StreamParameters, count int, sus *suspender,
+func selectOrchestrator(ctx context.Context, n *core.LivepeerNode, params *core.StreamParameters, count int, sus common.Suspender,
 	scorePred common.ScorePred) ([]*BroadcastSession, error) {
 
 	if n.OrchestratorPool == nil {
@@ -786,6 +853,11 @@ func selectOrchestrator(ctx context.Context, n *core.LivepeerNode, params *core.
 	var sessions []*BroadcastSession
 
 	for _, od := range ods {
+		if !allowedByConstraints(params.OrchConstraints, od.RemoteInfo, n.OrchPerfScore) {
+			clog.V(common.DEBUG).Infof(ctx, "Skipping orch=%v not allowed by the stream's constraints", od.RemoteInfo.GetTranscoder())
+			continue
+		}
+
 		var (
 			sessionID    string
 			balance      Balance
@@ -844,6 +916,10 @@ func selectOrchestrator(ctx context.Context, n *core.LivepeerNode, params *core.
 
 		sessions = append(sessions, session)
 	}
+	// Try orchestrators that have been unreliable in the past last
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return OrchReputation.reliable(latencyHistoryKey(sessions[i])) && !OrchReputation.reliable(latencyHistoryKey(sessions[j]))
+	})
 	return sessions, nil
 }
 
@@ -861,6 +937,7 @@ func processSegment(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSeg
 	}
 
 	clog.V(common.DEBUG).Infof(ctx, "Processing segment dur=%v bytes=%v", seg.Duration, len(seg.Data))
+	SegmentRetries.Observe(mid, seg.SeqNo)
 	if segPar != nil && segPar.ForceSessionReinit {
 		clog.V(common.DEBUG).Infof(ctx, "Requesting HW Session Reinitialization for seg.SeqNo=%v", seg.SeqNo)
 	}
@@ -868,6 +945,7 @@ func processSegment(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSeg
 		monitor.SegmentEmerged(ctx, nonce, seg.SeqNo, len(BroadcastJobVideoProfiles), seg.Duration)
 	}
 	atomic.AddUint64(&cxn.sourceBytes, uint64(len(seg.Data)))
+	cxn.progress.publish(&SegmentProgress{Type: ProgressSegmentReceived, SeqNo: seg.SeqNo, Duration: seg.Duration, Bytes: len(seg.Data)})
 
 	seg.Name = "" // hijack seg.Name to convey the uploaded URI
 	ext, err := common.ProfileFormatExtension(vProfile.Format)
@@ -880,8 +958,13 @@ func processSegment(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSeg
 	segDurMs := getSegDurMsString(seg)
 
 	hasZeroVideoFrame := seg.IsZeroFrame
+	var recorded chan struct{}
 	if ros != nil && !hasZeroVideoFrame {
+		recorded = make(chan struct{})
+		cxn.recordSaves.add()
 		go func() {
+			defer cxn.recordSaves.done()
+			defer close(recorded)
 			ctx, cancel := clog.WithTimeout(context.Background(), ctx, recordSegmentsMaxTimeout)
 			defer cancel()
 			now := time.Now()
@@ -901,7 +984,18 @@ func processSegment(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSeg
 			}
 		}()
 	}
-	uri, err := cpl.GetOSSession().SaveData(ctx, name, bytes.NewReader(seg.Data), nil, 0)
+	if thumbnailDue(cxn, seg) {
+		go saveThumbnail(ctx, cxn, seg, recorded)
+	}
+	stored, err := cpl.EncryptSegment(ctx, seg.SeqNo, seg.Data)
+	if err != nil {
+		clog.Errorf(ctx, "Error encrypting segment err=%q", err)
+		if monitor.Enabled {
+			monitor.SegmentUploadFailed(ctx, nonce, seg.SeqNo, monitor.SegmentUploadErrorUnknown, err, true, "")
+		}
+		return nil, err
+	}
+	uri, err := cpl.GetOSSession().SaveData(ctx, name, bytes.NewReader(stored), nil, 0)
 	if err != nil {
 		clog.Errorf(ctx, "Error saving segment err=%q", err)
 		if monitor.Enabled {
@@ -909,10 +1003,15 @@ func processSegment(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSeg
 		}
 		return nil, err
 	}
-	if cpl.GetOSSession().IsExternal() {
+	// Orchestrators are sent the data of encrypted segments instead, since they can't decrypt them
+	if cpl.GetOSSession().IsExternal() && !encryptSegments(cxn) {
 		seg.Name = uri // hijack seg.Name to convey the uploaded URI
 	}
 	err = cpl.InsertHLSSegment(vProfile, seg.SeqNo, uri, seg.Duration)
+	if err := cpl.InsertLLHLSPart(vProfile, seg.SeqNo, seg.Duration, seg.Data); err != nil {
+		clog.Errorf(ctx, "Error inserting LL-HLS part err=%q", err)
+	}
+	cxn.restreams.push(vProfile.Name, seg.SeqNo, seg.Duration, seg.Data)
 	if monitor.Enabled {
 		monitor.SourceSegmentAppeared(ctx, nonce, seg.SeqNo, string(mid), vProfile.Name, ros != nil)
 	}
@@ -933,7 +1032,15 @@ func processSegment(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSeg
 				return nil, err
 			}
 			name := fmt.Sprintf("%s/%d%s", profile.Name, seg.SeqNo, ext)
-			uri, err := cpl.GetOSSession().SaveData(ctx, name, bytes.NewReader(seg.Data), nil, 0)
+			stored, err := cpl.EncryptSegment(ctx, seg.SeqNo, seg.Data)
+			if err != nil {
+				clog.Errorf(ctx, "Error encrypting segment err=%q", err)
+				if monitor.Enabled {
+					monitor.SegmentUploadFailed(ctx, nonce, seg.SeqNo, monitor.SegmentUploadErrorUnknown, err, true, "")
+				}
+				return nil, err
+			}
+			uri, err := cpl.GetOSSession().SaveData(ctx, name, bytes.NewReader(stored), nil, 0)
 			if err != nil {
 				clog.Errorf(ctx, "Error saving segment err=%q", err)
 				if monitor.Enabled {
@@ -942,6 +1049,10 @@ func processSegment(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSeg
 				return nil, err
 			}
 			urls = append(urls, uri)
+			if err := cpl.InsertLLHLSPart(&profile, seg.SeqNo, seg.Duration, seg.Data); err != nil {
+				clog.Errorf(ctx, "Error inserting LL-HLS part err=%q", err)
+			}
+			cxn.restreams.push(profile.Name, seg.SeqNo, seg.Duration, seg.Data)
 			err = cpl.InsertHLSSegment(&profile, seg.SeqNo, uri, seg.Duration)
 			if err != nil {
 				clog.Errorf(ctx, "Error inserting segment err=%q", err)
@@ -966,6 +1077,17 @@ func processSegment(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSeg
 	if cxn.params != nil && len(cxn.params.Profiles) == 0 {
 		return []string{}, nil
 	}
+	if scope, exhausted := Budgets.Exhausted(ctx, cxn.params); exhausted {
+		switch Budgets.Action() {
+		case BudgetActionStop:
+			clog.Warningf(ctx, "Stopping current stream due to exhausted %s budget", scope)
+			rtmpStrm.Close()
+			return nil, errBudgetExhausted
+		case BudgetActionSource:
+			// The source segment is already in the playlist
+			return []string{}, nil
+		}
+	}
 	for len(attempts) < MaxAttempts {
 		// if transcodeSegment fails, retry; rudimentary
 		var info *data.TranscodeAttemptInfo
@@ -974,6 +1096,8 @@ func processSegment(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSeg
 		if err == nil {
 			break
 		}
+		cxn.progress.publish(&SegmentProgress{Type: ProgressError, SeqNo: seg.SeqNo, Orchestrator: info.Orchestrator.TranscoderUri,
+			OrchestratorAddress: info.Orchestrator.Address, Error: err.Error()})
 
 		if shouldStopStream(err) {
 			clog.Warningf(ctx, "Stopping current stream due to err=%q", err)
@@ -985,6 +1109,7 @@ func processSegment(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSeg
 			if monitor.Enabled {
 				monitor.SegmentTranscodeFailed(ctx, monitor.SegmentTranscodeErrorNonRetryable, nonce, seg.SeqNo, err, true)
 			}
+			sendTranscodeFailed(ctx, cxn, seg.SeqNo, err)
 			break
 		}
 		if ctxErr := ctx.Err(); ctxErr != nil {
@@ -1019,6 +1144,13 @@ func processSegment(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSeg
 		if monitor.Enabled {
 			monitor.SegmentTranscodeFailed(ctx, monitor.SegmentTranscodeErrorMaxAttempts, nonce, seg.SeqNo, err, true)
 		}
+		sendTranscodeFailed(ctx, cxn, seg.SeqNo, err)
+		if SegmentRetries != nil {
+			// A segment that is already queued is retried by the queue, it is not dropped
+			if err := SegmentRetries.Enqueue(ctx, cxn, seg, name, segPar); err == errRetryQueueFull {
+				clog.Warningf(ctx, "Dropping failed segment, the segment retry queue is full")
+			}
+		}
 	}
 	return urls, err
 }
@@ -1047,6 +1179,7 @@ func transcodeSegment(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSS
 			monitor.SegmentTranscodeFailed(ctx, monitor.SegmentTranscodeErrorNoOrchestrators, nonce, seg.SeqNo, errNoOrchs, true)
 		}
 		clog.Infof(ctx, "No sessions available for segment")
+		cxn.progress.publish(&SegmentProgress{Type: ProgressError, SeqNo: seg.SeqNo, Error: errNoOrchs.Error()})
 		// We may want to introduce a "non-retryable" error type here
 		// would help error propagation for live ingest.
 		// similar to the orchestrator's RemoteTranscoderFatalError
@@ -1064,21 +1197,30 @@ func transcodeSegment(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSS
 	if len(sessions) == 1 {
 		// shortcut for most common path
 		sess := sessions[0]
+		srcSeg := seg
 		if seg, err = prepareForTranscoding(ctx, cxn, sess, seg, name); err != nil {
 			return nil, info, err
 		}
 		sess.pushSegInFlight(seg)
 		var res *ReceivedTranscodeResult
-		res, err = SubmitSegment(ctx, sess.Clone(), seg, segPar, nonce, calcPerceptualHash, verified)
+		if shouldHedge(seg, calcPerceptualHash) {
+			sess, res, err = submitHedged(ctx, cxn, sess, srcSeg, seg, name, segPar, nonce, verified)
+			info.Orchestrator = data.OrchestratorMetadata{
+				TranscoderUri: sess.Transcoder(),
+				Address:       sess.Address(),
+			}
+		} else {
+			res, err = SubmitSegment(ctx, sess.Clone(), seg, segPar, nonce, calcPerceptualHash, verified)
+		}
 		if err != nil || res == nil {
 			if isNonRetryableError(err) {
 				cxn.sessManager.completeSession(ctx, sess, false)
 				return nil, info, err
 			}
-			cxn.sessManager.suspendAndRemoveOrch(sess)
 			if res == nil && err == nil {
 				err = errors.New("empty response")
 			}
+			cxn.sessManager.suspendAndRemoveOrch(sess, err)
 			return nil, info, err
 		}
 		// Ensure perceptual hash is generated if we ask for it
@@ -1166,7 +1308,7 @@ func prepareForTranscoding(ctx context.Context, cxn *rtmpConnection, sess *Broad
 			if monitor.Enabled {
 				monitor.SegmentUploadFailed(ctx, cxn.nonce, seg.SeqNo, monitor.SegmentUploadErrorOS, err, false, "")
 			}
-			cxn.sessManager.suspendAndRemoveOrch(sess)
+			cxn.sessManager.suspendAndRemoveOrch(sess, err)
 			return nil, err
 		}
 		segCopy := *seg
@@ -1177,7 +1319,7 @@ func prepareForTranscoding(ctx context.Context, cxn *rtmpConnection, sess *Broad
 	refresh, err := shouldRefreshSession(ctx, sess)
 	if err != nil {
 		clog.Errorf(ctx, "Error checking whether to refresh session manifestID=%s orch=%v err=%q", cxn.mid, sess.Transcoder(), err)
-		cxn.sessManager.suspendAndRemoveOrch(sess)
+		cxn.sessManager.suspendAndRemoveOrch(sess, err)
 		return nil, err
 	}
 
@@ -1185,7 +1327,7 @@ func prepareForTranscoding(ctx context.Context, cxn *rtmpConnection, sess *Broad
 		err := refreshSession(ctx, sess)
 		if err != nil {
 			clog.Errorf(ctx, "Error refreshing session manifestID=%s orch=%v err=%q", cxn.mid, sess.Transcoder(), err)
-			cxn.sessManager.suspendAndRemoveOrch(sess)
+			cxn.sessManager.suspendAndRemoveOrch(sess, err)
 			return nil, err
 		}
 	}
@@ -1211,6 +1353,9 @@ func downloadResults(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSe
 
 	var dlErr error
 	segData := make([][]byte, len(res.Segments))
+	// Fragmented MP4 renditions are saved and served without their init segment
+	segMedia := make([][]byte, len(res.Segments))
+	initURIs := make([]string, len(res.Segments))
 	n := len(res.Segments)
 	segURLs := make([]string, len(res.Segments))
 	segLock := &sync.Mutex{}
@@ -1229,20 +1374,27 @@ func downloadResults(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSe
 
 		bos := sess.BroadcasterOS
 		profile := sess.Params.Profiles[i]
+		fmp4 := profile.Format == common.FormatFMP4
 
 		bros := cpl.GetRecordOSSession()
 		var data []byte
 		// Download segment data in the following cases:
 		// - A verification policy is set. The segment data is needed for signature verification and/or pixel count verification
 		// - The segment data needs to be uploaded to the broadcaster's own OS
-		if verifier != nil || bros != nil || bos != nil && !bos.IsOwn(url) {
+		// - The segment data is served as a LL-HLS part
+		// - The segment is fragmented MP4, and its init segment needs to be split out
+		// - The segment is encrypted before it is uploaded to the broadcaster's OS
+		// - The segment is pushed to RTMP destinations
+		encrypt := sess.Params.Encrypt
+		restream := cxn.restreams.pushes(profile.Name)
+		if verifier != nil || bros != nil || bos != nil && !bos.IsOwn(url) || sess.Params.LLHLSPartTarget > 0 || fmp4 || encrypt || restream {
 			d, err := downloadSeg(ctx, url)
 			if err != nil {
 				errFunc(monitor.SegmentTranscodeErrorDownload, url, err)
 				segLock.Lock()
 				dlErr = err
 				segLock.Unlock()
-				cxn.sessManager.suspendAndRemoveOrch(sess)
+				cxn.sessManager.suspendAndRemoveOrch(sess, err)
 				return
 			}
 
@@ -1250,6 +1402,20 @@ func downloadResults(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSe
 			atomic.AddUint64(&cxn.transcodedBytes, uint64(len(data)))
 		}
 
+		media := data
+		var init []byte
+		if fmp4 {
+			var err error
+			init, media, err = core.SplitFMP4(data)
+			if err != nil {
+				errFunc(monitor.SegmentTranscodeErrorDownload, url, err)
+				segLock.Lock()
+				dlErr = err
+				segLock.Unlock()
+				return
+			}
+		}
+
 		if bros != nil {
 			go func() {
 				ctx, cancel := clog.WithTimeout(context.Background(), ctx, recordSegmentsMaxTimeout)
@@ -1258,12 +1424,20 @@ func downloadResults(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSe
 				name := fmt.Sprintf("%s/%d%s", profile.Name, seg.SeqNo, ext)
 				segDurMs := getSegDurMsString(seg)
 				now := time.Now()
-				uri, err := drivers.SaveRetried(ctx, bros, name, data, map[string]string{"duration": segDurMs}, 3)
+				var initURI string
+				var err error
+				if fmp4 {
+					initURI, err = cpl.SaveInitSegment(ctx, &profile, init, true)
+				}
+				var uri string
+				if err == nil {
+					uri, err = drivers.SaveRetried(ctx, bros, name, media, map[string]string{"duration": segDurMs}, 3)
+				}
 				took := time.Since(now)
 				if err != nil {
 					clog.Errorf(ctx, "Error saving nonce=%d manifestID=%s name=%s to record store err=%q", nonce, cxn.mid, name, err)
 				} else {
-					cpl.InsertHLSSegmentJSON(&profile, seg.SeqNo, uri, seg.Duration)
+					cpl.InsertFMP4SegmentJSON(&profile, seg.SeqNo, uri, initURI, seg.Duration)
 					clog.Infof(ctx, "Successfully saved nonce=%d manifestID=%s name=%s size=%d bytes to record store took=%s",
 						nonce, cxn.mid, name, len(data), took)
 				}
@@ -1274,14 +1448,29 @@ func downloadResults(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSe
 			}()
 		}
 
-		if bos != nil && !bos.IsOwn(url) {
+		var initURI string
+		if bos != nil && fmp4 {
+			var err error
+			initURI, err = cpl.SaveInitSegment(ctx, &profile, init, false)
+			if err != nil {
+				errFunc(monitor.SegmentTranscodeErrorSaveData, url, err)
+				return
+			}
+		}
+
+		if bos != nil && (!bos.IsOwn(url) || fmp4 || encrypt) {
 			ext, err := common.ProfileFormatExtension(profile.Format)
 			if err != nil {
 				errFunc(monitor.SegmentTranscodeErrorSaveData, url, err)
 				return
 			}
 			name := fmt.Sprintf("%s/%d%s", profile.Name, seg.SeqNo, ext)
-			newURL, err := bos.SaveData(ctx, name, bytes.NewReader(data), nil, 0)
+			stored, err := cpl.EncryptSegment(ctx, seg.SeqNo, media)
+			if err != nil {
+				errFunc(monitor.SegmentTranscodeErrorSaveData, url, err)
+				return
+			}
+			newURL, err := bos.SaveData(ctx, name, bytes.NewReader(stored), nil, 0)
 			if err != nil {
 				switch err.Error() {
 				case "Session ended":
@@ -1302,6 +1491,8 @@ func downloadResults(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSe
 		segLock.Lock()
 		segURLs[i] = url
 		segData[i] = data
+		segMedia[i] = media
+		initURIs[i] = initURI
 		segLock.Unlock()
 	}
 
@@ -1313,7 +1504,9 @@ func downloadResults(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSe
 		go dlFunc(v.Url, v.Pixels, i)
 	}
 	if cpl.GetRecordOSSession() != nil && len(res.Segments) > 0 {
+		cxn.recordSaves.add()
 		go func() {
+			defer cxn.recordSaves.done()
 			recordWG.Wait()
 			cpl.FlushRecord()
 		}()
@@ -1328,6 +1521,7 @@ func downloadResults(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSe
 		return nil, dlErr
 	}
 	updateSession(sess, res)
+	cxn.sessManager.recordSuccess(sess, res.LatencyScore)
 	cxn.sessManager.completeSession(ctx, sess, false)
 
 	downloadDur := time.Since(dlStart)
@@ -1338,6 +1532,13 @@ func downloadResults(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSe
 	if verifier != nil {
 		// verify potentially can change content of segURLs
 		err := verify(verifier, cxn, sess, seg, res.TranscodeData, segURLs, segData)
+		verified := err == nil
+		evt := &SegmentProgress{Type: ProgressVerification, SeqNo: seg.SeqNo, Orchestrator: sess.Transcoder(),
+			OrchestratorAddress: sess.Address(), Verified: &verified}
+		if err != nil {
+			evt.Error = err.Error()
+		}
+		cxn.progress.publish(evt)
 		if err != nil {
 			clog.Errorf(ctx, "Error verifying nonce=%d manifestID=%s seqNo=%d err=%q", nonce, cxn.mid, seg.SeqNo, err)
 			return nil, err
@@ -1345,22 +1546,48 @@ func downloadResults(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSe
 	}
 
 	for i, url := range segURLs {
-		err := cpl.InsertHLSSegment(&sess.Params.Profiles[i], seg.SeqNo, url, seg.Duration)
+		if err := cpl.InsertLLHLSPart(&sess.Params.Profiles[i], seg.SeqNo, seg.Duration, segMedia[i]); err != nil {
+			clog.Errorf(ctx, "LL-HLS part insertion error nonce=%d manifestID=%s seqNo=%d err=%q", nonce, cxn.mid, seg.SeqNo, err)
+		}
+		cxn.restreams.push(sess.Params.Profiles[i].Name, seg.SeqNo, seg.Duration, segData[i])
+		// The init URI is only set for fragmented MP4 renditions, which are otherwise inserted like the others
+		err := cpl.InsertFMP4Segment(&sess.Params.Profiles[i], seg.SeqNo, url, initURIs[i], seg.Duration)
 		if err != nil {
-			// InsertHLSSegment only returns ErrSegmentAlreadyExists error
+			// InsertHLSSegment returns ErrSegmentAlreadyExists error, or ErrHLSKey for encrypted segments whose
+			// key couldn't be fetched, which aren't listed
 			// Right now InsertHLSSegment call is atomic regarding transcoded segments - we either inserting
-			// all the transcoded segments or none, so we shouldn't hit this error
+			// all the transcoded segments or none, so we shouldn't hit the first error
 			// But report in case that InsertHLSSegment changed or something wrong is going on in other parts of workflow
 			clog.Errorf(ctx, "Playlist insertion error nonce=%d manifestID=%s seqNo=%d err=%q", nonce, cxn.mid, seg.SeqNo, err)
 			if monitor.Enabled {
-				monitor.SegmentTranscodeFailed(ctx, monitor.SegmentTranscodeErrorDuplicateSegment, nonce, seg.SeqNo, err, false)
+				code := monitor.SegmentTranscodeErrorDuplicateSegment
+				if errors.Is(err, core.ErrHLSKey) {
+					code = monitor.SegmentTranscodeErrorUnknown
+				}
+				monitor.SegmentTranscodeFailed(ctx, code, nonce, seg.SeqNo, err, false)
 			}
 		}
+		cxn.progress.publish(&SegmentProgress{
+			Type:                ProgressRendition,
+			SeqNo:               seg.SeqNo,
+			Rendition:           sess.Params.Profiles[i].Name,
+			Orchestrator:        sess.Transcoder(),
+			OrchestratorAddress: sess.Address(),
+			LatencyMs:           res.Latency.Milliseconds(),
+			Pixels:              res.Segments[i].Pixels,
+			Fee:                 renditionFee(sess, res.Segments[i].Pixels),
+		})
 	}
 
 	if monitor.Enabled {
 		monitor.SegmentFullyTranscoded(ctx, nonce, seg.SeqNo, common.ProfilesNames(sess.Params.Profiles), errCode, sess.OrchestratorInfo)
 	}
+	if atomic.CompareAndSwapUint32(&cxn.firstTranscoded, 0, 1) {
+		evt := newStreamEvent(EventFirstSegmentTranscoded, cxn.params)
+		evt.SeqNo = &seg.SeqNo
+		evt.Orchestrator = sess.Transcoder()
+		Events.Send(ctx, evt)
+	}
 
 	clog.V(common.DEBUG).Infof(ctx, "Successfully validated segment")
 	return segURLs, nil
@@ -1424,7 +1651,11 @@ func verify(verifier *verification.SegmentVerifier, cxn *rtmpConnection,
 				// Hence, trim the /stream/<manifestID> prefix if it exists.
 				pfx := fmt.Sprintf("/stream/%s/", sess.Params.ManifestID)
 				uri := strings.TrimPrefix(accepted.URIs[i], pfx)
-				_, err := sess.BroadcasterOS.SaveData(context.TODO(), uri, bytes.NewReader(data), nil, 0)
+				stored, err := cxn.pl.EncryptSegment(context.TODO(), source.SeqNo, data)
+				if err != nil {
+					return err
+				}
+				_, err = sess.BroadcasterOS.SaveData(context.TODO(), uri, bytes.NewReader(stored), nil, 0)
 				if err != nil {
 					return err
 				}
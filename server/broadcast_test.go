@@ -120,9 +120,9 @@ func bsmWithSessListExt(sessList, untrustedSessList []*BroadcastSession, noRefre
 		createSessionsUntrusted = createSessionsEmpty
 
 	}
-	trustedPool := NewSessionPool("test", len(sessList), 1, newSuspender(), createSessions, sel)
+	trustedPool := NewSessionPool(&core.StreamParameters{ManifestID: "test"}, len(sessList), 1, newCircuitBreakers(), createSessions, sel)
 	trustedPool.sessMap = sessMap
-	untrustedPool := NewSessionPool("test", len(untrustedSessList), 1, newSuspender(), createSessionsUntrusted, unsel)
+	untrustedPool := NewSessionPool(&core.StreamParameters{ManifestID: "test"}, len(untrustedSessList), 1, newCircuitBreakers(), createSessionsUntrusted, unsel)
 	untrustedPool.sessMap = untrustedSessMap
 
 	return &BroadcastSessionsManager{
@@ -213,7 +213,11 @@ type stubPlaylistManager struct {
 	profile    ffmpeg.VideoProfile
 	uri        string
 	os         drivers.OSSession
-	lock       sync.Mutex
+	recordOS   drivers.OSSession
+	thumbnail  []byte
+	// Recorded thumbnails by seqNo
+	thumbnailURIs map[uint64]string
+	lock          sync.Mutex
 }
 
 func (pm *stubPlaylistManager) ManifestID() core.ManifestID {
@@ -237,6 +241,26 @@ func (pm *stubPlaylistManager) GetHLSMediaPlaylist(rendition string) *m3u8.Media
 	return nil
 }
 
+func (pm *stubPlaylistManager) InsertLLHLSPart(profile *ffmpeg.VideoProfile, seqNo uint64, duration float64, data []byte) error {
+	return nil
+}
+
+func (pm *stubPlaylistManager) GetLLHLSMediaPlaylist(rendition string) *core.LLHLSPlaylist {
+	return nil
+}
+
+func (pm *stubPlaylistManager) GetDASHManifest() *core.DASHManifest {
+	return nil
+}
+
+func (pm *stubPlaylistManager) GetDVRMediaPlaylist(rendition string) *core.DVRPlaylist {
+	return nil
+}
+
+func (pm *stubPlaylistManager) EncryptSegment(ctx context.Context, seqNo uint64, data []byte) ([]byte, error) {
+	return data, nil
+}
+
 func (pm *stubPlaylistManager) GetOSSession() drivers.OSSession {
 	return pm.os
 }
@@ -244,10 +268,36 @@ func (pm *stubPlaylistManager) GetOSSession() drivers.OSSession {
 func (pm *stubPlaylistManager) Cleanup()     {}
 func (pm *stubPlaylistManager) FlushRecord() {}
 func (pm *stubPlaylistManager) GetRecordOSSession() drivers.OSSession {
-	return nil
+	return pm.recordOS
 }
 func (pm *stubPlaylistManager) InsertHLSSegmentJSON(profile *ffmpeg.VideoProfile, seqNo uint64, uri string, duration float64) {
 }
+func (pm *stubPlaylistManager) SaveInitSegment(ctx context.Context, profile *ffmpeg.VideoProfile, data []byte, record bool) (string, error) {
+	return "", nil
+}
+func (pm *stubPlaylistManager) InsertFMP4Segment(profile *ffmpeg.VideoProfile, seqNo uint64, uri, initURI string, duration float64) error {
+	return pm.InsertHLSSegment(profile, seqNo, uri, duration)
+}
+func (pm *stubPlaylistManager) InsertFMP4SegmentJSON(profile *ffmpeg.VideoProfile, seqNo uint64, uri, initURI string, duration float64) {
+}
+func (pm *stubPlaylistManager) InsertThumbnail(seqNo uint64, data []byte) {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+	pm.thumbnail = data
+}
+func (pm *stubPlaylistManager) InsertThumbnailJSON(profile *ffmpeg.VideoProfile, seqNo uint64, uri string) {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+	if pm.thumbnailURIs == nil {
+		pm.thumbnailURIs = make(map[uint64]string)
+	}
+	pm.thumbnailURIs[seqNo] = uri
+}
+func (pm *stubPlaylistManager) GetThumbnail() []byte {
+	pm.lock.Lock()
+	defer pm.lock.Unlock()
+	return pm.thumbnail
+}
 
 type stubSelector struct {
 	sess *BroadcastSession
@@ -507,7 +557,7 @@ func TestTranscodeSegment_UploadFailed_SuspendAndRemove(t *testing.T) {
 	assert.EqualError(err, "some error")
 	_, ok := cxn.sessManager.trustedPool.sessMap[sess.OrchestratorInfo.GetTranscoder()]
 	assert.False(ok)
-	assert.Greater(cxn.sessManager.trustedPool.sus.Suspended(sess.OrchestratorInfo.GetTranscoder()), 0)
+	assert.Contains(cxn.sessManager.trustedPool.breakers.circuits, sess.OrchestratorInfo.GetTranscoder())
 }
 
 func TestTranscodeSegment_RefreshSession(t *testing.T) {
@@ -565,7 +615,7 @@ func TestTranscodeSegment_RefreshSession(t *testing.T) {
 	assert.True(strings.Contains(err.Error(), "some error"))
 	_, ok := cxn.sessManager.trustedPool.sessMap[ts.URL]
 	assert.False(ok)
-	assert.Greater(cxn.sessManager.trustedPool.sus.Suspended(ts.URL), 0)
+	assert.Contains(cxn.sessManager.trustedPool.breakers.circuits, ts.URL)
 
 	cxn = &rtmpConnection{
 		mid:         core.ManifestID("foo"),
@@ -580,7 +630,7 @@ func TestTranscodeSegment_RefreshSession(t *testing.T) {
 	assert.True(strings.Contains(err.Error(), "Could not get orchestrator"))
 	_, ok = cxn.sessManager.trustedPool.sessMap[ts.URL]
 	assert.False(ok)
-	assert.Greater(cxn.sessManager.trustedPool.sus.Suspended(ts.URL), 0)
+	assert.Contains(cxn.sessManager.trustedPool.breakers.circuits, ts.URL)
 
 	// Expired ticket params -> GetOrchestratorInfo -> Still Expired -> Error
 	cxn = &rtmpConnection{
@@ -617,7 +667,7 @@ func TestTranscodeSegment_RefreshSession(t *testing.T) {
 	assert.EqualError(err, pm.ErrTicketParamsExpired.Error())
 	_, ok = cxn.sessManager.trustedPool.sessMap[ts.URL]
 	assert.False(ok)
-	assert.Greater(cxn.sessManager.trustedPool.sus.Suspended(ts.URL), 0)
+	assert.Contains(cxn.sessManager.trustedPool.breakers.circuits, ts.URL)
 
 	// Expired ticket params -> GetOrchestratorInfo -> No Longer Expired -> Complete Session
 	cxn = &rtmpConnection{
@@ -701,8 +751,6 @@ func TestTranscodeSegment_SuspendOrchestrator(t *testing.T) {
 	sess := StubBroadcastSession(ts.URL)
 	sess.Params.Profiles = []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9}
 	bsm := bsmWithSessList([]*BroadcastSession{sess})
-	bsm.trustedPool.poolSize = 40
-	bsm.trustedPool.numOrchs = 8
 	cxn := &rtmpConnection{
 		mid:         core.ManifestID("foo"),
 		nonce:       7,
@@ -714,7 +762,8 @@ func TestTranscodeSegment_SuspendOrchestrator(t *testing.T) {
 	_, _, err = transcodeSegment(context.TODO(), cxn, &stream.HLSSegment{Data: []byte("dummy"), Duration: 2.0}, "dummy", nil, nil)
 
 	assert.EqualError(err, "OrchestratorBusy")
-	assert.Equal(bsm.trustedPool.sus.Suspended(ts.URL), bsm.trustedPool.poolSize/bsm.trustedPool.numOrchs)
+	// OrchestratorBusy opens the circuit right away
+	assert.Equal(circuitOpen, bsm.trustedPool.breakers.state(ts.URL))
 }
 
 func TestTranscodeSegment_CompleteSession(t *testing.T) {
@@ -797,6 +846,105 @@ func TestTranscodeSegment_CompleteSession(t *testing.T) {
 	assert.Zero(bsm.trustedPool.sel.Size())
 }
 
+func TestTranscodeSegment_Hedged(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	oldFraction := HedgeFraction
+	defer func() { HedgeFraction = oldFraction }()
+	HedgeFraction = 0.1
+
+	tr := &net.TranscodeResult{
+		Result: &net.TranscodeResult_Data{
+			Data: &net.TranscodeData{
+				Segments: []*net.TranscodedSegmentData{{Url: "test.flv"}},
+				Sig:      []byte("bar"),
+			},
+		},
+	}
+	buf, err := proto.Marshal(tr)
+	require.Nil(err)
+
+	// The primary only responds once its request is aborted
+	aborted := make(chan struct{})
+	slowTs, slowMux := stubTLSServer()
+	defer slowTs.Close()
+	slowMux.HandleFunc("/segment", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			close(aborted)
+		case <-time.After(5 * time.Second):
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf)
+	})
+	fastTs, fastMux := stubTLSServer()
+	defer fastTs.Close()
+	fastMux.HandleFunc("/segment", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf)
+	})
+
+	slowSess := StubBroadcastSession(slowTs.URL)
+	slowSess.Params.Profiles = []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9}
+	fastSess := StubBroadcastSession(fastTs.URL)
+	fastSess.Params.Profiles = []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9}
+	// LIFOSelector returns the last session first
+	bsm := bsmWithSessListExt([]*BroadcastSession{fastSess, slowSess}, nil, true)
+	cxn := &rtmpConnection{
+		mid:         core.ManifestID("foo"),
+		nonce:       7,
+		pl:          &stubPlaylistManager{manifestID: core.ManifestID("foo")},
+		profile:     &ffmpeg.P144p30fps16x9,
+		sessManager: bsm,
+	}
+
+	_, info, err := transcodeSegment(context.TODO(), cxn, &stream.HLSSegment{Data: []byte("dummy"), Duration: 1.0}, "dummy", nil, nil)
+	assert.Nil(err)
+	assert.Equal(fastTs.URL, info.Orchestrator.TranscoderUri)
+
+	// The slow submission is aborted and its session is given back to the pool without being suspended
+	select {
+	case <-aborted:
+	case <-time.After(time.Second):
+		assert.Fail("primary submission was not aborted")
+	}
+	assert.Eventually(func() bool {
+		slowSess.lock.RLock()
+		defer slowSess.lock.RUnlock()
+		return len(slowSess.SegsInFlight) == 0
+	}, time.Second, 10*time.Millisecond)
+	assert.Zero(bsm.trustedPool.breakers.Suspended(slowTs.URL))
+	assert.Contains(bsm.trustedPool.sessMap, slowTs.URL)
+	assert.Contains(bsm.trustedPool.sessMap, fastTs.URL)
+
+	// Segments returned within the hedging delay are not hedged
+	HedgeFraction = 100
+	cxn.sessManager = bsmWithSessListExt([]*BroadcastSession{slowSess, fastSess}, nil, true)
+	_, info, err = transcodeSegment(context.TODO(), cxn, &stream.HLSSegment{Data: []byte("dummy"), Duration: 1.0}, "dummy", nil, nil)
+	assert.Nil(err)
+	assert.Equal(fastTs.URL, info.Orchestrator.TranscoderUri)
+}
+
+func TestSelectBackupSession(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := stubPoolExt(3)
+	sessList := pool.sessList()
+	primary := sessList[2]
+	pool.breakers.recordFailure("transcoder2", core.ErrOrchBusy)
+
+	// The primary and the sessions whose circuit is not closed are skipped, but given back to the selector
+	backup := pool.selectBackupSession(context.TODO(), primary)
+	assert.Equal("transcoder1", backup.Transcoder())
+	assert.Equal(2, pool.sel.Size())
+	assert.Contains(pool.sessList(), primary)
+	assert.Contains(pool.sessMap, "transcoder2")
+
+	assert.Nil(pool.selectBackupSession(context.TODO(), primary))
+	assert.Equal(2, pool.sel.Size())
+}
+
 func TestProcessSegment_MaxAttempts(t *testing.T) {
 	assert := assert.New(t)
 
@@ -1479,7 +1627,7 @@ func TestDownloadSegError_SuspendAndRemove(t *testing.T) {
 	assert.EqualError(err, "some error")
 	_, ok := cxn.sessManager.trustedPool.sessMap[sess.OrchestratorInfo.GetTranscoder()]
 	assert.False(ok)
-	assert.Greater(cxn.sessManager.trustedPool.sus.Suspended(sess.OrchestratorInfo.GetTranscoder()), 0)
+	assert.Contains(cxn.sessManager.trustedPool.breakers.circuits, sess.OrchestratorInfo.GetTranscoder())
 }
 
 func TestRefreshSession(t *testing.T) {
@@ -1699,6 +1847,28 @@ func TestProcessSegment_VideoFormat(t *testing.T) {
 	assert.Equal([]string{"P240p30fps16x9/0.ts"}, orchOS.saved)
 	assert.Equal([]string{"P240p30fps16x9/0.ts", "P144p30fps16x9/0.ts"}, bcastOS.saved)
 	assert.Equal("saved_P240p30fps16x9/0.ts", seg.Name)
+
+	// Check fragmented MP4, which is saved without its init segment. Reset OS for simplicity
+	bcastOS = &stubOSSession{host: "test://broad.com", external: true}
+	orchOS = &stubOSSession{host: "test://orch.com"}
+	sess.BroadcasterOS = bcastOS
+	sess.OrchestratorOS = orchOS
+	pl := &stubPlaylistManager{os: bcastOS}
+	cxn.pl = pl
+	cxn.profile.Format = ffmpeg.FormatMPEGTS
+	for i := range sess.Params.Profiles {
+		sess.Params.Profiles[i].Format = common.FormatFMP4
+	}
+	cxn.sessManager = bsmWithSessList([]*BroadcastSession{sess})
+	downloadSeg = func(ctx context.Context, url string) ([]byte, error) {
+		return []byte{0, 0, 0, 8, 'f', 't', 'y', 'p', 0, 0, 0, 8, 'm', 'o', 'o', 'v', 0, 0, 0, 8, 'm', 'o', 'o', 'f'}, nil
+	}
+
+	_, err = processSegment(context.Background(), cxn, seg, nil)
+
+	assert.Nil(err)
+	assert.Equal([]string{"P240p30fps16x9/0.ts", "P144p30fps16x9/0.m4s"}, bcastOS.saved)
+	assert.Equal("saved_P144p30fps16x9/0.m4s", pl.uri)
 }
 
 func TestProcessSegment_CheckDuration(t *testing.T) {
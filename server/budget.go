@@ -0,0 +1,335 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/clog"
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/core"
+)
+
+const (
+	// BudgetActionStop stops the stream once a budget is exhausted
+	BudgetActionStop = "stop"
+	// BudgetActionSource stops transcoding once a budget is exhausted, only the source rendition is published
+	BudgetActionSource = "source"
+	// BudgetActionWebhook keeps transcoding once a budget is exhausted, only the budget webhook is notified
+	BudgetActionWebhook = "webhook"
+)
+
+const (
+	budgetScopeStream  = "stream"
+	budgetScopeAccount = "account"
+	budgetScopeDaily   = "daily"
+)
+
+const budgetWebhookTimeout = 5 * time.Second
+
+// The spending of a stream is kept for streamSpendTTL after its last segment, so that a stream that
+// reconnects is still capped by its budget. The spending is written to the DB every budgetFlushInterval
+var streamSpendTTL = 24 * time.Hour
+var budgetFlushInterval = 30 * time.Second
+
+var errBudgetExhausted = errors.New("budget exhausted")
+
+// Budgets enforces the spending budgets of the gateway. Spending is not tracked if it is nil
+var Budgets *BudgetTracker
+
+// BudgetTracker tracks the fees spent on transcoding per stream, per account and per day.
+// Streams are identified by their external stream ID, or by their manifest ID if they have none.
+// Account and daily spending is reset at midnight UTC. The spending is kept in memory and
+// periodically written to the DB, so that it survives restarts of the gateway
+type BudgetTracker struct {
+	mu sync.Mutex
+	db *common.DB
+
+	// nil budgets are unlimited
+	streamBudget  *big.Rat
+	accountBudget *big.Rat
+	dailyBudget   *big.Rat
+
+	action     string
+	webhookURL *url.URL
+
+	// spending, and whether the exhaustion of the budget was notified already
+	streams          map[string]*big.Rat
+	streamsUpdated   map[string]time.Time
+	accounts         map[string]*big.Rat
+	daily            *big.Rat
+	notifiedStreams  map[string]bool
+	notifiedAccounts map[string]bool
+	notifiedDaily    bool
+	day              string
+
+	// spending updated since the last flush
+	dirty map[budgetKey]bool
+
+	now func() time.Time
+}
+
+type budgetKey struct {
+	scope string
+	id    string
+}
+
+type budgetWebhookEvent struct {
+	Event      string `json:"event"`
+	Scope      string `json:"scope"`
+	ManifestID string `json:"manifestID"`
+	AccountID  string `json:"accountID,omitempty"`
+	Spent      string `json:"spent"`
+	Budget     string `json:"budget"`
+}
+
+// NewBudgetTracker returns a BudgetTracker with the given budgets in wei, a nil budget is unlimited.
+// The webhook is notified whenever a budget is exhausted if webhookURL is not nil
+func NewBudgetTracker(streamBudget, accountBudget, dailyBudget *big.Rat, action string, webhookURL *url.URL) *BudgetTracker {
+	return &BudgetTracker{
+		streamBudget:     streamBudget,
+		accountBudget:    accountBudget,
+		dailyBudget:      dailyBudget,
+		action:           action,
+		webhookURL:       webhookURL,
+		streams:          make(map[string]*big.Rat),
+		streamsUpdated:   make(map[string]time.Time),
+		accounts:         make(map[string]*big.Rat),
+		daily:            new(big.Rat),
+		notifiedStreams:  make(map[string]bool),
+		notifiedAccounts: make(map[string]bool),
+		dirty:            make(map[budgetKey]bool),
+		now:              time.Now,
+	}
+}
+
+// Load loads the spending persisted in the DB and uses the DB to persist the spending from now on
+func (bt *BudgetTracker) Load(db *common.DB) error {
+	spends, err := db.SelectBudgetSpends(bt.now().Add(-streamSpendTTL).Unix())
+	if err != nil {
+		return err
+	}
+
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+
+	bt.db = db
+	bt.rollover()
+	for _, spend := range spends {
+		// Spending already recorded in this run is more accurate than the persisted spending
+		switch {
+		case spend.Scope == budgetScopeStream && bt.streams[spend.ID] == nil:
+			bt.streams[spend.ID] = spend.Spent
+			bt.streamsUpdated[spend.ID] = time.Unix(spend.UpdatedAt, 0)
+		case spend.Scope == budgetScopeAccount && spend.Day == bt.day && bt.accounts[spend.ID] == nil:
+			bt.accounts[spend.ID] = spend.Spent
+		case spend.Scope == budgetScopeDaily && spend.Day == bt.day && !bt.dirty[budgetKey{scope: budgetScopeDaily}]:
+			bt.daily = spend.Spent
+		}
+	}
+	return nil
+}
+
+// Run writes the updated spending to the DB every budgetFlushInterval until the context is done
+func (bt *BudgetTracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(budgetFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			bt.Flush()
+		case <-ctx.Done():
+			bt.Flush()
+			return
+		}
+	}
+}
+
+// Flush forgets the spending of streams that expired and writes the spending updated since the last flush to the DB
+func (bt *BudgetTracker) Flush() {
+	if bt == nil {
+		return
+	}
+
+	bt.mu.Lock()
+	now := bt.now()
+	bt.rollover()
+	for id, updated := range bt.streamsUpdated {
+		if now.Sub(updated) >= streamSpendTTL {
+			delete(bt.streams, id)
+			delete(bt.streamsUpdated, id)
+			delete(bt.notifiedStreams, id)
+			delete(bt.dirty, budgetKey{scope: budgetScopeStream, id: id})
+		}
+	}
+	db := bt.db
+	if db == nil {
+		bt.mu.Unlock()
+		return
+	}
+	var spends []common.DBBudgetSpend
+	for key := range bt.dirty {
+		spend := common.DBBudgetSpend{Scope: key.scope, ID: key.id, UpdatedAt: now.Unix()}
+		switch key.scope {
+		case budgetScopeStream:
+			spend.Spent = bt.streams[key.id]
+			spend.UpdatedAt = bt.streamsUpdated[key.id].Unix()
+		case budgetScopeAccount:
+			spend.Spent, spend.Day = bt.accounts[key.id], bt.day
+		case budgetScopeDaily:
+			spend.Spent, spend.Day = bt.daily, bt.day
+		}
+		spend.Spent = new(big.Rat).Set(spend.Spent)
+		spends = append(spends, spend)
+	}
+	bt.dirty = make(map[budgetKey]bool)
+	bt.mu.Unlock()
+
+	for i := range spends {
+		if err := db.UpdateBudgetSpend(&spends[i]); err != nil {
+			glog.Errorf("Error persisting budget spending scope=%s id=%s err=%q", spends[i].Scope, spends[i].ID, err)
+		}
+	}
+	if err := db.DeleteBudgetSpends(now.Add(-streamSpendTTL).Unix()); err != nil {
+		glog.Errorf("Error deleting expired budget spending err=%q", err)
+	}
+}
+
+// Record adds the fee of a segment submitted for the stream to the spending
+func (bt *BudgetTracker) Record(params *core.StreamParameters, fee *big.Rat) {
+	if bt == nil || params == nil || fee == nil {
+		return
+	}
+
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+
+	bt.rollover()
+	id := budgetStreamID(params)
+	addSpend(bt.streams, id, fee)
+	bt.streamsUpdated[id] = bt.now()
+	bt.dirty[budgetKey{scope: budgetScopeStream, id: id}] = true
+	if params.AccountID != "" {
+		addSpend(bt.accounts, params.AccountID, fee)
+		bt.dirty[budgetKey{scope: budgetScopeAccount, id: params.AccountID}] = true
+	}
+	bt.daily.Add(bt.daily, fee)
+	bt.dirty[budgetKey{scope: budgetScopeDaily}] = true
+}
+
+// Exhausted returns the scope of the first budget of the stream that is exhausted, if any.
+// The budget webhook is notified once per exhausted budget
+func (bt *BudgetTracker) Exhausted(ctx context.Context, params *core.StreamParameters) (string, bool) {
+	if bt == nil || params == nil {
+		return "", false
+	}
+
+	bt.mu.Lock()
+	defer bt.mu.Unlock()
+
+	bt.rollover()
+	event := budgetWebhookEvent{
+		Event:      "budgetExhausted",
+		ManifestID: string(params.ManifestID),
+		AccountID:  params.AccountID,
+	}
+	id := budgetStreamID(params)
+	var notified bool
+	switch {
+	case exceeds(bt.streams[id], bt.streamBudget):
+		event.Scope, event.Spent, event.Budget = budgetScopeStream, weiString(bt.streams[id]), weiString(bt.streamBudget)
+		notified, bt.notifiedStreams[id] = bt.notifiedStreams[id], true
+	case params.AccountID != "" && exceeds(bt.accounts[params.AccountID], bt.accountBudget):
+		event.Scope, event.Spent, event.Budget = budgetScopeAccount, weiString(bt.accounts[params.AccountID]), weiString(bt.accountBudget)
+		notified, bt.notifiedAccounts[params.AccountID] = bt.notifiedAccounts[params.AccountID], true
+	case exceeds(bt.daily, bt.dailyBudget):
+		event.Scope, event.Spent, event.Budget = budgetScopeDaily, weiString(bt.daily), weiString(bt.dailyBudget)
+		notified, bt.notifiedDaily = bt.notifiedDaily, true
+	default:
+		return "", false
+	}
+
+	if !notified {
+		clog.Warningf(ctx, "Budget exhausted scope=%s accountID=%s spent=%s budget=%s action=%s", event.Scope, event.AccountID, event.Spent, event.Budget, bt.action)
+		if bt.webhookURL != nil {
+			go notifyBudgetWebhook(ctx, bt.webhookURL, event)
+		}
+	}
+	return event.Scope, true
+}
+
+// Action returns the behaviour configured for exhausted budgets
+func (bt *BudgetTracker) Action() string {
+	return bt.action
+}
+
+// the caller needs to ensure bt.mu is acquired before calling this
+func (bt *BudgetTracker) rollover() {
+	day := bt.now().UTC().Format("2006-01-02")
+	if day == bt.day {
+		return
+	}
+	bt.day = day
+	bt.accounts = make(map[string]*big.Rat)
+	bt.daily = new(big.Rat)
+	bt.notifiedAccounts = make(map[string]bool)
+	bt.notifiedDaily = false
+	for key := range bt.dirty {
+		if key.scope != budgetScopeStream {
+			delete(bt.dirty, key)
+		}
+	}
+}
+
+// budgetStreamID returns the ID that the spending of the stream is tracked by, which is the same when the stream reconnects
+func budgetStreamID(params *core.StreamParameters) string {
+	if params.ExternalStreamID != "" {
+		return params.ExternalStreamID
+	}
+	return string(params.ManifestID)
+}
+
+func addSpend(spend map[string]*big.Rat, key string, fee *big.Rat) {
+	s, ok := spend[key]
+	if !ok {
+		s = new(big.Rat)
+		spend[key] = s
+	}
+	s.Add(s, fee)
+}
+
+func exceeds(spent, budget *big.Rat) bool {
+	return spent != nil && budget != nil && spent.Cmp(budget) >= 0
+}
+
+func weiString(r *big.Rat) string {
+	return r.FloatString(0)
+}
+
+func notifyBudgetWebhook(ctx context.Context, webhookURL *url.URL, event budgetWebhookEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		clog.Errorf(ctx, "Error marshalling budget webhook event err=%q", err)
+		return
+	}
+	client := &http.Client{Timeout: budgetWebhookTimeout}
+	resp, err := client.Post(webhookURL.String(), "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		clog.Errorf(ctx, "Error notifying budget webhook url=%s err=%q", webhookURL.Redacted(), err)
+		return
+	}
+	rbody, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		clog.Errorf(ctx, "Error notifying budget webhook url=%s err=%q", webhookURL.Redacted(), fmt.Sprintf("status=%d error=%s", resp.StatusCode, string(rbody)))
+	}
+}
@@ -0,0 +1,224 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/lpms/ffmpeg"
+	"github.com/livepeer/lpms/stream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudgetTracker_Exhausted(t *testing.T) {
+	assert := assert.New(t)
+
+	bt := NewBudgetTracker(big.NewRat(100, 1), big.NewRat(150, 1), big.NewRat(200, 1), BudgetActionStop, nil)
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	bt.now = func() time.Time { return now }
+
+	stream1 := &core.StreamParameters{ManifestID: "stream1", AccountID: "account1"}
+	stream2 := &core.StreamParameters{ManifestID: "stream2", AccountID: "account1"}
+	stream3 := &core.StreamParameters{ManifestID: "stream3"}
+
+	_, exhausted := bt.Exhausted(context.TODO(), stream1)
+	assert.False(exhausted)
+
+	// Stream budget
+	bt.Record(stream1, big.NewRat(60, 1))
+	bt.Record(stream1, big.NewRat(40, 1))
+	scope, exhausted := bt.Exhausted(context.TODO(), stream1)
+	assert.True(exhausted)
+	assert.Equal(budgetScopeStream, scope)
+	_, exhausted = bt.Exhausted(context.TODO(), stream2)
+	assert.False(exhausted)
+
+	// Account budget is shared by the streams of the account
+	bt.Record(stream2, big.NewRat(50, 1))
+	scope, exhausted = bt.Exhausted(context.TODO(), stream2)
+	assert.True(exhausted)
+	assert.Equal(budgetScopeAccount, scope)
+	_, exhausted = bt.Exhausted(context.TODO(), stream3)
+	assert.False(exhausted)
+
+	// Daily budget is shared by all streams
+	bt.Record(stream3, big.NewRat(50, 1))
+	scope, exhausted = bt.Exhausted(context.TODO(), stream3)
+	assert.True(exhausted)
+	assert.Equal(budgetScopeDaily, scope)
+
+	// Account and daily spending is reset the next day, stream spending is not
+	now = now.Add(24 * time.Hour)
+	_, exhausted = bt.Exhausted(context.TODO(), stream2)
+	assert.False(exhausted)
+	_, exhausted = bt.Exhausted(context.TODO(), stream3)
+	assert.False(exhausted)
+	scope, exhausted = bt.Exhausted(context.TODO(), stream1)
+	assert.True(exhausted)
+	assert.Equal(budgetScopeStream, scope)
+
+	// Stream spending is kept when the stream reconnects with the same external stream ID
+	reconnected := &core.StreamParameters{ManifestID: "stream4", ExternalStreamID: "stream1"}
+	scope, exhausted = bt.Exhausted(context.TODO(), reconnected)
+	assert.True(exhausted)
+	assert.Equal(budgetScopeStream, scope)
+
+	// Stream spending is forgotten once the stream had no segments for streamSpendTTL
+	now = now.Add(streamSpendTTL)
+	bt.Flush()
+	_, exhausted = bt.Exhausted(context.TODO(), stream1)
+	assert.False(exhausted)
+
+	// Segments without a fee are not tracked
+	bt.Record(stream1, nil)
+	assert.NotContains(bt.streams, "stream1")
+
+	// Spending is not tracked without a tracker
+	var nilTracker *BudgetTracker
+	nilTracker.Record(stream1, big.NewRat(1000, 1))
+	_, exhausted = nilTracker.Exhausted(context.TODO(), stream1)
+	assert.False(exhausted)
+}
+
+func TestBudgetTracker_Persistence(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dbh, dbraw, err := common.TempDB(t)
+	require.Nil(err)
+	defer dbh.Close()
+	defer dbraw.Close()
+
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	newTracker := func() *BudgetTracker {
+		bt := NewBudgetTracker(big.NewRat(100, 1), big.NewRat(150, 1), big.NewRat(200, 1), BudgetActionStop, nil)
+		bt.now = func() time.Time { return now }
+		require.Nil(bt.Load(dbh))
+		return bt
+	}
+	stream1 := &core.StreamParameters{ManifestID: "stream1", AccountID: "account1"}
+	stream2 := &core.StreamParameters{ManifestID: "stream2", AccountID: "account1"}
+	stream3 := &core.StreamParameters{ManifestID: "stream3"}
+
+	bt := newTracker()
+	bt.Record(stream1, big.NewRat(100, 1))
+	bt.Record(stream2, big.NewRat(50, 1))
+	bt.Record(stream3, big.NewRat(50, 1))
+	bt.Flush()
+
+	// The spending survives restarts
+	bt = newTracker()
+	scope, exhausted := bt.Exhausted(context.TODO(), stream1)
+	assert.True(exhausted)
+	assert.Equal(budgetScopeStream, scope)
+	scope, exhausted = bt.Exhausted(context.TODO(), stream2)
+	assert.True(exhausted)
+	assert.Equal(budgetScopeAccount, scope)
+	scope, exhausted = bt.Exhausted(context.TODO(), stream3)
+	assert.True(exhausted)
+	assert.Equal(budgetScopeDaily, scope)
+
+	// Account and daily spending of past days is not loaded
+	now = now.Add(24 * time.Hour)
+	bt = newTracker()
+	_, exhausted = bt.Exhausted(context.TODO(), stream3)
+	assert.False(exhausted)
+	scope, exhausted = bt.Exhausted(context.TODO(), stream1)
+	assert.True(exhausted)
+	assert.Equal(budgetScopeStream, scope)
+
+	// Expired stream spending is deleted from the DB
+	now = now.Add(time.Second)
+	bt.Flush()
+	spends, err := dbh.SelectBudgetSpends(0)
+	require.Nil(err)
+	assert.Empty(spends)
+}
+
+func TestBudgetTracker_Webhook(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	events := make(chan budgetWebhookEvent, 2)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var event budgetWebhookEvent
+		assert.Nil(json.Unmarshal(body, &event))
+		events <- event
+	}))
+	defer ts.Close()
+	webhookURL, err := url.Parse(ts.URL)
+	require.Nil(err)
+
+	bt := NewBudgetTracker(big.NewRat(100, 1), nil, nil, BudgetActionWebhook, webhookURL)
+	params := &core.StreamParameters{ManifestID: "stream1", AccountID: "account1"}
+	bt.Record(params, big.NewRat(101, 1))
+	_, exhausted := bt.Exhausted(context.TODO(), params)
+	assert.True(exhausted)
+
+	select {
+	case event := <-events:
+		expected := budgetWebhookEvent{Event: "budgetExhausted", Scope: budgetScopeStream, ManifestID: "stream1", AccountID: "account1", Spent: "101", Budget: "100"}
+		assert.Equal(expected, event)
+	case <-time.After(time.Second):
+		assert.Fail("budget webhook not notified")
+	}
+
+	// The webhook is notified only once per exhausted budget
+	_, exhausted = bt.Exhausted(context.TODO(), params)
+	assert.True(exhausted)
+	select {
+	case <-events:
+		assert.Fail("budget webhook notified twice")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestProcessSegment_BudgetExhausted(t *testing.T) {
+	assert := assert.New(t)
+
+	oldBudgets := Budgets
+	defer func() { Budgets = oldBudgets }()
+
+	transcodeCalls := 0
+	ts, mux := stubTLSServer()
+	defer ts.Close()
+	mux.HandleFunc("/segment", func(w http.ResponseWriter, r *http.Request) {
+		transcodeCalls++
+	})
+	params := &core.StreamParameters{ManifestID: "stream1", Profiles: []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9}}
+	strm := stream.NewBasicRTMPVideoStream(params)
+	cxn := &rtmpConnection{
+		mid:         params.ManifestID,
+		stream:      strm,
+		params:      params,
+		profile:     &ffmpeg.VideoProfile{Name: "unused"},
+		sessManager: bsmWithSessList([]*BroadcastSession{StubBroadcastSession(ts.URL)}),
+		pl:          &stubPlaylistManager{os: &stubOSSession{}},
+	}
+	seg := &stream.HLSSegment{}
+
+	// Only the source rendition is published
+	Budgets = NewBudgetTracker(big.NewRat(100, 1), nil, nil, BudgetActionSource, nil)
+	Budgets.Record(params, big.NewRat(100, 1))
+	urls, err := processSegment(context.Background(), cxn, seg, nil)
+	assert.Nil(err)
+	assert.Empty(urls)
+	assert.Zero(transcodeCalls)
+
+	// The stream is stopped
+	Budgets = NewBudgetTracker(big.NewRat(100, 1), nil, nil, BudgetActionStop, nil)
+	Budgets.Record(params, big.NewRat(100, 1))
+	_, err = processSegment(context.Background(), cxn, seg, nil)
+	assert.Equal(errBudgetExhausted, err)
+	assert.Zero(transcodeCalls)
+}
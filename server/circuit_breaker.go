@@ -0,0 +1,309 @@
+package server
+
+import (
+	"context"
+	"errors"
+	gonet "net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// An orchestrator's circuit opens once the weight of its consecutive failures reaches circuitFailureThreshold.
+// It stays open for circuitOpenDuration, doubled for every consecutive trip up to circuitMaxOpenDuration, and
+// is then half-open: up to circuitHalfOpenProbes probe segments are sent to the orchestrator and it is fully
+// re-admitted once all of them succeeded. A probe without an outcome frees its slot after circuitProbeTimeout
+var circuitFailureThreshold = 3
+var circuitOpenDuration = 30 * time.Second
+var circuitMaxOpenDuration = 10 * time.Minute
+var circuitHalfOpenProbes = 3
+var circuitProbeTimeout = 1 * time.Minute
+
+var errVerificationMismatch = errors.New("transcoded results do not match the trusted results")
+
+// orchCircuitBreakers is shared by all streams of the gateway so that an orchestrator failing for one stream
+// is avoided by the others
+var orchCircuitBreakers = newCircuitBreakers()
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitClosed:
+		return "closed"
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+type circuit struct {
+	state          circuitState
+	failures       int // weight of the consecutive failures while closed
+	trips          int // number of times the circuit opened since the orchestrator was last fully admitted
+	openUntil      time.Time
+	probes         []time.Time // start times of the probe segments in flight while half-open
+	probeSuccesses int
+	lastErr        string
+}
+
+// circuitBreakers tracks a circuit per orchestrator service URI. Orchestrators without a circuit are closed
+type circuitBreakers struct {
+	mu       sync.Mutex
+	circuits map[string]*circuit
+
+	now func() time.Time
+}
+
+func newCircuitBreakers() *circuitBreakers {
+	return &circuitBreakers{
+		circuits: make(map[string]*circuit),
+		now:      time.Now,
+	}
+}
+
+// failureWeight classifies the error returned for a segment by how much it counts towards opening the circuit
+func failureWeight(err error) int {
+	var netErr gonet.Error
+	switch {
+	case err == nil:
+		// empty response
+		return 1
+	case isNonRetryableError(err):
+		// the segment itself cannot be transcoded, which is not the orchestrator's fault
+		return 0
+	case errors.Is(err, errVerificationMismatch) || shouldStopSession(err):
+		return circuitFailureThreshold
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) ||
+		errors.As(err, &netErr) && netErr.Timeout():
+		return 2
+	}
+	return 1
+}
+
+// Suspended returns a non-zero value if segments should not be sent to the orchestrator.
+// 'orch' is the service URI of the orchestrator
+// The value returned is the suspension penalty associated with the orchestrator whereby lower is better
+func (cb *circuitBreakers) Suspended(orch string) int {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	c, ok := cb.circuits[orch]
+	if !ok {
+		return 0
+	}
+	now := cb.now()
+	cb.update(c, now)
+	switch c.state {
+	case circuitOpen:
+		return int(c.openUntil.Sub(now)/time.Second) + 1
+	case circuitHalfOpen:
+		if !c.canProbe() {
+			return 1
+		}
+	}
+	return 0
+}
+
+// allow returns true if a segment can be sent to the orchestrator; segments sent while the circuit is
+// half-open are counted as probes
+func (cb *circuitBreakers) allow(orch string) bool {
+	allowed, _ := cb.acquire(orch)
+	return allowed
+}
+
+// acquire is like allow, and also returns true if the segment holds a probe slot of the half-open circuit.
+// The slot is freed once the outcome of the segment is recorded, or by release if there is none
+func (cb *circuitBreakers) acquire(orch string) (bool, bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	c, ok := cb.circuits[orch]
+	if !ok {
+		return true, false
+	}
+	now := cb.now()
+	cb.update(c, now)
+	switch c.state {
+	case circuitOpen:
+		return false, false
+	case circuitHalfOpen:
+		if !c.canProbe() {
+			return false, false
+		}
+		c.probes = append(c.probes, now)
+		return true, true
+	}
+	return true, false
+}
+
+// release frees a probe slot of a segment whose outcome will not be recorded
+func (cb *circuitBreakers) release(orch string) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	c, ok := cb.circuits[orch]
+	if !ok {
+		return
+	}
+	cb.update(c, cb.now())
+	if c.state == circuitHalfOpen {
+		c.popProbe()
+	}
+}
+
+func (cb *circuitBreakers) recordSuccess(orch string) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	c, ok := cb.circuits[orch]
+	if !ok {
+		return
+	}
+	cb.update(c, cb.now())
+	switch c.state {
+	case circuitClosed:
+		delete(cb.circuits, orch)
+	case circuitHalfOpen:
+		c.popProbe()
+		c.probeSuccesses++
+		if c.probeSuccesses >= circuitHalfOpenProbes {
+			delete(cb.circuits, orch)
+		}
+	}
+	// A success while open is from a segment submitted before the circuit opened
+}
+
+func (cb *circuitBreakers) recordFailure(orch string, err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	weight := failureWeight(err)
+	c, ok := cb.circuits[orch]
+	if !ok {
+		if weight == 0 {
+			return
+		}
+		c = &circuit{}
+		cb.circuits[orch] = c
+	}
+	now := cb.now()
+	cb.update(c, now)
+	if err != nil {
+		c.lastErr = err.Error()
+	}
+	switch c.state {
+	case circuitClosed:
+		c.failures += weight
+		if c.failures >= circuitFailureThreshold {
+			cb.trip(c, now)
+		}
+	case circuitHalfOpen:
+		if weight == 0 {
+			c.popProbe()
+			return
+		}
+		cb.trip(c, now)
+	}
+}
+
+// state returns the current state of the orchestrator's circuit
+func (cb *circuitBreakers) state(orch string) circuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	c, ok := cb.circuits[orch]
+	if !ok {
+		return circuitClosed
+	}
+	cb.update(c, cb.now())
+	return c.state
+}
+
+type circuitStatus struct {
+	Orchestrator   string `json:"orchestrator"`
+	State          string `json:"state"`
+	Failures       int    `json:"failures"`
+	Trips          int    `json:"trips"`
+	OpenUntil      int64  `json:"openUntil,omitempty"`
+	ProbesInFlight int    `json:"probesInFlight"`
+	ProbeSuccesses int    `json:"probeSuccesses"`
+	LastError      string `json:"lastError,omitempty"`
+}
+
+// status returns the circuits of all orchestrators which are not fully admitted, sorted by service URI
+func (cb *circuitBreakers) status() []circuitStatus {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	now := cb.now()
+	res := make([]circuitStatus, 0, len(cb.circuits))
+	for orch, c := range cb.circuits {
+		cb.update(c, now)
+		s := circuitStatus{
+			Orchestrator:   orch,
+			State:          c.state.String(),
+			Failures:       c.failures,
+			Trips:          c.trips,
+			ProbesInFlight: len(c.probes),
+			ProbeSuccesses: c.probeSuccesses,
+			LastError:      c.lastErr,
+		}
+		if c.state == circuitOpen {
+			s.OpenUntil = c.openUntil.Unix()
+		}
+		res = append(res, s)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Orchestrator < res[j].Orchestrator })
+	return res
+}
+
+// the caller needs to ensure cb.mu is acquired before calling this
+func (cb *circuitBreakers) update(c *circuit, now time.Time) {
+	if c.state == circuitOpen && !now.Before(c.openUntil) {
+		c.state = circuitHalfOpen
+		c.probes = nil
+		c.probeSuccesses = 0
+	}
+	if c.state == circuitHalfOpen {
+		// forget probes whose outcome was never recorded
+		for len(c.probes) > 0 && now.Sub(c.probes[0]) > circuitProbeTimeout {
+			c.probes = c.probes[1:]
+		}
+	}
+}
+
+// the caller needs to ensure cb.mu is acquired before calling this
+func (cb *circuitBreakers) trip(c *circuit, now time.Time) {
+	openFor := circuitOpenDuration
+	for i := 0; i < c.trips && openFor < circuitMaxOpenDuration; i++ {
+		openFor *= 2
+	}
+	if openFor > circuitMaxOpenDuration {
+		openFor = circuitMaxOpenDuration
+	}
+	c.trips++
+	c.state = circuitOpen
+	c.openUntil = now.Add(openFor)
+	c.failures = 0
+	c.probes = nil
+	c.probeSuccesses = 0
+}
+
+func (c *circuit) canProbe() bool {
+	return c.probeSuccesses+len(c.probes) < circuitHalfOpenProbes
+}
+
+func (c *circuit) popProbe() {
+	if len(c.probes) > 0 {
+		c.probes = c.probes[1:]
+	}
+}
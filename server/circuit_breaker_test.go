@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailureWeight(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(1, failureWeight(nil))
+	assert.Equal(1, failureWeight(errors.New("some error")))
+	assert.Equal(2, failureWeight(fmt.Errorf("header timeout: %w", context.DeadlineExceeded)))
+	assert.Equal(circuitFailureThreshold, failureWeight(core.ErrOrchBusy))
+	assert.Equal(circuitFailureThreshold, failureWeight(errors.New("dial tcp 127.0.0.1:8935: connect: connection refused")))
+	assert.Equal(circuitFailureThreshold, failureWeight(errVerificationMismatch))
+	assert.Equal(0, failureWeight(fmt.Errorf("wrapped: %w", maxTranscodeAttempts)))
+}
+
+func TestCircuitBreakers_Trip(t *testing.T) {
+	assert := assert.New(t)
+
+	cb := newCircuitBreakers()
+	now := time.Now()
+	cb.now = func() time.Time { return now }
+
+	assert.Equal(circuitClosed, cb.state("foo"))
+	assert.True(cb.allow("foo"))
+	assert.Zero(cb.Suspended("foo"))
+
+	// Failures below the threshold keep the circuit closed and a success resets them
+	cb.recordFailure("foo", errors.New("some error"))
+	cb.recordFailure("foo", errors.New("some error"))
+	assert.Equal(circuitClosed, cb.state("foo"))
+	cb.recordSuccess("foo")
+	assert.Empty(cb.status())
+
+	// Non-retryable errors do not count
+	cb.recordFailure("foo", maxTranscodeAttempts)
+	assert.Empty(cb.status())
+
+	cb.recordFailure("foo", errors.New("some error"))
+	cb.recordFailure("foo", context.DeadlineExceeded)
+	assert.Equal(circuitOpen, cb.state("foo"))
+	assert.False(cb.allow("foo"))
+	assert.Equal(int(circuitOpenDuration/time.Second)+1, cb.Suspended("foo"))
+
+	// Other orchestrators are not affected
+	assert.True(cb.allow("bar"))
+
+	// Stop session errors open the circuit right away
+	cb.recordFailure("bar", core.ErrOrchBusy)
+	assert.Equal(circuitOpen, cb.state("bar"))
+}
+
+func TestCircuitBreakers_HalfOpen(t *testing.T) {
+	assert := assert.New(t)
+
+	oldProbes := circuitHalfOpenProbes
+	defer func() { circuitHalfOpenProbes = oldProbes }()
+	circuitHalfOpenProbes = 2
+
+	cb := newCircuitBreakers()
+	now := time.Now()
+	cb.now = func() time.Time { return now }
+
+	cb.recordFailure("foo", core.ErrOrchBusy)
+	assert.Equal(circuitOpen, cb.state("foo"))
+
+	// The circuit is half-open once the open duration elapsed and only lets through a limited number of probes
+	now = now.Add(circuitOpenDuration)
+	assert.Equal(circuitHalfOpen, cb.state("foo"))
+	assert.Zero(cb.Suspended("foo"))
+	assert.True(cb.allow("foo"))
+	assert.True(cb.allow("foo"))
+	assert.False(cb.allow("foo"))
+	assert.Equal(1, cb.Suspended("foo"))
+
+	// A failed probe opens the circuit again for twice as long
+	cb.recordFailure("foo", errors.New("some error"))
+	assert.Equal(circuitOpen, cb.state("foo"))
+	now = now.Add(circuitOpenDuration)
+	assert.Equal(circuitOpen, cb.state("foo"))
+	now = now.Add(circuitOpenDuration)
+	assert.Equal(circuitHalfOpen, cb.state("foo"))
+
+	// Probes without an outcome free their slot after a timeout
+	assert.True(cb.allow("foo"))
+	assert.True(cb.allow("foo"))
+	assert.False(cb.allow("foo"))
+	now = now.Add(circuitProbeTimeout + time.Second)
+	assert.True(cb.allow("foo"))
+	cb.recordSuccess("foo")
+	assert.Equal(circuitHalfOpen, cb.state("foo"))
+
+	// The orchestrator is fully re-admitted once all probes succeeded
+	assert.True(cb.allow("foo"))
+	cb.recordSuccess("foo")
+	assert.Equal(circuitClosed, cb.state("foo"))
+	assert.Empty(cb.status())
+}
+
+func TestCircuitBreakers_Release(t *testing.T) {
+	assert := assert.New(t)
+
+	cb := newCircuitBreakers()
+	now := time.Now()
+	cb.now = func() time.Time { return now }
+
+	allowed, probe := cb.acquire("foo")
+	assert.True(allowed)
+	assert.False(probe)
+
+	cb.recordFailure("foo", core.ErrOrchBusy)
+	now = now.Add(circuitOpenDuration)
+	for i := 0; i < circuitHalfOpenProbes; i++ {
+		allowed, probe = cb.acquire("foo")
+		assert.True(allowed)
+		assert.True(probe)
+	}
+	allowed, _ = cb.acquire("foo")
+	assert.False(allowed)
+
+	// Probe slots without an outcome are freed without waiting for the probe timeout
+	cb.release("foo")
+	allowed, probe = cb.acquire("foo")
+	assert.True(allowed)
+	assert.True(probe)
+	assert.Equal(circuitHalfOpen, cb.state("foo"))
+}
+
+func TestCircuitBreakers_MaxOpenDuration(t *testing.T) {
+	assert := assert.New(t)
+
+	cb := newCircuitBreakers()
+	now := time.Now()
+	cb.now = func() time.Time { return now }
+
+	for i := 0; i < 100; i++ {
+		cb.recordFailure("foo", core.ErrOrchBusy)
+		now = cb.circuits["foo"].openUntil
+		assert.Equal(circuitHalfOpen, cb.state("foo"))
+	}
+	cb.recordFailure("foo", core.ErrOrchBusy)
+	assert.Equal(now.Add(circuitMaxOpenDuration), cb.circuits["foo"].openUntil)
+}
+
+func TestSelectSessions_CircuitOpen(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := stubPool()
+	pool.breakers.recordFailure("transcoder2", core.ErrOrchBusy)
+
+	// The session of the orchestrator whose circuit is open is skipped and removed from the pool
+	sessions := pool.selectSessions(context.TODO(), 1)
+	assert.Len(sessions, 1)
+	assert.Equal("transcoder1", sessions[0].Transcoder())
+	assert.NotContains(pool.sessMap, "transcoder2")
+}
+
+func TestSelectSessions_HalfOpenProbe(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := stubPoolExt(1)
+	now := time.Now()
+	pool.breakers.now = func() time.Time { return now }
+	pool.breakers.recordFailure("transcoder1", core.ErrOrchBusy)
+	now = now.Add(circuitOpenDuration)
+
+	// The session newly taken from the selector holds a single probe slot, however many segments it is re-used for
+	sessions := pool.selectSessions(context.TODO(), 1)
+	assert.Len(sessions, 1)
+	sess := sessions[0]
+	sess.LatencyScore = 0.5
+	for i := 0; i < circuitHalfOpenProbes+1; i++ {
+		sessions = pool.selectSessions(context.TODO(), 1)
+		assert.Equal([]*BroadcastSession{sess}, sessions)
+	}
+	assert.Len(pool.breakers.circuits["transcoder1"].probes, 1)
+	assert.True(pool.probes[sess])
+
+	// The slot is freed once the session is given back without an outcome
+	pool.completeSession(sess)
+	assert.Empty(pool.breakers.circuits["transcoder1"].probes)
+	assert.Empty(pool.probes)
+
+	// Re-used sessions are dropped once the circuit opened again
+	pool.breakers.recordFailure("transcoder1", core.ErrOrchBusy)
+	assert.Empty(pool.selectSessions(context.TODO(), 1))
+	assert.NotContains(pool.sessMap, "transcoder1")
+}
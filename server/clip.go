@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/livepeer/go-livepeer/clog"
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-tools/drivers"
+	"github.com/livepeer/lpms/ffmpeg"
+	"github.com/livepeer/m3u8"
+)
+
+var errClipRange = errors.New("invalid clip range")
+var errClipEmpty = errors.New("no recorded segments in the clip")
+
+// Clips are at most maxClipDuration long, so that a request can't make the node read and trim a whole recording
+var maxClipDuration = 4 * time.Hour
+
+var trimSegment = core.TrimSegment
+
+// clipRequest is the range of a clip cut from a recording, either in media timestamps from the start of the recording
+// or in wall clock times
+type clipRequest struct {
+	track              string
+	start, end         time.Duration
+	startTime, endTime time.Time
+}
+
+// parseClipRequest reads the range of the clip from the `start` and `end` query parameters, in seconds of the
+// recording, or from `startTime` and `endTime`, in unix milliseconds. Clips are of the source unless `track` is set
+func parseClipRequest(q url.Values) (*clipRequest, error) {
+	c := &clipRequest{track: q.Get("track")}
+	if c.track == "" {
+		c.track = "source"
+	}
+	if q.Get("startTime") != "" || q.Get("endTime") != "" {
+		start, err := strconv.ParseInt(q.Get("startTime"), 10, 64)
+		if err != nil {
+			return nil, errClipRange
+		}
+		end, err := strconv.ParseInt(q.Get("endTime"), 10, 64)
+		if err != nil || end <= start || end-start > maxClipDuration.Milliseconds() {
+			return nil, errClipRange
+		}
+		c.startTime, c.endTime = time.UnixMilli(start), time.UnixMilli(end)
+		return c, nil
+	}
+	start, err := strconv.ParseFloat(q.Get("start"), 64)
+	if err != nil || math.IsNaN(start) || math.IsInf(start, 0) || start < 0 {
+		return nil, errClipRange
+	}
+	end, err := strconv.ParseFloat(q.Get("end"), 64)
+	if err != nil || math.IsNaN(end) || math.IsInf(end, 0) || end <= start || end-start > maxClipDuration.Seconds() ||
+		end > float64(math.MaxInt64)/float64(time.Second) {
+		return nil, errClipRange
+	}
+	c.start, c.end = time.Duration(start*float64(time.Second)), time.Duration(end*float64(time.Second))
+	return c, nil
+}
+
+// mediaRange returns the range of the clip in media timestamps. Wall clock times are matched against the times at
+// which the segments were recorded
+func (c *clipRequest) mediaRange(jpl *core.JsonPlaylist) (time.Duration, time.Duration) {
+	if c.startTime.IsZero() {
+		return c.start, c.end
+	}
+	return jpl.MediaTime(c.track, c.startTime), jpl.MediaTime(c.track, c.endTime)
+}
+
+// cutClip returns the recording playlist of the clip along with its ID. The segments at the boundaries of the clip are
+// trimmed and saved to the record store under clips/<ID>/, and the other segments are those of the recording.
+// Fragmented MP4 segments are kept whole since they can't be trimmed without their init segment
+func cutClip(ctx context.Context, sess drivers.OSSession, jpl *core.JsonPlaylist, manifestIDs []string,
+	c *clipRequest) (*core.JsonPlaylist, string, error) {
+
+	track, ok := jpl.Track(c.track)
+	if !ok {
+		return nil, "", errClipEmpty
+	}
+	start, end := c.mediaRange(jpl)
+	segs := jpl.ClipSegments(track.Name, start, end)
+	if len(segs) == 0 {
+		return nil, "", errClipEmpty
+	}
+	// The same clip is only saved once
+	id := fmt.Sprintf("%s_%d_%d", track.Name, start.Milliseconds(), end.Milliseconds())
+
+	for i := range segs {
+		seg := &segs[i]
+		ext := path.Ext(seg.URI)
+		format := common.ProfileExtensionFormat(ext)
+		if seg.Clip == nil || format == ffmpeg.FormatNone {
+			continue
+		}
+		fi, err := sess.ReadData(ctx, core.RecordingPath(manifestIDs, seg.URI))
+		if err != nil {
+			return nil, "", fmt.Errorf("error reading segment uri=%s: %w", seg.URI, err)
+		}
+		data, err := ioutil.ReadAll(fi.Body)
+		fi.Body.Close()
+		if err != nil {
+			return nil, "", fmt.Errorf("error reading segment uri=%s: %w", seg.URI, err)
+		}
+		trimmed, err := trimSegment(data, track, format, seg.Clip)
+		if err != nil {
+			// The whole segment is better than a gap in the clip
+			clog.Errorf(ctx, "Error trimming clip segment uri=%s err=%q", seg.URI, err)
+			seg.Clip = nil
+			continue
+		}
+		name := fmt.Sprintf("clips/%s/%d%s", id, seg.SeqNo, ext)
+		uri, err := drivers.SaveRetried(ctx, sess, name, trimmed, nil, 3)
+		if err != nil {
+			return nil, "", fmt.Errorf("error saving clip segment name=%s: %w", name, err)
+		}
+		seg.URI, seg.Duration, seg.Clip = uri, seg.Clip.To-seg.Clip.From, nil
+	}
+	return core.NewClipPlaylist(track, segs), id, nil
+}
+
+// serveClip streams the clip as MP4, or saves its HLS media playlist to the record store as clip_<ID>.m3u8 next to the
+// recording and returns it
+func (s *LivepeerServer) serveClip(ctx context.Context, w http.ResponseWriter, r *http.Request, sess drivers.OSSession,
+	jpl *core.JsonPlaylist, manifestIDs []string, manifestID string, c *clipRequest, resp *authWebhookResponse) {
+
+	clipJpl, id, err := cutClip(ctx, sess, jpl, manifestIDs, c)
+	if err == errClipEmpty {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		clog.Errorf(ctx, "Error cutting clip err=%q", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if path.Ext(r.URL.Path) == ".mp4" {
+		s.streamMP4(w, r, clipJpl, manifestID, c.track, "clip_"+id+".mp4")
+		return
+	}
+
+	segs := clipJpl.Segments[c.track]
+	mpl, err := m3u8.NewMediaPlaylist(uint(len(segs)), uint(len(segs)))
+	if err != nil {
+		clog.Errorf(ctx, "err=%q", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	mpl.Live = false
+	osUrl := ""
+	if resp != nil {
+		osUrl = resp.RecordObjectStoreURL
+	}
+	clipJpl.AddSegmentsToMPL(manifestIDs, c.track, mpl, osUrl)
+	name := "clip_" + id + ".m3u8"
+	if _, err := sess.SaveData(ctx, name, mpl.Encode(), nil, 0); err != nil {
+		clog.Errorf(ctx, "Error saving clip playlist to store err=%q", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Expose-Headers", "Content-Length")
+	w.Header().Set("Content-Type", "application/x-mpegURL")
+	w.Header().Set("Content-Location", name)
+	w.Write(mpl.Encode().Bytes())
+}
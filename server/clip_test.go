@@ -0,0 +1,149 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/livepeer/go-livepeer/core"
+	lpmon "github.com/livepeer/go-livepeer/monitor"
+	"github.com/livepeer/go-tools/drivers"
+	"github.com/livepeer/lpms/ffmpeg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseClipRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	c, err := parseClipRequest(url.Values{"start": {"1.5"}, "end": {"5"}})
+	assert.Nil(err)
+	assert.Equal(&clipRequest{track: "source", start: 1500 * time.Millisecond, end: 5 * time.Second}, c)
+
+	c, err = parseClipRequest(url.Values{"startTime": {"1700000000000"}, "endTime": {"1700000005000"}, "track": {"P144p30fps16x9"}})
+	assert.Nil(err)
+	assert.Equal("P144p30fps16x9", c.track)
+	assert.Equal(time.UnixMilli(1700000000000), c.startTime)
+	assert.Equal(time.UnixMilli(1700000005000), c.endTime)
+
+	// Clips can be as long as maxClipDuration
+	c, err = parseClipRequest(url.Values{"start": {"0"}, "end": {"14400"}})
+	assert.Nil(err)
+	assert.Equal(maxClipDuration, c.end-c.start)
+
+	for _, q := range []url.Values{
+		{},
+		{"start": {"1"}},
+		{"start": {"-1"}, "end": {"5"}},
+		{"start": {"5"}, "end": {"1"}},
+		{"start": {"a"}, "end": {"5"}},
+		{"startTime": {"1700000000000"}},
+		{"startTime": {"1700000005000"}, "endTime": {"1700000000000"}},
+		{"start": {"NaN"}, "end": {"5"}},
+		{"start": {"1"}, "end": {"NaN"}},
+		{"start": {"1"}, "end": {"+Inf"}},
+		{"start": {"-Inf"}, "end": {"5"}},
+		{"start": {"1e300"}, "end": {"1e301"}},
+		{"start": {"0"}, "end": {"14401"}},
+		{"startTime": {"1700000000000"}, "endTime": {"1700014400001"}},
+	} {
+		_, err = parseClipRequest(q)
+		assert.Equal(errClipRange, err, q.Encode())
+	}
+}
+
+func TestRecordingClip(t *testing.T) {
+	drivers.Testing = true
+	lpmon.NodeID = "testNode"
+	assert := assert.New(t)
+	require := require.New(t)
+	s, cancel := setupServerWithCancel()
+	defer serverCleanup(s)
+	defer cancel()
+
+	whts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"manifestID":"cliptest01", "recordObjectStore": "memory://recstore6"}`))
+	}))
+	defer whts.Close()
+	oldURL := AuthWebhookURL
+	defer func() { AuthWebhookURL = oldURL }()
+	AuthWebhookURL = mustParseUrl(t, whts.URL)
+
+	oldTrim := trimSegment
+	defer func() { trimSegment = oldTrim }()
+	trimSegment = func(data []byte, track core.JsonMediaTrack, format ffmpeg.Format, clip *core.SegmentClip) ([]byte, error) {
+		if string(data) == "badsegment" {
+			return nil, errors.New("trim error")
+		}
+		return []byte(fmt.Sprintf("%s_%d_%d", data, clip.From.Milliseconds(), clip.To.Milliseconds())), nil
+	}
+
+	makeReq := func(uri string) (int, string) {
+		writer := httptest.NewRecorder()
+		s.HandleRecordings(writer, httptest.NewRequest("GET", uri, nil))
+		resp := writer.Result()
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp.StatusCode, string(body)
+	}
+	readData := func(sess drivers.OSSession, name string) string {
+		fi, err := sess.ReadData(context.Background(), name)
+		require.Nil(err)
+		data, _ := ioutil.ReadAll(fi.Body)
+		fi.Body.Close()
+		return string(data)
+	}
+
+	mos := drivers.TestMemoryStorages["recstore6"]
+	msess := mos.NewSession("clip1")
+	source := ffmpeg.VideoProfile{Name: "source"}
+	jpl := core.NewJSONPlaylist()
+	for i := 1; i <= 3; i++ {
+		name := fmt.Sprintf("testNode/source/%d.ts", i)
+		msess.SaveData(context.TODO(), name, strings.NewReader(fmt.Sprintf("segment%d", i)), nil, 0)
+		jpl.InsertHLSSegment(&source, uint64(i), "clip1/"+name, 2)
+	}
+	bjpl, err := json.Marshal(jpl)
+	require.Nil(err)
+	msess.SaveData(context.TODO(), "testNode/playlist_1.json", bytes.NewReader(bjpl), nil, 0)
+
+	// Boundary segments are trimmed and saved with the clip
+	code, body := makeReq("/live/clip1/clip.m3u8?start=1&end=5")
+	assert.Equal(200, code)
+	assert.Contains(body, "#EXTINF:1.000,\nclips/source_1000_5000/1.ts\n")
+	assert.Contains(body, "#EXTINF:2.000,\ntestNode/source/2.ts\n")
+	assert.Contains(body, "#EXTINF:1.000,\nclips/source_1000_5000/3.ts\n")
+	assert.Contains(body, "#EXT-X-ENDLIST")
+	assert.Equal("segment1_1000_2000", readData(msess, "clip1/clips/source_1000_5000/1.ts"))
+	assert.Equal("segment3_0_1000", readData(msess, "clip1/clips/source_1000_5000/3.ts"))
+	assert.Equal(body, readData(msess, "clip1/clip_source_1000_5000.m3u8"))
+
+	// Clips that are within a segment
+	code, body = makeReq("/live/clip1/clip.m3u8?start=2.5&end=3")
+	assert.Equal(200, code)
+	assert.Contains(body, "#EXTINF:0.500,\nclips/source_2500_3000/2.ts\n")
+	assert.NotContains(body, "1.ts")
+	assert.NotContains(body, "3.ts")
+
+	// Segments that can't be trimmed are kept whole
+	msess.SaveData(context.TODO(), "testNode/source/1.ts", strings.NewReader("badsegment"), nil, 0)
+	code, body = makeReq("/live/clip1/clip.m3u8?start=1.5&end=3")
+	assert.Equal(200, code)
+	assert.Contains(body, "#EXTINF:2.000,\ntestNode/source/1.ts\n")
+
+	code, _ = makeReq("/live/clip1/clip.m3u8?start=5&end=1")
+	assert.Equal(400, code)
+	code, _ = makeReq("/live/clip1/clip.m3u8?start=10&end=20")
+	assert.Equal(404, code)
+	code, _ = makeReq("/live/clip1/clip.m3u8?start=1&end=5&track=P144p30fps16x9")
+	assert.Equal(404, code)
+}
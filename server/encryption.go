@@ -0,0 +1,34 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/lpms/ffmpeg"
+)
+
+// HLSKeyProvider supplies the keys that the segments of the streams are encrypted with, segments aren't encrypted
+// if nil
+var HLSKeyProvider core.KeyProvider
+
+// HLSKeyRotation is the number of segments between key rotations, the key of a stream is never rotated if 0
+var HLSKeyRotation uint
+
+var errEncryptedFMP4 = errors.New("fragmented MP4 renditions can't be encrypted")
+
+// encryptSegments checks whether the segments of the stream are encrypted before they are saved to the object store
+func encryptSegments(cxn *rtmpConnection) bool {
+	return cxn.params != nil && cxn.params.Encrypt
+}
+
+// checkEncryptedProfiles checks that the renditions can be encrypted, which is only supported for MPEG-TS since the
+// init segments of fragmented MP4 renditions would need to be encrypted too
+func checkEncryptedProfiles(profiles []ffmpeg.VideoProfile) error {
+	for _, profile := range profiles {
+		if profile.Format == common.FormatFMP4 {
+			return errEncryptedFMP4
+		}
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/lpms/ffmpeg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckEncryptedProfiles(t *testing.T) {
+	assert := assert.New(t)
+
+	profiles := []ffmpeg.VideoProfile{ffmpeg.P144p30fps16x9, ffmpeg.P240p30fps16x9}
+	assert.Nil(checkEncryptedProfiles(profiles))
+	profiles[1].Format = common.FormatFMP4
+	assert.Equal(errEncryptedFMP4, checkEncryptedProfiles(profiles))
+}
+
+func TestEncryptSegments(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.False(encryptSegments(&rtmpConnection{}))
+	assert.False(encryptSegments(&rtmpConnection{params: &core.StreamParameters{}}))
+	assert.True(encryptSegments(&rtmpConnection{params: &core.StreamParameters{Encrypt: true}}))
+}
@@ -0,0 +1,288 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/livepeer/go-livepeer/clog"
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/core"
+)
+
+const (
+	EventStreamStarted          = "stream.started"
+	EventStreamEnded            = "stream.ended"
+	EventFirstSegmentTranscoded = "stream.firstSegmentTranscoded"
+	EventOrchestratorSwapped    = "orchestrator.swapped"
+	EventTranscodeFailed        = "transcode.failed"
+	EventRecordingFinalized     = "recording.finalized"
+)
+
+// EventTypes are the events that can be subscribed to
+var EventTypes = []string{
+	EventStreamStarted,
+	EventStreamEnded,
+	EventFirstSegmentTranscoded,
+	EventOrchestratorSwapped,
+	EventTranscodeFailed,
+	EventRecordingFinalized,
+}
+
+const (
+	eventWebhookTimeout     = 5 * time.Second
+	eventWebhookMaxAttempts = 5
+	eventWebhookQueueSize   = 100
+	// Header with the HMAC-SHA256 signature of the timestamp and body of the event
+	eventSignatureHeader = "Livepeer-Signature"
+	// Max time to wait for the segments of a stream that ended to be saved before its recording is finalized
+	recordingFinalizeTimeout = 30 * time.Second
+)
+
+// Backoff before the first retry of an event, doubled on every retry
+var eventWebhookBackoff = time.Second
+
+// Events sends the stream events of the gateway to the event webhooks. Events aren't sent if nil
+var Events *EventWebhooks
+
+// StreamEvent is the body of the requests to the event webhooks
+type StreamEvent struct {
+	ID                   string  `json:"id"` // Same for all the attempts to deliver the event
+	Event                string  `json:"event"`
+	Timestamp            int64   `json:"timestamp"` // Unix milliseconds
+	ManifestID           string  `json:"manifestID"`
+	StreamID             string  `json:"streamID,omitempty"`
+	SessionID            string  `json:"sessionID,omitempty"`
+	SeqNo                *uint64 `json:"seqNo,omitempty"`
+	Orchestrator         string  `json:"orchestrator,omitempty"`
+	PreviousOrchestrator string  `json:"previousOrchestrator,omitempty"`
+	Error                string  `json:"error,omitempty"`
+}
+
+// EventWebhooks delivers the events to each webhook in the order they happened for each stream, retrying failed
+// deliveries with a backoff. Each webhook has its own queues, so a failing webhook doesn't delay the others. Requests
+// are signed with the secret if it is set
+type EventWebhooks struct {
+	urls   []*url.URL
+	secret []byte
+	events map[string]bool
+	client *http.Client
+
+	mu     sync.Mutex
+	queues map[eventQueueKey]*eventQueue
+}
+
+// eventQueueKey identifies the queue of the events of a stream for a webhook
+type eventQueueKey struct {
+	url int // index of the webhook
+	mid string
+}
+
+type eventQueue struct {
+	pending []*StreamEvent
+}
+
+// NewEventWebhooks returns the webhooks that are sent the given events, or all events if none is given
+func NewEventWebhooks(urls []*url.URL, secret string, events []string) (*EventWebhooks, error) {
+	subscribed := make(map[string]bool)
+	for _, evt := range events {
+		known := false
+		for _, t := range EventTypes {
+			known = known || evt == t
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown event %q", evt)
+		}
+		subscribed[evt] = true
+	}
+	if len(subscribed) == 0 {
+		for _, t := range EventTypes {
+			subscribed[t] = true
+		}
+	}
+	return &EventWebhooks{
+		urls:   urls,
+		secret: []byte(secret),
+		events: subscribed,
+		client: &http.Client{Timeout: eventWebhookTimeout},
+		queues: make(map[eventQueueKey]*eventQueue),
+	}, nil
+}
+
+// newStreamEvent returns an event of the stream with the given parameters
+func newStreamEvent(event string, params *core.StreamParameters) *StreamEvent {
+	evt := &StreamEvent{Event: event}
+	if params != nil {
+		evt.ManifestID = string(params.ManifestID)
+		evt.StreamID = params.ExternalStreamID
+		evt.SessionID = params.SessionID
+	}
+	return evt
+}
+
+// Send queues the event for delivery to each webhook after the previous events of its stream
+func (w *EventWebhooks) Send(ctx context.Context, evt *StreamEvent) {
+	if w == nil || !w.events[evt.Event] {
+		return
+	}
+	evt.ID = common.RandomIDGenerator(16)
+	evt.Timestamp = time.Now().UnixMilli()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i, u := range w.urls {
+		key := eventQueueKey{url: i, mid: evt.ManifestID}
+		q, ok := w.queues[key]
+		if !ok {
+			q = &eventQueue{}
+			w.queues[key] = q
+			go w.deliverQueue(clog.Clone(context.Background(), ctx), key, u, q)
+		}
+		if len(q.pending) >= eventWebhookQueueSize {
+			clog.Errorf(ctx, "Dropping event=%s url=%s, too many events are waiting to be delivered", evt.Event, u.Redacted())
+			continue
+		}
+		q.pending = append(q.pending, evt)
+	}
+}
+
+// deliverQueue delivers the events of the stream to the webhook one after the other, until there are none left
+func (w *EventWebhooks) deliverQueue(ctx context.Context, key eventQueueKey, u *url.URL, q *eventQueue) {
+	for {
+		w.mu.Lock()
+		if len(q.pending) == 0 {
+			delete(w.queues, key)
+			w.mu.Unlock()
+			return
+		}
+		evt := q.pending[0]
+		q.pending = q.pending[1:]
+		w.mu.Unlock()
+
+		body, err := json.Marshal(evt)
+		if err != nil {
+			clog.Errorf(ctx, "Error marshalling event=%s err=%q", evt.Event, err)
+			continue
+		}
+		w.deliver(ctx, u, evt, body)
+	}
+}
+
+func (w *EventWebhooks) deliver(ctx context.Context, u *url.URL, evt *StreamEvent, body []byte) {
+	backoff := eventWebhookBackoff
+	for attempt := 1; ; attempt++ {
+		err := w.post(u, body)
+		if err == nil {
+			return
+		}
+		if attempt == eventWebhookMaxAttempts {
+			clog.Errorf(ctx, "Giving up delivering event=%s id=%s url=%s attempts=%d err=%q", evt.Event, evt.ID, u.Redacted(), attempt, err)
+			return
+		}
+		clog.Warningf(ctx, "Error delivering event=%s id=%s url=%s attempt=%d err=%q", evt.Event, evt.ID, u.Redacted(), attempt, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (w *EventWebhooks) post(u *url.URL, body []byte) error {
+	req, err := http.NewRequest("POST", u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(w.secret) > 0 {
+		req.Header.Set(eventSignatureHeader, signEvent(w.secret, time.Now().Unix(), body))
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	rbody, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("status=%d error=%s", resp.StatusCode, string(rbody))
+	}
+	return nil
+}
+
+// signEvent returns the signature header of the event, with the HMAC-SHA256 of "<timestamp>.<body>" so that receivers
+// can reject replayed events
+func signEvent(secret []byte, timestamp int64, body []byte) string {
+	ts := strconv.FormatInt(timestamp, 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%s,v1=%s", ts, hex.EncodeToString(mac.Sum(nil)))
+}
+
+func sendTranscodeFailed(ctx context.Context, cxn *rtmpConnection, seqNo uint64, err error) {
+	evt := newStreamEvent(EventTranscodeFailed, cxn.params)
+	evt.SeqNo = &seqNo
+	evt.Error = err.Error()
+	Events.Send(ctx, evt)
+}
+
+// finalizeRecording sends the recording finalized event of the stream that ended, once its segments that are still
+// being saved to the record store are saved
+func finalizeRecording(ctx context.Context, cxn *rtmpConnection) {
+	evt := newStreamEvent(EventRecordingFinalized, cxn.params)
+	if !cxn.recordSaves.wait(recordingFinalizeTimeout) {
+		clog.Warningf(ctx, "Timed out waiting for the segments of the recording to be saved")
+		evt.Error = "timed out waiting for the segments of the recording to be saved"
+	}
+	Events.Send(ctx, evt)
+}
+
+// pendingSaves counts the segments of a stream that are being saved to the record store
+type pendingSaves struct {
+	mu   sync.Mutex
+	n    int
+	idle chan struct{} // closed once no segment is being saved
+}
+
+func (p *pendingSaves) add() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.n == 0 {
+		p.idle = make(chan struct{})
+	}
+	p.n++
+}
+
+func (p *pendingSaves) done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.n--
+	if p.n == 0 {
+		close(p.idle)
+	}
+}
+
+// wait waits until no segment is being saved, and returns false if it timed out
+func (p *pendingSaves) wait(timeout time.Duration) bool {
+	p.mu.Lock()
+	if p.n == 0 {
+		p.mu.Unlock()
+		return true
+	}
+	idle := p.idle
+	p.mu.Unlock()
+	select {
+	case <-idle:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
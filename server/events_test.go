@@ -0,0 +1,285 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type eventReceiver struct {
+	mu         sync.Mutex
+	events     []StreamEvent
+	signatures []string
+	bodies     [][]byte
+	failures   int // number of requests to fail before accepting events
+}
+
+func (r *eventReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	var evt StreamEvent
+	json.Unmarshal(body, &evt)
+	r.events = append(r.events, evt)
+	r.signatures = append(r.signatures, req.Header.Get(eventSignatureHeader))
+	r.bodies = append(r.bodies, body)
+}
+
+func (r *eventReceiver) received() []StreamEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]StreamEvent(nil), r.events...)
+}
+
+func newEventReceiver(t *testing.T) (*eventReceiver, *url.URL) {
+	r := &eventReceiver{}
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	u, err := url.Parse(ts.URL)
+	require.Nil(t, err)
+	return r, u
+}
+
+func TestNewEventWebhooks(t *testing.T) {
+	assert := assert.New(t)
+	w, err := NewEventWebhooks(nil, "", nil)
+	assert.Nil(err)
+	assert.Len(w.events, len(EventTypes))
+	w, err = NewEventWebhooks(nil, "", []string{EventStreamStarted, EventStreamEnded})
+	assert.Nil(err)
+	assert.Equal(map[string]bool{EventStreamStarted: true, EventStreamEnded: true}, w.events)
+	_, err = NewEventWebhooks(nil, "", []string{EventStreamStarted, "stream.paused"})
+	assert.EqualError(err, `unknown event "stream.paused"`)
+
+	// Events aren't sent without event webhooks
+	var nilWebhooks *EventWebhooks
+	nilWebhooks.Send(context.Background(), &StreamEvent{Event: EventStreamStarted})
+}
+
+func TestEventWebhooks_Send(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	r, u := newEventReceiver(t)
+	w, err := NewEventWebhooks([]*url.URL{u}, "", []string{EventStreamStarted, EventTranscodeFailed, EventStreamEnded})
+	require.Nil(err)
+
+	params := &core.StreamParameters{ManifestID: "mani", ExternalStreamID: "ext", SessionID: "sess"}
+	w.Send(context.Background(), newStreamEvent(EventStreamStarted, params))
+	// Events that weren't subscribed to aren't sent
+	w.Send(context.Background(), newStreamEvent(EventFirstSegmentTranscoded, params))
+	for i := uint64(0); i < 10; i++ {
+		seqNo := i
+		evt := newStreamEvent(EventTranscodeFailed, params)
+		evt.SeqNo = &seqNo
+		evt.Error = "no sessions"
+		w.Send(context.Background(), evt)
+	}
+	w.Send(context.Background(), newStreamEvent(EventStreamEnded, params))
+
+	// Events of a stream are delivered in order
+	require.Eventually(func() bool { return len(r.received()) == 12 }, time.Second, 10*time.Millisecond)
+	events := r.received()
+	assert.Equal(EventStreamStarted, events[0].Event)
+	assert.Equal("mani", events[0].ManifestID)
+	assert.Equal("ext", events[0].StreamID)
+	assert.Equal("sess", events[0].SessionID)
+	assert.NotEmpty(events[0].ID)
+	assert.NotZero(events[0].Timestamp)
+	assert.Nil(events[0].SeqNo)
+	for i := uint64(0); i < 10; i++ {
+		assert.Equal(EventTranscodeFailed, events[i+1].Event)
+		require.NotNil(events[i+1].SeqNo)
+		assert.Equal(i, *events[i+1].SeqNo)
+		assert.Equal("no sessions", events[i+1].Error)
+	}
+	assert.Equal(EventStreamEnded, events[11].Event)
+	// Requests aren't signed without a secret
+	assert.Empty(r.signatures[0])
+	assert.NotContains(string(r.bodies[0]), "seqNo")
+
+	// Queues are dropped once their events are delivered
+	assert.Eventually(func() bool {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return len(w.queues) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestEventWebhooks_Retries(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	oldBackoff := eventWebhookBackoff
+	defer func() { eventWebhookBackoff = oldBackoff }()
+	eventWebhookBackoff = time.Millisecond
+
+	r, u := newEventReceiver(t)
+	w, err := NewEventWebhooks([]*url.URL{u}, "", nil)
+	require.Nil(err)
+
+	// Failed deliveries are retried, and the next events wait for them
+	r.failures = eventWebhookMaxAttempts - 1
+	w.Send(context.Background(), &StreamEvent{Event: EventStreamStarted, ManifestID: "mani"})
+	w.Send(context.Background(), &StreamEvent{Event: EventStreamEnded, ManifestID: "mani"})
+	require.Eventually(func() bool { return len(r.received()) == 2 }, time.Second, 10*time.Millisecond)
+	events := r.received()
+	assert.Equal(EventStreamStarted, events[0].Event)
+	assert.Equal(EventStreamEnded, events[1].Event)
+
+	// Events are dropped after the max attempts
+	r.mu.Lock()
+	r.failures = eventWebhookMaxAttempts
+	r.mu.Unlock()
+	w.Send(context.Background(), &StreamEvent{Event: EventStreamStarted, ManifestID: "mani"})
+	w.Send(context.Background(), &StreamEvent{Event: EventStreamEnded, ManifestID: "mani"})
+	require.Eventually(func() bool { return len(r.received()) == 3 }, time.Second, 10*time.Millisecond)
+	assert.Equal(EventStreamEnded, r.received()[2].Event)
+}
+
+func TestEventWebhooks_FailingWebhook(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	oldBackoff := eventWebhookBackoff
+	defer func() { eventWebhookBackoff = oldBackoff }()
+	eventWebhookBackoff = 100 * time.Millisecond
+
+	failing, u1 := newEventReceiver(t)
+	r, u2 := newEventReceiver(t)
+	w, err := NewEventWebhooks([]*url.URL{u1, u2}, "", nil)
+	require.Nil(err)
+
+	// A webhook that fails doesn't delay the delivery of the events to the others
+	failing.failures = eventWebhookMaxAttempts
+	w.Send(context.Background(), &StreamEvent{Event: EventStreamStarted, ManifestID: "mani"})
+	w.Send(context.Background(), &StreamEvent{Event: EventStreamEnded, ManifestID: "mani"})
+	require.Eventually(func() bool { return len(r.received()) == 2 }, 50*time.Millisecond, time.Millisecond)
+	assert.Empty(failing.received())
+	events := r.received()
+	assert.Equal(EventStreamStarted, events[0].Event)
+	assert.Equal(EventStreamEnded, events[1].Event)
+
+	// The failing webhook still gets the next events in order once it gave up on the first one
+	require.Eventually(func() bool { return len(failing.received()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(EventStreamEnded, failing.received()[0].Event)
+	assert.Equal(events[1].ID, failing.received()[0].ID)
+}
+
+func TestEventWebhooks_Signature(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	r, u := newEventReceiver(t)
+	w, err := NewEventWebhooks([]*url.URL{u}, "secret", nil)
+	require.Nil(err)
+
+	w.Send(context.Background(), &StreamEvent{Event: EventStreamStarted, ManifestID: "mani"})
+	require.Eventually(func() bool { return len(r.received()) == 1 }, time.Second, 10*time.Millisecond)
+	r.mu.Lock()
+	sig, body := r.signatures[0], r.bodies[0]
+	r.mu.Unlock()
+
+	parts := strings.Split(sig, ",")
+	require.Len(parts, 2)
+	require.True(strings.HasPrefix(parts[0], "t="))
+	ts, err := strconv.ParseInt(strings.TrimPrefix(parts[0], "t="), 10, 64)
+	require.Nil(err)
+	assert.InDelta(time.Now().Unix(), ts, 5)
+	assert.Equal(sig, signEvent([]byte("secret"), ts, body))
+	assert.NotEqual(sig, signEvent([]byte("other"), ts, body))
+	assert.NotEqual(sig, signEvent([]byte("secret"), ts+1, body))
+
+	assert.Equal("t=1600000000,v1=1e56a11da123b137c26fa37b7c222060bdf22988aa9b3248c31244f8b2ef4a28",
+		signEvent([]byte("secret"), 1600000000, []byte("{}")))
+}
+
+func TestPendingSaves(t *testing.T) {
+	assert := assert.New(t)
+	var p pendingSaves
+	assert.True(p.wait(0))
+
+	p.add()
+	p.add()
+	assert.False(p.wait(10 * time.Millisecond))
+	p.done()
+	assert.False(p.wait(10 * time.Millisecond))
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		p.done()
+	}()
+	assert.True(p.wait(time.Second))
+
+	// Saves can start again once all saves are done
+	p.add()
+	assert.False(p.wait(10 * time.Millisecond))
+	p.done()
+	assert.True(p.wait(0))
+}
+
+func TestFinalizeRecording(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	r, u := newEventReceiver(t)
+	oldEvents := Events
+	defer func() { Events = oldEvents }()
+	var err error
+	Events, err = NewEventWebhooks([]*url.URL{u}, "", nil)
+	require.Nil(err)
+
+	cxn := &rtmpConnection{params: &core.StreamParameters{ManifestID: "mani"}}
+	cxn.recordSaves.add()
+	done := make(chan struct{})
+	go func() {
+		finalizeRecording(context.Background(), cxn)
+		close(done)
+	}()
+	// The recording is finalized once its segments are saved
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(r.received())
+	cxn.recordSaves.done()
+	<-done
+	require.Eventually(func() bool { return len(r.received()) == 1 }, time.Second, 10*time.Millisecond)
+	evt := r.received()[0]
+	assert.Equal(EventRecordingFinalized, evt.Event)
+	assert.Equal("mani", evt.ManifestID)
+	assert.Empty(evt.Error)
+}
+
+func TestSessionPool_OrchestratorSwappedEvent(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	r, u := newEventReceiver(t)
+	oldEvents := Events
+	defer func() { Events = oldEvents }()
+	var err error
+	Events, err = NewEventWebhooks([]*url.URL{u}, "", []string{EventOrchestratorSwapped})
+	require.Nil(err)
+
+	pool := stubPool()
+	pool.params = &core.StreamParameters{ManifestID: "mani", ExternalStreamID: "ext", SessionID: "sess"}
+	prev := pool.selectSessions(context.Background(), 1)[0]
+	next := pool.selectSessions(context.Background(), 1)[0]
+
+	require.Eventually(func() bool { return len(r.received()) == 1 }, time.Second, 10*time.Millisecond)
+	evt := r.received()[0]
+	assert.Equal(EventOrchestratorSwapped, evt.Event)
+	assert.Equal("mani", evt.ManifestID)
+	assert.Equal("ext", evt.StreamID)
+	assert.Equal("sess", evt.SessionID)
+	assert.Equal(next.Transcoder(), evt.Orchestrator)
+	assert.Equal(prev.Transcoder(), evt.PreviousOrchestrator)
+}
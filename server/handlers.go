@@ -219,6 +219,58 @@ func getAvailableTranscodingOptionsHandler() http.Handler {
 	})
 }
 
+// orchestratorReputationHandler returns the reputation of all orchestrators known to the gateway
+func orchestratorReputationHandler(rs *ReputationStore) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respondJson(w, rs.All())
+	})
+}
+
+// circuitBreakersHandler returns the circuit breaker state of the orchestrators which are not fully admitted
+func circuitBreakersHandler(cb *circuitBreakers) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respondJson(w, cb.status())
+	})
+}
+
+// addRestreamHandler pushes the source or a rendition of a running stream to an RTMP destination
+func (s *LivepeerServer) addRestreamHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cxn, ok := s.restreamConnection(r.FormValue("manifestID"))
+		if !ok {
+			respondWithError(w, errUnknownStream.Error(), http.StatusNotFound)
+			return
+		}
+		target, err := parseRestreamTarget(r.FormValue("url"), r.FormValue("profile"), cxn.params.Profiles)
+		if err != nil {
+			respond400(w, err.Error())
+			return
+		}
+		switch err := cxn.restreams.add(target); err {
+		case nil:
+			respondOk(w, []byte(fmt.Sprintf("Restreaming profile=%s\n", target.Profile)))
+		case errRestreamExists:
+			respondWithError(w, err.Error(), http.StatusConflict)
+		case errUnknownStream:
+			respondWithError(w, err.Error(), http.StatusNotFound)
+		default:
+			respond400(w, err.Error())
+		}
+	})
+}
+
+// removeRestreamHandler stops pushing a running stream to an RTMP destination
+func (s *LivepeerServer) removeRestreamHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cxn, ok := s.restreamConnection(r.FormValue("manifestID"))
+		if !ok || !cxn.restreams.remove(r.FormValue("url")) {
+			respondWithError(w, "unknown restream", http.StatusNotFound)
+			return
+		}
+		respondOk(w, []byte("Restream removed\n"))
+	})
+}
+
 // Rounds
 func currentRoundHandler(client eth.LivepeerEthClient) http.Handler {
 	return mustHaveClient(client, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -604,6 +656,18 @@ func (s *LivepeerServer) setMaxSessions() http.Handler {
 	})
 }
 
+func (s *LivepeerServer) drainHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.LivepeerNode.NodeType != core.OrchestratorNode && s.LivepeerNode.NodeType != core.TranscoderNode {
+			respond400(w, "Node must be orchestrator or transcoder node to drain")
+			return
+		}
+		s.LivepeerNode.Drain()
+		glog.Infof("Draining node, exiting once the running sessions ended or after -drainTimeout=%s", core.DrainTimeout)
+		respondOk(w, []byte("Draining node\n"))
+	})
+}
+
 // Bond, withdraw, reward
 func bondHandler(client eth.LivepeerEthClient) http.Handler {
 	return mustHaveClient(client, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -12,6 +12,7 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	ethcommon "github.com/ethereum/go-ethereum/common"
@@ -284,6 +285,42 @@ func TestGetAvailableTranscodingOptionsHandler(t *testing.T) {
 	assert.NotEmpty(body)
 }
 
+func TestCircuitBreakersHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	cb := newCircuitBreakers()
+	handler := circuitBreakersHandler(cb)
+
+	status, body := get(handler)
+	assert.Equal(http.StatusOK, status)
+	assert.JSONEq(`[]`, body)
+
+	cb.recordFailure("https://orch1.livepeer.org", errors.New("some error"))
+	status, body = get(handler)
+	assert.Equal(http.StatusOK, status)
+	expected := `[{"orchestrator":"https://orch1.livepeer.org","state":"closed","failures":1,"trips":0,"probesInFlight":0,"probeSuccesses":0,"lastError":"some error"}]`
+	assert.JSONEq(expected, body)
+}
+
+func TestOrchestratorReputationHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	// Reputation is not tracked
+	status, body := get(orchestratorReputationHandler(nil))
+	assert.Equal(http.StatusOK, status)
+	assert.JSONEq(`[]`, body)
+
+	rs := NewReputationStore()
+	rs.now = func() time.Time { return time.Unix(1600000000, 0) }
+	handler := orchestratorReputationHandler(rs)
+	rs.RecordSuccess("https://orch1.livepeer.org", 0.5)
+	rs.RecordFailure("https://orch1.livepeer.org", errors.New("some error"))
+	status, body = get(handler)
+	assert.Equal(http.StatusOK, status)
+	expected := `[{"orchestrator":"https://orch1.livepeer.org","successes":1,"failures":1,"verificationFailures":0,"latencySamples":1,"latencyScoreAvg":0.5,"lastFailureReason":"some error","lastFailureAt":1600000000}]`
+	assert.JSONEq(expected, body)
+}
+
 // Rounds
 func TestCurrentRoundHandler_Error(t *testing.T) {
 	assert := assert.New(t)
@@ -585,6 +622,24 @@ func TestSetPriceForBroadcasterHandler_NotOrchestrator(t *testing.T) {
 	assert.Equal(http.StatusBadRequest, status)
 }
 
+func TestDrainHandler(t *testing.T) {
+	assert := assert.New(t)
+	s := stubServer()
+	s.LivepeerNode.NodeType = core.BroadcasterNode
+	handler := s.drainHandler()
+
+	status, _ := postForm(handler, url.Values{})
+	assert.Equal(http.StatusBadRequest, status)
+	assert.False(s.LivepeerNode.IsDraining())
+
+	s.LivepeerNode.NodeType = core.OrchestratorNode
+	status, body := postForm(handler, url.Values{})
+	assert.Equal(http.StatusOK, status)
+	assert.Equal("Draining node", body)
+	assert.True(s.LivepeerNode.IsDraining())
+	<-s.LivepeerNode.Draining()
+}
+
 func TestSetPriceForBroadcasterHandler_WrongInput(t *testing.T) {
 	assert := assert.New(t)
 	s := stubServer()
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"strconv"
+)
+
+// hashRingReplicas is the number of points of each node on the ring, more points spread the keys
+// more evenly over the nodes
+var hashRingReplicas = 100
+
+// hashRing maps keys onto a set of nodes with consistent hashing, so that only the keys of a node
+// are moved when the node leaves and a joining node only takes over keys from the other nodes.
+// The mapping only depends on the set of nodes, so different gateways map a key onto the same node
+type hashRing struct {
+	points []uint64
+	nodes  map[uint64]string
+}
+
+func newHashRing(nodes []string) *hashRing {
+	r := &hashRing{nodes: make(map[uint64]string)}
+	for _, node := range nodes {
+		for i := 0; i < hashRingReplicas; i++ {
+			p := ringHash(node + "#" + strconv.Itoa(i))
+			if _, ok := r.nodes[p]; ok {
+				continue
+			}
+			r.nodes[p] = node
+			r.points = append(r.points, p)
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	return r
+}
+
+// preference returns the nodes in the order they are assigned the key, the first node is the owner
+// of the key and the following ones take over if the previous ones are unavailable
+func (r *hashRing) preference(key string) []string {
+	if len(r.points) == 0 {
+		return nil
+	}
+	h := ringHash(key)
+	start := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	var nodes []string
+	seen := make(map[string]bool)
+	for i := 0; i < len(r.points); i++ {
+		node := r.nodes[r.points[(start+i)%len(r.points)]]
+		if !seen[node] {
+			seen[node] = true
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+func ringHash(s string) uint64 {
+	sum := sha256.Sum256([]byte(s))
+	return binary.BigEndian.Uint64(sum[:8])
+}
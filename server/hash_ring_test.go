@@ -0,0 +1,54 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashRing_Preference(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(newHashRing(nil).preference("foo"))
+
+	nodes := []string{"o1", "o2", "o3", "o4"}
+	ring := newHashRing(nodes)
+	pref := ring.preference("foo")
+	assert.ElementsMatch(nodes, pref)
+	// The mapping only depends on the nodes, not on their order
+	assert.Equal(pref, newHashRing([]string{"o4", "o3", "o2", "o1"}).preference("foo"))
+
+	owners := make(map[string]string)
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("stream%d", i)
+		owners[key] = ring.preference(key)[0]
+		counts[owners[key]]++
+	}
+	// Keys are spread over all nodes
+	assert.Len(counts, len(nodes))
+
+	// Only the keys of a node that leaves are moved, to the next node in their preference
+	left := newHashRing([]string{"o1", "o2", "o4"})
+	for key, owner := range owners {
+		if owner == "o3" {
+			pref := ring.preference(key)
+			assert.Equal(pref[1], left.preference(key)[0])
+		} else {
+			assert.Equal(owner, left.preference(key)[0])
+		}
+	}
+
+	// A node that joins only takes over keys from the other nodes
+	joined := newHashRing([]string{"o1", "o2", "o3", "o4", "o5"})
+	moved := 0
+	for key, owner := range owners {
+		if newOwner := joined.preference(key)[0]; newOwner != owner {
+			assert.Equal("o5", newOwner)
+			moved++
+		}
+	}
+	assert.NotZero(moved)
+	assert.Less(moved, len(owners)/2)
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/livepeer/go-livepeer/clog"
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-livepeer/monitor"
+	"github.com/livepeer/lpms/stream"
+)
+
+// HedgeFraction is the fraction of a segment's duration after which a segment that has not been transcoded yet
+// is also submitted to a backup session. Hedging is disabled if HedgeFraction is 0
+var HedgeFraction = 0.0
+
+type submitAbortKey struct{}
+
+// withSubmitAbort returns a context that allows aborting a SubmitSegment() call made with it. SubmitSegment()
+// deliberately ignores the cancellation of the caller's context, so a dedicated signal is needed
+func withSubmitAbort(ctx context.Context) (context.Context, func()) {
+	abort := make(chan struct{})
+	var once sync.Once
+	return context.WithValue(ctx, submitAbortKey{}, abort), func() { once.Do(func() { close(abort) }) }
+}
+
+func submitAbortCh(ctx context.Context) <-chan struct{} {
+	abort, _ := ctx.Value(submitAbortKey{}).(chan struct{})
+	return abort
+}
+
+func shouldHedge(seg *stream.HLSSegment, calcPerceptualHash bool) bool {
+	// Segments that need a perceptual hash are verified against other sessions already
+	return HedgeFraction > 0 && seg.Duration > 0 && !calcPerceptualHash
+}
+
+// submitHedged submits seg, which was prepared for sess from srcSeg, to sess and, if no result has arrived within
+// HedgeFraction of the segment's duration, to a backup session as well. The session of the first successful result
+// is returned along with the result. The other submission is aborted and its session is completed once it returns
+func submitHedged(ctx context.Context, cxn *rtmpConnection, sess *BroadcastSession, srcSeg, seg *stream.HLSSegment, name string,
+	segPar *core.SegmentParameters, nonce uint64, verified bool) (*BroadcastSession, *ReceivedTranscodeResult, error) {
+
+	resc := make(chan *SubmitResult, 2)
+	primaryCtx, abortPrimary := withSubmitAbort(ctx)
+	go func() {
+		res, err := SubmitSegment(primaryCtx, sess.Clone(), seg, segPar, nonce, false, verified)
+		resc <- &SubmitResult{Session: sess, TranscodeResult: res, Err: err}
+	}()
+
+	timer := time.NewTimer(time.Duration(HedgeFraction * seg.Duration * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case r := <-resc:
+		return r.Session, r.TranscodeResult, r.Err
+	case <-timer.C:
+	}
+
+	backup := cxn.sessManager.selectBackupSession(ctx, sess)
+	if backup == nil {
+		clog.V(common.DEBUG).Infof(ctx, "No backup session available to hedge segment seqNo=%d", seg.SeqNo)
+		r := <-resc
+		return r.Session, r.TranscodeResult, r.Err
+	}
+	// seg might refer to the primary's storage, so prepare the backup's segment from the source segment
+	backupSeg, err := prepareForTranscoding(ctx, cxn, backup, srcSeg, name)
+	if err != nil {
+		clog.Errorf(ctx, "Could not prepare segment seqNo=%d for backup=%s err=%q", seg.SeqNo, backup.Transcoder(), err)
+		cxn.sessManager.completeSession(ctx, backup, false)
+		r := <-resc
+		return r.Session, r.TranscodeResult, r.Err
+	}
+	clog.Infof(ctx, "Hedging segment seqNo=%d orch=%s backup=%s", seg.SeqNo, sess.Transcoder(), backup.Transcoder())
+	if monitor.Enabled {
+		monitor.SegmentHedged(ctx)
+	}
+	backup.pushSegInFlight(backupSeg)
+	backupCtx, abortBackup := withSubmitAbort(ctx)
+	go func() {
+		res, err := SubmitSegment(backupCtx, backup.Clone(), backupSeg, segPar, nonce, false, false)
+		resc <- &SubmitResult{Session: backup, TranscodeResult: res, Err: err}
+	}()
+
+	first := <-resc
+	if first.Err == nil && first.TranscodeResult != nil {
+		if first.Session == backup {
+			clog.Infof(ctx, "Backup orch=%s won hedged segment seqNo=%d", backup.Transcoder(), seg.SeqNo)
+			if monitor.Enabled {
+				monitor.SegmentHedgeWon(ctx)
+			}
+			abortPrimary()
+		} else {
+			abortBackup()
+		}
+		go cxn.sessManager.completeHedgeLoser(ctx, resc)
+		return first.Session, first.TranscodeResult, first.Err
+	}
+
+	// The first submission failed, so the outcome depends on the other one
+	if isNonRetryableError(first.Err) {
+		cxn.sessManager.completeSession(ctx, first.Session, false)
+	} else {
+		cxn.sessManager.suspendAndRemoveOrch(first.Session, first.Err)
+	}
+	second := <-resc
+	return second.Session, second.TranscodeResult, second.Err
+}
+
+// completeHedgeLoser waits for the aborted submission of a hedged segment and gives its session back to the pool
+func (bsm *BroadcastSessionsManager) completeHedgeLoser(ctx context.Context, resc chan *SubmitResult) {
+	r := <-resc
+	if r.Err == nil && r.TranscodeResult != nil {
+		// The result arrived before the submission could be aborted, so keep the session up to date
+		updateSession(r.Session, r.TranscodeResult)
+		bsm.recordSuccess(r.Session, r.TranscodeResult.LatencyScore)
+	}
+	// An error is most likely caused by the abort, so it is not held against the orchestrator
+	bsm.completeSession(ctx, r.Session, false)
+}
+
+// selectBackupSession selects a session other than primary without changing the last used sessions of the pools
+func (bsm *BroadcastSessionsManager) selectBackupSession(ctx context.Context, primary *BroadcastSession) *BroadcastSession {
+	bsm.sessLock.Lock()
+	defer bsm.sessLock.Unlock()
+
+	if bsm.finished {
+		return nil
+	}
+	if sess := bsm.untrustedPool.selectBackupSession(ctx, primary); sess != nil {
+		return sess
+	}
+	return bsm.trustedPool.selectBackupSession(ctx, primary)
+}
+
+func (sp *SessionPool) selectBackupSession(ctx context.Context, exclude *BroadcastSession) *BroadcastSession {
+	sp.lock.Lock()
+	defer sp.lock.Unlock()
+
+	// Skipped sessions are given back to the selector once a backup session is found
+	var skipped []*BroadcastSession
+	defer func() {
+		for _, sess := range skipped {
+			sp.sel.Complete(sess)
+		}
+	}()
+	for sp.sel.Size() > 0 {
+		sess := sp.sel.Select(ctx)
+		if sess == nil {
+			return nil
+		}
+		// Sessions no longer in the map have been removed from the pool
+		if _, ok := sp.sessMap[sess.Transcoder()]; !ok {
+			continue
+		}
+		if sess == exclude || !sp.pick(sess) {
+			skipped = append(skipped, sess)
+			continue
+		}
+		return sess
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+)
+
+const defaultLatencyHistoryHalfLife = 10 * time.Minute
+const defaultLatencyHistorySamples = 64
+
+// unknownLatencyScore is the latency score of an orchestrator that failed every segment it was given, as if it
+// took the whole segment duration, before the penalty for its failure rate
+const unknownLatencyScore = 1.0
+
+// OrchLatencyHistory is shared by all streams of the gateway so that knowledge about an orchestrator
+// acquired by one stream is available to the others
+var OrchLatencyHistory = NewLatencyHistory(defaultLatencyHistoryHalfLife, defaultLatencyHistorySamples)
+
+type latencySample struct {
+	score float64
+	at    time.Time
+}
+
+type orchLatencyStats struct {
+	samples   []latencySample // oldest first
+	successes float64         // exponentially decayed number of successful segments
+	failures  float64         // exponentially decayed number of failed segments
+	updated   time.Time
+}
+
+// LatencyHistory keeps an exponentially decayed history of the latency scores and of the success rate
+// observed for each orchestrator
+type LatencyHistory struct {
+	mu         sync.Mutex
+	halfLife   time.Duration
+	maxSamples int
+	orchs      map[string]*orchLatencyStats
+
+	now func() time.Time
+}
+
+// NewLatencyHistory returns a LatencyHistory that weights observations by half for every halfLife elapsed
+// and keeps at most maxSamples latency samples per orchestrator
+func NewLatencyHistory(halfLife time.Duration, maxSamples int) *LatencyHistory {
+	return &LatencyHistory{
+		halfLife:   halfLife,
+		maxSamples: maxSamples,
+		orchs:      make(map[string]*orchLatencyStats),
+		now:        time.Now,
+	}
+}
+
+// RecordSuccess records the latency score of a segment successfully transcoded by the orchestrator
+func (h *LatencyHistory) RecordSuccess(orch string, latencyScore float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	stats := h.decayedStats(orch, now)
+	stats.successes++
+	stats.samples = append(stats.samples, latencySample{score: latencyScore, at: now})
+	if len(stats.samples) > h.maxSamples {
+		stats.samples = stats.samples[len(stats.samples)-h.maxSamples:]
+	}
+}
+
+// RecordFailure records a segment that the orchestrator failed to transcode
+func (h *LatencyHistory) RecordFailure(orch string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stats := h.decayedStats(orch, h.now())
+	stats.failures++
+}
+
+// Known returns true if any success or failure is recorded for the orchestrator
+func (h *LatencyHistory) Known(orch string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, ok := h.orchs[orch]
+	return ok
+}
+
+// Percentile returns the p-th percentile (0 < p <= 1) of the orchestrator's latency scores where
+// each sample is weighted by its age. The second return value is false if no sample is recorded
+func (h *LatencyHistory) Percentile(orch string, p float64) (float64, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stats, ok := h.orchs[orch]
+	if !ok || len(stats.samples) == 0 {
+		return 0, false
+	}
+
+	now := h.now()
+	samples := make([]latencySample, len(stats.samples))
+	copy(samples, stats.samples)
+	sort.Slice(samples, func(i, j int) bool { return samples[i].score < samples[j].score })
+
+	weights := make([]float64, len(samples))
+	var total float64
+	for i, s := range samples {
+		weights[i] = h.decay(now.Sub(s.at))
+		total += weights[i]
+	}
+
+	var cum float64
+	for i, s := range samples {
+		cum += weights[i]
+		if cum >= p*total {
+			return s.score, true
+		}
+	}
+	return samples[len(samples)-1].score, true
+}
+
+// SuccessRate returns the decayed ratio of successful segments for the orchestrator; orchestrators
+// without any recorded segment are considered fully successful
+func (h *LatencyHistory) SuccessRate(orch string) float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stats, ok := h.orchs[orch]
+	if !ok || stats.successes+stats.failures == 0 {
+		return 1.0
+	}
+	// Both counters decay by the same factor so the ratio does not depend on the time of the last update
+	return stats.successes / (stats.successes + stats.failures)
+}
+
+// Score returns the orchestrator's p-th percentile latency score penalized by its failure rate
+// whereby lower is better. Orchestrators with failures only are scored as if their latency score was
+// unknownLatencyScore. The second return value is false if the orchestrator is not known
+func (h *LatencyHistory) Score(orch string, p float64) (float64, bool) {
+	if !h.Known(orch) {
+		return 0, false
+	}
+	latency, ok := h.Percentile(orch, p)
+	if !ok {
+		latency = unknownLatencyScore
+	}
+	return latency / math.Max(h.SuccessRate(orch), 0.01), true
+}
+
+// the caller needs to ensure h.mu is acquired before calling this
+func (h *LatencyHistory) decayedStats(orch string, now time.Time) *orchLatencyStats {
+	stats, ok := h.orchs[orch]
+	if !ok {
+		stats = &orchLatencyStats{updated: now}
+		h.orchs[orch] = stats
+		return stats
+	}
+
+	factor := h.decay(now.Sub(stats.updated))
+	stats.successes *= factor
+	stats.failures *= factor
+	stats.updated = now
+	return stats
+}
+
+func (h *LatencyHistory) decay(age time.Duration) float64 {
+	if h.halfLife <= 0 || age <= 0 {
+		return 1.0
+	}
+	return math.Pow(0.5, float64(age)/float64(h.halfLife))
+}
+
+// latencyHistoryKey returns the key under which the session's orchestrator is tracked: its ETH address
+// if known, otherwise its service URI (e.g. in off-chain mode)
+func latencyHistoryKey(sess *BroadcastSession) string {
+	sess.lock.RLock()
+	defer sess.lock.RUnlock()
+
+	info := sess.OrchestratorInfo
+	if info == nil {
+		return ""
+	}
+	if tp := info.GetTicketParams(); tp != nil && len(tp.Recipient) > 0 {
+		return ethcommon.BytesToAddress(tp.Recipient).Hex()
+	}
+	if len(info.Address) > 0 {
+		return ethcommon.BytesToAddress(info.Address).Hex()
+	}
+	return info.Transcoder
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/livepeer/go-livepeer/net"
+	"github.com/livepeer/go-livepeer/pm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyHistory_Percentile(t *testing.T) {
+	assert := assert.New(t)
+
+	h := NewLatencyHistory(time.Minute, 4)
+	now := time.Now()
+	h.now = func() time.Time { return now }
+
+	_, ok := h.Percentile("foo", 0.9)
+	assert.False(ok)
+	assert.False(h.Known("foo"))
+
+	for _, score := range []float64{0.5, 0.6, 0.7, 2.0} {
+		h.RecordSuccess("foo", score)
+	}
+	assert.True(h.Known("foo"))
+
+	p, ok := h.Percentile("foo", 0.5)
+	assert.True(ok)
+	assert.Equal(0.6, p)
+	p, _ = h.Percentile("foo", 0.9)
+	assert.Equal(2.0, p)
+
+	// Only the last maxSamples samples are kept
+	h.RecordSuccess("foo", 0.4)
+	assert.Len(h.orchs["foo"].samples, 4)
+	p, _ = h.Percentile("foo", 0.25)
+	assert.Equal(0.4, p)
+
+	// Old samples weigh less than recent ones
+	h = NewLatencyHistory(time.Minute, 4)
+	h.now = func() time.Time { return now }
+	h.RecordSuccess("foo", 3.0)
+	h.RecordSuccess("foo", 3.0)
+	h.now = func() time.Time { return now.Add(10 * time.Minute) }
+	h.RecordSuccess("foo", 0.5)
+	p, _ = h.Percentile("foo", 0.9)
+	assert.Equal(0.5, p)
+}
+
+func TestLatencyHistory_SuccessRate(t *testing.T) {
+	assert := assert.New(t)
+
+	h := NewLatencyHistory(time.Minute, 4)
+	now := time.Now()
+	h.now = func() time.Time { return now }
+
+	assert.Equal(1.0, h.SuccessRate("foo"))
+
+	h.RecordSuccess("foo", 1.0)
+	h.RecordFailure("foo")
+	assert.Equal(0.5, h.SuccessRate("foo"))
+
+	score, ok := h.Score("foo", 0.9)
+	assert.True(ok)
+	assert.Equal(2.0, score)
+
+	// Failures decay over time
+	h.now = func() time.Time { return now.Add(time.Minute) }
+	h.RecordSuccess("foo", 1.0)
+	assert.InDelta(0.75, h.SuccessRate("foo"), 0.0001)
+
+	// Orchestrator with failures only is known and scored with the max failure penalty
+	_, ok = h.Score("bar", 0.9)
+	assert.False(ok)
+	h.RecordFailure("bar")
+	assert.True(h.Known("bar"))
+	assert.Equal(0.0, h.SuccessRate("bar"))
+	score, ok = h.Score("bar", 0.9)
+	assert.True(ok)
+	assert.Equal(100.0, score)
+}
+
+func TestLatencyHistoryKey(t *testing.T) {
+	assert := assert.New(t)
+
+	sess := StubBroadcastSession("transcoder1")
+	recipient := pm.RandAddress()
+	sess.OrchestratorInfo.TicketParams = &net.TicketParams{Recipient: recipient.Bytes()}
+	assert.Equal(recipient.Hex(), latencyHistoryKey(sess))
+
+	// Fall back to the orchestrator address and then to the service URI
+	addr := pm.RandAddress()
+	sess.OrchestratorInfo.TicketParams = nil
+	sess.OrchestratorInfo.Address = addr.Bytes()
+	assert.Equal(addr.Hex(), latencyHistoryKey(sess))
+
+	sess.OrchestratorInfo.Address = nil
+	assert.Equal("transcoder1", latencyHistoryKey(sess))
+}
@@ -60,6 +60,29 @@ const HLSBufferWindow = uint(5)
 const StreamKeyBytes = 6
 
 const SegLen = 2 * time.Second
+
+// LLHLSPartTarget is the part target of the LL-HLS playlists of the streams that don't set their own, no LL-HLS if 0
+var LLHLSPartTarget time.Duration
+
+// DVRWindow is the length of the DVR window of the streams that don't set their own, no DVR if 0
+var DVRWindow time.Duration
+
+// LLHLSSegmentTarget is the minimum duration of the LL-HLS segments, which are made of parts
+const LLHLSSegmentTarget = 2 * time.Second
+
+// LLHLSMinPartTarget is the shortest part target of LL-HLS playlists
+const LLHLSMinPartTarget = 200 * time.Millisecond
+
+var errLLHLSEncrypted = errors.New("LL-HLS parts can't be encrypted")
+
+// checkLLHLSPartTarget returns an error unless the LL-HLS segments are made of at least two parts of the part target
+func checkLLHLSPartTarget(partTarget time.Duration) error {
+	if partTarget < LLHLSMinPartTarget || partTarget > LLHLSSegmentTarget/2 {
+		return fmt.Errorf("part target must be between %v and %v, provided %v", LLHLSMinPartTarget, LLHLSSegmentTarget/2, partTarget)
+	}
+	return nil
+}
+
 const BroadcastRetry = 15 * time.Second
 
 var BroadcastJobVideoProfiles = []ffmpeg.VideoProfile{ffmpeg.P240p30fps4x3, ffmpeg.P360p30fps16x9}
@@ -91,6 +114,10 @@ type rtmpConnection struct {
 	transcodedBytes uint64
 	mu              sync.Mutex
 	mediaFormat     ffmpeg.MediaFormatInfo
+	restreams       *restreamer
+	progress        *progressFeed
+	recordSaves     pendingSaves
+	firstTranscoded uint32 // set once the first segment of the stream is transcoded
 }
 
 func (s *LivepeerServer) getActiveRtmpConnectionUnsafe(mid core.ManifestID) (*rtmpConnection, bool) {
@@ -117,6 +144,7 @@ type LivepeerServer struct {
 	internalManifests map[core.ManifestID]core.ManifestID
 	lastHLSStreamID   core.StreamID
 	lastManifestID    core.ManifestID
+	whipSessions      map[string]*whipSession
 	context           context.Context
 	connectionLock    *sync.RWMutex
 	serverLock        *sync.RWMutex
@@ -142,6 +170,19 @@ type authWebhookResponse struct {
 	VerificationFreq   uint                 `json:"verificationFreq"`
 	TimeoutMultiplier  int                  `json:"timeoutMultiplier"`
 	ForceSessionReinit bool                 `json:"forceSessionReinit"`
+	// Account that the spending of the stream is attributed to for per-account budgets
+	AccountID string `json:"accountID"`
+	// Format of the transcoded renditions, "mpegts" by default or "fmp4" for fragmented MP4
+	OutputFormat string `json:"outputFormat"`
+	// Number of source segments between thumbnails, overrides -thumbnailInterval if set
+	ThumbnailInterval uint `json:"thumbnailInterval"`
+	// Length of the DVR window in seconds, overrides -dvrWindow if set
+	DVRWindow uint `json:"dvrWindow"`
+	// Part target of the LL-HLS playlists in milliseconds, overrides -llhlsPartTarget if set
+	LLHLSPartTarget uint `json:"llhlsPartTarget"`
+	// RTMP destinations that the source or renditions of the stream are pushed to
+	Restreams []webhookRestreamTarget `json:"restreams"`
+	webhookOrchConstraints
 }
 
 func NewLivepeerServer(rtmpAddr string, lpNode *core.LivepeerNode, httpIngest bool, transcodingOptions string) (*LivepeerServer, error) {
@@ -183,10 +224,12 @@ func NewLivepeerServer(rtmpAddr string, lpNode *core.LivepeerNode, httpIngest bo
 		serverLock:              &sync.RWMutex{},
 		rtmpConnections:         make(map[core.ManifestID]*rtmpConnection),
 		internalManifests:       make(map[core.ManifestID]core.ManifestID),
+		whipSessions:            make(map[string]*whipSession),
 		recordingsAuthResponses: cache.New(time.Hour, 2*time.Hour),
 	}
 	if lpNode.NodeType == core.BroadcasterNode && httpIngest {
 		opts.HttpMux.HandleFunc("/live/", ls.HandlePush)
+		opts.HttpMux.HandleFunc("/whip/", ls.HandleWHIP)
 	}
 	opts.HttpMux.HandleFunc("/recordings/", ls.HandleRecordings)
 	return ls, nil
@@ -203,8 +246,8 @@ func (s *LivepeerServer) StartMediaServer(ctx context.Context, httpAddr string)
 	s.LPMS.HandleRTMPPublish(createRTMPStreamIDHandler(ctx, s, nil), gotRTMPStreamHandler(s), endRTMPStreamHandler(s))
 	s.LPMS.HandleRTMPPlay(getRTMPStreamHandler(s))
 
-	//LPMS handler for handling HLS video play
-	s.LPMS.HandleHLSPlay(getHLSMasterPlaylistHandler(s), getHLSMediaPlaylistHandler(s), getHLSSegmentHandler(s))
+	//Handler for handling HLS and DASH video play
+	s.HTTPMux.HandleFunc("/stream/", s.HandlePlayback)
 
 	//Start the LPMS server
 	lpmsCtx, cancel := context.WithCancel(ctx)
@@ -254,6 +297,12 @@ func createRTMPStreamIDHandler(_ctx context.Context, s *LivepeerServer, webhookR
 		var oss, ross drivers.OSSession
 		profiles := []ffmpeg.VideoProfile{}
 		var VerificationFreq uint
+		var accountID string
+		var orchConstraints *core.OrchestratorConstraints
+		thumbnailInterval := ThumbnailInterval
+		dvrWindow := DVRWindow
+		llhlsPartTarget := LLHLSPartTarget
+		var restreams []core.RestreamTarget
 		nonce := rand.Uint64()
 
 		// do not replace captured _ctx variable
@@ -301,9 +350,28 @@ func createRTMPStreamIDHandler(_ctx context.Context, s *LivepeerServer, webhookR
 				profiles = BroadcastJobVideoProfiles
 			}
 
+			switch resp.OutputFormat {
+			case "", "mpegts":
+			case "fmp4":
+				// Don't modify the default profiles
+				profiles = append([]ffmpeg.VideoProfile(nil), profiles...)
+				for i := range profiles {
+					if profiles[i].Format == ffmpeg.FormatNone {
+						profiles[i].Format = common.FormatFMP4
+					}
+				}
+			default:
+				errMsg := fmt.Sprintf("Unknown output format for streamID url=%s format=%q", url.String(), resp.OutputFormat)
+				clog.Errorf(ctx, errMsg)
+				return nil, fmt.Errorf(errMsg)
+			}
+
 			// set OS if it was provided
 			if resp.ObjectStore != "" {
 				os, err = drivers.ParseOSURL(resp.ObjectStore, false)
+				if err == nil {
+					os, err = core.WithFileDeletion(resp.ObjectStore, os)
+				}
 				if err != nil {
 					errMsg := fmt.Sprintf("Failed to parse object store url for streamID url=%s err=%q", url.String(), err)
 					clog.Errorf(ctx, errMsg)
@@ -321,6 +389,38 @@ func createRTMPStreamIDHandler(_ctx context.Context, s *LivepeerServer, webhookR
 			}
 
 			VerificationFreq = resp.VerificationFreq
+			accountID = resp.AccountID
+			if resp.ThumbnailInterval > 0 {
+				thumbnailInterval = resp.ThumbnailInterval
+			}
+			if resp.DVRWindow > 0 {
+				dvrWindow = time.Duration(resp.DVRWindow) * time.Second
+			}
+			if resp.LLHLSPartTarget > 0 {
+				llhlsPartTarget = time.Duration(resp.LLHLSPartTarget) * time.Millisecond
+				err := checkLLHLSPartTarget(llhlsPartTarget)
+				if err == nil && HLSKeyProvider != nil {
+					err = errLLHLSEncrypted
+				}
+				if err != nil {
+					errMsg := fmt.Sprintf("Invalid LL-HLS part target for streamID url=%s err=%q", url.String(), err)
+					clog.Errorf(ctx, errMsg)
+					return nil, fmt.Errorf(errMsg)
+				}
+			}
+			restreams, err = parseRestreamTargets(resp.Restreams, profiles)
+			if err != nil {
+				errMsg := fmt.Sprintf("Failed to parse restream targets for streamID url=%s err=%q", url.String(), err)
+				clog.Errorf(ctx, errMsg)
+				return nil, fmt.Errorf(errMsg)
+			}
+
+			orchConstraints, err = parseOrchConstraints(resp.webhookOrchConstraints)
+			if err != nil {
+				errMsg := fmt.Sprintf("Failed to parse orchestrator constraints for streamID url=%s err=%q", url.String(), err)
+				clog.Errorf(ctx, errMsg)
+				return nil, fmt.Errorf(errMsg)
+			}
 		} else {
 			profiles = BroadcastJobVideoProfiles
 		}
@@ -363,11 +463,17 @@ func createRTMPStreamIDHandler(_ctx context.Context, s *LivepeerServer, webhookR
 			SessionID:        sessionID,
 			RtmpKey:          key,
 			// HTTP push mutates `profiles` so make a copy of it
-			Profiles:         append([]ffmpeg.VideoProfile(nil), profiles...),
-			OS:               oss,
-			RecordOS:         ross,
-			VerificationFreq: VerificationFreq,
-			Nonce:            nonce,
+			Profiles:          append([]ffmpeg.VideoProfile(nil), profiles...),
+			OS:                oss,
+			RecordOS:          ross,
+			VerificationFreq:  VerificationFreq,
+			Nonce:             nonce,
+			AccountID:         accountID,
+			OrchConstraints:   orchConstraints,
+			ThumbnailInterval: thumbnailInterval,
+			DVRWindow:         dvrWindow,
+			LLHLSPartTarget:   llhlsPartTarget,
+			Restreams:         restreams,
 		}, nil
 	}
 }
@@ -381,6 +487,15 @@ func streamParams(d stream.AppData) *core.StreamParameters {
 	return p
 }
 
+// streamSegLen returns the length of the segments cut from the source of the stream. Streams with LL-HLS playlists
+// are segmented at their part target, since every transcoded segment becomes a part
+func streamSegLen(params *core.StreamParameters) time.Duration {
+	if params != nil && params.LLHLSPartTarget > 0 {
+		return params.LLHLSPartTarget
+	}
+	return SegLen
+}
+
 func gotRTMPStreamHandler(s *LivepeerServer) func(url *url.URL, rtmpStrm stream.RTMPVideoStream) (err error) {
 	return func(url *url.URL, rtmpStrm stream.RTMPVideoStream) (err error) {
 
@@ -414,7 +529,7 @@ func gotRTMPStreamHandler(s *LivepeerServer) func(url *url.URL, rtmpStrm stream.
 
 			segOptions := segmenter.SegmenterOptions{
 				StartSeq:  startSeq,
-				SegLength: SegLen,
+				SegLength: streamSegLen(cxn.params),
 			}
 			err := s.RTMPSegmenter.SegmentRTMPToHLS(context.Background(), rtmpStrm, hlsStrm, segOptions)
 			if err != nil {
@@ -475,6 +590,13 @@ func (s *LivepeerServer) registerConnection(ctx context.Context, rtmpStrm stream
 		params.OS = drivers.NodeStorage.NewSession(string(mid))
 	}
 	storage := params.OS
+	if HLSKeyProvider != nil {
+		if err := checkEncryptedProfiles(params.Profiles); err != nil {
+			clog.Errorf(ctx, "Can't encrypt the renditions of manifestID=%s err=%q", mid, err)
+			return nil, err
+		}
+		params.Encrypt = true
+	}
 
 	// Generate and set capabilities
 	if actualStreamCodec != nil {
@@ -497,6 +619,20 @@ func (s *LivepeerServer) registerConnection(ctx context.Context, rtmpStrm stream
 	}
 	hlsStrmID := core.MakeStreamID(mid, &vProfile)
 	playlist := core.NewBasicPlaylistManager(mid, storage, recordStorage)
+	if params.LLHLSPartTarget > 0 {
+		playlist.EnableLLHLS(params.LLHLSPartTarget, LLHLSSegmentTarget)
+	}
+	if params.DVRWindow > 0 {
+		// The memory store only keeps the latest segments of the stream
+		if storage.IsExternal() {
+			playlist.EnableDVR(params.DVRWindow)
+		} else {
+			clog.Warningf(ctx, "Not enabling the DVR window without an object store for manifestID=%s", mid)
+		}
+	}
+	if params.Encrypt {
+		playlist.EnableEncryption(core.NewHLSEncrypter(HLSKeyProvider, mid, HLSKeyRotation))
+	}
 
 	// first, initialize connection without SessionManager, which creates O and T sessions, and may leave
 	// connectionLock locked for significant amount of time
@@ -509,6 +645,8 @@ func (s *LivepeerServer) registerConnection(ctx context.Context, rtmpStrm stream
 		profile:      &vProfile,
 		params:       params,
 		lastUsed:     time.Now(),
+		restreams:    newRestreamer(clog.AddManifestID(ctx, string(mid))),
+		progress:     newProgressFeed(),
 	}
 	s.connectionLock.Lock()
 	oldCxn, exists := s.getActiveRtmpConnectionUnsafe(mid)
@@ -518,6 +656,11 @@ func (s *LivepeerServer) registerConnection(ctx context.Context, rtmpStrm stream
 		return oldCxn, errAlreadyExists
 	}
 	s.rtmpConnections[mid] = cxn
+	for _, target := range params.Restreams {
+		if err := cxn.restreams.add(target); err != nil {
+			clog.Errorf(ctx, "Error adding restream target for manifestID=%s err=%q", mid, err)
+		}
+	}
 	// do not obtain this lock again while initializing channel is open, it will cause deadlock if other goroutine already obtained the lock and called getActiveRtmpConnectionUnsafe()
 	s.connectionLock.Unlock()
 
@@ -526,9 +669,7 @@ func (s *LivepeerServer) registerConnection(ctx context.Context, rtmpStrm stream
 	if s.LivepeerNode.Eth != nil {
 		stakeRdr = &storeStakeReader{store: s.LivepeerNode.Database}
 	}
-	selFactory := func() BroadcastSessionsSelector {
-		return NewMinLSSelector(stakeRdr, SELECTOR_LATENCY_SCORE_THRESHOLD, s.LivepeerNode.SelectionAlgorithm, s.LivepeerNode.OrchPerfScore)
-	}
+	selFactory := newSessionsSelectorFactory(stakeRdr, s.LivepeerNode)
 
 	// safe, because other goroutines should be waiting on initializing channel
 	cxn.sessManager = NewSessionManager(ctx, s.LivepeerNode, params, selFactory)
@@ -541,6 +682,7 @@ func (s *LivepeerServer) registerConnection(ctx context.Context, rtmpStrm stream
 
 	// connection is ready, only monitoring below
 	close(cxn.initializing)
+	Events.Send(clog.AddManifestID(ctx, string(mid)), newStreamEvent(EventStreamStarted, params))
 
 	// need lock to access rtmpConnections
 	s.connectionLock.RLock()
@@ -586,6 +728,16 @@ func removeRTMPStream(ctx context.Context, s *LivepeerServer, extmid core.Manife
 	cxn.stream.Close()
 	cxn.sessManager.cleanup(ctx)
 	cxn.pl.Cleanup()
+	cxn.restreams.stop()
+	cxn.progress.close()
+	Events.Send(ctx, newStreamEvent(EventStreamEnded, cxn.params))
+	if Events != nil && cxn.pl.GetRecordOSSession() != nil {
+		go finalizeRecording(ctx, cxn)
+	}
+	SegmentRetries.RemoveStream(intmid)
+	if cxn.params != nil && cxn.params.OrchConstraints != nil && cxn.params.OrchConstraints.MaxPrice != nil {
+		cxn.params.OrchConstraints.MaxPrice.Stop()
+	}
 	clog.Infof(ctx, "Ended stream with manifestID=%s external manifestID=%s", intmid, extmid)
 	delete(s.rtmpConnections, intmid)
 	delete(s.internalManifests, extmid)
@@ -651,6 +803,139 @@ func getHLSMediaPlaylistHandler(s *LivepeerServer) func(url *url.URL) (*m3u8.Med
 	}
 }
 
+// getDVRMediaPlaylistHandler returns the DVR media playlists of the streams with a DVR window. Players start at the
+// offset in seconds of the `start` query parameter, counted from the start of the window or back from the live edge
+// if it is negative
+func getDVRMediaPlaylistHandler(s *LivepeerServer) func(url *url.URL) (*bytes.Buffer, error) {
+	return func(url *url.URL) (*bytes.Buffer, error) {
+		strmID := parseStreamID(url.Path)
+		s.connectionLock.RLock()
+		cxn, ok := s.getActiveRtmpConnectionUnsafe(strmID.ManifestID)
+		s.connectionLock.RUnlock()
+		if !ok || cxn.pl == nil {
+			return nil, vidplayer.ErrNotFound
+		}
+		pl := cxn.pl.GetDVRMediaPlaylist(strmID.Rendition)
+		if pl == nil {
+			return nil, vidplayer.ErrNotFound
+		}
+		var start *time.Duration
+		if startStr := url.Query().Get("start"); startStr != "" {
+			secs, err := strconv.ParseFloat(startStr, 64)
+			if err != nil {
+				return nil, errDVRBadRequest
+			}
+			offset := time.Duration(secs * float64(time.Second))
+			start = &offset
+		}
+		return pl.Encode(start)
+	}
+}
+
+// getDASHManifestHandler returns the live DASH manifest of the stream at /stream/<manifestID>.mpd
+func getDASHManifestHandler(s *LivepeerServer) func(url *url.URL) (*core.DASHManifest, error) {
+	return func(url *url.URL) (*core.DASHManifest, error) {
+		var manifestID core.ManifestID
+		if s.ExposeCurrentManifest && strings.ToLower(url.Path) == "/stream/current.mpd" {
+			manifestID = s.LastManifestID()
+		} else {
+			sid := parseStreamID(url.Path)
+			if sid.Rendition != "" {
+				return nil, vidplayer.ErrNotFound
+			}
+			manifestID = sid.ManifestID
+		}
+
+		s.connectionLock.RLock()
+		defer s.connectionLock.RUnlock()
+		cxn, ok := s.getActiveRtmpConnectionUnsafe(manifestID)
+		if !ok || cxn.pl == nil {
+			return nil, vidplayer.ErrNotFound
+		}
+		mpd := cxn.pl.GetDASHManifest()
+		if mpd == nil {
+			return nil, vidplayer.ErrNotFound
+		}
+		return mpd, nil
+	}
+}
+
+// getLLHLSPlaylist returns the LL-HLS playlist of the rendition of the stream, without holding the connection lock
+// while requests block on it
+func getLLHLSPlaylist(s *LivepeerServer, strmID core.StreamID) (*core.LLHLSPlaylist, error) {
+	s.connectionLock.RLock()
+	defer s.connectionLock.RUnlock()
+	cxn, ok := s.getActiveRtmpConnectionUnsafe(strmID.ManifestID)
+	if !ok || cxn.pl == nil {
+		return nil, vidplayer.ErrNotFound
+	}
+	pl := cxn.pl.GetLLHLSMediaPlaylist(strmID.Rendition)
+	if pl == nil {
+		return nil, vidplayer.ErrNotFound
+	}
+	return pl, nil
+}
+
+// getLLHLSMediaPlaylistHandler returns LL-HLS media playlists. Blocking playlist reloads wait until the playlist has
+// the segment or part requested with _HLS_msn and _HLS_part
+func getLLHLSMediaPlaylistHandler(s *LivepeerServer) func(ctx context.Context, url *url.URL) (*core.LLHLSPlaylist, error) {
+	return func(ctx context.Context, url *url.URL) (*core.LLHLSPlaylist, error) {
+		pl, err := getLLHLSPlaylist(s, parseStreamID(url.Path))
+		if err != nil {
+			return nil, err
+		}
+
+		q := url.Query()
+		msnStr, partStr := q.Get("_HLS_msn"), q.Get("_HLS_part")
+		if msnStr == "" {
+			if partStr != "" {
+				return nil, errLLHLSBadRequest
+			}
+			return pl, nil
+		}
+		msn, err := strconv.ParseUint(msnStr, 10, 64)
+		if err != nil {
+			return nil, errLLHLSBadRequest
+		}
+		part := -1
+		if partStr != "" {
+			part, err = strconv.Atoi(partStr)
+			if err != nil || part < 0 {
+				return nil, errLLHLSBadRequest
+			}
+		}
+		ctx, cancel := context.WithTimeout(ctx, llhlsBlockingTimeout(pl))
+		defer cancel()
+		if err := pl.Wait(ctx, msn, part); err != nil {
+			return nil, err
+		}
+		return pl, nil
+	}
+}
+
+// getLLHLSSegmentHandler returns the data of LL-HLS parts and segments. Requests for the part that is hinted in the
+// playlist wait for it
+func getLLHLSSegmentHandler(s *LivepeerServer) func(ctx context.Context, url *url.URL) ([]byte, error) {
+	return func(ctx context.Context, url *url.URL) ([]byte, error) {
+		strmID := parseStreamID(url.Path)
+		rendition, name := path.Split(strmID.Rendition)
+		msn, part, ok := core.ParseLLHLSURI(name)
+		if !ok {
+			return nil, vidplayer.ErrNotFound
+		}
+		pl, err := getLLHLSPlaylist(s, core.MakeStreamIDFromString(string(strmID.ManifestID), strings.TrimSuffix(rendition, "/")))
+		if err != nil {
+			return nil, err
+		}
+		if part < 0 {
+			return pl.Segment(msn)
+		}
+		ctx, cancel := context.WithTimeout(ctx, llhlsBlockingTimeout(pl))
+		defer cancel()
+		return pl.Part(ctx, msn, part)
+	}
+}
+
 func getHLSSegmentHandler(s *LivepeerServer) func(url *url.URL) ([]byte, error) {
 	return func(url *url.URL) ([]byte, error) {
 		// Strip the /stream/ prefix
@@ -1231,25 +1516,34 @@ func (s *LivepeerServer) streamMP4(w http.ResponseWriter, r *http.Request, jpl *
 		fname := fmt.Sprintf("pipe:%d", ir.Fd())
 
 		in := &ffmpeg.TranscodeOptionsIn{Fname: fname, Transmuxing: true}
-		go func(segUri string, iw *os.File) {
+		// Fragmented MP4 segments can only be read after their init segment
+		segUris := []string{seg.URI}
+		if seg.InitURI != "" {
+			segUris = []string{seg.InitURI, seg.URI}
+		}
+		go func(segUris []string, iw *os.File) {
 			defer iw.Close()
-			glog.V(common.VERBOSE).Infof("Adding manifestID=%s track=%s uri=%s to mp4", manifestID, track, segUri)
-			resp, err := http.Get(segUri)
-			if err != nil {
-				glog.Errorf("Error getting HTTP uri=%s manifestID=%s err=%q", segUri, manifestID, err)
-				return
-			}
-			defer resp.Body.Close()
-			if resp.StatusCode != 200 {
-				glog.Errorf("Non-200 response for status=%v uri=%s manifestID=%s request=%s", resp.Status, segUri, manifestID, r.URL.String())
-				return
-			}
-			wn, err := io.Copy(iw, resp.Body)
-			if err != nil {
-				glog.Errorf("Error transmuxing to mp4 request=%s uri=%s manifestID=%s err=%q", r.URL.String(), segUri, manifestID, err)
+			for _, segUri := range segUris {
+				glog.V(common.VERBOSE).Infof("Adding manifestID=%s track=%s uri=%s to mp4", manifestID, track, segUri)
+				resp, err := http.Get(segUri)
+				if err != nil {
+					glog.Errorf("Error getting HTTP uri=%s manifestID=%s err=%q", segUri, manifestID, err)
+					return
+				}
+				if resp.StatusCode != 200 {
+					resp.Body.Close()
+					glog.Errorf("Non-200 response for status=%v uri=%s manifestID=%s request=%s", resp.Status, segUri, manifestID, r.URL.String())
+					return
+				}
+				wn, err := io.Copy(iw, resp.Body)
+				resp.Body.Close()
+				atomic.AddInt64(&sourceBytesSent, wn)
+				if err != nil {
+					glog.Errorf("Error transmuxing to mp4 request=%s uri=%s manifestID=%s err=%q", r.URL.String(), segUri, manifestID, err)
+					return
+				}
 			}
-			atomic.AddInt64(&sourceBytesSent, wn)
-		}(seg.URI, iw)
+		}(segUris, iw)
 
 		_, err = tc.Transcode(in, out)
 		ir.Close()
@@ -1268,7 +1562,7 @@ func (s *LivepeerServer) HandleRecordings(w http.ResponseWriter, r *http.Request
 		return
 	}
 	ext := path.Ext(r.URL.Path)
-	if ext != ".m3u8" && ext != ".ts" && ext != ".mp4" {
+	if ext != ".m3u8" && ext != ".ts" && ext != ".mp4" && ext != ".m4s" && ext != ".mpd" && ext != ".vtt" && ext != ".jpg" {
 		glog.Errorf(`/recordings request wrong extension=%s url=%s host=%s`, ext, r.URL, r.Host)
 		w.WriteHeader(http.StatusBadRequest)
 		return
@@ -1291,11 +1585,28 @@ func (s *LivepeerServer) HandleRecordings(w http.ResponseWriter, r *http.Request
 		glog.V(common.VERBOSE).Infof("request=%s took=%s headers=%+v", r.URL.String(), time.Since(now), w.Header())
 	}()
 	returnMasterPlaylist := pp[3] == "index.m3u8"
+	returnDASHManifest := pp[3] == "index.mpd"
+	// The thumbnails track and its sprite sheets are made from the thumbnails of the source segments
+	returnThumbnails := pp[3] == "thumbnails.vtt"
+	sprite, returnSprite := core.ParseSpriteName(pp[3])
 	var track string
-	if !returnMasterPlaylist {
+	if returnThumbnails || returnSprite {
+		track = "source"
+	} else if !returnMasterPlaylist && !returnDASHManifest {
 		tp := strings.Split(pp[3], ".")
 		track = tp[0]
 	}
+	// Clips are cut from the recording of a track
+	var clip *clipRequest
+	if pp[3] == "clip.mp4" || pp[3] == "clip.m3u8" {
+		c, err := parseClipRequest(r.URL.Query())
+		if err != nil {
+			glog.Errorf(`/recordings request wrong clip range url=%s host=%s`, r.URL, r.Host)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		clip, track = c, c.track
+	}
 	manifestID := pp[2]
 	requestFileName := strings.Join(pp[2:], "/")
 	var fromCache bool
@@ -1346,9 +1657,15 @@ func (s *LivepeerServer) HandleRecordings(w http.ResponseWriter, r *http.Request
 	if err == nil && fi != nil && fi.Body != nil {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Expose-Headers", "Content-Length")
-		if ext == ".ts" {
-			contentType, _ := common.TypeByExtension(".ts")
+		if ext == ".ts" || ext == ".m4s" || ext == ".mp4" {
+			// Segments, and init segments of fragmented MP4 renditions
+			contentType, _ := common.TypeByExtension(ext)
 			w.Header().Set("Content-Type", contentType)
+		} else if ext == ".mpd" {
+			w.Header().Set("Cache-Control", "max-age=5")
+			w.Header().Set("Content-Type", "application/dash+xml")
+		} else if ext == ".jpg" {
+			w.Header().Set("Content-Type", "image/jpeg")
 		} else {
 			w.Header().Set("Cache-Control", "max-age=5")
 			w.Header().Set("Content-Type", "application/x-mpegURL")
@@ -1378,7 +1695,7 @@ func (s *LivepeerServer) HandleRecordings(w http.ResponseWriter, r *http.Request
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
-	if time.Since(latestPlaylistTime) > 24*time.Hour && !finalizeSet && ext == ".m3u8" {
+	if time.Since(latestPlaylistTime) > 24*time.Hour && !finalizeSet && ext == ".m3u8" && clip == nil {
 		finalize = true
 	}
 
@@ -1408,7 +1725,7 @@ func (s *LivepeerServer) HandleRecordings(w http.ResponseWriter, r *http.Request
 				return
 			}
 			manifestMainJspl.AddMaster(jspl)
-			if finalize {
+			if finalize || returnDASHManifest {
 				for trackName := range jspl.Segments {
 					manifestMainJspl.AddTrack(jspl, trackName)
 				}
@@ -1425,7 +1742,7 @@ func (s *LivepeerServer) HandleRecordings(w http.ResponseWriter, r *http.Request
 		// join sessions
 		for _, jspl := range jsonPlaylists {
 			mainJspl.AddMaster(jspl)
-			if finalize {
+			if finalize || returnDASHManifest {
 				for trackName := range jspl.Segments {
 					mainJspl.AddDiscontinuedTrack(jspl, trackName)
 				}
@@ -1434,6 +1751,10 @@ func (s *LivepeerServer) HandleRecordings(w http.ResponseWriter, r *http.Request
 			}
 		}
 	}
+	if clip != nil {
+		s.serveClip(ctx, w, r, sess, mainJspl, manifests, manifestID, clip, resp)
+		return
+	}
 	if ext == ".mp4" {
 		if segs, has := mainJspl.Segments[track]; !has || len(segs) == 0 {
 			w.WriteHeader(http.StatusNotFound)
@@ -1442,6 +1763,57 @@ func (s *LivepeerServer) HandleRecordings(w http.ResponseWriter, r *http.Request
 		s.streamMP4(w, r, mainJspl, manifestID, track, pp[len(pp)-1])
 		return
 	}
+	if ext == ".vtt" || ext == ".jpg" {
+		if !returnThumbnails && !returnSprite {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Expose-Headers", "Content-Length")
+		w.Header().Set("Cache-Control", "max-age=5")
+		if returnThumbnails {
+			w.Header().Set("Content-Type", "text/vtt")
+			w.Write(mainJspl.EncodeThumbnailsVTT(track).Bytes())
+			return
+		}
+		thumbnails := mainJspl.SpriteThumbnails(manifests, track, sprite)
+		if len(thumbnails) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, datas, err := drivers.ParallelReadFiles(ctx, sess, thumbnails, 16)
+		if err != nil {
+			clog.Errorf(ctx, "Error reading thumbnails from store err=%q", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		data, err := core.ComposeSprite(datas)
+		if err != nil {
+			clog.Errorf(ctx, "Error composing sprite sheet err=%q", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write(data)
+		return
+	}
+	osUrl := ""
+	if resp != nil {
+		osUrl = resp.RecordObjectStoreURL
+	}
+	if ext == ".mpd" {
+		if !returnDASHManifest {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mpd := mainJspl.EncodeDASH(manifests, osUrl)
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Expose-Headers", "Content-Length")
+		w.Header().Set("Cache-Control", "max-age=5")
+		w.Header().Set("Content-Type", "application/dash+xml")
+		w.Write(mpd.Bytes())
+		return
+	}
 
 	masterPList := m3u8.NewMasterPlaylist()
 	mediaLists := make(map[string]*m3u8.MediaPlaylist)
@@ -1482,6 +1854,14 @@ func (s *LivepeerServer) HandleRecordings(w http.ResponseWriter, r *http.Request
 			}
 		}
 		nows := time.Now()
+		_, err = sess.SaveData(ctx, "index.mpd", mainJspl.EncodeDASH(manifests, osUrl), nil, 0)
+		clog.V(common.VERBOSE).Infof(ctx, "Saving manifest fileName=%s took=%s", "index.mpd", time.Since(nows))
+		if err != nil {
+			clog.Errorf(ctx, "Error saving DASH manifest to store err=%q", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		nows = time.Now()
 		_, err = sess.SaveData(ctx, "index.m3u8", masterPList.Encode(), nil, 0)
 		clog.V(common.VERBOSE).Infof(ctx, "Saving playlist fileName=%s took=%s", "index.m3u8", time.Since(nows))
 		if err != nil {
@@ -1492,10 +1872,6 @@ func (s *LivepeerServer) HandleRecordings(w http.ResponseWriter, r *http.Request
 	} else if !returnMasterPlaylist {
 		mpl := mediaLists[track]
 		if mpl != nil {
-			osUrl := ""
-			if resp != nil {
-				osUrl = resp.RecordObjectStoreURL
-			}
 			mainJspl.AddSegmentsToMPL(manifests, track, mpl, osUrl)
 			// check (debug code)
 			startSeq := mpl.Segments[0].SeqId
@@ -1577,6 +1953,7 @@ func (s *LivepeerServer) GetNodeStatus() *common.NodeStatus {
 	s.connectionLock.RLock()
 	defer s.connectionLock.RUnlock()
 	streamInfo := make(map[string]common.StreamInfo)
+	restreams := make(map[string][]common.RestreamStatus)
 	for _, cxn := range s.rtmpConnections {
 		if cxn.pl == nil {
 			continue
@@ -1589,6 +1966,9 @@ func (s *LivepeerServer) GetNodeStatus() *common.NodeStatus {
 			SourceBytes:     sb,
 			TranscodedBytes: tb,
 		}
+		if status := cxn.restreams.status(); len(status) > 0 {
+			restreams[string(cpl.ManifestID())] = status
+		}
 	}
 	res := &common.NodeStatus{
 		Manifests:             m,
@@ -1602,6 +1982,7 @@ func (s *LivepeerServer) GetNodeStatus() *common.NodeStatus {
 		RegisteredTranscoders: []common.RemoteTranscoderInfo{},
 		LocalTranscoding:      s.LivepeerNode.TranscoderManager == nil,
 		BroadcasterPrices:     make(map[string]*big.Rat),
+		Restreams:             restreams,
 	}
 	for k, v := range s.internalManifests {
 		res.InternalManifests[string(k)] = string(v)
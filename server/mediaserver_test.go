@@ -237,14 +237,14 @@ func TestSelectOrchestrator(t *testing.T) {
 	mid := core.RandomManifestID()
 	storage := drivers.NodeStorage.NewSession(string(mid))
 	sp := &core.StreamParameters{ManifestID: mid, Profiles: []ffmpeg.VideoProfile{ffmpeg.P360p30fps16x9}, OS: storage}
-	if _, err := selectOrchestrator(context.TODO(), s.LivepeerNode, sp, 4, newSuspender(), common.ScoreAtLeast(0)); err != errDiscovery {
+	if _, err := selectOrchestrator(context.TODO(), s.LivepeerNode, sp, 4, newCircuitBreakers(), common.ScoreAtLeast(0)); err != errDiscovery {
 		t.Error("Expected error with discovery")
 	}
 
 	sd := &stubDiscovery{}
 	// Discovery returned no orchestrators
 	s.LivepeerNode.OrchestratorPool = sd
-	if sess, err := selectOrchestrator(context.TODO(), s.LivepeerNode, sp, 4, newSuspender(), common.ScoreAtLeast(0)); sess != nil || err != errNoOrchs {
+	if sess, err := selectOrchestrator(context.TODO(), s.LivepeerNode, sp, 4, newCircuitBreakers(), common.ScoreAtLeast(0)); sess != nil || err != errNoOrchs {
 		t.Error("Expected nil session")
 	}
 
@@ -255,7 +255,7 @@ func TestSelectOrchestrator(t *testing.T) {
 		{PriceInfo: &net.PriceInfo{PricePerUnit: 1, PixelsPerUnit: 1}, TicketParams: &net.TicketParams{}, AuthToken: authToken0},
 		{PriceInfo: &net.PriceInfo{PricePerUnit: 1, PixelsPerUnit: 1}, TicketParams: &net.TicketParams{}, AuthToken: authToken1},
 	}
-	sess, _ := selectOrchestrator(context.TODO(), s.LivepeerNode, sp, 4, newSuspender(), common.ScoreAtLeast(0))
+	sess, _ := selectOrchestrator(context.TODO(), s.LivepeerNode, sp, 4, newCircuitBreakers(), common.ScoreAtLeast(0))
 
 	if len(sess) != len(sd.infos) {
 		t.Error("Expected session length of 2")
@@ -290,7 +290,7 @@ func TestSelectOrchestrator(t *testing.T) {
 	externalStorage := drivers.NodeStorage.NewSession(string(mid))
 	sp.OS = externalStorage
 
-	sess, err := selectOrchestrator(context.TODO(), s.LivepeerNode, sp, 4, newSuspender(), common.ScoreAtLeast(0))
+	sess, err := selectOrchestrator(context.TODO(), s.LivepeerNode, sp, 4, newCircuitBreakers(), common.ScoreAtLeast(0))
 	assert.Nil(err)
 
 	// B should initialize new OS session using auth token sessionID
@@ -378,7 +378,7 @@ func TestSelectOrchestrator(t *testing.T) {
 	expSessionID2 := "bar"
 	sender.On("StartSession", mock.Anything).Return(expSessionID2).Once()
 
-	sess, err = selectOrchestrator(context.TODO(), s.LivepeerNode, sp, 4, newSuspender(), common.ScoreAtLeast(0))
+	sess, err = selectOrchestrator(context.TODO(), s.LivepeerNode, sp, 4, newCircuitBreakers(), common.ScoreAtLeast(0))
 	require.Nil(err)
 
 	assert.Len(sess, 2)
@@ -407,7 +407,7 @@ func TestSelectOrchestrator(t *testing.T) {
 	// Skip orchestrator if missing auth token
 	sd.infos[0].AuthToken = nil
 
-	sess, err = selectOrchestrator(context.TODO(), s.LivepeerNode, sp, 4, newSuspender(), func(float32) bool { return true })
+	sess, err = selectOrchestrator(context.TODO(), s.LivepeerNode, sp, 4, newCircuitBreakers(), func(float32) bool { return true })
 	require.Nil(err)
 
 	assert.Len(sess, 1)
@@ -417,7 +417,7 @@ func TestSelectOrchestrator(t *testing.T) {
 	sd.infos[0].AuthToken = &net.AuthToken{}
 	sd.infos[0].TicketParams = nil
 
-	sess, err = selectOrchestrator(context.TODO(), s.LivepeerNode, sp, 4, newSuspender(), func(float32) bool { return true })
+	sess, err = selectOrchestrator(context.TODO(), s.LivepeerNode, sp, 4, newCircuitBreakers(), func(float32) bool { return true })
 	require.Nil(err)
 
 	assert.Len(sess, 1)
@@ -693,6 +693,133 @@ func TestCreateRTMPStreamHandlerWebhook(t *testing.T) {
 	osinfo = params.RecordOS.GetInfo()
 	assert.Equal(int32(net.OSInfo_S3), int32(osinfo.StorageType))
 	assert.Equal("http://record.store", osinfo.S3Info.Host)
+
+	// set orchestrator constraints
+	ts18 := makeServer(`{"manifestID":"a4", "maxPricePerUnit": 10, "pixelsPerUnit": 2, "orchAllowlist": ["https://o1.example.com"], "orchDenylist": ["https://o2.example.com"], "minPerfScore": 0.5}`)
+	defer ts18.Close()
+	id5, err := createSid(u)
+	require.NoError(t, err)
+	params = id5.(*core.StreamParameters)
+	require.NotNil(t, params.OrchConstraints)
+	assert.Equal(big.NewRat(5, 1), params.OrchConstraints.MaxPrice.Value())
+	assert.Equal([]string{"https://o1.example.com"}, params.OrchConstraints.Allowlist)
+	assert.Equal([]string{"https://o2.example.com"}, params.OrchConstraints.Denylist)
+	assert.Equal(0.5, params.OrchConstraints.MinPerfScore)
+
+	// do not create stream if the max price is invalid
+	ts19 := makeServer(`{"manifestID":"a4", "maxPricePerUnit": -1}`)
+	defer ts19.Close()
+	sid, err = createSid(u)
+	require.Error(t, err)
+	assert.Nil(sid)
+
+	// set the output format, without changing the default profiles
+	ts20 := makeServer(`{"manifestID":"a5", "outputFormat": "fmp4"}`)
+	defer ts20.Close()
+	id6, err := createSid(u)
+	require.NoError(t, err)
+	params = id6.(*core.StreamParameters)
+	assert.Len(params.Profiles, 1)
+	assert.Equal(common.FormatFMP4, params.Profiles[0].Format)
+	assert.Equal(ffmpeg.FormatNone, BroadcastJobVideoProfiles[0].Format)
+
+	// do not create stream if the output format is unknown
+	ts21 := makeServer(`{"manifestID":"a5", "outputFormat": "webm"}`)
+	defer ts21.Close()
+	sid, err = createSid(u)
+	require.Error(t, err)
+	assert.Nil(sid)
+
+	// thumbnail interval from the webhook overrides the node default
+	oldThumbnailInterval := ThumbnailInterval
+	defer func() { ThumbnailInterval = oldThumbnailInterval }()
+	ThumbnailInterval = 5
+	ts22 := makeServer(`{"manifestID":"a5"}`)
+	defer ts22.Close()
+	id7, err := createSid(u)
+	require.NoError(t, err)
+	assert.Equal(uint(5), id7.(*core.StreamParameters).ThumbnailInterval)
+	ts23 := makeServer(`{"manifestID":"a5", "thumbnailInterval": 2}`)
+	defer ts23.Close()
+	id8, err := createSid(u)
+	require.NoError(t, err)
+	assert.Equal(uint(2), id8.(*core.StreamParameters).ThumbnailInterval)
+
+	// DVR window from the webhook overrides the node default
+	oldDVRWindow := DVRWindow
+	defer func() { DVRWindow = oldDVRWindow }()
+	DVRWindow = time.Hour
+	ts24 := makeServer(`{"manifestID":"a5"}`)
+	defer ts24.Close()
+	id9, err := createSid(u)
+	require.NoError(t, err)
+	assert.Equal(time.Hour, id9.(*core.StreamParameters).DVRWindow)
+	ts25 := makeServer(`{"manifestID":"a5", "dvrWindow": 600}`)
+	defer ts25.Close()
+	id10, err := createSid(u)
+	require.NoError(t, err)
+	assert.Equal(10*time.Minute, id10.(*core.StreamParameters).DVRWindow)
+
+	// restream targets push the source unless a rendition is set
+	ts26 := makeServer(`{"manifestID":"a5", "profiles": [{"name": "P144p30fps16x9", "width": 256, "height": 144, "bitrate": 400000, "fps": 30}],
+		"restreams": [{"url": "rtmp://live.example.com/app/key1"}, {"url": "rtmps://live.example.com/app/key2", "profile": "P144p30fps16x9"}]}`)
+	defer ts26.Close()
+	id11, err := createSid(u)
+	require.NoError(t, err)
+	assert.Equal([]core.RestreamTarget{
+		{URL: "rtmp://live.example.com/app/key1", Profile: "source"},
+		{URL: "rtmps://live.example.com/app/key2", Profile: "P144p30fps16x9"},
+	}, id11.(*core.StreamParameters).Restreams)
+
+	// do not create stream if a restream target is invalid
+	ts27 := makeServer(`{"manifestID":"a5", "restreams": [{"url": "https://live.example.com/app/key1"}]}`)
+	defer ts27.Close()
+	sid, err = createSid(u)
+	require.Error(t, err)
+	assert.Nil(sid)
+
+	// LL-HLS part target from the webhook overrides the node default
+	oldLLHLSPartTarget := LLHLSPartTarget
+	defer func() { LLHLSPartTarget = oldLLHLSPartTarget }()
+	LLHLSPartTarget = 500 * time.Millisecond
+	ts28 := makeServer(`{"manifestID":"a5"}`)
+	defer ts28.Close()
+	id12, err := createSid(u)
+	require.NoError(t, err)
+	assert.Equal(500*time.Millisecond, id12.(*core.StreamParameters).LLHLSPartTarget)
+	LLHLSPartTarget = 0
+	ts29 := makeServer(`{"manifestID":"a5", "llhlsPartTarget": 300}`)
+	defer ts29.Close()
+	id13, err := createSid(u)
+	require.NoError(t, err)
+	assert.Equal(300*time.Millisecond, id13.(*core.StreamParameters).LLHLSPartTarget)
+
+	// do not create stream if the part target is out of range, or if segments are encrypted
+	ts30 := makeServer(`{"manifestID":"a5", "llhlsPartTarget": 1500}`)
+	defer ts30.Close()
+	sid, err = createSid(u)
+	require.Error(t, err)
+	assert.Nil(sid)
+	oldKeyProvider := HLSKeyProvider
+	defer func() { HLSKeyProvider = oldKeyProvider }()
+	HLSKeyProvider = core.NewFileKeyProvider(t.TempDir(), "")
+	ts31 := makeServer(`{"manifestID":"a5", "llhlsPartTarget": 300}`)
+	defer ts31.Close()
+	sid, err = createSid(u)
+	require.Error(t, err)
+	assert.Nil(sid)
+}
+
+func TestStreamSegLen(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(SegLen, streamSegLen(nil))
+	assert.Equal(SegLen, streamSegLen(&core.StreamParameters{}))
+	// Only LL-HLS streams are segmented at their part target
+	assert.Equal(500*time.Millisecond, streamSegLen(&core.StreamParameters{LLHLSPartTarget: 500 * time.Millisecond}))
+	assert.Nil(checkLLHLSPartTarget(LLHLSMinPartTarget))
+	assert.Nil(checkLLHLSPartTarget(LLHLSSegmentTarget / 2))
+	assert.EqualError(checkLLHLSPartTarget(100*time.Millisecond), "part target must be between 200ms and 1s, provided 100ms")
+	assert.Error(checkLLHLSPartTarget(1500 * time.Millisecond))
 }
 
 func TestCreateRTMPStreamHandler(t *testing.T) {
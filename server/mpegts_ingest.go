@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/clog"
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-livepeer/monitor"
+	"github.com/livepeer/lpms/ffmpeg"
+	"github.com/livepeer/lpms/stream"
+)
+
+// ingestMPEGTS segments the MPEG-TS stream with the lpms segmenter until it ends and processes the segments like the
+// segments of an RTMP stream. The stream is registered with the codec of its first segment, like streams pushed over HTTP. stop is called
+// to end the ingest if the stream can't be registered or if it ended, e.g. another ingest replaced it, and onSegment
+// is called for every segment that is processed
+func (s *LivepeerServer) ingestMPEGTS(ctx context.Context, r io.Reader, st stream.RTMPVideoStream, stop func(), onSegment func(context.Context)) error {
+	var cxn *rtmpConnection
+	// Data that was already read is still segmented after the ingest is stopped, ignore its segments
+	stopped := false
+	stopIngest := func() {
+		stopped = true
+		stop()
+	}
+
+	err := segmentMPEGTS(ctx, s.LivepeerNode.WorkDir, r, streamSegLen(streamParams(st.AppData())), func(seg *stream.HLSSegment) {
+		if stopped {
+			return
+		}
+		ctx := clog.AddSeqNo(ctx, seg.SeqNo)
+		status, mediaFormat, err := ffmpeg.GetCodecInfoBytes(seg.Data)
+		if err != nil {
+			clog.Errorf(ctx, "Error getting codec info of segment err=%q", err)
+			return
+		}
+		seg.IsZeroFrame = status == ffmpeg.CodecStatusNeedsBypass
+
+		if cxn == nil {
+			var vcodec *ffmpeg.VideoCodec
+			if vcodecVal, ok := ffmpeg.FfmpegNameToVideoCodec[mediaFormat.Vcodec]; ok {
+				vcodec = &vcodecVal
+			} else {
+				clog.Warningf(ctx, "Couldn't detect input video stream codec=%s", mediaFormat.Vcodec)
+			}
+			cxn, err = s.registerConnection(ctx, st, vcodec, mediaFormat.PixFormat, nil)
+			if err != nil {
+				clog.Errorf(ctx, "Error registering stream err=%q", err)
+				cxn = nil
+				stopIngest()
+				return
+			}
+			cxn.mediaFormat = mediaFormat
+			if monitor.Enabled {
+				monitor.StreamCreated(string(cxn.mid), cxn.nonce)
+				monitor.StreamStarted(cxn.nonce)
+			}
+			glog.Infof("\n\nVideo Created With ManifestID: %v\n\n", cxn.mid)
+		} else if !s.isActiveConnection(cxn) {
+			clog.Infof(ctx, "Stopping ingest, stream ended")
+			stopIngest()
+			return
+		}
+
+		var segPar core.SegmentParameters
+		cxn.mu.Lock()
+		if cxn.mediaFormat != mediaFormat {
+			// Reinitialize HW Session if video segment resolution has changed
+			cxn.mediaFormat = mediaFormat
+			segPar.ForceSessionReinit = true
+		}
+		cxn.mu.Unlock()
+		s.connectionLock.Lock()
+		cxn.lastUsed = time.Now()
+		s.connectionLock.Unlock()
+
+		if onSegment != nil {
+			onSegment(ctx)
+		}
+		go processSegment(ctx, cxn, seg, &segPar)
+	})
+
+	if cxn != nil && s.isActiveConnection(cxn) {
+		removeRTMPStream(context.Background(), s, cxn.mid)
+	} else if cxn == nil {
+		st.Close()
+	}
+	return err
+}
+
+// isActiveConnection returns false if the stream of the connection ended, e.g. another ingest replaced it
+func (s *LivepeerServer) isActiveConnection(cxn *rtmpConnection) bool {
+	s.connectionLock.RLock()
+	defer s.connectionLock.RUnlock()
+	active, exists := s.getActiveRtmpConnectionUnsafe(cxn.mid)
+	return exists && active == cxn
+}
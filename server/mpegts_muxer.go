@@ -0,0 +1,233 @@
+package server
+
+import (
+	"io"
+)
+
+const (
+	tsPacketSize = 188
+	tsSyncByte   = 0x47
+	tsPATPID     = 0
+
+	tsPMTPID   = 0x1000
+	tsVideoPID = 0x100
+	tsAudioPID = 0x101
+
+	tsStreamTypeH264        = 0x1B
+	tsStreamTypePrivateData = 0x06
+
+	pesStreamIDVideo    = 0xE0
+	pesStreamIDPrivate1 = 0xBD
+
+	// PTS are 33 bit on a 90kHz clock
+	ptsClockRate = 90000
+	ptsMask      = 1<<33 - 1
+)
+
+// mpegtsMuxer writes H.264 video and Opus audio to an MPEG-TS stream. Opus is carried as private data with the
+// registration descriptor of Opus, like ffmpeg does. The PAT and PMT are written before every keyframe, and the PCR is
+// written with every PES packet of the video, or of the audio if there is no video
+type mpegtsMuxer struct {
+	w            io.Writer
+	video, audio bool
+
+	tablesWritten bool
+	cc            map[int]byte
+	pkt           []byte
+}
+
+func newMPEGTSMuxer(w io.Writer, video, audio bool) *mpegtsMuxer {
+	return &mpegtsMuxer{
+		w:     w,
+		video: video,
+		audio: audio,
+		cc:    make(map[int]byte),
+		pkt:   make([]byte, 0, tsPacketSize),
+	}
+}
+
+// writeVideo writes an H.264 access unit in Annex B format with the 90kHz PTS
+func (m *mpegtsMuxer) writeVideo(pts int64, au []byte) error {
+	keyframe := containsKeyframe(au)
+	if keyframe || !m.tablesWritten {
+		if err := m.writeTables(); err != nil {
+			return err
+		}
+	}
+	pes := append(pesHeader(pesStreamIDVideo, pts, 0), au...)
+	return m.writePES(tsVideoPID, pes, keyframe, pts)
+}
+
+// writeAudio writes an Opus packet with the 90kHz PTS
+func (m *mpegtsMuxer) writeAudio(pts int64, packet []byte) error {
+	if !m.tablesWritten {
+		if err := m.writeTables(); err != nil {
+			return err
+		}
+	}
+	// The control header has the size of the packet as a sequence of 0xFF bytes followed by the remainder
+	data := []byte{0x7F, 0xE0}
+	for n := len(packet); ; n -= 0xFF {
+		if n < 0xFF {
+			data = append(data, byte(n))
+			break
+		}
+		data = append(data, 0xFF)
+	}
+	data = append(data, packet...)
+	pcr := int64(-1)
+	if !m.video {
+		pcr = pts
+	}
+	return m.writePES(tsAudioPID, append(pesHeader(pesStreamIDPrivate1, pts, len(data)), data...), !m.video, pcr)
+}
+
+func (m *mpegtsMuxer) writeTables() error {
+	pat := []byte{0x00, 0xB0, 0, 0x00, 0x01, 0xC1, 0, 0, 0x00, 0x01, 0xE0 | tsPMTPID>>8, tsPMTPID & 0xFF}
+	if err := m.writePSI(tsPATPID, pat); err != nil {
+		return err
+	}
+
+	pcrPID := tsAudioPID
+	if m.video {
+		pcrPID = tsVideoPID
+	}
+	pmt := []byte{0x02, 0xB0, 0, 0x00, 0x01, 0xC1, 0, 0, 0xE0 | byte(pcrPID>>8), byte(pcrPID), 0xF0, 0}
+	if m.video {
+		pmt = append(pmt, tsStreamTypeH264, 0xE0|tsVideoPID>>8, tsVideoPID&0xFF, 0xF0, 0)
+	}
+	if m.audio {
+		// Registration descriptor of Opus and extension descriptor with the stereo channel configuration
+		desc := []byte{0x05, 4, 'O', 'p', 'u', 's', 0x7F, 2, 0x80, 2}
+		pmt = append(pmt, tsStreamTypePrivateData, 0xE0|tsAudioPID>>8, tsAudioPID&0xFF, 0xF0, byte(len(desc)))
+		pmt = append(pmt, desc...)
+	}
+	if err := m.writePSI(tsPMTPID, pmt); err != nil {
+		return err
+	}
+	m.tablesWritten = true
+	return nil
+}
+
+// writePSI writes a section that fits in a single packet, setting its length and appending its CRC
+func (m *mpegtsMuxer) writePSI(pid int, sec []byte) error {
+	sectionLen := len(sec) - 3 + 4
+	sec[1] = sec[1]&0xF0 | byte(sectionLen>>8)
+	sec[2] = byte(sectionLen)
+	crc := crc32MPEG2(sec)
+	sec = append(sec, byte(crc>>24), byte(crc>>16), byte(crc>>8), byte(crc))
+
+	pkt := append(m.packetHeader(pid, true, false), 0)
+	pkt = append(pkt, sec...)
+	for len(pkt) < tsPacketSize {
+		pkt = append(pkt, 0xFF)
+	}
+	_, err := m.w.Write(pkt)
+	return err
+}
+
+// writePES splits the PES packet into TS packets. The first one has the PCR if it isn't negative
+func (m *mpegtsMuxer) writePES(pid int, pes []byte, randomAccess bool, pcr int64) error {
+	first := true
+	for len(pes) > 0 {
+		// Adaptation field without its length
+		var af []byte
+		if first && (randomAccess || pcr >= 0) {
+			af = []byte{0}
+			if randomAccess {
+				af[0] |= 0x40
+			}
+			if pcr >= 0 {
+				pcr &= ptsMask
+				af[0] |= 0x10
+				af = append(af, byte(pcr>>25), byte(pcr>>17), byte(pcr>>9), byte(pcr>>1), byte(pcr<<7)|0x7E, 0)
+			}
+		}
+		afSize := 0
+		if af != nil {
+			afSize = 1 + len(af)
+		}
+		if 4+afSize+len(pes) < tsPacketSize {
+			// Stuff the last packet of the PES packet
+			if af == nil && tsPacketSize-4-len(pes) >= 2 {
+				af = []byte{0}
+			} else if af == nil {
+				af = []byte{}
+			}
+			for 4+1+len(af)+len(pes) < tsPacketSize {
+				af = append(af, 0xFF)
+			}
+		}
+
+		pkt := m.packetHeader(pid, first, af != nil)
+		if af != nil {
+			pkt = append(append(pkt, byte(len(af))), af...)
+		}
+		n := tsPacketSize - len(pkt)
+		if n > len(pes) {
+			n = len(pes)
+		}
+		pkt = append(pkt, pes[:n]...)
+		pes = pes[n:]
+		if _, err := m.w.Write(pkt); err != nil {
+			return err
+		}
+		first = false
+	}
+	return nil
+}
+
+func (m *mpegtsMuxer) packetHeader(pid int, pusi, adaptationField bool) []byte {
+	cc := m.cc[pid]
+	m.cc[pid] = (cc + 1) & 0x0F
+	hdr := append(m.pkt[:0], tsSyncByte, byte(pid>>8&0x1F), byte(pid), 0x10|cc)
+	if pusi {
+		hdr[1] |= 0x40
+	}
+	if adaptationField {
+		hdr[3] |= 0x20
+	}
+	return hdr
+}
+
+// pesHeader returns the header of a PES packet with the PTS. The length of the payload is only set if it fits, which
+// is allowed for video streams
+func pesHeader(streamID byte, pts int64, payloadLen int) []byte {
+	length := 0
+	if payloadLen > 0 && 8+payloadLen <= 0xFFFF {
+		length = 8 + payloadLen
+	}
+	pts &= ptsMask
+	return []byte{0, 0, 1, streamID, byte(length >> 8), byte(length), 0x80, 0x80, 5,
+		byte(0x21 | pts>>29&0x0E), byte(pts >> 22), byte(pts>>14&0xFE | 1), byte(pts >> 7), byte(pts<<1&0xFE | 1)}
+}
+
+// crc32MPEG2 returns the CRC of PSI sections, which is not reflected unlike the CRC of hash/crc32
+func crc32MPEG2(data []byte) uint32 {
+	crc := uint32(0xFFFFFFFF)
+	for _, b := range data {
+		crc ^= uint32(b) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// containsKeyframe returns true if the H.264 access unit has an IDR picture or parameter sets, which encoders send
+// with keyframes
+func containsKeyframe(au []byte) bool {
+	for i := 0; i+3 < len(au); i++ {
+		if au[i] != 0 || au[i+1] != 0 || au[i+2] != 1 {
+			continue
+		}
+		if t := au[i+3] & 0x1F; t == 5 || t == 7 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tsPayload returns the payload of the TS packet
+func tsPayload(pkt []byte) []byte {
+	if pkt[3]&0x20 != 0 {
+		return pkt[4+1+int(pkt[4]):]
+	}
+	return pkt[4:]
+}
+
+// psiSection returns the section of a PSI table starting in the payload, without its CRC
+func psiSection(payload []byte, tableID byte) ([]byte, bool) {
+	if len(payload) < 1 || len(payload) < 1+int(payload[0])+3 {
+		return nil, false
+	}
+	sec := payload[1+int(payload[0]):]
+	if sec[0] != tableID {
+		return nil, false
+	}
+	end := 3 + (int(sec[1]&0x0F)<<8 | int(sec[2])) - 4
+	if end < 8 || end > len(sec) {
+		return nil, false
+	}
+	return sec[:end], true
+}
+
+// parsePAT returns the PID of the PMT of the first program
+func parsePAT(payload []byte) (int, bool) {
+	sec, ok := psiSection(payload, 0x00)
+	if !ok || len(sec) < 12 {
+		return 0, false
+	}
+	return int(sec[10]&0x1F)<<8 | int(sec[11]), true
+}
+
+// parsePMT returns the PID and stream type of the first stream
+func parsePMT(payload []byte) (int, byte, bool) {
+	sec, ok := psiSection(payload, 0x02)
+	if !ok || len(sec) < 12 {
+		return 0, 0, false
+	}
+	i := 12 + (int(sec[10]&0x0F)<<8 | int(sec[11]))
+	if i+5 > len(sec) {
+		return 0, 0, false
+	}
+	return int(sec[i+1]&0x1F)<<8 | int(sec[i+2]), sec[i], true
+}
+
+// parsePES returns the PTS and the elementary stream data of a PES packet
+func parsePES(payload []byte) (int64, []byte, bool) {
+	if len(payload) < 14 || payload[0] != 0 || payload[1] != 0 || payload[2] != 1 || payload[7]&0x80 == 0 {
+		return 0, nil, false
+	}
+	p := payload[9:14]
+	pts := int64(p[0]>>1&0x07)<<30 | int64(p[1])<<22 | int64(p[2]>>1)<<15 | int64(p[3])<<7 | int64(p[4]>>1)
+	return pts, payload[9+int(payload[8]):], true
+}
+
+var (
+	testH264IDR   = []byte{0, 0, 0, 1, 0x09, 0xF0, 0, 0, 0, 1, 0x65, 0x88}
+	testH264Frame = []byte{0, 0, 0, 1, 0x09, 0xF0, 0, 0, 0, 1, 0x41, 0x9A}
+)
+
+func TestMPEGTSMuxer(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var buf bytes.Buffer
+	m := newMPEGTSMuxer(&buf, true, true)
+	// 5s at 30fps with a keyframe every second, and an audio packet with every frame
+	for i := int64(0); i < 150; i++ {
+		au := testH264Frame
+		if i%30 == 0 {
+			au = testH264IDR
+		}
+		require.Nil(m.writeVideo(3000*i, au))
+		require.Nil(m.writeAudio(3000*i+100, []byte{0xFC, 1, 2}))
+	}
+	ts := buf.Bytes()
+	require.Zero(len(ts) % tsPacketSize)
+
+	// The stream starts with the PAT and the PMT, whose CRCs are valid
+	pmtPID, ok := parsePAT(tsPayload(ts[:tsPacketSize]))
+	require.True(ok)
+	assert.Equal(tsPMTPID, pmtPID)
+	refPID, streamType, ok := parsePMT(tsPayload(ts[tsPacketSize : 2*tsPacketSize]))
+	require.True(ok)
+	assert.Equal(tsVideoPID, refPID)
+	assert.Equal(byte(tsStreamTypeH264), streamType)
+	for _, pkt := range [][]byte{ts[:tsPacketSize], ts[tsPacketSize : 2*tsPacketSize]} {
+		payload := tsPayload(pkt)
+		sec := payload[1:]
+		sectionLen := int(sec[1]&0x0F)<<8 | int(sec[2])
+		assert.Zero(crc32MPEG2(sec[:3+sectionLen]))
+	}
+	// PAT with the PMT on the PID that ffmpeg uses, which has a well known CRC
+	assert.Equal([]byte{0x2A, 0xB1, 0x04, 0xB2}, tsPayload(ts[:tsPacketSize])[1+12:1+16])
+	// The PMT has the Opus registration descriptor
+	assert.True(bytes.Contains(tsPayload(ts[tsPacketSize:2*tsPacketSize]), []byte{0x05, 4, 'O', 'p', 'u', 's'}))
+
+	// The keyframe has the random access indicator and the PCR
+	video := ts[2*tsPacketSize : 3*tsPacketSize]
+	assert.Equal(byte(tsVideoPID&0xFF), video[2])
+	assert.Equal(byte(0x50), video[5])
+	pts, es, ok := parsePES(tsPayload(video))
+	require.True(ok)
+	assert.Zero(pts)
+	assert.Equal(testH264IDR, es)
+
+	// Opus packets are prefixed with the control header
+	audio := ts[3*tsPacketSize : 4*tsPacketSize]
+	assert.Equal(byte(tsAudioPID&0xFF), audio[2])
+	pts, es, ok = parsePES(tsPayload(audio))
+	require.True(ok)
+	assert.Equal(int64(100), pts)
+	assert.Equal([]byte{0x7F, 0xE0, 3, 0xFC, 1, 2}, es)
+
+	// The tables are repeated before every keyframe, so that the segmenter can cut on them
+	pats := 0
+	for i := 0; i < len(ts); i += tsPacketSize {
+		if ts[i+1]&0x1F == 0 && ts[i+2] == tsPATPID {
+			pats++
+		}
+	}
+	assert.Equal(5, pats)
+}
+
+func TestMPEGTSMuxer_AudioOnly(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var buf bytes.Buffer
+	m := newMPEGTSMuxer(&buf, false, true)
+	// Packets that don't fit in a single TS packet have their size split in the control header
+	packet := bytes.Repeat([]byte{0xFC}, 300)
+	for i := int64(0); i < 100; i++ {
+		require.Nil(m.writeAudio(1800*i, packet))
+	}
+	ts := buf.Bytes()
+	require.Zero(len(ts) % tsPacketSize)
+
+	refPID, streamType, ok := parsePMT(tsPayload(ts[tsPacketSize : 2*tsPacketSize]))
+	require.True(ok)
+	assert.Equal(tsAudioPID, refPID)
+	assert.Equal(byte(tsStreamTypePrivateData), streamType)
+
+	// Audio packets have the PCR since there is no video
+	audio := ts[2*tsPacketSize : 3*tsPacketSize]
+	assert.Equal(byte(0x50), audio[5])
+	_, es, ok := parsePES(tsPayload(audio))
+	require.True(ok)
+	assert.Equal([]byte{0x7F, 0xE0, 0xFF, 45, 0xFC}, es[:5])
+	// The continuity counter of the second packet of the PES packet is incremented
+	assert.Equal(audio[3]&0x0F+1, ts[3*tsPacketSize+3]&0x0F)
+}
+
+func TestContainsKeyframe(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(containsKeyframe(testH264IDR))
+	assert.False(containsKeyframe(testH264Frame))
+	// SPS
+	assert.True(containsKeyframe([]byte{0, 0, 1, 0x67}))
+	assert.False(containsKeyframe([]byte{0, 0, 1}))
+}
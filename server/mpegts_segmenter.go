@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"time"
+
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/lpms/segmenter"
+	"github.com/livepeer/lpms/stream"
+)
+
+// segmentMPEGTS segments the MPEG-TS stream with the lpms segmenter until it ends and passes the segments to out.
+// The segmenter reads the stream from a loopback TCP connection, like it reads RTMP streams from the local RTMP
+// server, so the stream needs a video and an audio track. The segment that is cut when the stream ends is dropped,
+// like the last segment of RTMP streams
+func segmentMPEGTS(ctx context.Context, workDir string, r io.Reader, segLen time.Duration, out func(*stream.HLSSegment)) error {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+
+	readErr := make(chan error, 1)
+	go func() {
+		conn, err := ln.Accept()
+		ln.Close()
+		if err != nil {
+			readErr <- err
+			return
+		}
+		_, err = io.Copy(conn, r)
+		// Report the error before the segmenter reads the end of the stream
+		readErr <- err
+		conn.Close()
+	}()
+
+	segCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	seg := segmenter.NewFFMpegVideoSegmenter(workDir, string(core.RandomManifestID()), "tcp://"+ln.Addr().String(),
+		segmenter.SegmenterOptions{SegLength: segLen})
+	segErr := make(chan error, 1)
+	polled := make(chan struct{})
+	defer close(polled)
+	go func() {
+		segErr <- seg.RTMPToHLS(segCtx, false)
+		cancel()
+		// Remove the files once the segments that were cut before the stream ended are polled
+		<-polled
+		seg.Cleanup()
+	}()
+
+	for {
+		// Segments are still returned after the segmenter exited, until the last complete one
+		vseg, err := seg.PollSegment(segCtx)
+		if err != nil {
+			select {
+			case err := <-segErr:
+				if err != nil {
+					return err
+				}
+			default:
+				// The segmenter is still running, e.g. it timed out waiting for a keyframe. It ends once the caller
+				// stops the stream
+				if err != context.Canceled {
+					return err
+				}
+			}
+			select {
+			case err := <-readErr:
+				return err
+			default:
+				return nil
+			}
+		}
+		out(&stream.HLSSegment{SeqNo: vseg.SeqNo, Name: vseg.Name, Data: vseg.Data, Duration: vseg.Length.Seconds()})
+	}
+}
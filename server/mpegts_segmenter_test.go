@@ -0,0 +1,47 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/livepeer/lpms/stream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmentMPEGTS(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	data, err := ioutil.ReadFile("../core/test.ts")
+	require.Nil(err)
+	segment := func(r io.Reader) ([]*stream.HLSSegment, error) {
+		var segs []*stream.HLSSegment
+		err := segmentMPEGTS(context.Background(), t.TempDir(), r, 2*time.Second, func(seg *stream.HLSSegment) {
+			segs = append(segs, seg)
+		})
+		return segs, err
+	}
+
+	// The stream is cut on the keyframes after every 2 seconds
+	segs, err := segment(bytes.NewReader(data))
+	require.Nil(err)
+	require.GreaterOrEqual(len(segs), 2)
+	for i, seg := range segs {
+		assert.Equal(uint64(i), seg.SeqNo)
+		assert.NotEmpty(seg.Data)
+		assert.Zero(len(seg.Data) % 188)
+		assert.GreaterOrEqual(seg.Duration, 2.0)
+	}
+
+	// Errors reading the stream are returned once it is segmented
+	errRead := errors.New("read error")
+	_, err = segment(io.MultiReader(bytes.NewReader(data), iotest.ErrReader(errRead)))
+	assert.Equal(errRead, err)
+}
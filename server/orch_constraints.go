@@ -0,0 +1,118 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-livepeer/net"
+)
+
+// webhookOrchConstraints are the fields of the auth webhook response that restrict the orchestrators used for a stream
+type webhookOrchConstraints struct {
+	MaxPricePerUnit json.Number `json:"maxPricePerUnit"`
+	PixelsPerUnit   json.Number `json:"pixelsPerUnit"`
+	Currency        string      `json:"currency"`
+	OrchAllowlist   []string    `json:"orchAllowlist"`
+	OrchDenylist    []string    `json:"orchDenylist"`
+	MinPerfScore    float64     `json:"minPerfScore"`
+}
+
+// parseOrchConstraints returns the orchestrator constraints of a stream, or nil if the stream has none.
+// The max price is converted to wei per pixel and kept up to date if it is in a custom currency
+func parseOrchConstraints(wc webhookOrchConstraints) (*core.OrchestratorConstraints, error) {
+	if wc.MaxPricePerUnit == "" && len(wc.OrchAllowlist) == 0 && len(wc.OrchDenylist) == 0 && wc.MinPerfScore <= 0 {
+		return nil, nil
+	}
+
+	c := &core.OrchestratorConstraints{
+		Allowlist:    wc.OrchAllowlist,
+		Denylist:     wc.OrchDenylist,
+		MinPerfScore: wc.MinPerfScore,
+	}
+	if wc.MaxPricePerUnit != "" {
+		pr, ok := new(big.Rat).SetString(string(wc.MaxPricePerUnit))
+		if !ok || pr.Sign() <= 0 {
+			return nil, fmt.Errorf("max price per unit must be greater than 0, provided %v", wc.MaxPricePerUnit)
+		}
+		px := big.NewRat(1, 1)
+		if wc.PixelsPerUnit != "" {
+			px, ok = new(big.Rat).SetString(string(wc.PixelsPerUnit))
+			if !ok || px.Sign() <= 0 {
+				return nil, fmt.Errorf("pixels per unit must be greater than 0, provided %v", wc.PixelsPerUnit)
+			}
+		}
+		maxPrice, err := core.NewAutoConvertedPrice(wc.Currency, new(big.Rat).Quo(pr, px), nil)
+		if err != nil {
+			return nil, fmt.Errorf("error converting price: %w", err)
+		}
+		c.MaxPrice = maxPrice
+	}
+	return c, nil
+}
+
+// streamMaxPrice returns the max price per pixel of the stream, which is the broadcaster's max price
+// unless the stream has its own
+func streamMaxPrice(params *core.StreamParameters) *big.Rat {
+	if params != nil && params.OrchConstraints != nil && params.OrchConstraints.MaxPrice != nil {
+		return params.OrchConstraints.MaxPrice.Value()
+	}
+	return BroadcastCfg.MaxPrice()
+}
+
+// allowedByConstraints returns true if the stream's orchestrator constraints allow the orchestrator.
+// Unlike the broadcaster's max price and min performance score, the constraints of a stream are strict
+func allowedByConstraints(c *core.OrchestratorConstraints, info *net.OrchestratorInfo, perfScore *common.PerfScore) bool {
+	if c == nil {
+		return true
+	}
+	if len(c.Allowlist) > 0 && !orchMatches(c.Allowlist, info) {
+		return false
+	}
+	if orchMatches(c.Denylist, info) {
+		return false
+	}
+	if c.MaxPrice != nil {
+		price, err := common.RatPriceInfo(info.GetPriceInfo())
+		if err != nil || price != nil && price.Cmp(c.MaxPrice.Value()) > 0 {
+			return false
+		}
+	}
+	if c.MinPerfScore > 0 && perfScore != nil {
+		perfScore.Mu.Lock()
+		defer perfScore.Mu.Unlock()
+		// Without any scores the performance stats are not available, so the filter is not used
+		if len(perfScore.Scores) > 0 && perfScore.Scores[orchAddress(info)] < c.MinPerfScore {
+			return false
+		}
+	}
+	return true
+}
+
+// orchMatches returns true if any of the entries is the ETH address or the service URI of the orchestrator
+func orchMatches(entries []string, info *net.OrchestratorInfo) bool {
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if ethcommon.IsHexAddress(entry) {
+			addr := ethcommon.HexToAddress(entry)
+			if addr == orchAddress(info) || len(info.GetAddress()) > 0 && addr == ethcommon.BytesToAddress(info.GetAddress()) {
+				return true
+			}
+		} else if entry != "" && strings.TrimSuffix(entry, "/") == strings.TrimSuffix(info.GetTranscoder(), "/") {
+			return true
+		}
+	}
+	return false
+}
+
+// orchAddress returns the address that the orchestrator receives payments with
+func orchAddress(info *net.OrchestratorInfo) ethcommon.Address {
+	if tp := info.GetTicketParams(); tp != nil && len(tp.Recipient) > 0 {
+		return ethcommon.BytesToAddress(tp.Recipient)
+	}
+	return ethcommon.BytesToAddress(info.GetAddress())
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"math/big"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-livepeer/net"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOrchConstraints(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// No constraints
+	c, err := parseOrchConstraints(webhookOrchConstraints{})
+	assert.Nil(err)
+	assert.Nil(c)
+
+	c, err = parseOrchConstraints(webhookOrchConstraints{MaxPricePerUnit: "10", PixelsPerUnit: "4", OrchDenylist: []string{"https://o1.example.com"}, MinPerfScore: 0.5})
+	require.Nil(err)
+	require.NotNil(c.MaxPrice)
+	assert.Equal(big.NewRat(5, 2), c.MaxPrice.Value())
+	assert.Equal([]string{"https://o1.example.com"}, c.Denylist)
+	assert.Empty(c.Allowlist)
+	assert.Equal(0.5, c.MinPerfScore)
+
+	// Pixels per unit defaults to 1
+	c, err = parseOrchConstraints(webhookOrchConstraints{MaxPricePerUnit: "3"})
+	require.Nil(err)
+	assert.Equal(big.NewRat(3, 1), c.MaxPrice.Value())
+
+	_, err = parseOrchConstraints(webhookOrchConstraints{MaxPricePerUnit: "0"})
+	assert.EqualError(err, "max price per unit must be greater than 0, provided 0")
+	_, err = parseOrchConstraints(webhookOrchConstraints{MaxPricePerUnit: "1", PixelsPerUnit: "-1"})
+	assert.EqualError(err, "pixels per unit must be greater than 0, provided -1")
+}
+
+func TestAllowedByConstraints(t *testing.T) {
+	assert := assert.New(t)
+
+	addr := ethcommon.HexToAddress("0x0000000000000000000000000000000000000001")
+	info := &net.OrchestratorInfo{
+		Transcoder:   "https://o1.example.com",
+		PriceInfo:    &net.PriceInfo{PricePerUnit: 10, PixelsPerUnit: 1},
+		TicketParams: &net.TicketParams{Recipient: addr.Bytes()},
+	}
+
+	// No constraints
+	assert.True(allowedByConstraints(nil, info, nil))
+
+	// Allowlist and denylist match the ETH address or the service URI
+	assert.True(allowedByConstraints(&core.OrchestratorConstraints{Allowlist: []string{addr.Hex()}}, info, nil))
+	assert.True(allowedByConstraints(&core.OrchestratorConstraints{Allowlist: []string{"https://o1.example.com/"}}, info, nil))
+	assert.False(allowedByConstraints(&core.OrchestratorConstraints{Allowlist: []string{"https://o2.example.com"}}, info, nil))
+	assert.False(allowedByConstraints(&core.OrchestratorConstraints{Denylist: []string{addr.Hex()}}, info, nil))
+	assert.True(allowedByConstraints(&core.OrchestratorConstraints{Denylist: []string{"https://o2.example.com"}}, info, nil))
+
+	// Max price is strict
+	assert.True(allowedByConstraints(&core.OrchestratorConstraints{MaxPrice: core.NewFixedPrice(big.NewRat(10, 1))}, info, nil))
+	assert.False(allowedByConstraints(&core.OrchestratorConstraints{MaxPrice: core.NewFixedPrice(big.NewRat(9, 1))}, info, nil))
+
+	// Min performance score is only used if the performance stats are available
+	c := &core.OrchestratorConstraints{MinPerfScore: 0.5}
+	assert.True(allowedByConstraints(c, info, &common.PerfScore{Scores: map[ethcommon.Address]float64{}}))
+	assert.True(allowedByConstraints(c, info, &common.PerfScore{Scores: map[ethcommon.Address]float64{addr: 0.6}}))
+	assert.False(allowedByConstraints(c, info, &common.PerfScore{Scores: map[ethcommon.Address]float64{addr: 0.4}}))
+	assert.False(allowedByConstraints(c, info, &common.PerfScore{Scores: map[ethcommon.Address]float64{{}: 0.9}}))
+}
+
+func TestStreamMaxPrice(t *testing.T) {
+	assert := assert.New(t)
+
+	defer BroadcastCfg.SetMaxPrice(nil)
+	BroadcastCfg.SetMaxPrice(core.NewFixedPrice(big.NewRat(1, 1)))
+
+	assert.Equal(big.NewRat(1, 1), streamMaxPrice(nil))
+	assert.Equal(big.NewRat(1, 1), streamMaxPrice(&core.StreamParameters{}))
+	params := &core.StreamParameters{OrchConstraints: &core.OrchestratorConstraints{MaxPrice: core.NewFixedPrice(big.NewRat(2, 1))}}
+	assert.Equal(big.NewRat(2, 1), streamMaxPrice(params))
+}
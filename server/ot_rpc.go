@@ -103,18 +103,32 @@ func runTranscoder(n *core.LivepeerNode, orchAddr string, capacity int, caps []c
 		return err
 	}
 
-	// Catch interrupt signal to shut down transcoder
-	exitc := make(chan os.Signal)
+	// Sessions the transcoder is working on, that a draining transcoder waits for
+	var sessionsMu sync.Mutex
+	sessions := make(map[string]bool)
+
+	// Catch interrupt signal to shut down transcoder, and SIGTERM to drain it
+	exitc := make(chan os.Signal, 1)
 	signal.Notify(exitc, os.Interrupt, syscall.SIGTERM)
 	defer signal.Stop(exitc)
 	go func() {
 		select {
 		case sig := <-exitc:
-			glog.Infof("Exiting Livepeer Transcoder: %v", sig)
-			// Cancelling context will close connection to orchestrator
-			cancel()
+			if sig != syscall.SIGTERM {
+				glog.Infof("Exiting Livepeer Transcoder: %v", sig)
+				// Cancelling context will close connection to orchestrator
+				cancel()
+				return
+			}
+			glog.Infof("Draining Livepeer Transcoder: %v", sig)
+			n.Drain()
+		case <-n.Draining():
+		case <-ctx.Done():
 			return
 		}
+		waitForTranscoderSessions(&sessionsMu, sessions, exitc)
+		glog.Infof("Exiting drained Livepeer Transcoder")
+		cancel()
 	}()
 
 	httpc := &http.Client{Transport: &http2.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
@@ -129,16 +143,61 @@ func runTranscoder(n *core.LivepeerNode, orchAddr string, capacity int, caps []c
 		if notify.SegData != nil && notify.SegData.AuthToken != nil && len(notify.SegData.AuthToken.SessionId) > 0 && len(notify.Url) == 0 {
 			// session teardown signal
 			n.Transcoder.EndTranscodingSession(notify.SegData.AuthToken.SessionId)
+			sessionsMu.Lock()
+			delete(sessions, notify.SegData.AuthToken.SessionId)
+			sessionsMu.Unlock()
 		} else {
+			var sessionID string
+			if notify.SegData != nil && notify.SegData.AuthToken != nil {
+				sessionID = notify.SegData.AuthToken.SessionId
+			}
+			sessionsMu.Lock()
+			draining := !sessions[sessionID] && n.IsDraining()
+			if !draining {
+				sessions[sessionID] = true
+			}
+			sessionsMu.Unlock()
 			wg.Add(1)
 			go func() {
-				runTranscode(n, orchAddr, httpc, notify)
+				if draining {
+					// The orchestrator assigns new sessions to another transcoder
+					glog.Infof("Rejecting segment of new session=%s of draining transcoder taskId=%d", sessionID, notify.TaskId)
+					sendTranscodeResult(context.Background(), n, orchAddr, httpc, notify, "", &bytes.Buffer{}, nil, core.ErrTranscoderDraining)
+				} else {
+					runTranscode(n, orchAddr, httpc, notify)
+				}
 				wg.Done()
 			}()
 		}
 	}
 }
 
+// waitForTranscoderSessions blocks until the sessions of a draining transcoder ended, -drainTimeout passed or another
+// signal was received
+func waitForTranscoderSessions(mu *sync.Mutex, sessions map[string]bool, exitc chan os.Signal) {
+	deadline := time.NewTimer(core.DrainTimeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		mu.Lock()
+		running := len(sessions)
+		mu.Unlock()
+		if running == 0 {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			glog.Infof("Sessions=%d still running after drain timeout=%s", running, core.DrainTimeout)
+			return
+		case sig := <-exitc:
+			glog.Infof("Exiting draining Livepeer Transcoder: %v", sig)
+			return
+		}
+	}
+}
+
 func runTranscode(n *core.LivepeerNode, orchAddr string, httpc *http.Client, notify *net.NotifySegment) {
 
 	glog.Infof("Transcoding taskId=%d url=%s", notify.TaskId, notify.Url)
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/lpms/vidplayer"
+)
+
+var errLLHLSBadRequest = errors.New("invalid blocking playlist reload")
+var errDVRBadRequest = errors.New("invalid DVR start offset")
+
+// llhlsBlockingTimeout is how long requests wait for a segment or a part of the playlist, which is three times the
+// target duration
+func llhlsBlockingTimeout(pl *core.LLHLSPlaylist) time.Duration {
+	return 3 * (LLHLSSegmentTarget + pl.PartTarget())
+}
+
+// HandlePlayback serves the playback of the streams in place of LPMS, which only serves HLS. HLS master playlists, media
+// playlists and segments are served like LPMS does, along with the DASH manifests, the init segments of fragmented
+// MP4 renditions and the thumbnails. When LL-HLS is enabled, the media playlists come from the LL-HLS playlists
+// instead, along with their parts and segments. Streams with a DVR window have their media playlists span the window.
+// The progress of the segments of the streams is streamed at /stream/<manifestID>/events
+func (s *LivepeerServer) HandlePlayback(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Expose-Headers", "Content-Length")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS")
+		// Clients of the progress feed authenticate with a bearer token
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ext := path.Ext(r.URL.Path)
+	switch ext {
+	case "":
+		if parseStreamID(r.URL.Path).Rendition != "events" {
+			http.Error(w, "only HLS and DASH requests are supported", http.StatusNotFound)
+			return
+		}
+		s.HandleSegmentProgress(w, r)
+	case ".m3u8":
+		mpl, err := getHLSMasterPlaylistHandler(s)(r.URL)
+		if err == nil && mpl != nil && len(mpl.Variants) > 0 {
+			writePlaybackResponse(w, "application/x-mpegURL", "max-age=5", mpl.Encode().Bytes())
+			return
+		}
+		pl, err := getLLHLSMediaPlaylistHandler(s)(r.Context(), r.URL)
+		if err == nil {
+			writePlaybackResponse(w, "application/x-mpegURL", "no-cache", pl.Encode().Bytes())
+			return
+		} else if err != vidplayer.ErrNotFound {
+			writePlaybackError(w, r, err)
+			return
+		}
+		dvrPl, err := getDVRMediaPlaylistHandler(s)(r.URL)
+		if err == nil {
+			writePlaybackResponse(w, "application/x-mpegURL", "max-age=1", dvrPl.Bytes())
+			return
+		} else if err != vidplayer.ErrNotFound {
+			writePlaybackError(w, r, err)
+			return
+		}
+		mediaPl, err := getHLSMediaPlaylistHandler(s)(r.URL)
+		if err != nil {
+			writePlaybackError(w, r, err)
+			return
+		}
+		writePlaybackResponse(w, "application/x-mpegURL", "max-age=5", mediaPl.Encode().Bytes())
+	case ".mpd":
+		mpd, err := getDASHManifestHandler(s)(r.URL)
+		if err != nil {
+			writePlaybackError(w, r, err)
+			return
+		}
+		writePlaybackResponse(w, "application/dash+xml", "max-age=1", mpd.Encode(time.Now()).Bytes())
+	case ".ts", ".mp4", ".m4s":
+		data, err := getLLHLSSegmentHandler(s)(r.Context(), r.URL)
+		if err == vidplayer.ErrNotFound {
+			// Not a part or a segment of a LL-HLS playlist
+			data, err = getHLSSegmentHandler(s)(r.URL)
+		}
+		if err != nil {
+			writePlaybackError(w, r, err)
+			return
+		}
+		contentType, _ := common.TypeByExtension(ext)
+		writePlaybackResponse(w, contentType, "max-age=60", data)
+	case ".jpg":
+		if data, err := getThumbnailHandler(s)(r.URL); err == nil {
+			writePlaybackResponse(w, "image/jpeg", "max-age=1", data)
+			return
+		}
+		data, err := getHLSSegmentHandler(s)(r.URL)
+		if err != nil {
+			writePlaybackError(w, r, err)
+			return
+		}
+		writePlaybackResponse(w, "image/jpeg", "max-age=60", data)
+	default:
+		http.Error(w, "only HLS and DASH requests are supported", http.StatusNotFound)
+	}
+}
+
+func writePlaybackResponse(w http.ResponseWriter, contentType, cacheControl string, data []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", cacheControl)
+	if _, err := w.Write(data); err != nil {
+		glog.V(4).Infof("Error writing playback response err=%q", err)
+	}
+}
+
+func writePlaybackError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, errLLHLSBadRequest), errors.Is(err, core.ErrLLHLSTooFarAhead),
+		errors.Is(err, errDVRBadRequest), errors.Is(err, core.ErrDVRStartOutOfWindow):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, context.DeadlineExceeded):
+		http.Error(w, "timed out waiting for the playlist", http.StatusServiceUnavailable)
+	case r.Context().Err() != nil:
+		// The player went away
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
@@ -0,0 +1,215 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/lpms/ffmpeg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlePlayback_LLHLS(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, cancel := setupServerWithCancel()
+	defer serverCleanup(s)
+	defer cancel()
+
+	pl := core.NewBasicPlaylistManager("mani", nil, nil)
+	pl.EnableLLHLS(500*time.Millisecond, LLHLSSegmentTarget)
+	s.connectionLock.Lock()
+	s.rtmpConnections["mani"] = &rtmpConnection{mid: "mani", pl: pl, profile: &ffmpeg.P144p30fps16x9}
+	s.connectionLock.Unlock()
+
+	profile := ffmpeg.P144p30fps16x9
+	require.Nil(pl.InsertHLSSegment(&profile, 0, "mani/P144p30fps16x9/0.ts", 0.5))
+	require.Nil(pl.InsertLLHLSPart(&profile, 0, 0.5, []byte("part0")))
+
+	get := func(target string) *http.Response {
+		w := httptest.NewRecorder()
+		s.HandlePlayback(w, httptest.NewRequest("GET", target, nil))
+		return w.Result()
+	}
+	body := func(resp *http.Response) string {
+		b, err := io.ReadAll(resp.Body)
+		assert.Nil(err)
+		return string(b)
+	}
+
+	// Master playlists are served like before
+	resp := get("/stream/mani.m3u8")
+	require.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("application/x-mpegURL", resp.Header.Get("Content-Type"))
+	assert.Contains(body(resp), "mani/P144p30fps16x9.m3u8")
+
+	// Media playlists have the LL-HLS tags
+	resp = get("/stream/mani/P144p30fps16x9.m3u8")
+	require.Equal(http.StatusOK, resp.StatusCode)
+	playlist := body(resp)
+	assert.Contains(playlist, "#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=1.500\n")
+	assert.Contains(playlist, "#EXT-X-PART:DURATION=0.500,URI=\"P144p30fps16x9/llhls_0_0.ts\",INDEPENDENT=YES\n")
+	assert.Contains(playlist, "#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"P144p30fps16x9/llhls_0_1.ts\"\n")
+
+	// Parts are served from the playlist
+	resp = get("/stream/mani/P144p30fps16x9/llhls_0_0.ts")
+	require.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("video/mp2t", resp.Header.Get("Content-Type"))
+	assert.Equal("part0", body(resp))
+
+	// Blocking playlist reloads and requests for the hinted part wait until the part is added
+	reloaded := make(chan string)
+	go func() { reloaded <- body(get("/stream/mani/P144p30fps16x9.m3u8?_HLS_msn=0&_HLS_part=1")) }()
+	hinted := make(chan string)
+	go func() { hinted <- body(get("/stream/mani/P144p30fps16x9/llhls_0_1.ts")) }()
+	time.Sleep(20 * time.Millisecond)
+	for i := uint64(1); i < 4; i++ {
+		require.Nil(pl.InsertLLHLSPart(&profile, i, 0.5, []byte(fmt.Sprintf("part%d", i))))
+	}
+	assert.Contains(<-reloaded, "URI=\"P144p30fps16x9/llhls_0_1.ts\",INDEPENDENT=YES\n")
+	assert.Equal("part1", <-hinted)
+
+	// Whole segments are made of the parts
+	resp = get("/stream/mani/P144p30fps16x9/llhls_0.ts")
+	require.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("part0part1part2part3", body(resp))
+
+	// Invalid blocking playlist reloads
+	assert.Equal(http.StatusBadRequest, get("/stream/mani/P144p30fps16x9.m3u8?_HLS_part=1").StatusCode)
+	assert.Equal(http.StatusBadRequest, get("/stream/mani/P144p30fps16x9.m3u8?_HLS_msn=x").StatusCode)
+	assert.Equal(http.StatusBadRequest, get("/stream/mani/P144p30fps16x9.m3u8?_HLS_msn=5").StatusCode)
+
+	// Unknown streams, renditions and segments
+	assert.Equal(http.StatusNotFound, get("/stream/other/P144p30fps16x9.m3u8").StatusCode)
+	assert.Equal(http.StatusNotFound, get("/stream/mani/P240p30fps16x9.m3u8").StatusCode)
+	assert.Equal(http.StatusNotFound, get("/stream/mani/P144p30fps16x9/llhls_5.ts").StatusCode)
+	assert.Equal(http.StatusNotFound, get("/stream/mani/P144p30fps16x9/0.ts").StatusCode)
+	assert.Equal(http.StatusNotFound, get("/stream/mani/P144p30fps16x9.mpd").StatusCode)
+	w := httptest.NewRecorder()
+	s.HandlePlayback(w, httptest.NewRequest("POST", "/stream/mani.m3u8", nil))
+	assert.Equal(http.StatusMethodNotAllowed, w.Code)
+
+	// Requests waiting on the playlist of a stream that ends return
+	go func() { reloaded <- body(get("/stream/mani/P144p30fps16x9.m3u8?_HLS_msn=1&_HLS_part=1")) }()
+	time.Sleep(20 * time.Millisecond)
+	pl.Cleanup()
+	assert.True(strings.HasPrefix(<-reloaded, "not found"))
+}
+
+func TestHandlePlayback_DASH(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, cancel := setupServerWithCancel()
+	defer serverCleanup(s)
+	defer cancel()
+
+	pl := core.NewBasicPlaylistManager("mani", nil, nil)
+	s.connectionLock.Lock()
+	s.rtmpConnections["mani"] = &rtmpConnection{mid: "mani", pl: pl, profile: &ffmpeg.P144p30fps16x9}
+	s.connectionLock.Unlock()
+
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.HandlePlayback(w, httptest.NewRequest("GET", target, nil))
+		return w
+	}
+
+	// Manifests are available once the stream starts
+	w := get("/stream/mani.mpd")
+	require.Equal(http.StatusOK, w.Code)
+	assert.Equal("application/dash+xml", w.Header().Get("Content-Type"))
+	assert.Equal("*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(w.Body.String(), `type="dynamic"`)
+	assert.NotContains(w.Body.String(), "<Representation")
+
+	profile := ffmpeg.P144p30fps16x9
+	require.Nil(pl.InsertHLSSegment(&profile, 0, "mani/P144p30fps16x9/0.ts", 2))
+	w = get("/stream/mani.mpd")
+	require.Equal(http.StatusOK, w.Code)
+	assert.Contains(w.Body.String(), `<Representation id="P144p30fps16x9" mimeType="video/mp2t"`)
+	assert.Contains(w.Body.String(), `<SegmentURL media="mani/P144p30fps16x9/0.ts"></SegmentURL>`)
+
+	// Manifests are only served for streams
+	assert.Equal(http.StatusNotFound, get("/stream/other.mpd").Code)
+	assert.Equal(http.StatusNotFound, get("/stream/mani/P144p30fps16x9.mpd").Code)
+}
+
+func TestHandlePlayback_Thumbnail(t *testing.T) {
+	assert := assert.New(t)
+
+	s, cancel := setupServerWithCancel()
+	defer serverCleanup(s)
+	defer cancel()
+
+	pl := core.NewBasicPlaylistManager("mani", nil, nil)
+	s.connectionLock.Lock()
+	s.rtmpConnections["mani"] = &rtmpConnection{mid: "mani", pl: pl, profile: &ffmpeg.P144p30fps16x9}
+	s.connectionLock.Unlock()
+
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.HandlePlayback(w, httptest.NewRequest("GET", target, nil))
+		return w
+	}
+
+	// Streams only have a thumbnail once one was made
+	assert.Equal(http.StatusNotFound, get("/stream/mani/thumbnail.jpg").Code)
+
+	pl.InsertThumbnail(4, []byte("thumb4"))
+	pl.InsertThumbnail(2, []byte("thumb2"))
+	w := get("/stream/mani/thumbnail.jpg")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("image/jpeg", w.Header().Get("Content-Type"))
+	assert.Equal("thumb4", w.Body.String())
+
+	assert.Equal(http.StatusNotFound, get("/stream/other/thumbnail.jpg").Code)
+}
+
+func TestHandlePlayback_DVR(t *testing.T) {
+	assert := assert.New(t)
+
+	s, cancel := setupServerWithCancel()
+	defer serverCleanup(s)
+	defer cancel()
+
+	pl := core.NewBasicPlaylistManager("mani", nil, nil)
+	s.connectionLock.Lock()
+	s.rtmpConnections["mani"] = &rtmpConnection{mid: "mani", pl: pl, profile: &ffmpeg.P144p30fps16x9}
+	s.connectionLock.Unlock()
+
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.HandlePlayback(w, httptest.NewRequest("GET", target, nil))
+		return w
+	}
+
+	vProfile := ffmpeg.P144p30fps16x9
+	pl.EnableDVR(time.Minute)
+	for i := uint64(0); i < 10; i++ {
+		assert.Nil(pl.InsertHLSSegment(&vProfile, i, fmt.Sprintf("https://os.test/mani/P144p30fps16x9/%d.ts", i), 2))
+	}
+
+	// The DVR playlist has the segments of the window, unlike the live playlist
+	w := get("/stream/mani/P144p30fps16x9.m3u8")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("application/x-mpegURL", w.Header().Get("Content-Type"))
+	assert.Contains(w.Body.String(), "#EXT-X-MEDIA-SEQUENCE:0\n")
+	assert.Contains(w.Body.String(), "https://os.test/mani/P144p30fps16x9/9.ts\n")
+	assert.NotContains(w.Body.String(), "#EXT-X-START")
+
+	w = get("/stream/mani/P144p30fps16x9.m3u8?start=-6")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Contains(w.Body.String(), "#EXT-X-START:TIME-OFFSET=14.000,PRECISE=YES\n")
+
+	assert.Equal(http.StatusBadRequest, get("/stream/mani/P144p30fps16x9.m3u8?start=30").Code)
+	assert.Equal(http.StatusBadRequest, get("/stream/mani/P144p30fps16x9.m3u8?start=abc").Code)
+	assert.Equal(http.StatusNotFound, get("/stream/other/P144p30fps16x9.m3u8").Code)
+}
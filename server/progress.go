@@ -0,0 +1,228 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/lpms/vidplayer"
+)
+
+const (
+	ProgressSegmentReceived = "segment.received"
+	ProgressRendition       = "rendition"
+	ProgressVerification    = "verification"
+	ProgressError           = "error"
+)
+
+// Number of events that are buffered for a client of the progress feed before its events are dropped
+const progressSubscriberBuffer = 64
+
+// Interval at which comments are sent to the clients of the progress feed, so that idle connections aren't closed
+var progressKeepAliveInterval = 15 * time.Second
+
+// SegmentProgressSecret is the bearer token that clients of /stream/<manifestID>/events send to receive the
+// orchestrators and fees of the stream. These are left out of the events if the secret isn't set
+var SegmentProgressSecret string
+
+// SegmentProgress is an event of the progress feed of a stream
+type SegmentProgress struct {
+	Type                string  `json:"type"`
+	Timestamp           int64   `json:"timestamp"` // Unix milliseconds
+	SeqNo               uint64  `json:"seqNo"`
+	Duration            float64 `json:"duration,omitempty"` // Duration of the source segment in seconds
+	Bytes               int     `json:"bytes,omitempty"`
+	Rendition           string  `json:"rendition,omitempty"`
+	Orchestrator        string  `json:"orchestrator,omitempty"`
+	OrchestratorAddress string  `json:"orchestratorAddress,omitempty"`
+	LatencyMs           int64   `json:"latencyMs,omitempty"` // Time to upload the segment and receive its transcode results
+	Pixels              int64   `json:"pixels,omitempty"`
+	Fee                 string  `json:"fee,omitempty"` // Fee of the rendition in wei
+	Verified            *bool   `json:"verified,omitempty"`
+	Error               string  `json:"error,omitempty"`
+}
+
+// progressFeed fans out the progress of the segments of a stream to the clients following it. Events are not buffered
+// for clients that are too slow, and nothing is published if the feed is nil
+type progressFeed struct {
+	mu     sync.Mutex
+	subs   map[chan *SegmentProgress]bool
+	closed bool
+}
+
+func newProgressFeed() *progressFeed {
+	return &progressFeed{subs: make(map[chan *SegmentProgress]bool)}
+}
+
+func (f *progressFeed) publish(evt *SegmentProgress) {
+	if f == nil {
+		return
+	}
+	evt.Timestamp = time.Now().UnixMilli()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subs {
+		select {
+		case ch <- evt:
+		default:
+			glog.V(common.DEBUG).Infof("Dropping progress event=%s seqNo=%d for a slow client", evt.Type, evt.SeqNo)
+		}
+	}
+}
+
+// subscribe returns the channel of the events published from now on, which is closed when the feed is closed, or
+// false if the feed is already closed
+func (f *progressFeed) subscribe() (chan *SegmentProgress, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil, false
+	}
+	ch := make(chan *SegmentProgress, progressSubscriberBuffer)
+	f.subs[ch] = true
+	return ch, true
+}
+
+func (f *progressFeed) unsubscribe(ch chan *SegmentProgress) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.subs[ch] {
+		delete(f.subs, ch)
+		close(ch)
+	}
+}
+
+func (f *progressFeed) close() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	for ch := range f.subs {
+		delete(f.subs, ch)
+		close(ch)
+	}
+}
+
+// renditionFee returns the fee of a rendition with the price per pixel of the orchestrator, or an empty string
+// if the orchestrator has no price
+func renditionFee(sess *BroadcastSession, pixels int64) string {
+	price, err := common.RatPriceInfo(sess.OrchestratorInfo.GetPriceInfo())
+	if err != nil || price == nil {
+		return ""
+	}
+	return new(big.Rat).Mul(price, new(big.Rat).SetInt64(pixels)).FloatString(3)
+}
+
+// redacted returns a copy of the event without the orchestrator and fee, for the clients of the public feed
+func (evt *SegmentProgress) redacted() *SegmentProgress {
+	c := *evt
+	c.Orchestrator, c.OrchestratorAddress, c.Fee = "", "", ""
+	return &c
+}
+
+// progressAuthorized checks whether the request to the public feed carries the SegmentProgressSecret bearer token
+func progressAuthorized(r *http.Request) bool {
+	if SegmentProgressSecret == "" {
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(SegmentProgressSecret)) == 1
+}
+
+// subscribeProgress returns the progress feed of the running stream and the channel of its events, or false if the
+// stream isn't running
+func (s *LivepeerServer) subscribeProgress(mid core.ManifestID) (*progressFeed, chan *SegmentProgress, bool) {
+	s.connectionLock.RLock()
+	cxn, ok := s.getActiveRtmpConnectionUnsafe(mid)
+	s.connectionLock.RUnlock()
+	if !ok || cxn.progress == nil {
+		return nil, nil, false
+	}
+	events, ok := cxn.progress.subscribe()
+	if !ok {
+		return nil, nil, false
+	}
+	return cxn.progress, events, true
+}
+
+// HandleSegmentProgress streams the progress of the segments of a stream at /stream/<manifestID>/events as Server-Sent
+// Events, until the stream ends or the client goes away. The orchestrators and fees of the stream are left out unless
+// the request carries the SegmentProgressSecret bearer token
+func (s *LivepeerServer) HandleSegmentProgress(w http.ResponseWriter, r *http.Request) {
+	feed, events, ok := s.subscribeProgress(parseStreamID(r.URL.Path).ManifestID)
+	if !ok {
+		http.Error(w, vidplayer.ErrNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	defer feed.unsubscribe(events)
+	streamProgress(w, r, events, !progressAuthorized(r))
+}
+
+// segmentProgressHandler streams the progress of the segments of the stream with the manifestID param like
+// HandleSegmentProgress, with the orchestrators and fees of the stream, on the CLI port
+func (s *LivepeerServer) segmentProgressHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		feed, events, ok := s.subscribeProgress(core.ManifestID(r.FormValue("manifestID")))
+		if !ok {
+			respondWithError(w, errUnknownStream.Error(), http.StatusNotFound)
+			return
+		}
+		defer feed.unsubscribe(events)
+		streamProgress(w, r, events, false)
+	})
+}
+
+// streamProgress writes the events as Server-Sent Events until the channel is closed or the client goes away
+func streamProgress(w http.ResponseWriter, r *http.Request, events chan *SegmentProgress, redact bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respond500(w, "streaming is not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(progressKeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case evt, ok := <-events:
+			if !ok {
+				// The stream ended
+				return
+			}
+			if redact {
+				evt = evt.redacted()
+			}
+			data, err := json.Marshal(evt)
+			if err != nil {
+				glog.Errorf("Error marshalling progress event=%s err=%q", evt.Type, err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-livepeer/net"
+	"github.com/livepeer/lpms/ffmpeg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressFeed(t *testing.T) {
+	assert := assert.New(t)
+
+	// Nothing is published without a feed
+	var nilFeed *progressFeed
+	nilFeed.publish(&SegmentProgress{Type: ProgressSegmentReceived})
+	nilFeed.close()
+
+	f := newProgressFeed()
+	f.publish(&SegmentProgress{Type: ProgressSegmentReceived, SeqNo: 1})
+	a, ok := f.subscribe()
+	assert.True(ok)
+	b, ok := f.subscribe()
+	assert.True(ok)
+
+	// Events are published to all the clients from the time they subscribed
+	f.publish(&SegmentProgress{Type: ProgressSegmentReceived, SeqNo: 2})
+	evt := <-a
+	assert.Equal(uint64(2), evt.SeqNo)
+	assert.NotZero(evt.Timestamp)
+	assert.Equal(uint64(2), (<-b).SeqNo)
+
+	// Events are dropped for slow clients
+	for i := 0; i < progressSubscriberBuffer+10; i++ {
+		f.publish(&SegmentProgress{Type: ProgressRendition, SeqNo: uint64(i)})
+	}
+	assert.Len(a, progressSubscriberBuffer)
+
+	// The channels of the clients that went away are closed
+	f.unsubscribe(b)
+	for range b {
+	}
+	f.unsubscribe(b)
+
+	f.close()
+	for range a {
+	}
+	_, ok = f.subscribe()
+	assert.False(ok)
+	f.publish(&SegmentProgress{Type: ProgressRendition})
+}
+
+func TestRenditionFee(t *testing.T) {
+	assert := assert.New(t)
+	sess := &BroadcastSession{OrchestratorInfo: &net.OrchestratorInfo{}}
+	assert.Equal("", renditionFee(sess, 1000))
+	sess.OrchestratorInfo.PriceInfo = &net.PriceInfo{PricePerUnit: 3, PixelsPerUnit: 2}
+	assert.Equal("1500.000", renditionFee(sess, 1000))
+}
+
+func TestSegmentProgressHandler(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, cancel := setupServerWithCancel()
+	defer serverCleanup(s)
+	defer cancel()
+
+	feed := newProgressFeed()
+	s.connectionLock.Lock()
+	s.rtmpConnections["mani"] = &rtmpConnection{mid: "mani", pl: core.NewBasicPlaylistManager("mani", nil, nil),
+		profile: &ffmpeg.P144p30fps16x9, progress: feed}
+	s.connectionLock.Unlock()
+	ts := httptest.NewServer(mustHaveFormParams(s.segmentProgressHandler(), "manifestID"))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	require.Nil(err)
+	resp.Body.Close()
+	assert.Equal(http.StatusBadRequest, resp.StatusCode)
+	resp, err = http.Get(ts.URL + "?manifestID=unknown")
+	require.Nil(err)
+	resp.Body.Close()
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+
+	oldKeepAlive := progressKeepAliveInterval
+	defer func() { progressKeepAliveInterval = oldKeepAlive }()
+	progressKeepAliveInterval = 50 * time.Millisecond
+	resp, err = http.Get(ts.URL + "?manifestID=mani")
+	require.Nil(err)
+	defer resp.Body.Close()
+	require.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal("no-cache", resp.Header.Get("Cache-Control"))
+
+	verified := true
+	feed.publish(&SegmentProgress{Type: ProgressSegmentReceived, SeqNo: 3, Duration: 2, Bytes: 100})
+	feed.publish(&SegmentProgress{Type: ProgressVerification, SeqNo: 3, Orchestrator: "https://orch:8935", Verified: &verified})
+	reader := bufio.NewReader(resp.Body)
+	var events []SegmentProgress
+	keepAlives := 0
+	for len(events) < 2 || keepAlives == 0 {
+		line, err := reader.ReadString('\n')
+		require.Nil(err)
+		switch {
+		case strings.HasPrefix(line, "data: "):
+			var evt SegmentProgress
+			require.Nil(json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &evt))
+			events = append(events, evt)
+		case line == ": keepalive\n":
+			keepAlives++
+		}
+	}
+	assert.Equal(ProgressSegmentReceived, events[0].Type)
+	assert.Equal(uint64(3), events[0].SeqNo)
+	assert.Equal(2.0, events[0].Duration)
+	assert.Equal(100, events[0].Bytes)
+	assert.Equal(ProgressVerification, events[1].Type)
+	assert.Equal("https://orch:8935", events[1].Orchestrator)
+	require.NotNil(events[1].Verified)
+	assert.True(*events[1].Verified)
+
+	// The feed ends with the stream
+	feed.close()
+	done := make(chan error)
+	go func() {
+		_, err := reader.ReadString(0)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		assert.NotNil(err)
+	case <-time.After(time.Second):
+		assert.Fail("progress feed did not end with the stream")
+	}
+	feed.mu.Lock()
+	assert.Empty(feed.subs)
+	feed.mu.Unlock()
+}
+
+func TestHandleSegmentProgress(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, cancel := setupServerWithCancel()
+	defer serverCleanup(s)
+	defer cancel()
+
+	feed := newProgressFeed()
+	s.connectionLock.Lock()
+	s.rtmpConnections["mani"] = &rtmpConnection{mid: "mani", pl: core.NewBasicPlaylistManager("mani", nil, nil),
+		profile: &ffmpeg.P144p30fps16x9, progress: feed}
+	s.connectionLock.Unlock()
+	ts := httptest.NewServer(http.HandlerFunc(s.HandlePlayback))
+	defer ts.Close()
+
+	oldSecret := SegmentProgressSecret
+	defer func() { SegmentProgressSecret = oldSecret }()
+	SegmentProgressSecret = "s3cr3t"
+
+	get := func(path, token string) *http.Response {
+		req, err := http.NewRequest("GET", ts.URL+path, nil)
+		require.Nil(err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.Nil(err)
+		return resp
+	}
+	resp := get("/stream/unknown/events", "")
+	resp.Body.Close()
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+	resp = get("/stream/mani/other", "")
+	resp.Body.Close()
+	assert.Equal(http.StatusNotFound, resp.StatusCode)
+
+	public := get("/stream/mani/events", "")
+	defer public.Body.Close()
+	require.Equal(http.StatusOK, public.StatusCode)
+	assert.Equal("text/event-stream", public.Header.Get("Content-Type"))
+	wrongToken := get("/stream/mani/events", "other")
+	defer wrongToken.Body.Close()
+	require.Equal(http.StatusOK, wrongToken.StatusCode)
+	authorized := get("/stream/mani/events", "s3cr3t")
+	defer authorized.Body.Close()
+	require.Equal(http.StatusOK, authorized.StatusCode)
+
+	feed.publish(&SegmentProgress{Type: ProgressRendition, SeqNo: 3, Rendition: "P144p30fps16x9",
+		Orchestrator: "https://orch:8935", OrchestratorAddress: "0xabc", LatencyMs: 700, Pixels: 1000, Fee: "1500.000"})
+	readEvent := func(resp *http.Response) SegmentProgress {
+		reader := bufio.NewReader(resp.Body)
+		for {
+			line, err := reader.ReadString('\n')
+			require.Nil(err)
+			if strings.HasPrefix(line, "data: ") {
+				var evt SegmentProgress
+				require.Nil(json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &evt))
+				return evt
+			}
+		}
+	}
+
+	// The orchestrators and fees are only sent to the clients with the secret
+	for _, resp := range []*http.Response{public, wrongToken} {
+		evt := readEvent(resp)
+		assert.Equal(SegmentProgress{Type: ProgressRendition, Timestamp: evt.Timestamp, SeqNo: 3,
+			Rendition: "P144p30fps16x9", LatencyMs: 700, Pixels: 1000}, evt)
+	}
+	evt := readEvent(authorized)
+	assert.Equal("https://orch:8935", evt.Orchestrator)
+	assert.Equal("0xabc", evt.OrchestratorAddress)
+	assert.Equal("1500.000", evt.Fee)
+
+	// No client receives the orchestrators and fees if the secret is not set
+	SegmentProgressSecret = ""
+	assert.False(progressAuthorized(&http.Request{Header: http.Header{"Authorization": []string{"Bearer "}}}))
+}
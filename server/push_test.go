@@ -1499,7 +1499,7 @@ func TestPush_MultipartReturnMultiSession(t *testing.T) {
 
 	bsm := bsmWithSessListExt([]*BroadcastSession{sess1}, []*BroadcastSession{sess3, sess2}, false)
 	bsm.VerificationFreq = 1
-	assert.Equal(0, bsm.untrustedPool.sus.count)
+	assert.Empty(bsm.untrustedPool.breakers.status())
 	// hack: stop pool from refreshing
 	bsm.untrustedPool.refreshing = true
 
@@ -1595,8 +1595,7 @@ func TestPush_MultipartReturnMultiSession(t *testing.T) {
 	assert.Equal(1, i)
 	assert.Equal(uint64(12*2), cxn.sourceBytes)
 	assert.Equal(2, unverifiedHashCalled)
-	assert.Contains(bsm.untrustedPool.sus.list, ts2.URL)
-	assert.Equal(0, bsm.untrustedPool.sus.count)
+	assert.Equal(circuitOpen, bsm.untrustedPool.breakers.state(ts2.URL))
 }
 
 func createStubTranscoder(transcodedSegData []byte, transcodedSegPhash []byte, tCallback func()) *httptest.Server {
@@ -1739,7 +1738,7 @@ func TestPush_FastVerificationFlow(t *testing.T) {
 	// check that untrusted bad session is suspended after first segment, note that if "good" untrusted session is verified first,
 	// "bad" session will never get verified and suspended
 	validateMultipartResponse(assert, resp, 17, 1)
-	assert.Contains(bsm.untrustedPool.sus.list, untrustedTBadSegment.URL)
+	assert.Equal(circuitOpen, bsm.untrustedPool.breakers.state(untrustedTBadSegment.URL))
 
 	// check that untrusted good session is used for next segment
 	w = httptest.NewRecorder()
@@ -1760,7 +1759,7 @@ func TestPush_FastVerificationFlow(t *testing.T) {
 	resp = w.Result()
 	defer resp.Body.Close()
 	validateMultipartResponse(assert, resp, 18, 1)
-	assert.Contains(bsm.untrustedPool.sus.list, untrustedTBadHash.URL)
+	assert.Equal(circuitOpen, bsm.untrustedPool.breakers.state(untrustedTBadHash.URL))
 
 	// check that trusted session is used, when both untrusted sessions are bad
 	bsm = initServerWithBSM(srv, []*BroadcastSession{trustedSess}, []*BroadcastSession{untrustedSessBadSegment, untrustedSessBadHash}, 1)
@@ -1772,7 +1771,7 @@ func TestPush_FastVerificationFlow(t *testing.T) {
 	defer resp.Body.Close()
 	validateMultipartResponse(assert, resp, 18, 1)
 	// we do not suspend sessions if none of the session from untrusted pool match results of trusted session
-	assert.Equal(0, len(bsm.untrustedPool.sus.list))
+	assert.Empty(bsm.untrustedPool.breakers.status())
 
 	// check that trusted session is used for next segment in the above case
 	w = httptest.NewRecorder()
@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -16,6 +19,7 @@ import (
 	"github.com/livepeer/go-tools/drivers"
 	"github.com/livepeer/lpms/ffmpeg"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRecordingHandler(t *testing.T) {
@@ -189,3 +193,67 @@ func TestRecording(t *testing.T) {
 	assert.NotNil(err)
 	assert.Nil(fir)
 }
+
+func TestRecordingThumbnails(t *testing.T) {
+	drivers.Testing = true
+	lpmon.NodeID = "testNode"
+	assert := assert.New(t)
+	require := require.New(t)
+	s, cancel := setupServerWithCancel()
+	defer serverCleanup(s)
+	defer cancel()
+	whts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"manifestID":"thumbtest01", "recordObjectStore": "memory://recstore6"}`))
+	}))
+	defer whts.Close()
+	oldURL := AuthWebhookURL
+	defer func() { AuthWebhookURL = oldURL }()
+	AuthWebhookURL = mustParseUrl(t, whts.URL)
+
+	makeReq := func(uri string) (*http.Response, []byte) {
+		writer := httptest.NewRecorder()
+		s.HandleRecordings(writer, httptest.NewRequest("GET", uri, nil))
+		resp := writer.Result()
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp, body
+	}
+
+	var thumbnail bytes.Buffer
+	require.Nil(jpeg.Encode(&thumbnail, image.NewRGBA(image.Rect(0, 0, core.ThumbnailWidth, core.ThumbnailHeight)), nil))
+	_, err := drivers.ParseOSURL("memory://recstore6", true)
+	require.Nil(err)
+	mos := drivers.TestMemoryStorages["recstore6"]
+	msess := mos.NewSession("sess1")
+	jpl := core.NewJSONPlaylist()
+	source := ffmpeg.VideoProfile{Name: "source"}
+	for i := uint64(0); i < 3; i++ {
+		jpl.InsertHLSSegment(&source, i, fmt.Sprintf("sess1/testNode/source/%d.ts", i), 2)
+	}
+	for _, seqNo := range []uint64{0, 2} {
+		uri, err := msess.SaveData(context.TODO(), fmt.Sprintf("testNode/thumbnails/%d.jpg", seqNo), bytes.NewReader(thumbnail.Bytes()), nil, 0)
+		require.Nil(err)
+		jpl.InsertThumbnail(&source, seqNo, uri)
+	}
+	bjpl, err := json.Marshal(jpl)
+	require.Nil(err)
+	msess.SaveData(context.TODO(), "testNode/playlist_1.json", bytes.NewReader(bjpl), nil, 0)
+
+	// The thumbnails track points to the tiles of the thumbnails in the sprite sheets served next to it
+	resp, body := makeReq("/recordings/sess1/thumbnails.vtt")
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal("text/vtt", resp.Header.Get("Content-Type"))
+	assert.Equal("WEBVTT\n"+
+		"\n00:00:00.000 --> 00:00:04.000\nsprite_0.jpg#xywh=0,0,256,144\n"+
+		"\n00:00:04.000 --> 00:00:06.000\nsprite_0.jpg#xywh=256,0,256,144\n", string(body))
+
+	resp, body = makeReq("/recordings/sess1/sprite_0.jpg")
+	assert.Equal(200, resp.StatusCode)
+	assert.Equal("image/jpeg", resp.Header.Get("Content-Type"))
+	sprite, err := jpeg.Decode(bytes.NewReader(body))
+	require.Nil(err)
+	assert.Equal(image.Rect(0, 0, 2*core.ThumbnailWidth, core.ThumbnailHeight), sprite.Bounds())
+
+	resp, _ = makeReq("/recordings/sess1/sprite_1.jpg")
+	assert.Equal(404, resp.StatusCode)
+}
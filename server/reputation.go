@@ -0,0 +1,250 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/common"
+)
+
+// The reputation of an orchestrator is only consulted for selection once it recently transcoded or failed at least
+// reputationMinSegments segments. Orchestrators whose recent success rate is below reputationMinSuccessRate are
+// considered unreliable. Segments weigh half as much for selection for every reputationHalfLife elapsed, so that
+// orchestrators recover from past failures. The average latency score weights new samples by reputationLatencyAlpha
+var reputationMinSegments int64 = 10
+var reputationMinSuccessRate = 0.9
+var reputationHalfLife = time.Hour
+var reputationLatencyAlpha = 0.1
+var reputationFlushInterval = 30 * time.Second
+
+// OrchReputation is shared by all streams of the gateway and persisted in the DB, so that the knowledge
+// about orchestrators survives restarts of the gateway. Reputation is not tracked if it is nil
+var OrchReputation *ReputationStore
+
+// ReputationStore keeps the reputation of orchestrators in memory and periodically writes the updated
+// reputations to the DB
+type ReputationStore struct {
+	mu    sync.Mutex
+	db    *common.DB
+	orchs map[string]*common.DBOrchReputation
+	dirty map[string]bool
+
+	now func() time.Time
+}
+
+// NewReputationStore returns an empty ReputationStore that is not backed by a DB yet
+func NewReputationStore() *ReputationStore {
+	return &ReputationStore{
+		orchs: make(map[string]*common.DBOrchReputation),
+		dirty: make(map[string]bool),
+		now:   time.Now,
+	}
+}
+
+// Load loads the reputations persisted in the DB and uses the DB to persist the reputations from now on
+func (r *ReputationStore) Load(db *common.DB) error {
+	reps, err := db.SelectOrchReputations()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.db = db
+	for _, rep := range reps {
+		if _, ok := r.orchs[rep.Orchestrator]; ok {
+			// Already updated in this run which is more accurate than the persisted reputation
+			continue
+		}
+		r.orchs[rep.Orchestrator] = rep
+	}
+	return nil
+}
+
+// Run writes the updated reputations to the DB every reputationFlushInterval until the context is done
+func (r *ReputationStore) Run(ctx context.Context) {
+	ticker := time.NewTicker(reputationFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.Flush()
+		case <-ctx.Done():
+			r.Flush()
+			return
+		}
+	}
+}
+
+// Flush writes the reputations updated since the last flush to the DB
+func (r *ReputationStore) Flush() {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	db := r.db
+	if db == nil {
+		r.mu.Unlock()
+		return
+	}
+	var reps []common.DBOrchReputation
+	for orch := range r.dirty {
+		reps = append(reps, *r.orchs[orch])
+	}
+	r.dirty = make(map[string]bool)
+	r.mu.Unlock()
+
+	for i := range reps {
+		if err := db.UpdateOrchReputation(&reps[i]); err != nil {
+			glog.Errorf("Error persisting reputation orch=%s err=%q", reps[i].Orchestrator, err)
+		}
+	}
+}
+
+// RecordSuccess records the latency score of a segment successfully transcoded by the orchestrator
+func (r *ReputationStore) RecordSuccess(orch string, latencyScore float64) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rep := r.get(orch)
+	r.decay(rep)
+	rep.Successes++
+	rep.RecentSuccesses++
+	if rep.LatencySamples == 0 {
+		rep.LatencyScoreAvg = latencyScore
+	} else {
+		rep.LatencyScoreAvg += reputationLatencyAlpha * (latencyScore - rep.LatencyScoreAvg)
+	}
+	rep.LatencySamples++
+}
+
+// RecordFailure records a segment that the orchestrator failed to transcode, a nil error stands for an empty response
+func (r *ReputationStore) RecordFailure(orch string, err error) {
+	if r == nil || isNonRetryableError(err) {
+		// The segment itself cannot be transcoded, which is not the orchestrator's fault
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rep := r.get(orch)
+	r.decay(rep)
+	rep.Failures++
+	rep.RecentFailures++
+	if errors.Is(err, errVerificationMismatch) {
+		rep.VerificationFailures++
+	}
+	rep.LastFailureReason = "empty response"
+	if err != nil {
+		rep.LastFailureReason = err.Error()
+	}
+	rep.LastFailureAt = r.now().Unix()
+}
+
+// Get returns a copy of the reputation of the orchestrator
+func (r *ReputationStore) Get(orch string) (common.DBOrchReputation, bool) {
+	if r == nil {
+		return common.DBOrchReputation{}, false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rep, ok := r.orchs[orch]
+	if !ok {
+		return common.DBOrchReputation{}, false
+	}
+	return *rep, true
+}
+
+// All returns a copy of the reputation of all orchestrators sorted by orchestrator
+func (r *ReputationStore) All() []common.DBOrchReputation {
+	if r == nil {
+		return []common.DBOrchReputation{}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reps := make([]common.DBOrchReputation, 0, len(r.orchs))
+	for _, rep := range r.orchs {
+		reps = append(reps, *rep)
+	}
+	sort.Slice(reps, func(i, j int) bool { return reps[i].Orchestrator < reps[j].Orchestrator })
+	return reps
+}
+
+// reliable returns false if the orchestrator's recent success rate is too low, orchestrators with too few recent
+// segments are reliable
+func (r *ReputationStore) reliable(orch string) bool {
+	successes, failures, ok := r.recent(orch)
+	return !ok || successes+failures < float64(reputationMinSegments) || successes/(successes+failures) >= reputationMinSuccessRate
+}
+
+// latencyScore returns the average latency score of a reliable orchestrator with enough recent segments
+func (r *ReputationStore) latencyScore(orch string) (float64, bool) {
+	rep, ok := r.Get(orch)
+	successes, failures, _ := r.recent(orch)
+	if !ok || rep.LatencySamples == 0 || successes+failures < float64(reputationMinSegments) ||
+		successes/(successes+failures) < reputationMinSuccessRate {
+		return 0, false
+	}
+	return rep.LatencyScoreAvg, true
+}
+
+// recent returns the decayed number of successes and failures of the orchestrator
+func (r *ReputationStore) recent(orch string) (float64, float64, bool) {
+	if r == nil {
+		return 0, 0, false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rep, ok := r.orchs[orch]
+	if !ok {
+		return 0, 0, false
+	}
+	factor := r.decayFactor(rep)
+	return rep.RecentSuccesses * factor, rep.RecentFailures * factor, true
+}
+
+// the caller needs to ensure r.mu is acquired before calling this
+func (r *ReputationStore) decay(rep *common.DBOrchReputation) {
+	factor := r.decayFactor(rep)
+	rep.RecentSuccesses *= factor
+	rep.RecentFailures *= factor
+	rep.DecayedAt = r.now().Unix()
+}
+
+// the caller needs to ensure r.mu is acquired before calling this
+func (r *ReputationStore) decayFactor(rep *common.DBOrchReputation) float64 {
+	age := r.now().Sub(time.Unix(rep.DecayedAt, 0))
+	if rep.DecayedAt == 0 || reputationHalfLife <= 0 || age <= 0 {
+		return 1.0
+	}
+	return math.Pow(0.5, float64(age)/float64(reputationHalfLife))
+}
+
+// the caller needs to ensure r.mu is acquired before calling this
+func (r *ReputationStore) get(orch string) *common.DBOrchReputation {
+	rep, ok := r.orchs[orch]
+	if !ok {
+		rep = &common.DBOrchReputation{Orchestrator: orch}
+		r.orchs[orch] = rep
+	}
+	r.dirty[orch] = true
+	return rep
+}
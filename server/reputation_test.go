@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReputationStore_Record(t *testing.T) {
+	assert := assert.New(t)
+
+	rs := NewReputationStore()
+	_, ok := rs.Get("foo")
+	assert.False(ok)
+
+	rs.RecordSuccess("foo", 1.0)
+	rs.RecordSuccess("foo", 2.0)
+	rep, ok := rs.Get("foo")
+	assert.True(ok)
+	assert.Equal(int64(2), rep.Successes)
+	assert.Equal(int64(2), rep.LatencySamples)
+	assert.InDelta(1.1, rep.LatencyScoreAvg, 0.0001)
+
+	// Non-retryable errors are not the orchestrator's fault
+	rs.RecordFailure("foo", maxTranscodeAttempts)
+	rep, _ = rs.Get("foo")
+	assert.Zero(rep.Failures)
+
+	rs.RecordFailure("foo", errVerificationMismatch)
+	rs.RecordFailure("foo", nil)
+	rep, _ = rs.Get("foo")
+	assert.Equal(int64(2), rep.Failures)
+	assert.Equal(int64(1), rep.VerificationFailures)
+	assert.Equal("empty response", rep.LastFailureReason)
+	assert.NotZero(rep.LastFailureAt)
+
+	// A nil store does not track anything
+	var nilStore *ReputationStore
+	nilStore.RecordSuccess("foo", 1.0)
+	nilStore.RecordFailure("foo", nil)
+	nilStore.Flush()
+	_, ok = nilStore.Get("foo")
+	assert.False(ok)
+	assert.Empty(nilStore.All())
+}
+
+func TestReputationStore_Persist(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dbh, dbraw, err := common.TempDB(t)
+	require.Nil(err)
+	defer dbh.Close()
+	defer dbraw.Close()
+
+	rs := NewReputationStore()
+	require.Nil(rs.Load(dbh))
+	rs.RecordSuccess("foo", 0.5)
+	rs.RecordFailure("bar", errors.New("some error"))
+	rs.Flush()
+	assert.Empty(rs.dirty)
+
+	// The reputation survives a restart
+	rs = NewReputationStore()
+	require.Nil(rs.Load(dbh))
+	reps := rs.All()
+	require.Len(reps, 2)
+	assert.Equal("bar", reps[0].Orchestrator)
+	assert.Equal("some error", reps[0].LastFailureReason)
+	assert.Equal("foo", reps[1].Orchestrator)
+	assert.Equal(int64(1), reps[1].Successes)
+	assert.Equal(0.5, reps[1].LatencyScoreAvg)
+
+	// Only updated reputations are written
+	rs.RecordSuccess("foo", 0.5)
+	assert.Len(rs.dirty, 1)
+	rs.Flush()
+	dbReps, err := dbh.SelectOrchReputations()
+	require.Nil(err)
+	assert.Equal(int64(2), dbReps[1].Successes)
+}
+
+func TestReputationStore_Reliable(t *testing.T) {
+	assert := assert.New(t)
+
+	rs := NewReputationStore()
+	assert.True(rs.reliable("foo"))
+	_, ok := rs.latencyScore("foo")
+	assert.False(ok)
+
+	for i := int64(0); i < reputationMinSegments-1; i++ {
+		rs.RecordSuccess("foo", 0.5)
+	}
+	rs.RecordFailure("foo", errors.New("some error"))
+	assert.True(rs.reliable("foo"))
+	score, ok := rs.latencyScore("foo")
+	assert.True(ok)
+	assert.Equal(0.5, score)
+
+	// Too few segments to tell
+	rs.RecordFailure("bar", errors.New("some error"))
+	assert.True(rs.reliable("bar"))
+
+	rs.RecordFailure("foo", errors.New("some error"))
+	assert.False(rs.reliable("foo"))
+	_, ok = rs.latencyScore("foo")
+	assert.False(ok)
+
+	// Past failures weigh less over time, so the orchestrator recovers once it succeeds again
+	now := time.Now()
+	rs.now = func() time.Time { return now.Add(4 * reputationHalfLife) }
+	assert.True(rs.reliable("foo"))
+	for i := int64(0); i < reputationMinSegments; i++ {
+		rs.RecordSuccess("foo", 0.5)
+	}
+	assert.True(rs.reliable("foo"))
+	rep, _ := rs.Get("foo")
+	assert.Equal(int64(2), rep.Failures)
+	assert.InDelta(2.0/16, rep.RecentFailures, 0.01)
+}
+
+func TestMinLSSelector_Reputation(t *testing.T) {
+	assert := assert.New(t)
+
+	var sessions []*BroadcastSession
+	for i := 1; i <= 4; i++ {
+		sessions = append(sessions, StubBroadcastSession(fmt.Sprintf("transcoder%d", i)))
+	}
+	rs := NewReputationStore()
+	for i := int64(0); i < reputationMinSegments; i++ {
+		rs.RecordSuccess(latencyHistoryKey(sessions[1]), 0.8)
+		rs.RecordSuccess(latencyHistoryKey(sessions[2]), 0.4)
+		rs.RecordSuccess(latencyHistoryKey(sessions[3]), 2.0)
+	}
+
+	for i := int64(0); i < reputationMinSegments; i++ {
+		rs.RecordFailure(latencyHistoryKey(sessions[0]), errors.New("some error"))
+	}
+
+	sel := NewMinLSSelector(nil, 1.0, nil, nil)
+	sel.reputation = rs
+	sel.Add(sessions)
+
+	// Orchestrators that are unreliable or too slow according to their reputation are left out of the selection,
+	// which otherwise keeps the configured order, unless there are no other orchestrators
+	assert.Equal("transcoder2", sel.Select(context.TODO()).Transcoder())
+	assert.Equal("transcoder3", sel.Select(context.TODO()).Transcoder())
+	assert.Equal("transcoder1", sel.Select(context.TODO()).Transcoder())
+	assert.Equal("transcoder4", sel.Select(context.TODO()).Transcoder())
+	assert.Nil(sel.Select(context.TODO()))
+}
@@ -0,0 +1,481 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/livepeer/go-livepeer/clog"
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-livepeer/monitor"
+	"github.com/livepeer/joy4/av"
+	"github.com/livepeer/joy4/format/rtmp"
+	"github.com/livepeer/joy4/format/ts"
+	"github.com/livepeer/lpms/ffmpeg"
+)
+
+const (
+	restreamQueueSize         = 8
+	restreamTimeout           = 10 * time.Second
+	restreamMinBackoff        = time.Second
+	restreamMaxBackoff        = 30 * time.Second
+	restreamSourceName        = "source"
+	restreamStateDialing      = "connecting"
+	restreamStateLive         = "live"
+	restreamStateReconnecting = "reconnecting"
+)
+
+var errRestreamURL = errors.New("restream destinations must be rtmp:// or rtmps:// URLs")
+var errRestreamProfile = errors.New("unknown restream profile")
+var errRestreamFMP4 = errors.New("fragmented MP4 renditions can't be restreamed")
+var errRestreamExists = errors.New("stream is already pushed to the destination")
+var errRestreamSegment = errors.New("error demuxing segment")
+
+// Number of RTMP destinations that the streams of the node are pushed to
+var restreamTargets int64
+
+// webhookRestreamTarget is an RTMP destination in the auth webhook response, the source is pushed if no profile is set
+type webhookRestreamTarget struct {
+	URL     string `json:"url"`
+	Profile string `json:"profile"`
+}
+
+// restreamConn is the publishing side of an RTMP connection
+type restreamConn interface {
+	WriteHeader(streams []av.CodecData) error
+	WritePacket(pkt av.Packet) error
+	Close() error
+}
+
+// dialRestream connects to the RTMP destination, over TLS for rtmps:// URLs
+var dialRestream = func(u *url.URL) (restreamConn, error) {
+	host := u.Host
+	if u.Port() == "" {
+		if u.Scheme == "rtmps" {
+			host += ":443"
+		} else {
+			host += ":1935"
+		}
+	}
+	dialer := &net.Dialer{Timeout: restreamTimeout}
+	var netconn net.Conn
+	var err error
+	if u.Scheme == "rtmps" {
+		netconn, err = tls.DialWithDialer(dialer, "tcp", host, &tls.Config{ServerName: u.Hostname()})
+	} else {
+		netconn, err = dialer.Dial("tcp", host)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn := rtmp.NewConn(&timeoutConn{Conn: netconn, timeout: restreamTimeout})
+	conn.URL = u
+	return conn, nil
+}
+
+// timeoutConn fails the reads and writes that block for longer than the timeout, so that stalled destinations are
+// reconnected instead of holding up the push
+type timeoutConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *timeoutConn) Read(b []byte) (int, error) {
+	c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+	return c.Conn.Read(b)
+}
+
+func (c *timeoutConn) Write(b []byte) (int, error) {
+	c.Conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	return c.Conn.Write(b)
+}
+
+// parseRestreamTarget checks the destination URL, and that the pushed profile is the source or one of the MPEG-TS
+// renditions of the stream
+func parseRestreamTarget(rawURL, profile string, profiles []ffmpeg.VideoProfile) (core.RestreamTarget, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "rtmp" && u.Scheme != "rtmps") || u.Host == "" {
+		return core.RestreamTarget{}, errRestreamURL
+	}
+	if profile == "" || profile == restreamSourceName {
+		return core.RestreamTarget{URL: rawURL, Profile: restreamSourceName}, nil
+	}
+	for _, p := range profiles {
+		if p.Name != profile {
+			continue
+		}
+		if p.Format == common.FormatFMP4 {
+			return core.RestreamTarget{}, errRestreamFMP4
+		}
+		return core.RestreamTarget{URL: rawURL, Profile: profile}, nil
+	}
+	return core.RestreamTarget{}, errRestreamProfile
+}
+
+func parseRestreamTargets(targets []webhookRestreamTarget, profiles []ffmpeg.VideoProfile) ([]core.RestreamTarget, error) {
+	var res []core.RestreamTarget
+	for _, t := range targets {
+		target, err := parseRestreamTarget(t.URL, t.Profile, profiles)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, target)
+	}
+	return res, nil
+}
+
+// redactRestreamURL hides the stream key of the destination, which is the last element of its path
+func redactRestreamURL(u *url.URL) string {
+	r := *u
+	r.User = nil
+	r.RawQuery = ""
+	r.RawPath = ""
+	if i := strings.LastIndex(r.Path, "/"); i > 0 {
+		r.Path = r.Path[:i+1] + "xxxxx"
+	}
+	return r.String()
+}
+
+// restreamConnection returns the connection of the stream with the external or internal manifest ID
+func (s *LivepeerServer) restreamConnection(mid string) (*rtmpConnection, bool) {
+	s.connectionLock.RLock()
+	defer s.connectionLock.RUnlock()
+	intmid := core.ManifestID(mid)
+	if _intmid, ok := s.internalManifests[intmid]; ok {
+		intmid = _intmid
+	}
+	cxn, ok := s.getActiveRtmpConnectionUnsafe(intmid)
+	return cxn, ok && cxn.restreams != nil
+}
+
+// restreamer pushes the source or the renditions of a stream to RTMP destinations. Segments are pushed in the order
+// they are transcoded, and the destinations are reconnected to with a backoff when the push fails
+type restreamer struct {
+	ctx context.Context
+
+	mu      sync.Mutex
+	pushers map[string]*restreamPusher // by destination URL
+	stopped bool
+}
+
+func newRestreamer(ctx context.Context) *restreamer {
+	return &restreamer{ctx: ctx, pushers: make(map[string]*restreamPusher)}
+}
+
+// add starts pushing the stream to the destination
+func (r *restreamer) add(target core.RestreamTarget) error {
+	u, err := url.Parse(target.URL)
+	if err != nil {
+		return errRestreamURL
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped {
+		return errUnknownStream
+	}
+	if _, ok := r.pushers[target.URL]; ok {
+		return errRestreamExists
+	}
+	ctx, cancel := context.WithCancel(r.ctx)
+	p := &restreamPusher{
+		target:   target,
+		url:      u,
+		redacted: redactRestreamURL(u),
+		queue:    make(chan restreamSegment, restreamQueueSize),
+		cancel:   cancel,
+		state:    restreamStateDialing,
+	}
+	r.pushers[target.URL] = p
+	go p.run(ctx)
+	clog.Infof(ctx, "Started restreaming profile=%s target=%s", target.Profile, p.redacted)
+	restreamTargetsChanged(1)
+	return nil
+}
+
+// remove stops pushing the stream to the destination, and returns whether it was pushed to it
+func (r *restreamer) remove(targetURL string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.pushers[targetURL]
+	if !ok {
+		return false
+	}
+	p.cancel()
+	delete(r.pushers, targetURL)
+	clog.Infof(r.ctx, "Stopped restreaming profile=%s target=%s", p.target.Profile, p.redacted)
+	restreamTargetsChanged(-1)
+	return true
+}
+
+// stop stops pushing the stream to all of its destinations when the stream ends
+func (r *restreamer) stop() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range r.pushers {
+		p.cancel()
+	}
+	restreamTargetsChanged(-len(r.pushers))
+	r.pushers = make(map[string]*restreamPusher)
+	r.stopped = true
+}
+
+// pushes checks whether the profile is pushed to any destination
+func (r *restreamer) pushes(profile string) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range r.pushers {
+		if p.target.Profile == profile {
+			return true
+		}
+	}
+	return false
+}
+
+// push queues the MPEG-TS segment of the profile to the destinations that it is pushed to. Segments are dropped if a
+// destination can't keep up
+func (r *restreamer) push(profile string, seqNo uint64, duration float64, data []byte) {
+	if r == nil || len(data) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range r.pushers {
+		if p.target.Profile != profile {
+			continue
+		}
+		select {
+		case p.queue <- restreamSegment{seqNo: seqNo, duration: duration, data: data}:
+		default:
+			clog.Warningf(r.ctx, "Dropping segment pushed too slowly profile=%s target=%s seqNo=%d", profile, p.redacted, seqNo)
+			p.setError(r.ctx, errors.New("segment queue full"))
+		}
+	}
+}
+
+// status returns the status of the destinations, ordered by URL
+func (r *restreamer) status() []common.RestreamStatus {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var res []common.RestreamStatus
+	for _, p := range r.pushers {
+		res = append(res, p.status())
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Target < res[j].Target })
+	return res
+}
+
+func restreamTargetsChanged(delta int) {
+	targets := atomic.AddInt64(&restreamTargets, int64(delta))
+	if monitor.Enabled {
+		monitor.RestreamTargets(int(targets))
+	}
+}
+
+type restreamSegment struct {
+	seqNo    uint64
+	duration float64
+	data     []byte
+}
+
+// restreamPusher pushes the segments of a profile to a destination over a single RTMP connection at a time
+type restreamPusher struct {
+	target   core.RestreamTarget
+	url      *url.URL
+	redacted string
+	queue    chan restreamSegment
+	cancel   context.CancelFunc
+
+	mu         sync.Mutex
+	state      string
+	bytesSent  uint64
+	reconnects int
+	lastErr    string
+}
+
+func (p *restreamPusher) run(ctx context.Context) {
+	var conn restreamConn
+	var offset time.Duration
+	var lastSeqNo uint64
+	var pushed bool
+	var retryAt time.Time
+	backoff := restreamMinBackoff
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	for {
+		var seg restreamSegment
+		select {
+		case <-ctx.Done():
+			return
+		case seg = <-p.queue:
+		}
+		// Segments that were transcoded late can't be pushed once the following ones were
+		if pushed && seg.seqNo <= lastSeqNo {
+			continue
+		}
+
+		header := false
+		if conn == nil {
+			if time.Now().Before(retryAt) {
+				continue
+			}
+			c, err := dialRestream(p.url)
+			if err != nil {
+				clog.Errorf(ctx, "Error connecting to restream target=%s err=%q", p.redacted, err)
+				p.setError(ctx, err)
+				retryAt = time.Now().Add(backoff)
+				backoff = minDuration(2*backoff, restreamMaxBackoff)
+				continue
+			}
+			if p.reconnecting() && monitor.Enabled {
+				monitor.RestreamReconnected(ctx)
+			}
+			conn, header, offset = c, true, 0
+		}
+
+		sent, err := writeRestreamSegment(conn, header, seg.data, offset)
+		p.sent(ctx, sent)
+		if errors.Is(err, errRestreamSegment) {
+			// The connection is fine, skip the segment
+			clog.Errorf(ctx, "Error restreaming segment target=%s seqNo=%d err=%q", p.redacted, seg.seqNo, err)
+			p.setError(ctx, err)
+		} else if err != nil {
+			clog.Errorf(ctx, "Error pushing to restream target=%s seqNo=%d err=%q", p.redacted, seg.seqNo, err)
+			p.disconnected(ctx, err)
+			conn.Close()
+			conn = nil
+			retryAt = time.Now().Add(backoff)
+			backoff = minDuration(2*backoff, restreamMaxBackoff)
+			continue
+		}
+		offset += time.Duration(seg.duration * float64(time.Second))
+		lastSeqNo, pushed = seg.seqNo, true
+		backoff = restreamMinBackoff
+		p.setState(restreamStateLive)
+	}
+}
+
+// writeRestreamSegment demuxes the MPEG-TS segment and writes its packets, with their timestamps shifted to start at the
+// offset. The header is written with the streams of the first segment that is pushed over the connection
+func writeRestreamSegment(conn restreamConn, header bool, data []byte, offset time.Duration) (int, error) {
+	demuxer := ts.NewDemuxer(bytes.NewReader(data))
+	streams, err := demuxer.Streams()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errRestreamSegment, err)
+	}
+	if header {
+		if err := conn.WriteHeader(streams); err != nil {
+			return 0, err
+		}
+	}
+	sent := 0
+	start := time.Duration(-1)
+	for {
+		pkt, err := demuxer.ReadPacket()
+		if err == io.EOF {
+			return sent, nil
+		}
+		if err != nil {
+			return sent, fmt.Errorf("%w: %v", errRestreamSegment, err)
+		}
+		if start < 0 {
+			start = pkt.Time
+		}
+		pkt.Time -= start
+		if pkt.Time < 0 {
+			pkt.Time = 0
+		}
+		pkt.Time += offset
+		if err := conn.WritePacket(pkt); err != nil {
+			return sent, err
+		}
+		sent += len(pkt.Data)
+	}
+}
+
+func (p *restreamPusher) setError(ctx context.Context, err error) {
+	p.mu.Lock()
+	p.lastErr = err.Error()
+	p.mu.Unlock()
+	if monitor.Enabled {
+		monitor.RestreamError(ctx)
+	}
+}
+
+// disconnected records that the connection to the destination failed, and is reconnected to
+func (p *restreamPusher) disconnected(ctx context.Context, err error) {
+	p.setError(ctx, err)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.state != restreamStateReconnecting {
+		p.state = restreamStateReconnecting
+		p.reconnects++
+	}
+}
+
+func (p *restreamPusher) sent(ctx context.Context, n int) {
+	if n == 0 {
+		return
+	}
+	p.mu.Lock()
+	p.bytesSent += uint64(n)
+	p.mu.Unlock()
+	if monitor.Enabled {
+		monitor.RestreamBytesSent(ctx, n)
+	}
+}
+
+func (p *restreamPusher) reconnecting() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state == restreamStateReconnecting
+}
+
+func (p *restreamPusher) setState(state string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state = state
+}
+
+func (p *restreamPusher) status() common.RestreamStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return common.RestreamStatus{
+		Target:     p.redacted,
+		Profile:    p.target.Profile,
+		State:      p.state,
+		BytesSent:  p.bytesSent,
+		Reconnects: p.reconnects,
+		LastError:  p.lastErr,
+	}
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
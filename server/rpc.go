@@ -49,7 +49,8 @@ type Orchestrator interface {
 	TranscoderSecret() string
 	Sign([]byte) ([]byte, error)
 	VerifySig(ethcommon.Address, string, []byte) bool
-	CheckCapacity(core.ManifestID) error
+	CheckCapacity(context.Context, ethcommon.Address, core.ManifestID, time.Duration) error
+	AdmissionQueueDepth() int
 	TranscodeSeg(context.Context, *core.SegTranscodingMetadata, *stream.HLSSegment) (*core.TranscodeResult, error)
 	ServeTranscoder(stream net.Transcoder_RegisterTranscoderServer, capacity int, capabilities *net.Capabilities)
 	TranscoderResults(job int64, res *core.RemoteTranscoderResult)
@@ -57,6 +58,7 @@ type Orchestrator interface {
 	TicketParams(sender ethcommon.Address, priceInfo *net.PriceInfo) (*net.TicketParams, error)
 	PriceInfo(sender ethcommon.Address, manifestID core.ManifestID) (*net.PriceInfo, error)
 	SufficientBalance(addr ethcommon.Address, manifestID core.ManifestID) bool
+	SufficientPixelRate(addr ethcommon.Address) bool
 	DebitFees(addr ethcommon.Address, manifestID core.ManifestID, price *net.PriceInfo, pixels int64)
 	Capabilities() *net.Capabilities
 	AuthToken(sessionID string, expiration int64) *net.AuthToken
@@ -121,6 +123,15 @@ type BroadcastSession struct {
 	InitialPrice     *net.PriceInfo
 }
 
+// NewBroadcastSession returns a session of the stream with the orchestrator
+func NewBroadcastSession(params *core.StreamParameters, info *net.OrchestratorInfo) *BroadcastSession {
+	return &BroadcastSession{
+		Params:           params,
+		OrchestratorInfo: info,
+		lock:             &sync.RWMutex{},
+	}
+}
+
 func (bs *BroadcastSession) Transcoder() string {
 	bs.lock.RLock()
 	defer bs.lock.RUnlock()
@@ -150,6 +161,7 @@ type ReceivedTranscodeResult struct {
 	*net.TranscodeData
 	Info         *net.OrchestratorInfo
 	LatencyScore float64
+	Latency      time.Duration // Time to upload the segment and receive its transcode results
 }
 
 type lphttp struct {
@@ -365,12 +377,13 @@ func orchestratorInfo(orch Orchestrator, addr ethcommon.Address, serviceURI stri
 	authToken := orch.AuthToken(sessionID, expiration)
 
 	tr := net.OrchestratorInfo{
-		Transcoder:   serviceURI,
-		TicketParams: params,
-		PriceInfo:    priceInfo,
-		Address:      orch.Address().Bytes(),
-		Capabilities: orch.Capabilities(),
-		AuthToken:    authToken,
+		Transcoder:          serviceURI,
+		TicketParams:        params,
+		PriceInfo:           priceInfo,
+		Address:             orch.Address().Bytes(),
+		Capabilities:        orch.Capabilities(),
+		AuthToken:           authToken,
+		AdmissionQueueDepth: uint32(orch.AdmissionQueueDepth()),
 	}
 
 	os := drivers.NodeStorage.NewSession(authToken.SessionId)
@@ -391,7 +404,8 @@ func verifyOrchestratorReq(orch Orchestrator, addr ethcommon.Address, sig []byte
 		glog.Error("orchestrator req sig check failed")
 		return fmt.Errorf("orchestrator req sig check failed")
 	}
-	return orch.CheckCapacity("")
+	// Discovery requests don't wait in the admission queue
+	return orch.CheckCapacity(context.Background(), addr, "", 0)
 }
 
 type discoveryAuthWebhookRes struct {
@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"sync"
 	"testing"
 	"time"
 
@@ -75,6 +76,12 @@ type stubOrchestrator struct {
 	offchain     bool
 	caps         *core.Capabilities
 	authToken    *net.AuthToken
+
+	admissionQueueDepth int
+	// CheckCapacity waits for the admission wait before returning sessCapErr, like an orchestrator at capacity
+	sessCapWait bool
+	capWaitMu   sync.Mutex
+	capWait     time.Duration
 }
 
 func (r *stubOrchestrator) ServiceURI() *url.URL {
@@ -145,6 +152,10 @@ func (r *stubOrchestrator) SufficientBalance(addr ethcommon.Address, manifestID
 	return true
 }
 
+func (r *stubOrchestrator) SufficientPixelRate(addr ethcommon.Address) bool {
+	return true
+}
+
 func (r *stubOrchestrator) DebitFees(addr ethcommon.Address, manifestID core.ManifestID, price *net.PriceInfo, pixels int64) {
 }
 
@@ -173,9 +184,18 @@ func newStubOrchestrator() *stubOrchestrator {
 	return &stubOrchestrator{priv: pk, block: big.NewInt(5)}
 }
 
-func (r *stubOrchestrator) CheckCapacity(mid core.ManifestID) error {
+func (r *stubOrchestrator) CheckCapacity(ctx context.Context, sender ethcommon.Address, mid core.ManifestID, wait time.Duration) error {
+	r.capWaitMu.Lock()
+	r.capWait = wait
+	r.capWaitMu.Unlock()
+	if r.sessCapWait {
+		time.Sleep(wait)
+	}
 	return r.sessCapErr
 }
+func (r *stubOrchestrator) AdmissionQueueDepth() int {
+	return r.admissionQueueDepth
+}
 func (r *stubOrchestrator) ServeTranscoder(stream net.Transcoder_RegisterTranscoderServer, capacity int, capabilities *net.Capabilities) {
 }
 func (r *stubOrchestrator) TranscoderResults(job int64, res *core.RemoteTranscoderResult) {
@@ -819,6 +839,18 @@ func TestGetOrchestrator_GivenValidSig_ReturnsTranscoderURI(t *testing.T) {
 	assert.Equal(uri, oInfo.Transcoder)
 }
 
+func TestGetOrchestrator_ReturnsAdmissionQueueDepth(t *testing.T) {
+	drivers.NodeStorage = drivers.NewMemoryDriver(nil)
+	orch := newStubOrchestrator()
+	orch.admissionQueueDepth = 3
+
+	oInfo, err := orchestratorInfo(orch, ethcommon.Address{}, "http://someuri.com", "")
+
+	assert := assert.New(t)
+	assert.Nil(err)
+	assert.Equal(uint32(3), oInfo.AdmissionQueueDepth)
+}
+
 func TestGetOrchestrator_GivenInvalidSig_ReturnsError(t *testing.T) {
 	orch := &mockOrchestrator{}
 	drivers.NodeStorage = drivers.NewMemoryDriver(nil)
@@ -1318,15 +1350,23 @@ func (o *mockOrchestrator) PriceInfo(sender ethcommon.Address, manifestID core.M
 	return nil, args.Error(1)
 }
 
-func (o *mockOrchestrator) CheckCapacity(mid core.ManifestID) error {
+func (o *mockOrchestrator) CheckCapacity(ctx context.Context, sender ethcommon.Address, mid core.ManifestID, wait time.Duration) error {
 	return nil
 }
 
+func (o *mockOrchestrator) AdmissionQueueDepth() int {
+	return 0
+}
+
 func (o *mockOrchestrator) SufficientBalance(addr ethcommon.Address, manifestID core.ManifestID) bool {
 	args := o.Called(addr, manifestID)
 	return args.Bool(0)
 }
 
+func (o *mockOrchestrator) SufficientPixelRate(addr ethcommon.Address) bool {
+	return true
+}
+
 func (o *mockOrchestrator) DebitFees(addr ethcommon.Address, manifestID core.ManifestID, price *net.PriceInfo, pixels int64) {
 	o.Called(addr, manifestID, price, pixels)
 }
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/livepeer/go-livepeer/clog"
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-livepeer/monitor"
+	"github.com/livepeer/go-livepeer/verification"
+	"github.com/livepeer/lpms/stream"
+)
+
+// A segment in the retry queue is retried at most segmentRetryMaxAttempts times, waiting segmentRetryInitialBackoff
+// before the first retry and doubling the wait up to segmentRetryMaxBackoff after every failed retry
+var segmentRetryMaxAttempts = 5
+var segmentRetryInitialBackoff = 2 * time.Second
+var segmentRetryMaxBackoff = 30 * time.Second
+
+var errRetryQueueFull = errors.New("segment retry queue is full")
+var errSegmentQueued = errors.New("segment is already queued for retries")
+
+// SegmentRetries retries the segments that failed to transcode after MaxAttempts in the background.
+// Failed segments are dropped if it is nil
+var SegmentRetries *SegmentRetryQueue
+
+type segmentTranscoder func(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSegment, name string,
+	verifier *verification.SegmentVerifier, segPar *core.SegmentParameters) ([]string, error)
+
+// SegmentRetryQueue is a bounded queue of segments that failed to transcode. The segments are retried with
+// other orchestrators while they are still in the playlist window, so that their renditions are inserted in the
+// playlist late rather than never. Segments of streams that are recorded are retried regardless of the playlist
+// window, since the late renditions still complete the recording
+type SegmentRetryQueue struct {
+	mu      sync.Mutex
+	size    int
+	pending int
+	streams map[core.ManifestID]*retryStream
+
+	transcode segmentTranscoder
+}
+
+type retryStream struct {
+	latestSeqNo uint64
+	segs        map[uint64]bool
+	done        chan struct{}
+}
+
+// NewSegmentRetryQueue returns a SegmentRetryQueue that holds at most size segments
+func NewSegmentRetryQueue(size int) *SegmentRetryQueue {
+	return &SegmentRetryQueue{
+		size:    size,
+		streams: make(map[core.ManifestID]*retryStream),
+		transcode: func(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSegment, name string,
+			verifier *verification.SegmentVerifier, segPar *core.SegmentParameters) ([]string, error) {
+			urls, _, err := transcodeSegment(ctx, cxn, seg, name, verifier, segPar)
+			return urls, err
+		},
+	}
+}
+
+// Observe records the latest segment of the stream, which moves the playlist window
+func (q *SegmentRetryQueue) Observe(mid core.ManifestID, seqNo uint64) {
+	if q == nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	s := q.stream(mid)
+	if seqNo > s.latestSeqNo {
+		s.latestSeqNo = seqNo
+	}
+}
+
+// Enqueue schedules the retries of a segment that failed to transcode, it returns errSegmentQueued if the segment
+// is already queued and errRetryQueueFull if the queue is full. A nil queue is always full
+func (q *SegmentRetryQueue) Enqueue(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSegment, name string,
+	segPar *core.SegmentParameters) error {
+	if q == nil {
+		return errRetryQueueFull
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	s := q.stream(cxn.mid)
+	if s.segs[seg.SeqNo] {
+		return errSegmentQueued
+	}
+	if q.pending >= q.size {
+		return errRetryQueueFull
+	}
+	q.pending++
+	s.segs[seg.SeqNo] = true
+	if monitor.Enabled {
+		monitor.SegmentRetryQueueSize(q.pending)
+	}
+
+	go q.retry(clog.Clone(context.Background(), ctx), cxn, seg, name, segPar, s.done)
+	return nil
+}
+
+// RemoveStream drops the queued segments of a stream that ended
+func (q *SegmentRetryQueue) RemoveStream(mid core.ManifestID) {
+	if q == nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if s, ok := q.streams[mid]; ok {
+		close(s.done)
+		delete(q.streams, mid)
+	}
+}
+
+// Size returns the number of queued segments
+func (q *SegmentRetryQueue) Size() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pending
+}
+
+func (q *SegmentRetryQueue) retry(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSegment, name string,
+	segPar *core.SegmentParameters, done chan struct{}) {
+
+	defer q.remove(cxn.mid, seg.SeqNo, done)
+
+	backoff := segmentRetryInitialBackoff
+	for attempt := 1; attempt <= segmentRetryMaxAttempts; attempt++ {
+		select {
+		case <-time.After(backoff):
+		case <-done:
+			return
+		}
+		backoff *= 2
+		if backoff > segmentRetryMaxBackoff {
+			backoff = segmentRetryMaxBackoff
+		}
+
+		if !q.inWindow(cxn, seg.SeqNo) {
+			clog.Warningf(ctx, "Dropping segment retry, segment left the playlist window")
+			return
+		}
+		if _, exhausted := Budgets.Exhausted(ctx, cxn.params); exhausted && Budgets.Action() != BudgetActionWebhook {
+			return
+		}
+
+		var sv *verification.SegmentVerifier
+		if Policy != nil {
+			sv = verification.NewSegmentVerifier(Policy)
+		}
+		_, err := q.transcode(ctx, cxn, seg, name, sv, segPar)
+		if err == nil {
+			clog.Infof(ctx, "Transcoded segment on retry attempt=%d", attempt)
+			if monitor.Enabled {
+				monitor.SegmentRetried(ctx, true)
+			}
+			return
+		}
+		clog.Warningf(ctx, "Error retrying segment attempt=%d err=%q", attempt, err)
+		if shouldStopStream(err) || isNonRetryableError(err) || ctx.Err() != nil {
+			break
+		}
+	}
+	if monitor.Enabled {
+		monitor.SegmentRetried(ctx, false)
+	}
+}
+
+// inWindow returns true if the renditions of the segment can still be inserted in the playlist
+func (q *SegmentRetryQueue) inWindow(cxn *rtmpConnection, seqNo uint64) bool {
+	if cxn.pl != nil && cxn.pl.GetRecordOSSession() != nil {
+		return true
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	s, ok := q.streams[cxn.mid]
+	return ok && (seqNo >= s.latestSeqNo || s.latestSeqNo-seqNo < uint64(core.LIVE_LIST_LENGTH))
+}
+
+func (q *SegmentRetryQueue) remove(mid core.ManifestID, seqNo uint64, done chan struct{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.pending--
+	if s, ok := q.streams[mid]; ok && s.done == done {
+		delete(s.segs, seqNo)
+	}
+	if monitor.Enabled {
+		monitor.SegmentRetryQueueSize(q.pending)
+	}
+}
+
+// the caller needs to ensure q.mu is acquired before calling this
+func (q *SegmentRetryQueue) stream(mid core.ManifestID) *retryStream {
+	s, ok := q.streams[mid]
+	if !ok {
+		s = &retryStream{segs: make(map[uint64]bool), done: make(chan struct{})}
+		q.streams[mid] = s
+	}
+	return s
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-livepeer/verification"
+	"github.com/livepeer/lpms/stream"
+	"github.com/stretchr/testify/assert"
+)
+
+func stubRetryTranscoder(results ...error) (segmentTranscoder, func() int) {
+	var mu sync.Mutex
+	calls := 0
+	transcode := func(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSegment, name string,
+		verifier *verification.SegmentVerifier, segPar *core.SegmentParameters) ([]string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		err := results[calls%len(results)]
+		calls++
+		if err != nil {
+			return nil, err
+		}
+		return []string{"url"}, nil
+	}
+	return transcode, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+}
+
+func TestSegmentRetryQueue_Retry(t *testing.T) {
+	assert := assert.New(t)
+
+	oldBackoff, oldMaxBackoff := segmentRetryInitialBackoff, segmentRetryMaxBackoff
+	defer func() { segmentRetryInitialBackoff, segmentRetryMaxBackoff = oldBackoff, oldMaxBackoff }()
+	segmentRetryInitialBackoff, segmentRetryMaxBackoff = time.Millisecond, 2*time.Millisecond
+
+	cxn := &rtmpConnection{mid: "stream1", pl: &stubPlaylistManager{}}
+
+	// The segment is retried until it is transcoded
+	q := NewSegmentRetryQueue(1)
+	var calls func() int
+	q.transcode, calls = stubRetryTranscoder(errors.New("some error"), errors.New("some error"), nil)
+	q.Observe(cxn.mid, 1)
+	assert.Nil(q.Enqueue(context.TODO(), cxn, &stream.HLSSegment{SeqNo: 1}, "name", nil))
+	// The queue is bounded
+	assert.Equal(errRetryQueueFull, q.Enqueue(context.TODO(), cxn, &stream.HLSSegment{SeqNo: 2}, "name", nil))
+	// A queued segment is reported as queued even if the queue is full
+	assert.Equal(errSegmentQueued, q.Enqueue(context.TODO(), cxn, &stream.HLSSegment{SeqNo: 1}, "name", nil))
+	assert.Eventually(func() bool { return q.Size() == 0 }, time.Second, time.Millisecond)
+	assert.Equal(3, calls())
+
+	// Retries are given up after segmentRetryMaxAttempts
+	q = NewSegmentRetryQueue(1)
+	q.transcode, calls = stubRetryTranscoder(errors.New("some error"))
+	assert.Nil(q.Enqueue(context.TODO(), cxn, &stream.HLSSegment{SeqNo: 1}, "name", nil))
+	assert.Eventually(func() bool { return q.Size() == 0 }, time.Second, time.Millisecond)
+	assert.Equal(segmentRetryMaxAttempts, calls())
+
+	// Non-retryable errors are not retried
+	q = NewSegmentRetryQueue(1)
+	q.transcode, calls = stubRetryTranscoder(maxTranscodeAttempts)
+	assert.Nil(q.Enqueue(context.TODO(), cxn, &stream.HLSSegment{SeqNo: 1}, "name", nil))
+	assert.Eventually(func() bool { return q.Size() == 0 }, time.Second, time.Millisecond)
+	assert.Equal(1, calls())
+
+	// A nil queue does not retry anything
+	var nilQueue *SegmentRetryQueue
+	nilQueue.Observe(cxn.mid, 1)
+	assert.Equal(errRetryQueueFull, nilQueue.Enqueue(context.TODO(), cxn, &stream.HLSSegment{SeqNo: 1}, "name", nil))
+	nilQueue.RemoveStream(cxn.mid)
+}
+
+func TestSegmentRetryQueue_Window(t *testing.T) {
+	assert := assert.New(t)
+
+	oldBackoff := segmentRetryInitialBackoff
+	defer func() { segmentRetryInitialBackoff = oldBackoff }()
+	segmentRetryInitialBackoff = 10 * time.Millisecond
+
+	cxn := &rtmpConnection{mid: "stream1", pl: &stubPlaylistManager{}}
+
+	// Segments that left the playlist window are not retried
+	q := NewSegmentRetryQueue(2)
+	var calls func() int
+	q.transcode, calls = stubRetryTranscoder(nil)
+	q.Observe(cxn.mid, 1)
+	assert.Nil(q.Enqueue(context.TODO(), cxn, &stream.HLSSegment{SeqNo: 1}, "name", nil))
+	// Segments are queued once, which is not reported as a full queue
+	assert.Equal(errSegmentQueued, q.Enqueue(context.TODO(), cxn, &stream.HLSSegment{SeqNo: 1}, "name", nil))
+	q.Observe(cxn.mid, 1+uint64(core.LIVE_LIST_LENGTH))
+	assert.Eventually(func() bool { return q.Size() == 0 }, time.Second, time.Millisecond)
+	assert.Zero(calls())
+
+	// Segments of ended streams are not retried
+	q.Observe(cxn.mid, 10)
+	assert.Nil(q.Enqueue(context.TODO(), cxn, &stream.HLSSegment{SeqNo: 10}, "name", nil))
+	q.RemoveStream(cxn.mid)
+	assert.Eventually(func() bool { return q.Size() == 0 }, time.Second, time.Millisecond)
+	assert.Zero(calls())
+}
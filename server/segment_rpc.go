@@ -115,6 +115,12 @@ func (h *lphttp) ServeSegment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !orch.SufficientPixelRate(sender) {
+		clog.Errorf(ctx, "Sender is over the max pixel rate of its priority class")
+		http.Error(w, core.ErrSenderQuota.Error(), http.StatusForbidden)
+		return
+	}
+
 	oInfo, err := orchestratorInfo(orch, sender, orch.ServiceURI().String(), core.ManifestID(segData.AuthToken.SessionId))
 	if err != nil {
 		clog.Errorf(ctx, "Error updating orchestrator info - err=%q", err)
@@ -286,6 +292,8 @@ func makeFfmpegVideoProfiles(protoProfiles []*net.VideoProfile) ([]ffmpeg.VideoP
 		case net.VideoProfile_MPEGTS:
 		case net.VideoProfile_MP4:
 			format = ffmpeg.FormatMP4
+		case net.VideoProfile_FMP4:
+			format = common.FormatFMP4
 		default:
 			return nil, errFormat
 		}
@@ -356,6 +364,7 @@ func verifySegCreds(ctx context.Context, orch Orchestrator, segCreds string, bro
 	if err != nil {
 		return nil, ctx, err
 	}
+	md.Sender = broadcaster
 	ctx = clog.AddManifestID(ctx, string(md.ManifestID))
 
 	if !orch.VerifySig(broadcaster, string(md.Flatten()), segData.Sig) {
@@ -384,7 +393,7 @@ func verifySegCreds(ctx context.Context, orch Orchestrator, segCreds string, bro
 		return nil, ctx, errors.New("expired auth token")
 	}
 
-	if err := orch.CheckCapacity(core.ManifestID(segData.AuthToken.SessionId)); err != nil {
+	if err := orch.CheckCapacity(ctx, broadcaster, core.ManifestID(segData.AuthToken.SessionId), admissionWait(md.Duration)); err != nil {
 		clog.Errorf(ctx, "Cannot process manifest err=%q", err)
 		return nil, ctx, err
 	}
@@ -392,6 +401,26 @@ func verifySegCreds(ctx context.Context, orch Orchestrator, segCreds string, bro
 	return md, ctx, nil
 }
 
+// segUploadTimeout returns the timeout for the segment upload, until HTTP returns OK 200
+func segUploadTimeout(dur time.Duration) time.Duration {
+	uploadTimeout := time.Duration(common.SegUploadTimeoutMultiplier * float64(dur))
+	if uploadTimeout < common.MinSegmentUploadTimeout {
+		uploadTimeout = common.MinSegmentUploadTimeout
+	}
+	return uploadTimeout
+}
+
+// admissionWait returns how long a new session waits for a session slot. Orchestrators only return OK 200 once the
+// session is admitted, so the wait is capped at half of the upload timeout of the broadcaster, which leaves time to
+// upload the segment
+func admissionWait(dur time.Duration) time.Duration {
+	wait := time.Duration(common.AdmissionWaitMultiplier * float64(dur))
+	if max := segUploadTimeout(dur) / 2; wait > max {
+		wait = max
+	}
+	return wait
+}
+
 func SubmitSegment(ctx context.Context, sess *BroadcastSession, seg *stream.HLSSegment, segPar *core.SegmentParameters,
 	nonce uint64, calcPerceptualHash, verified bool) (*ReceivedTranscodeResult, error) {
 
@@ -447,6 +476,7 @@ func SubmitSegment(ctx context.Context, sess *BroadcastSession, seg *stream.HLSS
 
 		return nil, err
 	}
+	Budgets.Record(params, fee)
 
 	// timeout for the whole HTTP call: segment upload, transcoding, reading response
 	httpTimeout := common.HTTPTimeout
@@ -455,18 +485,24 @@ func SubmitSegment(ctx context.Context, sess *BroadcastSession, seg *stream.HLSS
 	if paddedDur > httpTimeout.Seconds() {
 		httpTimeout = time.Duration(paddedDur * float64(time.Second))
 	}
-	// timeout for the segment upload, until HTTP returns OK 200
-	uploadTimeout := time.Duration(common.SegUploadTimeoutMultiplier * seg.Duration * float64(time.Second))
-	if uploadTimeout < common.MinSegmentUploadTimeout {
-		uploadTimeout = common.MinSegmentUploadTimeout
-	}
+	uploadTimeout := segUploadTimeout(time.Duration(seg.Duration * float64(time.Second)))
 	if params.TimeoutMultiplier > 1 {
 		uploadTimeout = time.Duration(params.TimeoutMultiplier) * uploadTimeout
 		httpTimeout = time.Duration(params.TimeoutMultiplier) * httpTimeout
 	}
 
+	abort := submitAbortCh(ctx)
 	ctx, cancel := context.WithTimeout(clog.Clone(context.Background(), ctx), httpTimeout)
 	defer cancel()
+	if abort != nil {
+		go func() {
+			select {
+			case <-abort:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
 
 	ti := sess.OrchestratorInfo
 
@@ -616,14 +652,15 @@ func SubmitSegment(ctx context.Context, sess *BroadcastSession, seg *stream.HLSS
 		TranscodeData: tdata,
 		Info:          tr.Info,
 		LatencyScore:  tookAllDur.Seconds() / segDuration,
+		Latency:       tookAllDur,
 	}, nil
 }
 
 func genSegCreds(sess *BroadcastSession, seg *stream.HLSSegment, segPar *core.SegmentParameters, calcPerceptualHash bool) (string, error) {
 
-	// Send credentials for our own storage
+	// Send credentials for our own storage, unless the segments are encrypted before they are saved there
 	var storage *net.OSInfo
-	if bos := sess.BroadcasterOS; bos != nil && bos.IsExternal() {
+	if bos := sess.BroadcasterOS; bos != nil && bos.IsExternal() && !sess.Params.Encrypt {
 		storage = core.ToNetOSInfo(bos.GetInfo())
 	}
 
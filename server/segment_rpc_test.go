@@ -121,6 +121,50 @@ func TestServeSegment_MismatchHashError(t *testing.T) {
 	assert.Equal("Forbidden", strings.TrimSpace(string(body)))
 }
 
+type overPixelRateOrchestrator struct {
+	*mockOrchestrator
+}
+
+func (o *overPixelRateOrchestrator) SufficientPixelRate(addr ethcommon.Address) bool {
+	return false
+}
+
+func TestServeSegment_OverPixelRate(t *testing.T) {
+	orch := &overPixelRateOrchestrator{&mockOrchestrator{}}
+	handler := serveSegmentHandler(orch)
+
+	orch.On("VerifySig", mock.Anything, mock.Anything, mock.Anything).Return(true)
+	orch.On("AuthToken", mock.Anything, mock.Anything).Return(stubAuthToken)
+
+	s := &BroadcastSession{
+		Broadcaster: stubBroadcaster2(),
+		Params: &core.StreamParameters{
+			ManifestID: core.RandomManifestID(),
+			Profiles:   []ffmpeg.VideoProfile{ffmpeg.P720p30fps16x9},
+		},
+		OrchestratorInfo: &net.OrchestratorInfo{AuthToken: stubAuthToken},
+	}
+	creds, err := genSegCreds(s, &stream.HLSSegment{}, nil, false)
+	require.Nil(t, err)
+
+	orch.On("ProcessPayment", mock.Anything, core.ManifestID(s.OrchestratorInfo.AuthToken.SessionId)).Return(nil)
+	headers := map[string]string{
+		paymentHeader: "",
+		segmentHeader: creds,
+	}
+	resp := httpPostResp(handler, bytes.NewReader([]byte("foo")), headers)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.Nil(t, err)
+
+	// Segments of senders over their pixel rate are rejected before they are transcoded, like capped orchestrators
+	assert := assert.New(t)
+	assert.Equal(http.StatusForbidden, resp.StatusCode)
+	assert.Contains(strings.TrimSpace(string(body)), core.ErrOrchCap.Error())
+	orch.AssertNotCalled(t, "TranscodeSeg", mock.Anything, mock.Anything)
+}
+
 func TestServeSegment_TranscodeSegError(t *testing.T) {
 	orch := &mockOrchestrator{}
 	handler := serveSegmentHandler(orch)
@@ -529,6 +573,11 @@ func TestMakeFfmpegVideoProfiles(t *testing.T) {
 	assert.Equal(ffmpegProfiles[0].Format, ffmpeg.FormatMP4)
 	assert.Equal(ffmpegProfiles[1].Format, ffmpeg.FormatMPEGTS)
 
+	videoProfiles[1].Format = net.VideoProfile_FMP4
+	ffmpegProfiles, err = makeFfmpegVideoProfiles(videoProfiles)
+	assert.Nil(err)
+	assert.Equal(ffmpegProfiles[1].Format, common.FormatFMP4)
+
 	// Invalid format should return error
 	videoProfiles[1].Format = -1
 	ffmpegProfiles, err = makeFfmpegVideoProfiles(videoProfiles)
@@ -2172,6 +2221,43 @@ func TestSubmitSegment_Success(t *testing.T) {
 	balance.AssertCalled(t, "Credit", ratMatcher(change))
 }
 
+func TestSubmitSegment_AdmissionWait(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// The wait is a fraction of the segment duration, capped at half of the upload timeout
+	assert.Equal(100*time.Millisecond, admissionWait(200*time.Millisecond))
+	assert.Equal(time.Second, admissionWait(2*time.Second))
+	assert.Equal(2500*time.Millisecond, admissionWait(10*time.Second))
+	for _, dur := range []time.Duration{0, 200 * time.Millisecond, 2 * time.Second, 10 * time.Second, time.Minute} {
+		assert.Less(admissionWait(dur), segUploadTimeout(dur))
+	}
+
+	orch := &stubOrchestrator{offchain: true, sessCapErr: core.ErrOrchCap, sessCapWait: true}
+	ts, mux := stubTLSServer()
+	defer ts.Close()
+	mux.Handle("/segment", serveSegmentHandler(orch))
+
+	oldUploadTimeout := common.MinSegmentUploadTimeout
+	defer func() { common.MinSegmentUploadTimeout = oldUploadTimeout }()
+	common.MinSegmentUploadTimeout = 200 * time.Millisecond
+
+	// Sessions that aren't admitted are rejected before the broadcaster times out the upload
+	sess := &BroadcastSession{
+		Broadcaster:      stubBroadcaster2(),
+		Params:           &core.StreamParameters{ManifestID: core.RandomManifestID()},
+		OrchestratorInfo: &net.OrchestratorInfo{Transcoder: ts.URL, AuthToken: stubAuthToken},
+	}
+	seg := &stream.HLSSegment{Data: []byte("foo"), Duration: 1}
+	_, err := SubmitSegment(context.Background(), sess, seg, nil, 0, false, true)
+	require.NotNil(err)
+	assert.Equal("OrchestratorCapped", err.Error())
+	orch.capWaitMu.Lock()
+	assert.Equal(250*time.Millisecond, orch.capWait)
+	orch.capWaitMu.Unlock()
+
+}
+
 func TestSendReqWithTimeout(t *testing.T) {
 	assert := assert.New(t)
 
@@ -3,15 +3,34 @@ package server
 import (
 	"container/heap"
 	"context"
+	"math"
 	"math/big"
+	"sort"
+	"strings"
 
 	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/livepeer/go-livepeer/clog"
 	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/core"
 )
 
 const SELECTOR_LATENCY_SCORE_THRESHOLD = 1.0
 
+const (
+	// SelectorMinLS selects sessions based on the latency score of the last segment
+	SelectorMinLS = "minls"
+	// SelectorLatency selects sessions based on a tail percentile of the decayed latency history
+	SelectorLatency = "latency"
+	// SelectorSticky selects the orchestrator that the manifest ID of the stream is consistently hashed onto
+	SelectorSticky = "sticky"
+)
+
+// SessionSelector is the selector used by gateways for new streams, one of SelectorMinLS, SelectorLatency or SelectorSticky
+var SessionSelector = SelectorMinLS
+
+// LatencySelectorPercentile is the percentile of the latency history used by the latency selector
+var LatencySelectorPercentile = 0.9
+
 // BroadcastSessionsSelector selects the next BroadcastSession to use
 type BroadcastSessionsSelector interface {
 	Add(sessions []*BroadcastSession)
@@ -23,6 +42,23 @@ type BroadcastSessionsSelector interface {
 
 type BroadcastSessionsSelectorFactory func() BroadcastSessionsSelector
 
+// newSessionsSelectorFactory returns a factory for the selector configured with SessionSelector
+func newSessionsSelectorFactory(stakeRdr stakeReader, node *core.LivepeerNode) BroadcastSessionsSelectorFactory {
+	switch SessionSelector {
+	case SelectorLatency:
+		return func() BroadcastSessionsSelector {
+			return NewLatencySelector(stakeRdr, OrchLatencyHistory, LatencySelectorPercentile, SELECTOR_LATENCY_SCORE_THRESHOLD, node.SelectionAlgorithm, node.OrchPerfScore)
+		}
+	case SelectorSticky:
+		return func() BroadcastSessionsSelector {
+			return NewStickySelector(node.OrchestratorPool)
+		}
+	}
+	return func() BroadcastSessionsSelector {
+		return NewMinLSSelector(stakeRdr, SELECTOR_LATENCY_SCORE_THRESHOLD, node.SelectionAlgorithm, node.OrchPerfScore)
+	}
+}
+
 type sessHeap []*BroadcastSession
 
 func (h sessHeap) Len() int {
@@ -99,6 +135,7 @@ type MinLSSelector struct {
 	stakeRdr           stakeReader
 	selectionAlgorithm common.SelectionAlgorithm
 	perfScore          *common.PerfScore
+	reputation         *ReputationStore
 
 	minLS float64
 }
@@ -113,6 +150,7 @@ func NewMinLSSelector(stakeRdr stakeReader, minLS float64, selectionAlgorithm co
 		stakeRdr:           stakeRdr,
 		selectionAlgorithm: selectionAlgorithm,
 		perfScore:          perfScore,
+		reputation:         OrchReputation,
 		minLS:              minLS,
 	}
 }
@@ -128,7 +166,8 @@ func (s *MinLSSelector) Complete(sess *BroadcastSession) {
 }
 
 // Select returns the session with the lowest latency score if it is good enough.
-// Otherwise, a session without a latency score yet is returned
+// Otherwise, a session without a latency score yet is returned, leaving out orchestrators
+// whose persisted reputation is not good enough
 func (s *MinLSSelector) Select(ctx context.Context) *BroadcastSession {
 	sess := s.knownSessions.Peek()
 	if sess == nil {
@@ -161,17 +200,23 @@ func (s *MinLSSelector) selectUnknownSession(ctx context.Context) *BroadcastSess
 		return nil
 	}
 
+	candidates := s.reputedSessions()
 	if s.stakeRdr == nil {
 		// Sessions are selected based on the order of unknownSessions in off-chain mode
-		sess := s.unknownSessions[0]
-		s.unknownSessions = s.unknownSessions[1:]
+		sess := candidates[0]
+		for i := range s.unknownSessions {
+			if s.unknownSessions[i] == sess {
+				s.unknownSessions = append(s.unknownSessions[:i], s.unknownSessions[i+1:]...)
+				break
+			}
+		}
 		return sess
 	}
 
 	var addrs []ethcommon.Address
 	prices := map[ethcommon.Address]*big.Rat{}
 	addrCount := make(map[ethcommon.Address]int)
-	for _, sess := range s.unknownSessions {
+	for _, sess := range candidates {
 		if sess.OrchestratorInfo.GetTicketParams() == nil {
 			continue
 		}
@@ -185,7 +230,7 @@ func (s *MinLSSelector) selectUnknownSession(ctx context.Context) *BroadcastSess
 			prices[addr] = big.NewRat(pi.PricePerUnit, pi.PixelsPerUnit)
 		}
 	}
-	maxPrice := BroadcastCfg.MaxPrice()
+	maxPrice := streamMaxPrice(s.unknownSessions[0].Params)
 
 	stakes, err := s.stakeRdr.Stakes(addrs)
 	if err != nil {
@@ -218,12 +263,196 @@ func (s *MinLSSelector) selectUnknownSession(ctx context.Context) *BroadcastSess
 	return nil
 }
 
+// reputedSessions returns the unknown sessions whose orchestrator is neither unreliable nor too slow according
+// to its persisted reputation, or all unknown sessions if there is none, for the selection algorithm to select from
+func (s *MinLSSelector) reputedSessions() []*BroadcastSession {
+	var res []*BroadcastSession
+	for _, sess := range s.unknownSessions {
+		orch := latencyHistoryKey(sess)
+		if !s.reputation.reliable(orch) {
+			continue
+		}
+		if score, ok := s.reputation.latencyScore(orch); ok && score > s.minLS {
+			continue
+		}
+		res = append(res, sess)
+	}
+	if len(res) == 0 {
+		return s.unknownSessions
+	}
+	return res
+}
+
 func (s *MinLSSelector) removeUnknownSession(i int) {
 	n := len(s.unknownSessions)
 	s.unknownSessions[n-1], s.unknownSessions[i] = s.unknownSessions[i], s.unknownSessions[n-1]
 	s.unknownSessions = s.unknownSessions[:n-1]
 }
 
+// LatencySelector selects the next BroadcastSession whose orchestrator has the lowest tail latency score
+// according to the shared LatencyHistory if it is good enough.
+// Otherwise, it selects a session for an orchestrator without any latency history using the MinLSSelector logic
+// LatencySelector is not concurrency safe so the caller is responsible for ensuring safety for concurrent method calls
+type LatencySelector struct {
+	knownSessions []*BroadcastSession
+	unknown       *MinLSSelector
+
+	history    *LatencyHistory
+	percentile float64
+	maxScore   float64
+}
+
+// NewLatencySelector returns an instance of LatencySelector ranking orchestrators on the given percentile of their latency history
+func NewLatencySelector(stakeRdr stakeReader, history *LatencyHistory, percentile, maxScore float64, selectionAlgorithm common.SelectionAlgorithm, perfScore *common.PerfScore) *LatencySelector {
+	return &LatencySelector{
+		unknown:    NewMinLSSelector(stakeRdr, maxScore, selectionAlgorithm, perfScore),
+		history:    history,
+		percentile: percentile,
+		maxScore:   maxScore,
+	}
+}
+
+// Add adds the sessions to the selector, sessions for orchestrators without latency history are selected by the selection algorithm
+func (s *LatencySelector) Add(sessions []*BroadcastSession) {
+	for _, sess := range sessions {
+		if s.history.Known(latencyHistoryKey(sess)) {
+			s.knownSessions = append(s.knownSessions, sess)
+		} else {
+			s.unknown.Add([]*BroadcastSession{sess})
+		}
+	}
+}
+
+// Complete returns the session to the selector
+func (s *LatencySelector) Complete(sess *BroadcastSession) {
+	s.Add([]*BroadcastSession{sess})
+}
+
+// Select returns the session with the lowest tail latency score if it is good enough.
+// Otherwise, a session without latency history is returned
+func (s *LatencySelector) Select(ctx context.Context) *BroadcastSession {
+	best := -1
+	var bestScore float64
+	for i, sess := range s.knownSessions {
+		score, ok := s.history.Score(latencyHistoryKey(sess), s.percentile)
+		if !ok {
+			// No history anymore, should not happen as the history is never pruned
+			score = math.Inf(1)
+		}
+		if best < 0 || score < bestScore {
+			best, bestScore = i, score
+		}
+	}
+
+	if best < 0 || (bestScore > s.maxScore && s.unknown.Size() > 0) {
+		if sess := s.unknown.Select(ctx); sess != nil || best < 0 {
+			return sess
+		}
+	}
+
+	sess := s.knownSessions[best]
+	s.knownSessions = append(s.knownSessions[:best], s.knownSessions[best+1:]...)
+	clog.V(common.DEBUG).Infof(ctx, "Selected orch=%v with latency score p%v=%v", sess.Transcoder(), s.percentile*100, bestScore)
+	return sess
+}
+
+// Size returns the number of sessions stored by the selector
+func (s *LatencySelector) Size() int {
+	return len(s.knownSessions) + s.unknown.Size()
+}
+
+// Clear resets the selector's state
+func (s *LatencySelector) Clear() {
+	s.knownSessions = nil
+	s.unknown.Clear()
+}
+
+// StickySelector selects the next BroadcastSession whose orchestrator the manifest ID of the stream is
+// consistently hashed onto. The orchestrators known by the pool form the hash ring, so that streams are
+// assigned to the same orchestrator by all gateways sharing the same orchestrators, and only the streams of
+// an orchestrator that leaves or the ones taken over by an orchestrator that joins are moved.
+// If the orchestrator is not available, the next one on the ring is selected. Sessions whose orchestrator
+// is not known by the pool are selected last in the order they were added
+// StickySelector is not concurrency safe so the caller is responsible for ensuring safety for concurrent method calls
+type StickySelector struct {
+	sessions []*BroadcastSession
+	pool     common.OrchestratorPool
+
+	// the ring is only rebuilt when the orchestrators known by the pool change
+	ring      *hashRing
+	ringNodes string
+}
+
+// NewStickySelector returns an instance of StickySelector hashing onto the orchestrators known by the pool
+func NewStickySelector(pool common.OrchestratorPool) *StickySelector {
+	return &StickySelector{pool: pool}
+}
+
+// Add adds the sessions to the selector
+func (s *StickySelector) Add(sessions []*BroadcastSession) {
+	s.sessions = append(s.sessions, sessions...)
+}
+
+// Complete returns the session to the selector
+func (s *StickySelector) Complete(sess *BroadcastSession) {
+	s.Add([]*BroadcastSession{sess})
+}
+
+// Select returns the session whose orchestrator comes first on the hash ring for the stream
+func (s *StickySelector) Select(ctx context.Context) *BroadcastSession {
+	if len(s.sessions) == 0 {
+		return nil
+	}
+
+	rank := make(map[string]int)
+	var mid core.ManifestID
+	if s.sessions[0].Params != nil {
+		mid = s.sessions[0].Params.ManifestID
+	}
+	for i, node := range s.hashRing().preference(string(mid)) {
+		rank[node] = i
+	}
+
+	best, bestRank := 0, len(rank)
+	for i, sess := range s.sessions {
+		if r, ok := rank[strings.TrimSuffix(sess.Transcoder(), "/")]; ok && r < bestRank {
+			best, bestRank = i, r
+		}
+	}
+
+	sess := s.sessions[best]
+	s.sessions = append(s.sessions[:best], s.sessions[best+1:]...)
+	clog.V(common.DEBUG).Infof(ctx, "Selected orch=%v with hash ring rank=%v", sess.Transcoder(), bestRank)
+	return sess
+}
+
+// Size returns the number of sessions stored by the selector
+func (s *StickySelector) Size() int {
+	return len(s.sessions)
+}
+
+// Clear resets the selector's state
+func (s *StickySelector) Clear() {
+	s.sessions = nil
+}
+
+func (s *StickySelector) hashRing() *hashRing {
+	var nodes []string
+	if s.pool != nil {
+		for _, info := range s.pool.GetInfos() {
+			if info.URL != nil {
+				nodes = append(nodes, strings.TrimSuffix(info.URL.String(), "/"))
+			}
+		}
+	}
+	sort.Strings(nodes)
+	key := strings.Join(nodes, ",")
+	if s.ring == nil || key != s.ringNodes {
+		s.ring, s.ringNodes = newHashRing(nodes), key
+	}
+	return s.ring
+}
+
 // LIFOSelector selects the next BroadcastSession in LIFO order
 // now used only in tests
 type LIFOSelector []*BroadcastSession
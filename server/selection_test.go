@@ -5,7 +5,9 @@ import (
 	"context"
 	"errors"
 	"math/big"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/livepeer/go-livepeer/core"
 	"github.com/livepeer/go-livepeer/net"
@@ -386,3 +388,131 @@ func TestMinLSSelector_SelectUnknownSession_NilStakeReader(t *testing.T) {
 		i++
 	}
 }
+
+func TestLatencySelector(t *testing.T) {
+	assert := assert.New(t)
+
+	history := NewLatencyHistory(time.Minute, 8)
+	sel := NewLatencySelector(nil, history, 0.9, 1.0, stubSelectionAlgorithm{}, nil)
+	assert.Zero(sel.Size())
+	assert.Nil(sel.Select(context.TODO()))
+
+	fast := StubBroadcastSession("fast")
+	slow := StubBroadcastSession("slow")
+	unknown := StubBroadcastSession("unknown")
+
+	// fast has a good last score but a bad tail latency
+	history.RecordSuccess(latencyHistoryKey(fast), 0.5)
+	history.RecordSuccess(latencyHistoryKey(fast), 3.0)
+	history.RecordSuccess(latencyHistoryKey(fast), 0.4)
+	history.RecordSuccess(latencyHistoryKey(slow), 0.8)
+	history.RecordSuccess(latencyHistoryKey(slow), 0.9)
+
+	sel.Add([]*BroadcastSession{fast, slow, unknown})
+	assert.Equal(3, sel.Size())
+	assert.Len(sel.knownSessions, 2)
+	assert.Equal(1, sel.unknown.Size())
+
+	// Select the session with the best tail latency
+	sess := sel.Select(context.TODO())
+	assert.Same(slow, sess)
+	assert.Equal(2, sel.Size())
+
+	// Remaining known session is not good enough so select the unknown session
+	sess = sel.Select(context.TODO())
+	assert.Same(unknown, sess)
+	assert.Equal(1, sel.Size())
+
+	// Without unknown sessions, fall back to the remaining known session
+	sess = sel.Select(context.TODO())
+	assert.Same(fast, sess)
+	assert.Zero(sel.Size())
+
+	// Failures penalize the score
+	history.RecordFailure(latencyHistoryKey(slow))
+	history.RecordFailure(latencyHistoryKey(slow))
+	history.RecordFailure(latencyHistoryKey(slow))
+	sel.Complete(slow)
+	sel.Complete(unknown)
+	assert.Len(sel.knownSessions, 1)
+	assert.Equal(1, sel.unknown.Size())
+	sess = sel.Select(context.TODO())
+	assert.Same(unknown, sess)
+
+	// Orchestrators with failures only are not explored as unknown orchestrators again
+	failing := StubBroadcastSession("failing")
+	history.RecordFailure(latencyHistoryKey(failing))
+	sel.Add([]*BroadcastSession{failing})
+	assert.Len(sel.knownSessions, 2)
+	assert.Zero(sel.unknown.Size())
+	sess = sel.Select(context.TODO())
+	assert.Same(slow, sess)
+
+	sel.Clear()
+	assert.Zero(sel.Size())
+	assert.Nil(sel.knownSessions)
+}
+
+type stubInfosPool struct {
+	stubDiscovery
+	urls []string
+}
+
+func (p *stubInfosPool) GetInfos() []common.OrchestratorLocalInfo {
+	var infos []common.OrchestratorLocalInfo
+	for _, u := range p.urls {
+		pu, _ := url.Parse(u)
+		infos = append(infos, common.OrchestratorLocalInfo{URL: pu})
+	}
+	return infos
+}
+
+func TestStickySelector(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	urls := []string{"https://o1.example.com", "https://o2.example.com", "https://o3.example.com"}
+	pool := &stubInfosPool{urls: urls}
+	sel := NewStickySelector(pool)
+	assert.Zero(sel.Size())
+	assert.Nil(sel.Select(context.TODO()))
+
+	params := &core.StreamParameters{ManifestID: "stream1"}
+	sessions := make(map[string]*BroadcastSession)
+	var all []*BroadcastSession
+	for _, u := range append(urls, "https://unknown.example.com") {
+		sess := StubBroadcastSession(u + "/")
+		sess.Params = params
+		sessions[u] = sess
+		all = append(all, sess)
+	}
+	pref := newHashRing(urls).preference("stream1")
+	require.Len(pref, 3)
+
+	// The orchestrator the stream is hashed onto is selected whenever it is available
+	sel.Add(all)
+	assert.Equal(4, sel.Size())
+	sess := sel.Select(context.TODO())
+	assert.Same(sessions[pref[0]], sess)
+	sel.Complete(sess)
+	assert.Same(sessions[pref[0]], sel.Select(context.TODO()))
+
+	// Otherwise the next orchestrator on the ring is selected
+	assert.Same(sessions[pref[1]], sel.Select(context.TODO()))
+
+	// The stream is rebalanced once its orchestrator leaves
+	sel.Complete(sessions[pref[0]])
+	sel.Complete(sessions[pref[1]])
+	pool.urls = []string{pref[1], pref[2]}
+	assert.Same(sessions[pref[1]], sel.Select(context.TODO()))
+
+	// Sessions whose orchestrator is not known by the pool are selected last
+	assert.Same(sessions[pref[2]], sel.Select(context.TODO()))
+	assert.Same(sessions["https://unknown.example.com"], sel.Select(context.TODO()))
+	assert.Same(sessions[pref[0]], sel.Select(context.TODO()))
+	assert.Nil(sel.Select(context.TODO()))
+
+	sel.Add(all)
+	sel.Clear()
+	assert.Zero(sel.Size())
+}
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/livepeer/go-livepeer/core"
 	"github.com/livepeer/lpms/stream"
 	"github.com/stretchr/testify/assert"
 )
@@ -51,7 +52,7 @@ func poolWithSessList(sessList []*BroadcastSession) *sessionPoolLIFO {
 		// return sessList, nil
 		return nil, nil
 	}
-	pool := NewSessionPool("test", len(sessList), 1, newSuspender(), createSessions, sel)
+	pool := NewSessionPool(&core.StreamParameters{ManifestID: "test"}, len(sessList), 1, newCircuitBreakers(), createSessions, sel)
 	pool.sessMap = sessMap
 	return newSessionPoolLIFO(pool)
 }
@@ -387,7 +388,7 @@ func TestSelectSession_MultipleInFlight(t *testing.T) {
 	completeSegStub(sess1)
 
 	// send in multiple segments with delay > segDur but < 2*segDur and only a single session available
-	pool.suspend(expectedSess0.OrchestratorInfo.GetTranscoder())
+	pool.breakers.recordFailure(expectedSess0.OrchestratorInfo.GetTranscoder(), core.ErrOrchBusy)
 	pool.removeSession(expectedSess0)
 	assert.Len(pool.sessMap, 1)
 
@@ -408,7 +409,7 @@ func TestSelectSession_MultipleInFlight(t *testing.T) {
 	assert.Len(pool.lastSess[0].SegsInFlight, 0)
 
 	// send in multiple segments with delay > 2*segDur and only a single session available
-	pool.suspend(expectedSess0.OrchestratorInfo.Transcoder)
+	pool.breakers.recordFailure(expectedSess0.OrchestratorInfo.Transcoder, core.ErrOrchBusy)
 	pool.removeSession(expectedSess0)
 	assert.Len(pool.sessMap, 1)
 
@@ -426,8 +427,8 @@ func TestSelectSession_MultipleInFlight(t *testing.T) {
 	completeSegStub(sess0)
 
 	// remove both session and check if selector returns nil and sets lastSession to nil
-	pool.suspend(expectedSess0.OrchestratorInfo.Transcoder)
-	pool.suspend(expectedSess1.OrchestratorInfo.Transcoder)
+	pool.breakers.recordFailure(expectedSess0.OrchestratorInfo.Transcoder, core.ErrOrchBusy)
+	pool.breakers.recordFailure(expectedSess1.OrchestratorInfo.Transcoder, core.ErrOrchBusy)
 	pool.removeSession(expectedSess0)
 	pool.removeSession(expectedSess1)
 	pool.lock.Lock() // refresh session could be running in parallel and modifying sessMap
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	srt "github.com/datarhei/gosrt"
+	"github.com/golang/glog"
+	"github.com/livepeer/go-livepeer/clog"
+	"github.com/livepeer/go-livepeer/monitor"
+	"github.com/livepeer/lpms/ffmpeg"
+	"github.com/livepeer/lpms/stream"
+)
+
+var (
+	errSRTMode        = errors.New("only publishing is supported")
+	errSRTEncrypted   = errors.New("encrypted connections are not enabled")
+	errSRTUnencrypted = errors.New("connection must be encrypted")
+)
+
+// Number of SRT ingest connections
+var srtConnections int64
+
+// StartSRTIngest listens for SRT connections on the UDP address and ingests the MPEG-TS stream sent on each
+// connection like an RTMP stream. The stream ID of a connection is authenticated and mapped to a manifest ID in the
+// same way as the RTMP URL. If the passphrase is set, connections must be encrypted with it, otherwise they must not
+// be encrypted
+func (s *LivepeerServer) StartSRTIngest(ctx context.Context, addr string, latency time.Duration, passphrase string) error {
+	cfg := srt.DefaultConfig()
+	cfg.ReceiverLatency = latency
+	l, err := srt.Listen("srt", addr, cfg)
+	if err != nil {
+		return err
+	}
+	glog.Infof("SRT ingest listening on srt://%v", l.Addr())
+
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+	go func() {
+		for {
+			req, err := l.Accept2()
+			if err != nil {
+				return
+			}
+			go s.handleSRTConnRequest(ctx, req, l.Addr().String(), passphrase)
+		}
+	}()
+	return nil
+}
+
+func (s *LivepeerServer) handleSRTConnRequest(ctx context.Context, req srt.ConnRequest, host, passphrase string) {
+	ctx = clog.AddVal(ctx, clog.ClientIP, req.RemoteAddr().String())
+	reject := func(reason srt.RejectionReason, err error) {
+		clog.Errorf(ctx, "Rejecting SRT connection streamid=%q reason=%d err=%q", req.StreamId(), reason, err)
+		req.Reject(reason)
+		if monitor.Enabled {
+			monitor.SRTConnectionRejected()
+		}
+	}
+
+	switch {
+	case req.IsEncrypted() && passphrase == "":
+		reject(srt.REJ_UNSECURE, errSRTEncrypted)
+		return
+	case !req.IsEncrypted() && passphrase != "":
+		reject(srt.REJ_UNSECURE, errSRTUnencrypted)
+		return
+	case req.IsEncrypted():
+		if err := req.SetPassphrase(passphrase); err != nil {
+			reject(srt.REJ_BADSECRET, err)
+			return
+		}
+	}
+
+	u, err := srtStreamURL(host, req.StreamId())
+	if err != nil {
+		reason := srt.REJX_BAD_REQUEST
+		if errors.Is(err, errSRTMode) {
+			reason = srt.REJX_BAD_MODE
+		}
+		reject(reason, err)
+		return
+	}
+	appData, err := (createRTMPStreamIDHandler(ctx, s, nil))(u)
+	if err != nil {
+		reason := srt.REJX_ISE
+		if errors.Is(err, errForbidden) {
+			reason = srt.REJX_FORBIDDEN
+		}
+		reject(reason, err)
+		return
+	}
+	params := streamParams(appData)
+	params.Format = ffmpeg.FormatMPEGTS
+	for i, v := range params.Profiles {
+		if ffmpeg.FormatNone == v.Format {
+			params.Profiles[i].Format = ffmpeg.FormatMPEGTS
+		}
+	}
+
+	conn, err := req.Accept()
+	if err != nil {
+		clog.Errorf(ctx, "Error accepting SRT connection streamid=%q err=%q", req.StreamId(), err)
+		return
+	}
+	defer conn.Close()
+
+	var stats srt.Statistics
+	conn.Stats(&stats)
+	ctx = clog.AddManifestID(ctx, string(params.ManifestID))
+	clog.Infof(ctx, "Got SRT connection url=%s streamid=%q latency=%dms encrypted=%t", u, req.StreamId(),
+		stats.Instantaneous.MsRecvTsbPdDelay, req.IsEncrypted())
+	if monitor.Enabled {
+		monitor.SRTConnections(int(atomic.AddInt64(&srtConnections, 1)))
+		defer func() {
+			monitor.SRTConnections(int(atomic.AddInt64(&srtConnections, -1)))
+		}()
+	}
+
+	s.ingestSRT(ctx, conn, stream.NewBasicRTMPVideoStream(appData))
+}
+
+// ingestSRT ingests the MPEG-TS stream of the connection until it ends
+func (s *LivepeerServer) ingestSRT(ctx context.Context, conn srt.Conn, st stream.RTMPVideoStream) {
+	var stats, lastStats srt.Statistics
+	err := s.ingestMPEGTS(ctx, conn, st, func() { conn.Close() }, func(ctx context.Context) {
+		if monitor.Enabled {
+			conn.Stats(&stats)
+			cur, last := stats.Accumulated, lastStats.Accumulated
+			monitor.SRTStats(ctx, cur.PktRecv-last.PktRecv, cur.PktRecvLoss-last.PktRecvLoss,
+				cur.PktRecvRetrans-last.PktRecvRetrans, cur.PktRecvDrop-last.PktRecvDrop,
+				time.Duration(stats.Instantaneous.MsRTT*float64(time.Millisecond)))
+			lastStats = stats
+		}
+	})
+
+	conn.Stats(&stats)
+	clog.Infof(ctx, "Ended SRT connection received=%d lost=%d retransmitted=%d dropped=%d undecrypted=%d err=%q",
+		stats.Accumulated.PktRecv, stats.Accumulated.PktRecvLoss, stats.Accumulated.PktRecvRetrans,
+		stats.Accumulated.PktRecvDrop, stats.Accumulated.PktRecvUndecrypt, err)
+}
+
+// srtStreamURL returns the URL of the stream that is authenticated like an RTMP URL. The stream ID of the connection
+// is either the stream path, e.g. `live/<manifestID>?<query>`, or uses the SRT access control syntax, e.g.
+// `#!::r=live/<manifestID>,m=publish`, whose other keys are passed as query parameters
+func srtStreamURL(host, streamID string) (*url.URL, error) {
+	resource, query := streamID, url.Values{}
+	if strings.HasPrefix(streamID, "#!::") {
+		resource = ""
+		for _, kv := range strings.Split(strings.TrimPrefix(streamID, "#!::"), ",") {
+			k, v, _ := strings.Cut(kv, "=")
+			switch k {
+			case "r":
+				resource = v
+			case "m":
+				if v != "publish" {
+					return nil, fmt.Errorf("%w mode=%s", errSRTMode, v)
+				}
+			case "":
+			default:
+				query.Set(k, v)
+			}
+		}
+	} else if r, q, ok := strings.Cut(streamID, "?"); ok {
+		var err error
+		if query, err = url.ParseQuery(q); err != nil {
+			return nil, err
+		}
+		resource = r
+	}
+
+	p := strings.Trim(resource, "/")
+	if !strings.HasPrefix(p, "live/") && !strings.HasPrefix(p, "stream/") {
+		p = "live/" + p
+	}
+	return &url.URL{Scheme: "srt", Host: host, Path: "/" + p, RawQuery: query.Encode()}, nil
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	srt "github.com/datarhei/gosrt"
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSRTStreamURL(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	tests := []struct {
+		streamID string
+		url      string
+		mid      core.ManifestID
+	}{
+		{"", "srt://127.0.0.1:9000/live/", ""},
+		{"abc", "srt://127.0.0.1:9000/live/abc", "abc"},
+		{"live/abc", "srt://127.0.0.1:9000/live/abc", "abc"},
+		{"/stream/abc/", "srt://127.0.0.1:9000/stream/abc", "abc"},
+		{"abc?token=secret", "srt://127.0.0.1:9000/live/abc?token=secret", "abc"},
+		{"#!::r=abc,m=publish", "srt://127.0.0.1:9000/live/abc", "abc"},
+		{"#!::u=user,r=live/abc,token=secret", "srt://127.0.0.1:9000/live/abc?token=secret&u=user", "abc"},
+	}
+	for _, tt := range tests {
+		u, err := srtStreamURL("127.0.0.1:9000", tt.streamID)
+		require.Nil(err, tt.streamID)
+		assert.Equal(tt.url, u.String(), tt.streamID)
+		assert.Equal(tt.mid, parseManifestID(u.Path), tt.streamID)
+	}
+
+	// Only publishing is supported
+	_, err := srtStreamURL("127.0.0.1:9000", "#!::r=abc,m=request")
+	assert.True(errors.Is(err, errSRTMode))
+
+	_, err = srtStreamURL("127.0.0.1:9000", "abc?%zz")
+	assert.NotNil(err)
+}
+
+func TestSRTIngest_Encryption(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, cancel := setupServerWithCancel()
+	defer serverCleanup(s)
+	defer cancel()
+
+	start := func(passphrase string) string {
+		udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.Nil(err)
+		addr := udp.LocalAddr().String()
+		udp.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		require.Nil(s.StartSRTIngest(ctx, addr, 120*time.Millisecond, passphrase))
+		return addr
+	}
+	dial := func(addr, streamID, passphrase string) error {
+		cfg := srt.DefaultConfig()
+		cfg.StreamId = streamID
+		cfg.Passphrase = passphrase
+		conn, err := srt.Dial("srt", addr, cfg)
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}
+	rejected := func(reason srt.RejectionReason) string {
+		return "connection rejected: REJECT (" + strconv.FormatUint(uint64(reason), 32) + ")"
+	}
+
+	// Encrypted connections are refused without a passphrase
+	addr := start("")
+	assert.Nil(dial(addr, "plain", ""))
+	assert.ErrorContains(dial(addr, "encrypted", "passphrase1"), rejected(srt.REJ_UNSECURE))
+	assert.ErrorContains(dial(addr, "#!::r=plain,m=request", ""), rejected(srt.REJX_BAD_MODE))
+
+	// Connections must be encrypted with the passphrase
+	addr = start("passphrase1")
+	assert.Nil(dial(addr, "encrypted", "passphrase1"))
+	assert.ErrorContains(dial(addr, "plain", ""), rejected(srt.REJ_UNSECURE))
+	assert.ErrorContains(dial(addr, "wrong", "passphrase2"), rejected(srt.REJ_BADSECRET))
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/livepeer/go-livepeer/clog"
+	"github.com/livepeer/go-livepeer/common"
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/go-tools/drivers"
+	"github.com/livepeer/lpms/stream"
+	"github.com/livepeer/lpms/vidplayer"
+)
+
+// ThumbnailInterval is the number of source segments between the thumbnails of the streams that don't set their own
+// interval. Thumbnails are disabled if 0
+var ThumbnailInterval uint
+
+var generateThumbnail = core.GenerateThumbnail
+
+// thumbnailDue checks whether a thumbnail is made from the source segment, which is every Nth segment
+func thumbnailDue(cxn *rtmpConnection, seg *stream.HLSSegment) bool {
+	if cxn.params == nil || cxn.params.ThumbnailInterval == 0 || seg.IsZeroFrame {
+		return false
+	}
+	return seg.SeqNo%uint64(cxn.params.ThumbnailInterval) == 0
+}
+
+// saveThumbnail makes the thumbnail of the source segment and saves it to the object store of the stream, where it
+// becomes the latest thumbnail, and to the record store. The thumbnail is only added to the recording once the source
+// segment is, which is signalled by closing `recorded`
+func saveThumbnail(ctx context.Context, cxn *rtmpConnection, seg *stream.HLSSegment, recorded <-chan struct{}) {
+	cpl := cxn.pl
+	start := time.Now()
+	data, err := generateThumbnail(seg.Data)
+	if err != nil {
+		clog.Errorf(ctx, "Error generating thumbnail seqNo=%d err=%q", seg.SeqNo, err)
+		return
+	}
+	clog.V(common.VERBOSE).Infof(ctx, "Generated thumbnail seqNo=%d bytes=%d took=%s", seg.SeqNo, len(data), time.Since(start))
+
+	name := fmt.Sprintf("thumbnails/%d.jpg", seg.SeqNo)
+	if _, err := cpl.GetOSSession().SaveData(ctx, name, bytes.NewReader(data), nil, 0); err != nil {
+		clog.Errorf(ctx, "Error saving thumbnail name=%s err=%q", name, err)
+	} else {
+		cpl.InsertThumbnail(seg.SeqNo, data)
+	}
+
+	ros := cpl.GetRecordOSSession()
+	if ros == nil || recorded == nil {
+		return
+	}
+	ctx, cancel := clog.WithTimeout(context.Background(), ctx, recordSegmentsMaxTimeout)
+	defer cancel()
+	uri, err := drivers.SaveRetried(ctx, ros, name, data, nil, 3)
+	if err != nil {
+		clog.Errorf(ctx, "Error saving thumbnail name=%s to record store err=%q", name, err)
+		return
+	}
+	select {
+	case <-recorded:
+	case <-ctx.Done():
+		return
+	}
+	cpl.InsertThumbnailJSON(cxn.profile, seg.SeqNo, uri)
+	cpl.FlushRecord()
+}
+
+// getThumbnailHandler returns the latest thumbnail of the stream, which is served at /stream/<manifestID>/thumbnail.jpg
+func getThumbnailHandler(s *LivepeerServer) func(url *url.URL) ([]byte, error) {
+	return func(url *url.URL) ([]byte, error) {
+		sid := parseStreamID(url.Path)
+		if sid.Rendition != "thumbnail" || !strings.HasSuffix(url.Path, ".jpg") {
+			return nil, vidplayer.ErrNotFound
+		}
+
+		s.connectionLock.RLock()
+		defer s.connectionLock.RUnlock()
+		cxn, ok := s.getActiveRtmpConnectionUnsafe(sid.ManifestID)
+		if !ok || cxn.pl == nil {
+			return nil, vidplayer.ErrNotFound
+		}
+		data := cxn.pl.GetThumbnail()
+		if data == nil {
+			return nil, vidplayer.ErrNotFound
+		}
+		return data, nil
+	}
+}
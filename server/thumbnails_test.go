@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/livepeer/go-livepeer/core"
+	"github.com/livepeer/lpms/ffmpeg"
+	"github.com/livepeer/lpms/stream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThumbnailDue(t *testing.T) {
+	assert := assert.New(t)
+
+	params := &core.StreamParameters{ThumbnailInterval: 2}
+	cxn := &rtmpConnection{params: params}
+	assert.True(thumbnailDue(cxn, &stream.HLSSegment{SeqNo: 4}))
+	assert.False(thumbnailDue(cxn, &stream.HLSSegment{SeqNo: 5}))
+	assert.False(thumbnailDue(cxn, &stream.HLSSegment{SeqNo: 4, IsZeroFrame: true}))
+
+	params.ThumbnailInterval = 0
+	assert.False(thumbnailDue(cxn, &stream.HLSSegment{SeqNo: 4}))
+	assert.False(thumbnailDue(&rtmpConnection{}, &stream.HLSSegment{SeqNo: 4}))
+}
+
+func TestSaveThumbnail(t *testing.T) {
+	assert := assert.New(t)
+
+	oldGenerateThumbnail := generateThumbnail
+	defer func() { generateThumbnail = oldGenerateThumbnail }()
+	generateThumbnail = func(data []byte) ([]byte, error) { return append([]byte("thumb_"), data...), nil }
+
+	bcastOS := &stubOSSession{host: "test://broad.com"}
+	pl := &stubPlaylistManager{os: bcastOS}
+	cxn := &rtmpConnection{pl: pl, profile: &ffmpeg.P144p30fps16x9}
+
+	saveThumbnail(context.Background(), cxn, &stream.HLSSegment{SeqNo: 4, Data: []byte("seg4")}, nil)
+	assert.Equal([]string{"thumbnails/4.jpg"}, bcastOS.saved)
+	assert.Equal([]byte("thumb_seg4"), pl.GetThumbnail())
+
+	// Thumbnails are added to the recording once their segment is
+	recordOS := &stubOSSession{host: "test://record.com"}
+	pl.recordOS = recordOS
+	recorded := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		saveThumbnail(context.Background(), cxn, &stream.HLSSegment{SeqNo: 6, Data: []byte("seg6")}, recorded)
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(pl.thumbnailURIs)
+	close(recorded)
+	<-done
+	assert.Equal([]string{"thumbnails/6.jpg"}, recordOS.saved)
+	assert.Equal(map[uint64]string{6: "saved_thumbnails/6.jpg"}, pl.thumbnailURIs)
+	assert.Equal([]byte("thumb_seg6"), pl.GetThumbnail())
+
+	// Thumbnails that can't be made are skipped
+	generateThumbnail = func(data []byte) ([]byte, error) { return nil, errors.New("no frames") }
+	saveThumbnail(context.Background(), cxn, &stream.HLSSegment{SeqNo: 8, Data: []byte("seg8")}, nil)
+	assert.Len(bcastOS.saved, 2)
+	assert.Equal([]byte("thumb_seg6"), pl.GetThumbnail())
+}
@@ -49,6 +49,11 @@ func (s *LivepeerServer) cliWebServerHandlers(bindAddr string) *http.ServeMux {
 	mux.Handle("/setBroadcastConfig", mustHaveFormParams(setBroadcastConfigHandler()))
 	mux.Handle("/getBroadcastConfig", getBroadcastConfigHandler())
 	mux.Handle("/getAvailableTranscodingOptions", getAvailableTranscodingOptionsHandler())
+	mux.Handle("/circuitBreakers", circuitBreakersHandler(orchCircuitBreakers))
+	mux.Handle("/orchestratorReputation", orchestratorReputationHandler(OrchReputation))
+	mux.Handle("/addRestream", mustHaveFormParams(s.addRestreamHandler(), "manifestID", "url"))
+	mux.Handle("/removeRestream", mustHaveFormParams(s.removeRestreamHandler(), "manifestID", "url"))
+	mux.Handle("/segmentProgress", mustHaveFormParams(s.segmentProgressHandler(), "manifestID"))
 
 	// Rounds
 	mux.Handle("/currentRound", currentRoundHandler(client))
@@ -61,6 +66,7 @@ func (s *LivepeerServer) cliWebServerHandlers(bindAddr string) *http.ServeMux {
 	mux.Handle("/setMaxFaceValue", mustHaveFormParams(s.setMaxFaceValueHandler(), "maxfacevalue"))
 	mux.Handle("/setPriceForBroadcaster", mustHaveFormParams(s.setPriceForBroadcaster(), "pricePerUnit", "pixelsPerUnit", "broadcasterEthAddr"))
 	mux.Handle("/setMaxSessions", mustHaveFormParams(s.setMaxSessions(), "maxSessions"))
+	mux.Handle("/drain", mustHaveFormParams(s.drainHandler()))
 
 	// Bond, withdraw, reward
 	mux.Handle("/bond", mustHaveFormParams(bondHandler(client), "amount", "toAddr"))
@@ -0,0 +1,380 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livepeer/go-livepeer/clog"
+	"github.com/livepeer/lpms/ffmpeg"
+	"github.com/livepeer/lpms/stream"
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v4"
+	"github.com/pion/webrtc/v4/pkg/media/samplebuilder"
+)
+
+const (
+	whipMaxOfferSize = 64 * 1024
+	// Number of packets that the samples of a track are reordered over
+	whipMaxLate = 256
+	// Minimum interval between keyframe requests
+	whipKeyframeRequestInterval = time.Second
+)
+
+// WHIPPublicIPs are the IPs advertised in the ICE candidates of WHIP sessions, e.g. if the broadcaster is behind NAT
+var WHIPPublicIPs []string
+
+var errNoWHIPMedia = errors.New("H.264 video and Opus audio are required")
+
+// whipSession is the WebRTC session of a WHIP publisher. The media of its tracks is muxed to an MPEG-TS stream that is
+// ingested like an SRT stream
+type whipSession struct {
+	id     string
+	pc     *webrtc.PeerConnection
+	start  time.Time
+	segLen time.Duration
+
+	mu  sync.Mutex
+	mux *mpegtsMuxer
+	r   *io.PipeReader
+	w   *io.PipeWriter
+
+	closeOnce sync.Once
+}
+
+// HandleWHIP negotiates the WebRTC sessions of WHIP publishers. The SDP offer that is POSTed to /whip/<stream> is
+// authenticated like the RTMP URL /live/<stream>, and the session ends once the publisher disconnects or DELETEs the
+// resource URL returned in the Location header. The stream name can also be sent as bearer token, which WHIP clients
+// use for the stream key
+func (s *LivepeerServer) HandleWHIP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	switch r.Method {
+	case http.MethodOptions:
+		w.Header().Set("Access-Control-Allow-Methods", "POST, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		w.Header().Set("Access-Control-Expose-Headers", "Location")
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodPost:
+		s.startWHIPSession(w, r)
+	case http.MethodDelete:
+		s.connectionLock.RLock()
+		sess, ok := s.whipSessions[path.Base(r.URL.Path)]
+		s.connectionLock.RUnlock()
+		if !ok {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		sess.close()
+		w.WriteHeader(http.StatusOK)
+	default:
+		// Trickle ICE and ICE restarts are not supported
+		w.Header().Set("Allow", "POST, DELETE, OPTIONS")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *LivepeerServer) startWHIPSession(w http.ResponseWriter, r *http.Request) {
+	ctx := clog.AddVal(r.Context(), clog.ClientIP, getRemoteAddr(r))
+	errorOut := func(status int, format string, args ...interface{}) {
+		msg := fmt.Sprintf(format, args...)
+		clog.Errorf(ctx, "WHIP error status=%d err=%q", status, msg)
+		http.Error(w, msg, status)
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/sdp" {
+		errorOut(http.StatusUnsupportedMediaType, "Unsupported content type=%s", r.Header.Get("Content-Type"))
+		return
+	}
+	offer, err := io.ReadAll(http.MaxBytesReader(w, r.Body, whipMaxOfferSize))
+	if err != nil {
+		errorOut(http.StatusBadRequest, "Error reading offer err=%q", err)
+		return
+	}
+
+	u := whipStreamURL(r)
+	appData, err := (createRTMPStreamIDHandler(ctx, s, nil))(u)
+	if err != nil {
+		if errors.Is(err, errForbidden) {
+			errorOut(http.StatusForbidden, "Could not create stream ID: url=%s", u)
+		} else {
+			errorOut(http.StatusInternalServerError, "Could not create stream ID: url=%s", u)
+		}
+		return
+	}
+	params := streamParams(appData)
+	params.Format = ffmpeg.FormatMPEGTS
+	for i, v := range params.Profiles {
+		if ffmpeg.FormatNone == v.Format {
+			params.Profiles[i].Format = ffmpeg.FormatMPEGTS
+		}
+	}
+	ctx = clog.AddManifestID(ctx, string(params.ManifestID))
+	s.connectionLock.RLock()
+	_, exists := s.getActiveRtmpConnectionUnsafe(params.ManifestID)
+	s.connectionLock.RUnlock()
+	if exists {
+		errorOut(http.StatusConflict, "Stream already exists manifestID=%s", params.ManifestID)
+		return
+	}
+
+	sess, answer, err := newWHIPSession(string(offer), streamSegLen(params))
+	if err != nil {
+		errorOut(http.StatusBadRequest, "Error negotiating WebRTC session err=%q", err)
+		return
+	}
+	ctx = clog.AddVal(ctx, "whip_session", sess.id)
+	clog.Infof(ctx, "Got WHIP session url=%s", u)
+
+	s.connectionLock.Lock()
+	s.whipSessions[sess.id] = sess
+	s.connectionLock.Unlock()
+
+	ingestCtx := clog.Clone(context.Background(), ctx)
+	go func() {
+		err := s.ingestMPEGTS(ingestCtx, sess.r, stream.NewBasicRTMPVideoStream(appData), sess.close, nil)
+		sess.close()
+		s.connectionLock.Lock()
+		delete(s.whipSessions, sess.id)
+		s.connectionLock.Unlock()
+		clog.Infof(ingestCtx, "Ended WHIP session err=%q", err)
+	}()
+
+	w.Header().Set("Access-Control-Expose-Headers", "Location")
+	w.Header().Set("Content-Type", "application/sdp")
+	w.Header().Set("Location", path.Join(r.URL.Path, sess.id))
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(answer))
+}
+
+// newWHIPSession creates the WebRTC session of the offer and returns the answer, once the ICE candidates are gathered
+// since trickle ICE is not supported. Keyframes are requested at least every segment length
+func newWHIPSession(offer string, segLen time.Duration) (*whipSession, string, error) {
+	api, err := newWHIPAPI()
+	if err != nil {
+		return nil, "", err
+	}
+	pc, err := api.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		return nil, "", err
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		pc.Close()
+		return nil, "", err
+	}
+	sess := &whipSession{
+		id:     hex.EncodeToString(id),
+		pc:     pc,
+		start:  time.Now(),
+		segLen: segLen,
+	}
+	sess.r, sess.w = io.Pipe()
+	pc.OnTrack(func(track *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+		sess.readTrack(track)
+	})
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		if state == webrtc.PeerConnectionStateFailed || state == webrtc.PeerConnectionStateClosed {
+			go sess.close()
+		}
+	})
+
+	answer, err := negotiate(pc, offer)
+	if err != nil {
+		pc.Close()
+		return nil, "", err
+	}
+	video, audio, err := whipMedia(pc)
+	if err != nil {
+		pc.Close()
+		return nil, "", err
+	}
+	// Media only flows once the publisher got the answer
+	sess.mux = newMPEGTSMuxer(sess.w, video, audio)
+	return sess, answer, nil
+}
+
+func negotiate(pc *webrtc.PeerConnection, offer string) (string, error) {
+	if err := pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: offer}); err != nil {
+		return "", err
+	}
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		return "", err
+	}
+	gatherComplete := webrtc.GatheringCompletePromise(pc)
+	if err := pc.SetLocalDescription(answer); err != nil {
+		return "", err
+	}
+	<-gatherComplete
+	return pc.LocalDescription().SDP, nil
+}
+
+// whipMedia returns whether video and audio are received, which are the media sections that are accepted by the answer
+func whipMedia(pc *webrtc.PeerConnection) (bool, bool, error) {
+	desc, err := pc.LocalDescription().Unmarshal()
+	if err != nil {
+		return false, false, err
+	}
+	var video, audio bool
+	for _, m := range desc.MediaDescriptions {
+		_, recvonly := m.Attribute(webrtc.RTPTransceiverDirectionRecvonly.String())
+		_, sendrecv := m.Attribute(webrtc.RTPTransceiverDirectionSendrecv.String())
+		if m.MediaName.Port.Value == 0 || !(recvonly || sendrecv) {
+			continue
+		}
+		switch m.MediaName.Media {
+		case "video":
+			video = true
+		case "audio":
+			audio = true
+		}
+	}
+	// The segmenter needs a video and an audio track, like for RTMP streams
+	if !video || !audio {
+		return false, false, errNoWHIPMedia
+	}
+	return video, audio, nil
+}
+
+// newWHIPAPI returns the WebRTC API that only accepts the codecs that can be muxed to MPEG-TS
+func newWHIPAPI() (*webrtc.API, error) {
+	m := &webrtc.MediaEngine{}
+	videoFeedback := []webrtc.RTCPFeedback{{Type: "goog-remb"}, {Type: "ccm", Parameter: "fir"}, {Type: "nack"}, {Type: "nack", Parameter: "pli"}}
+	for i, profileLevelID := range []string{"42001f", "42e01f", "4d001f", "64001f"} {
+		err := m.RegisterCodec(webrtc.RTPCodecParameters{
+			RTPCodecCapability: webrtc.RTPCodecCapability{
+				MimeType:     webrtc.MimeTypeH264,
+				ClockRate:    90000,
+				SDPFmtpLine:  "level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=" + profileLevelID,
+				RTCPFeedback: videoFeedback,
+			},
+			PayloadType: webrtc.PayloadType(102 + 2*i),
+		}, webrtc.RTPCodecTypeVideo)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := m.RegisterCodec(webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 2, SDPFmtpLine: "minptime=10;useinbandfec=1"},
+		PayloadType:        111,
+	}, webrtc.RTPCodecTypeAudio)
+	if err != nil {
+		return nil, err
+	}
+
+	i := &interceptor.Registry{}
+	if err := webrtc.RegisterDefaultInterceptors(m, i); err != nil {
+		return nil, err
+	}
+	se := webrtc.SettingEngine{}
+	if len(WHIPPublicIPs) > 0 {
+		se.SetNAT1To1IPs(WHIPPublicIPs, webrtc.ICECandidateTypeHost)
+	}
+	return webrtc.NewAPI(webrtc.WithMediaEngine(m), webrtc.WithInterceptorRegistry(i), webrtc.WithSettingEngine(se)), nil
+}
+
+// readTrack muxes the samples of the track until the session ends. The timestamps of the samples are relative to the
+// start of the session, using the arrival time of the first sample of the track to synchronize the tracks
+func (sess *whipSession) readTrack(track *webrtc.TrackRemote) {
+	var depacketizer rtp.Depacketizer
+	video := false
+	switch {
+	case strings.EqualFold(track.Codec().MimeType, webrtc.MimeTypeH264):
+		depacketizer, video = &codecs.H264Packet{}, true
+	case strings.EqualFold(track.Codec().MimeType, webrtc.MimeTypeOpus):
+		depacketizer = &codecs.OpusPacket{}
+	default:
+		return
+	}
+	clockRate := int64(track.Codec().ClockRate)
+	sb := samplebuilder.New(whipMaxLate, depacketizer, track.Codec().ClockRate)
+
+	var started bool
+	var lastTimestamp uint32
+	var pts, lastKeyframePTS int64
+	lastKeyframeRequest := time.Time{}
+	requestKeyframe := func() {
+		if time.Since(lastKeyframeRequest) < whipKeyframeRequestInterval {
+			return
+		}
+		lastKeyframeRequest = time.Now()
+		sess.pc.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: uint32(track.SSRC())}})
+	}
+
+	for {
+		pkt, _, err := track.ReadRTP()
+		if err != nil {
+			return
+		}
+		sb.Push(pkt)
+		for sample := sb.Pop(); sample != nil; sample = sb.Pop() {
+			if !started {
+				started = true
+				pts = int64(time.Since(sess.start).Seconds() * ptsClockRate)
+				lastKeyframePTS = -1
+			} else {
+				pts += int64(sample.PacketTimestamp-lastTimestamp) * ptsClockRate / clockRate
+			}
+			lastTimestamp = sample.PacketTimestamp
+
+			sess.mu.Lock()
+			if video {
+				err = sess.mux.writeVideo(pts, sample.Data)
+			} else {
+				err = sess.mux.writeAudio(pts, sample.Data)
+			}
+			sess.mu.Unlock()
+			if err != nil {
+				return
+			}
+
+			if !video {
+				continue
+			}
+			// Request keyframes to recover from losses and to cut segments once they last long enough
+			if containsKeyframe(sample.Data) {
+				lastKeyframePTS = pts
+			}
+			if lastKeyframePTS < 0 || sample.PrevDroppedPackets > 0 || pts-lastKeyframePTS >= int64(sess.segLen.Seconds()*ptsClockRate) {
+				requestKeyframe()
+			}
+		}
+	}
+}
+
+// close ends the session. The pipe is closed first to unblock the tracks that are writing to it, since close is also
+// called by its reader
+func (sess *whipSession) close() {
+	sess.closeOnce.Do(func() {
+		sess.w.Close()
+		sess.pc.Close()
+	})
+}
+
+// whipStreamURL returns the URL of the stream that is authenticated like an RTMP URL, which is /live/<stream> for
+// /whip/<stream>. The bearer token is the stream name if the URL has none
+func whipStreamURL(r *http.Request) *url.URL {
+	p := strings.Trim(strings.TrimPrefix(r.URL.Path, "/whip"), "/")
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && p == "" {
+		p = token
+	}
+	if !strings.HasPrefix(p, "live/") && !strings.HasPrefix(p, "stream/") {
+		p = "live/" + p
+	}
+	u := *r.URL
+	u.Path, u.RawPath = "/"+p, ""
+	return &u
+}
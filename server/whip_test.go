@@ -0,0 +1,169 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// whipTestPublisher returns a peer connection that publishes H.264 video and Opus audio, and its offer
+func whipTestPublisher(t *testing.T) (*webrtc.PeerConnection, string) {
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	require.Nil(t, err)
+	t.Cleanup(func() { pc.Close() })
+
+	for _, c := range []webrtc.RTPCodecCapability{
+		{MimeType: webrtc.MimeTypeH264, ClockRate: 90000},
+		{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 2},
+	} {
+		track, err := webrtc.NewTrackLocalStaticSample(c, "track", "whip")
+		require.Nil(t, err)
+		_, err = pc.AddTransceiverFromTrack(track, webrtc.RTPTransceiverInit{Direction: webrtc.RTPTransceiverDirectionSendonly})
+		require.Nil(t, err)
+	}
+	offer, err := pc.CreateOffer(nil)
+	require.Nil(t, err)
+	gatherComplete := webrtc.GatheringCompletePromise(pc)
+	require.Nil(t, pc.SetLocalDescription(offer))
+	<-gatherComplete
+	return pc, pc.LocalDescription().SDP
+}
+
+func whipRequest(method, target, offer string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(offer))
+	req.Header.Set("Content-Type", "application/sdp")
+	return req
+}
+
+func TestWHIP_Session(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, cancel := setupServerWithCancel()
+	defer serverCleanup(s)
+	defer cancel()
+
+	var webhookURLs []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req authWebhookReq
+		assert.Nil(json.NewDecoder(r.Body).Decode(&req))
+		webhookURLs = append(webhookURLs, req.URL)
+		val, err := json.Marshal(authWebhookResponse{ManifestID: "whip"})
+		assert.Nil(err)
+		w.Write(val)
+	}))
+	defer ts.Close()
+	oldURL := AuthWebhookURL
+	defer func() { AuthWebhookURL = oldURL }()
+	AuthWebhookURL = mustParseUrl(t, ts.URL)
+
+	pc, offer := whipTestPublisher(t)
+
+	// Offers must be SDP
+	w := httptest.NewRecorder()
+	req := whipRequest("POST", "/whip/stream", offer)
+	req.Header.Set("Content-Type", "application/json")
+	s.HandleWHIP(w, req)
+	assert.Equal(http.StatusUnsupportedMediaType, w.Code)
+
+	// Invalid offers are rejected
+	w = httptest.NewRecorder()
+	s.HandleWHIP(w, whipRequest("POST", "/whip/stream", "invalid"))
+	assert.Equal(http.StatusBadRequest, w.Code)
+
+	// The stream name is taken from the bearer token if the URL has none
+	w = httptest.NewRecorder()
+	req = whipRequest("POST", "/whip/?token=secret", offer)
+	req.Header.Set("Authorization", "Bearer streamkey")
+	s.HandleWHIP(w, req)
+	resp := w.Result()
+	require.Equal(http.StatusCreated, resp.StatusCode)
+	assert.Equal([]string{"/live/stream", "/live/streamkey?token=secret"}, webhookURLs)
+	assert.Equal("application/sdp", resp.Header.Get("Content-Type"))
+	assert.Equal("*", resp.Header.Get("Access-Control-Allow-Origin"))
+	answer, err := io.ReadAll(resp.Body)
+	require.Nil(err)
+	assert.Nil(pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: string(answer)}))
+
+	location := resp.Header.Get("Location")
+	require.True(strings.HasPrefix(location, "/whip/"))
+	s.connectionLock.RLock()
+	assert.Len(s.whipSessions, 1)
+	s.connectionLock.RUnlock()
+
+	// The session ends once its resource is deleted
+	w = httptest.NewRecorder()
+	s.HandleWHIP(w, httptest.NewRequest("DELETE", location, nil))
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Eventually(func() bool {
+		s.connectionLock.RLock()
+		defer s.connectionLock.RUnlock()
+		return len(s.whipSessions) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	w = httptest.NewRecorder()
+	s.HandleWHIP(w, httptest.NewRequest("DELETE", location, nil))
+	assert.Equal(http.StatusNotFound, w.Code)
+
+	// Trickle ICE is not supported
+	w = httptest.NewRecorder()
+	s.HandleWHIP(w, httptest.NewRequest("PATCH", location, nil))
+	assert.Equal(http.StatusMethodNotAllowed, w.Code)
+
+	// Browsers check the CORS headers before publishing
+	w = httptest.NewRecorder()
+	s.HandleWHIP(w, httptest.NewRequest("OPTIONS", "/whip/stream", nil))
+	assert.Equal(http.StatusNoContent, w.Code)
+	assert.Equal("Location", w.Header().Get("Access-Control-Expose-Headers"))
+}
+
+func TestWHIP_Forbidden(t *testing.T) {
+	assert := assert.New(t)
+
+	s, cancel := setupServerWithCancel()
+	defer serverCleanup(s)
+	defer cancel()
+
+	oldURL := AuthWebhookURL
+	defer func() { AuthWebhookURL = oldURL }()
+	AuthWebhookURL = &url.URL{Path: "notaurl"}
+
+	_, offer := whipTestPublisher(t)
+	w := httptest.NewRecorder()
+	s.HandleWHIP(w, whipRequest("POST", "/whip/stream", offer))
+	assert.Equal(http.StatusForbidden, w.Code)
+	assert.Empty(s.whipSessions)
+}
+
+func TestWHIPStreamURL(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		target string
+		token  string
+		url    string
+	}{
+		{"/whip/abc", "", "/live/abc"},
+		{"/whip/abc/", "key", "/live/abc"},
+		{"/whip/live/abc?token=secret", "", "/live/abc?token=secret"},
+		{"/whip/stream/abc", "", "/stream/abc"},
+		{"/whip/", "key", "/live/key"},
+		{"/whip", "", "/live/"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", tt.target, nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		assert.Equal(tt.url, whipStreamURL(req).RequestURI(), tt.target)
+	}
+}